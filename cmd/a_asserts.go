@@ -10,6 +10,7 @@ func assertRunWithErrorMessage(toExecute func() error, exitCode int, msg string,
 	if err != nil {
 		args = append(args, "error", err.Error())
 		slog.Error(msg, args...)
+		closeFixtureRecorder()
 		os.Exit(exitCode)
 	}
 }
@@ -19,6 +20,7 @@ func assertRunWithParamAndErrorMessage[T any](toExecute func(T) error, param T,
 	if err != nil {
 		args = append(args, "error", err.Error())
 		slog.Error(msg, args...)
+		closeFixtureRecorder()
 		os.Exit(exitCode)
 	}
 }
@@ -28,6 +30,7 @@ func assertRunWithResultAndErrorMessage[T any](toExecute func() (T, error), exit
 	if err != nil {
 		args = append(args, "error", err.Error())
 		slog.Error(msg, args...)
+		closeFixtureRecorder()
 		os.Exit(exitCode)
 	}
 	return result
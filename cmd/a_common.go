@@ -12,7 +12,10 @@ import (
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/configuration"
 	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/core"
 	collector_engines "github.com/mavryk-network/mavpay/engines/collector"
+	journal_engines "github.com/mavryk-network/mavpay/engines/journal"
+	replay_engines "github.com/mavryk-network/mavpay/engines/replay"
 	signer_engines "github.com/mavryk-network/mavpay/engines/signer"
 	transactor_engines "github.com/mavryk-network/mavpay/engines/transactor"
 	"github.com/mavryk-network/mavpay/extension"
@@ -32,6 +35,19 @@ func (cae *configurationAndEngines) Unwrap() (*configuration.RuntimeConfiguratio
 	return cae.Configuration, cae.Collector, cae.Signer, cae.Transactor
 }
 
+// fixtureRecorder is set when engine calls are recorded, it has to be closed before exiting
+var fixtureRecorder *replay_engines.FixtureRecorder
+
+func closeFixtureRecorder() {
+	if fixtureRecorder == nil {
+		return
+	}
+	if err := fixtureRecorder.Close(); err != nil {
+		slog.Warn("failed to close fixture file", "error", err.Error())
+	}
+	fixtureRecorder = nil
+}
+
 func loadConfigurationEnginesExtensions() (*configurationAndEngines, error) {
 	config, err := configuration.Load()
 	if err != nil {
@@ -45,16 +61,43 @@ func loadConfigurationEnginesExtensions() (*configurationAndEngines, error) {
 			return nil, errors.Join(constants.ErrSignerLoadFailed, err)
 		}
 	}
-	// for testing point transactor to testnet
-	// transactorEngine, err := clients.InitDefaultTransactor("https://basenet.rpc.mavryk.network/", "https://basenet.api.mavryk.network/") // (config.Network.RpcUrl, config.Network.MvktUrl)
-	transactorEngine, err := transactor_engines.InitDefaultTransactor(config)
-	if err != nil {
-		return nil, errors.Join(constants.ErrTransactorLoadFailed, err)
+	if config.PayoutConfiguration.SigningPolicy != nil {
+		signerEngine = signer_engines.NewPolicySigner(signerEngine, config.PayoutConfiguration.SigningPolicy)
 	}
+	var transactorEngine common.TransactorEngine
+	var collector common.CollectorEngine
+	if replayFile := state.Global.GetReplayFilePath(); replayFile != "" {
+		fixtures, err := replay_engines.LoadFixtures(replayFile)
+		if err != nil {
+			return nil, err
+		}
+		transactorEngine = replay_engines.NewReplayTransactor(fixtures)
+		collector = replay_engines.NewReplayColletor(fixtures)
+	} else {
+		// for testing point transactor to testnet
+		// transactorEngine, err := clients.InitDefaultTransactor("https://basenet.rpc.mavryk.network/", "https://basenet.api.mavryk.network/") // (config.Network.RpcUrl, config.Network.MvktUrl)
+		transactorEngine, err = transactor_engines.InitDefaultTransactor(config)
+		if err != nil {
+			return nil, errors.Join(constants.ErrTransactorLoadFailed, err)
+		}
 
-	collector, err := collector_engines.InitDefaultRpcAndMvktColletor(config)
-	if err != nil {
-		return nil, err
+		collector, err = collector_engines.Load(config)
+		if err != nil {
+			return nil, errors.Join(constants.ErrCollectorLoadFailed, err)
+		}
+		if !state.Global.IsCacheDisabled() {
+			collector = collector_engines.NewCachedColletor(collector, state.Global.GetCacheDirectory(), config.PayoutConfiguration.BalanceCheckMode)
+		}
+
+		if recordFile := state.Global.GetRecordFilePath(); recordFile != "" {
+			recorder, err := replay_engines.NewFixtureRecorder(recordFile)
+			if err != nil {
+				return nil, err
+			}
+			fixtureRecorder = recorder
+			transactorEngine = replay_engines.NewRecordingTransactor(transactorEngine, recorder)
+			collector = replay_engines.NewRecordingColletor(collector, recorder)
+		}
 	}
 
 	if utils.IsTty() {
@@ -77,6 +120,34 @@ func loadConfigurationEnginesExtensions() (*configurationAndEngines, error) {
 	}, nil
 }
 
+// newPayoutJournal returns nil in dry run, nothing is paid so there is nothing to reconcile
+func newPayoutJournal(isDryRun bool) common.PayoutJournal {
+	if isDryRun {
+		return nil
+	}
+	return journal_engines.NewFsJournal(state.Global.GetPayoutJournalFilePath())
+}
+
+// reconcilePayoutJournal resolves payouts of the locked cycles interrupted by a crash before anything else is paid
+func reconcilePayoutJournal(journal common.PayoutJournal, collector common.CollectorEngine, transactor common.TransactorEngine, reporter common.ReporterEngine, cycles []int64) error {
+	if journal == nil {
+		return nil
+	}
+	slog.Info("checking payout journal for interrupted payouts", "cycles", cycles)
+	return core.ReconcilePayoutJournal(common.NewReconcilePayoutsEngineContext(journal, collector, transactor, reporter), cycles)
+}
+
+// reportCarryOverLedger stores payouts carried over by a run with nothing to pay out, otherwise the ledger is stored after execution
+func reportCarryOverLedger(preparationResult *common.PreparePayoutsResult, reporter common.ReporterEngine) {
+	ledgerReporter, ok := reporter.(common.CarryOverLedgerAwareReporter)
+	if preparationResult.CarryOverLedger == nil || !ok {
+		return
+	}
+	if err := ledgerReporter.ReportCarryOverLedger(preparationResult.CarryOverLedger); err != nil {
+		slog.Warn("failed to report carry-over ledger", "error", err.Error())
+	}
+}
+
 func loadGeneratedPayoutsFromBytes(data []byte) (*common.CyclePayoutBlueprint, error) {
 	payouts, err := utils.PayoutBlueprintFromJson(data)
 	if err != nil {
@@ -2,9 +2,10 @@ package cmd
 
 const (
 	// ops
-	EXIT_OPERTION_FAILED   = 1
-	EXIT_OPERTION_CANCELED = 2
-	EXIT_IVNALID_ARGS      = 3
+	EXIT_OPERTION_FAILED     = 1
+	EXIT_OPERTION_CANCELED   = 2
+	EXIT_IVNALID_ARGS        = 3
+	EXIT_CYCLE_DATA_MISMATCH = 4
 
 	// payouts io
 	EXIT_PAYOUT_WRITE_FAILURE                = 10
@@ -25,4 +25,9 @@ const (
 	START_DATE_FLAG                  = "start-date"
 	END_DATE_FLAG                    = "end-date"
 	MONTH_FLAG                       = "month"
+	TOLERANCE_FLAG                   = "tolerance"
+	FORCE_FLAG                       = "force"
+	REMOVE_SOURCE_FLAG               = "remove-source"
+	SIGNATURES_FLAG                  = "signatures"
+	ALL_FLAG                         = "all"
 )
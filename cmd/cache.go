@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+
+	collector_engines "github.com/mavryk-network/mavpay/engines/collector"
+	"github.com/mavryk-network/mavpay/state"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "manage cycle data cache",
+	Long:  "manages on-disk cache of completed cycles data",
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "clears cycle data cache",
+	Long:  "removes cached cycle data, all of them (--all) or only the ones of specified cycle (--cycle)",
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool(ALL_FLAG)
+		var cycle *int64
+		if cmd.Flags().Changed(CYCLE_FLAG) {
+			value, _ := cmd.Flags().GetInt64(CYCLE_FLAG)
+			cycle = &value
+		}
+		if all == (cycle != nil) {
+			slog.Error("specify either --all or --cycle")
+			os.Exit(EXIT_IVNALID_ARGS)
+		}
+
+		removed := assertRunWithResultAndErrorMessage(func() (int, error) {
+			return collector_engines.ClearCache(state.Global.GetCacheDirectory(), cycle)
+		}, EXIT_OPERTION_FAILED, "failed to clear cache")
+		if cycle != nil {
+			slog.Info("cache cleared", "removed", removed, "cycle", *cycle)
+			return
+		}
+		slog.Info("cache cleared", "removed", removed)
+	},
+}
+
+func init() {
+	cacheClearCmd.Flags().Int64(CYCLE_FLAG, 0, "clear only cached data of the cycle")
+	cacheClearCmd.Flags().Bool(ALL_FLAG, false, "clear all cached data")
+	cacheCmd.AddCommand(cacheClearCmd)
+	RootCmd.AddCommand(cacheCmd)
+}
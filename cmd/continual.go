@@ -42,6 +42,7 @@ func processCycleInContinualMode(context *configurationAndEngines, forceConfirma
 			extension.CloseScopedExtensions()
 			if endCycle != 0 && lastProcessedCycle >= endCycle {
 				slog.Info("end cycle reached, exiting")
+				closeFixtureRecorder()
 				os.Exit(0)
 			}
 		default:
@@ -69,6 +70,12 @@ func processCycleInContinualMode(context *configurationAndEngines, forceConfirma
 	}
 	defer unlock()
 
+	journal := newPayoutJournal(isDryRun)
+	if err := reconcilePayoutJournal(journal, collector, transactor, fsReporter, []int64{cycleToProcess}); err != nil {
+		slog.Error("failed to reconcile payout journal", "error", err.Error())
+		return retry()
+	}
+
 	slog.Info("===================== PROCESSING START =====================")
 	slog.Info("processing cycle", "cycle", cycleToProcess)
 
@@ -88,11 +95,12 @@ func processCycleInContinualMode(context *configurationAndEngines, forceConfirma
 
 	slog.Info("checking reports of past payouts")
 	preparationResult := assertRunWithResult(func() (*common.PreparePayoutsResult, error) {
-		return core.PrepareCyclePayouts(generationResult, config, common.NewPreparePayoutsEngineContext(collector, signer, fsReporter, notifyAdminFactory(config)), &common.PreparePayoutsOptions{})
+		return core.PrepareCyclePayouts(generationResult, config, common.NewPreparePayoutsEngineContext(collector, signer, fsReporter, notifyAdminFactory(config)).WithTransactor(transactor), &common.PreparePayoutsOptions{})
 	}, EXIT_OPERTION_FAILED)
 
 	if len(preparationResult.ValidPayouts) == 0 {
 		slog.Info("nothing to pay out, skipping")
+		reportCarryOverLedger(preparationResult, fsReporter)
 		return
 	}
 
@@ -105,7 +113,7 @@ func processCycleInContinualMode(context *configurationAndEngines, forceConfirma
 
 	slog.Info("executing payouts", "valid", len(preparationResult.ValidPayouts), "invalid", len(preparationResult.InvalidPayouts), "accumulated", len(preparationResult.AccumulatedPayouts), "already_successfull", len(preparationResult.ReportsOfPastSuccesfulPayouts))
 	executionResult := assertRunWithResult(func() (*common.ExecutePayoutsResult, error) {
-		return core.ExecutePayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(signer, transactor, fsReporter, notifyAdminFactory(config)), &common.ExecutePayoutsOptions{
+		return core.ExecutePayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(signer, transactor, fsReporter, notifyAdminFactory(config)).WithJournal(journal), &common.ExecutePayoutsOptions{
 			MixInContractCalls: mixInContractCalls,
 			MixInFATransfers:   mixInFATransfers,
 			DryRun:             isDryRun,
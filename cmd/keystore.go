@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/keystore"
+	"github.com/mavryk-network/mavpay/state"
+	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/spf13/cobra"
+)
+
+func assertKeystoreDoesNotExist(keystorePath string, force bool) {
+	if _, err := os.Stat(keystorePath); err == nil && !force {
+		slog.Error("keystore already exists, use --force to overwrite it", "path", keystorePath)
+		os.Exit(EXIT_IVNALID_ARGS)
+	}
+}
+
+func readNewKeystorePassphrase(env string) []byte {
+	return assertRunWithResultAndErrorMessage(func() ([]byte, error) {
+		return keystore.ReadPassphrase(keystore.PassphraseSource{
+			Fd:      state.Global.GetPassphraseFd(),
+			Env:     env,
+			Prompt:  "Enter new keystore passphrase:",
+			Confirm: true,
+		})
+	}, EXIT_IVNALID_ARGS, "failed to read passphrase")
+}
+
+func saveKeystore(key mavryk.PrivateKey, passphrase []byte, keystorePath string) {
+	ks := assertRunWithResultAndErrorMessage(func() (*keystore.Keystore, error) {
+		return keystore.Encrypt([]byte(key.String()), passphrase, key.Address().String())
+	}, EXIT_OPERTION_FAILED, "failed to encrypt keystore")
+	assertRunWithErrorMessage(func() error {
+		return ks.Save(keystorePath)
+	}, EXIT_OPERTION_FAILED, "failed to save keystore", "path", keystorePath)
+	slog.Info("keystore saved", "path", keystorePath, "address", ks.Address)
+}
+
+func readPlaintextKey(keyFile string) (mavryk.PrivateKey, error) {
+	var secret string
+	if keyBytes, err := os.ReadFile(keyFile); err == nil {
+		secret = string(keyBytes)
+	} else if os.IsNotExist(err) && utils.IsTty() {
+		if err := survey.AskOne(&survey.Password{Message: "Enter private key to import:"}, &secret); err != nil {
+			return mavryk.PrivateKey{}, err
+		}
+	} else {
+		return mavryk.PrivateKey{}, err
+	}
+	return mavryk.ParsePrivateKey(strings.TrimSpace(secret))
+}
+
+var keystoreCmd = &cobra.Command{
+	Use:   "keystore",
+	Short: "manage encrypted payout wallet keystore",
+	Long:  "creates and manages passphrase encrypted keystore of the payout wallet used by 'local-keystore' wallet mode",
+}
+
+var keystoreCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "creates keystore with new key",
+	Long:  "generates new payout wallet key and stores it in the passphrase encrypted keystore",
+	Run: func(cmd *cobra.Command, args []string) {
+		force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+		keystorePath := state.Global.GetKeystoreFilePath()
+		assertKeystoreDoesNotExist(keystorePath, force)
+
+		key := assertRunWithResultAndErrorMessage(func() (mavryk.PrivateKey, error) {
+			return mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+		}, EXIT_OPERTION_FAILED, "failed to generate key")
+		passphrase := readNewKeystorePassphrase(keystore.PASSPHRASE_ENV)
+		saveKeystore(key, passphrase, keystorePath)
+	},
+}
+
+var keystoreImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "imports existing key to keystore",
+	Long:  "encrypts existing plaintext private key (payout_wallet_private.key by default) to the keystore, prompts for the key if the file does not exist",
+	Run: func(cmd *cobra.Command, args []string) {
+		force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+		removeSource, _ := cmd.Flags().GetBool(REMOVE_SOURCE_FLAG)
+		keyFile, _ := cmd.Flags().GetString(FROM_FILE_FLAG)
+		if keyFile == "" {
+			keyFile = state.Global.GetPrivateKeyFilePath()
+		}
+		keystorePath := state.Global.GetKeystoreFilePath()
+		assertKeystoreDoesNotExist(keystorePath, force)
+
+		key := assertRunWithResultAndErrorMessage(func() (mavryk.PrivateKey, error) {
+			key, err := readPlaintextKey(keyFile)
+			if err != nil {
+				return mavryk.PrivateKey{}, errors.Join(constants.ErrSignerLoadFailed, err)
+			}
+			return key, nil
+		}, EXIT_OPERTION_FAILED, "failed to read private key", "path", keyFile)
+		passphrase := readNewKeystorePassphrase(keystore.PASSPHRASE_ENV)
+		saveKeystore(key, passphrase, keystorePath)
+
+		if _, err := os.Stat(keyFile); err != nil {
+			return
+		}
+		if !removeSource {
+			slog.Warn("plaintext private key is still stored on disk, remove it or use --remove-source", "path", keyFile)
+			return
+		}
+		assertRunWithErrorMessage(func() error {
+			return os.Remove(keyFile)
+		}, EXIT_OPERTION_FAILED, "failed to remove plaintext private key", "path", keyFile)
+		slog.Info("plaintext private key removed", "path", keyFile)
+	},
+}
+
+var keystoreChangePassphraseCmd = &cobra.Command{
+	Use:   "change-passphrase",
+	Short: "changes keystore passphrase",
+	Long:  fmt.Sprintf("re-encrypts the keystore with new passphrase, current passphrase is read from %s and new one from %s if prompt is not available (or first and second line of passphrase file descriptor)", keystore.PASSPHRASE_ENV, keystore.NEW_PASSPHRASE_ENV),
+	Run: func(cmd *cobra.Command, args []string) {
+		keystorePath := state.Global.GetKeystoreFilePath()
+		ks := assertRunWithResultAndErrorMessage(func() (*keystore.Keystore, error) {
+			return keystore.Load(keystorePath)
+		}, EXIT_OPERTION_FAILED, "failed to load keystore", "path", keystorePath)
+
+		passphrase := assertRunWithResultAndErrorMessage(func() ([]byte, error) {
+			return keystore.ReadPassphrase(keystore.PassphraseSource{
+				Fd:     state.Global.GetPassphraseFd(),
+				Env:    keystore.PASSPHRASE_ENV,
+				Prompt: "Enter current keystore passphrase:",
+			})
+		}, EXIT_IVNALID_ARGS, "failed to read passphrase")
+		newPassphrase := readNewKeystorePassphrase(keystore.NEW_PASSPHRASE_ENV)
+
+		changed := assertRunWithResultAndErrorMessage(func() (*keystore.Keystore, error) {
+			return ks.ChangePassphrase(passphrase, newPassphrase)
+		}, EXIT_OPERTION_FAILED, "failed to change passphrase")
+		assertRunWithErrorMessage(func() error {
+			return changed.Save(keystorePath)
+		}, EXIT_OPERTION_FAILED, "failed to save keystore", "path", keystorePath)
+		slog.Info("keystore passphrase changed", "path", keystorePath, "address", changed.Address)
+	},
+}
+
+func init() {
+	keystoreCreateCmd.Flags().Bool(FORCE_FLAG, false, "overwrite existing keystore")
+	keystoreImportCmd.Flags().Bool(FORCE_FLAG, false, "overwrite existing keystore")
+	keystoreImportCmd.Flags().String(FROM_FILE_FLAG, "", "plaintext private key file to import (defaults to payout_wallet_private.key)")
+	keystoreImportCmd.Flags().Bool(REMOVE_SOURCE_FLAG, false, "remove plaintext private key file after successful import")
+	keystoreCmd.AddCommand(keystoreCreateCmd)
+	keystoreCmd.AddCommand(keystoreImportCmd)
+	keystoreCmd.AddCommand(keystoreChangePassphraseCmd)
+	RootCmd.AddCommand(keystoreCmd)
+}
@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/configuration"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/core"
+	reporter_engines "github.com/mavryk-network/mavpay/engines/reporter"
+	signer_engines "github.com/mavryk-network/mavpay/engines/signer"
+	"github.com/mavryk-network/mavpay/extension"
+	"github.com/mavryk-network/mavpay/state"
+	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+func writeJsonToFile(toFile string, data any, errSaveFailed error) error {
+	slog.Info("writing file", "path", toFile)
+	content, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return errors.Join(errSaveFailed, err)
+	}
+	if err := os.WriteFile(toFile, content, 0644); err != nil {
+		return errors.Join(errSaveFailed, err)
+	}
+	return nil
+}
+
+func loadUnsignedPayoutsFromFile(fromFile string) (*common.UnsignedPayouts, error) {
+	slog.Info("reading unsigned payouts from file", "path", fromFile)
+	data, err := os.ReadFile(fromFile)
+	if err != nil {
+		return nil, errors.Join(constants.ErrUnsignedPayoutsLoadFailed, err)
+	}
+	unsignedPayouts := &common.UnsignedPayouts{}
+	if err := json.Unmarshal(data, unsignedPayouts); err != nil {
+		return nil, errors.Join(constants.ErrUnsignedPayoutsLoadFailed, err)
+	}
+	if unsignedPayouts.Version != common.UNSIGNED_PAYOUTS_VERSION || unsignedPayouts.PreparationResult == nil {
+		return nil, errors.Join(constants.ErrUnsignedPayoutsLoadFailed, fmt.Errorf("unsupported version %d or missing preparation result", unsignedPayouts.Version))
+	}
+	return unsignedPayouts, nil
+}
+
+func loadOperationSignaturesFromFile(fromFile string) ([]common.OperationSignature, error) {
+	slog.Info("reading operation signatures from file", "path", fromFile)
+	data, err := os.ReadFile(fromFile)
+	if err != nil {
+		return nil, errors.Join(constants.ErrOperationSignaturesLoadFailed, err)
+	}
+	signatures := make([]common.OperationSignature, 0)
+	if err := json.Unmarshal(data, &signatures); err != nil {
+		return nil, errors.Join(constants.ErrOperationSignaturesLoadFailed, err)
+	}
+	return signatures, nil
+}
+
+func getPayoutsCycles(payouts []common.PayoutRecipe) []int64 {
+	return lo.Uniq(lo.Map(payouts, func(payout common.PayoutRecipe, _ int) int64 {
+		return payout.Cycle
+	}))
+}
+
+var offlineCmd = &cobra.Command{
+	Use:   "offline",
+	Short: "air-gapped signing of payouts",
+	Long: `pays out with payout wallet key kept on the offline machine:
+1. 'offline export' (online machine, 'offline' wallet mode) - generates payouts and exports completed unsigned operations
+2. 'offline sign' (offline machine) - signs exported operations
+3. 'offline broadcast' (online machine) - broadcasts signed operations, waits for confirmation and writes reports
+exported operations are forged on the head block at the time of the export and have to be broadcasted before
+the chain advances by max_operations_ttl blocks of the network, the last valid level is written to each batch
+as 'valid_until_level', broadcast refuses operations with expired branch and payouts have to be exported again`,
+}
+
+var offlineExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "exports unsigned payout operations",
+	Long:  "generates payouts, splits them into batches and exports completed unsigned operations for signing on the offline machine",
+	Run: func(cmd *cobra.Command, args []string) {
+		config, collector, signer, transactor := assertRunWithResult(loadConfigurationEnginesExtensions, EXIT_CONFIGURATION_LOAD_FAILURE).Unwrap()
+		defer extension.CloseExtensions()
+
+		cycle, _ := cmd.Flags().GetInt64(CYCLE_FLAG)
+		skipBalanceCheck, _ := cmd.Flags().GetBool(SKIP_BALANCE_CHECK_FLAG)
+		mixInContractCalls, _ := cmd.Flags().GetBool(DISABLE_SEPERATE_SC_PAYOUTS_FLAG)
+		mixInFATransfers, _ := cmd.Flags().GetBool(DISABLE_SEPERATE_FA_PAYOUTS_FLAG)
+		toFile, _ := cmd.Flags().GetString(TO_FILE_FLAG)
+		if toFile == "" {
+			slog.Error("output file has to be specified", "flag", TO_FILE_FLAG)
+			os.Exit(EXIT_IVNALID_ARGS)
+		}
+
+		if cycle <= 0 {
+			lastCompletedCycle := assertRunWithResultAndErrorMessage(collector.GetLastCompletedCycle, EXIT_OPERTION_FAILED, "failed to get last completed cycle")
+			cycle = lastCompletedCycle + cycle
+		}
+
+		generationResult, err := core.GeneratePayouts(config, common.NewGeneratePayoutsEngines(collector, signer, notifyAdminFactory(config)),
+			&common.GeneratePayoutsOptions{
+				Cycle:            cycle,
+				SkipBalanceCheck: skipBalanceCheck,
+			})
+		if errors.Is(err, constants.ErrNoCycleDataAvailable) {
+			slog.Info("no data available for cycle, skipping", "cycle", cycle)
+			return
+		}
+		if err != nil {
+			slog.Error("failed to generate payouts", "error", err.Error())
+			os.Exit(EXIT_OPERTION_FAILED)
+		}
+
+		fsReporter := reporter_engines.NewFileSystemReporter(config, &common.ReporterEngineOptions{})
+		preparationResult := assertRunWithResult(func() (*common.PreparePayoutsResult, error) {
+			return core.PrepareCyclePayouts(generationResult, config, common.NewPreparePayoutsEngineContext(collector, signer, fsReporter, notifyAdminFactory(config)).WithTransactor(transactor), &common.PreparePayoutsOptions{})
+		}, EXIT_OPERTION_FAILED)
+
+		cycles := getPayoutsCycles(generationResult.Payouts)
+		if !state.Global.GetWantsOutputJson() {
+			PrintPreparationResults(preparationResult, cycles...)
+		}
+		if len(preparationResult.ValidPayouts) == 0 {
+			slog.Info("nothing to pay out", "phase", "result")
+			reportCarryOverLedger(preparationResult, fsReporter)
+			return
+		}
+
+		unsignedPayouts := assertRunWithResultAndErrorMessage(func() (*common.UnsignedPayouts, error) {
+			return core.ExportUnsignedPayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(signer, transactor, fsReporter, notifyAdminFactory(config)), &common.ExecutePayoutsOptions{
+				MixInContractCalls: mixInContractCalls,
+				MixInFATransfers:   mixInFATransfers,
+			})
+		}, EXIT_OPERTION_FAILED, "failed to export unsigned payouts")
+		assertRunWithErrorMessage(func() error {
+			return writeJsonToFile(toFile, unsignedPayouts, constants.ErrUnsignedPayoutsSaveFailed)
+		}, EXIT_PAYOUT_WRITE_FAILURE, "failed to write unsigned payouts")
+		validUntil := lo.Min(lo.Map(unsignedPayouts.Batches, func(batch common.UnsignedBatch, _ int) int64 { return batch.ValidUntilLevel }))
+		slog.Info("unsigned payouts exported", "path", toFile, "cycles", cycles, "batches", len(unsignedPayouts.Batches), "valid_until_level", validUntil, "phase", "result")
+	},
+}
+
+var offlineSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "signs exported payout operations",
+	Long:  "rebuilds exported operations from their payouts, signs them with the payout wallet and writes signatures, does not require network access",
+	Run: func(cmd *cobra.Command, args []string) {
+		fromFile, _ := cmd.Flags().GetString(FROM_FILE_FLAG)
+		toFile, _ := cmd.Flags().GetString(TO_FILE_FLAG)
+		confirmed, _ := cmd.Flags().GetBool(CONFIRM_FLAG)
+		if fromFile == "" || toFile == "" {
+			slog.Error("input and output files have to be specified", "flags", []string{FROM_FILE_FLAG, TO_FILE_FLAG})
+			os.Exit(EXIT_IVNALID_ARGS)
+		}
+
+		config := assertRunWithResultAndErrorMessage(configuration.Load, EXIT_CONFIGURATION_LOAD_FAILURE, "failed to load configuration")
+		signer := state.Global.SignerOverride
+		if signer == nil {
+			signer = assertRunWithResultAndErrorMessage(func() (common.SignerEngine, error) {
+				return signer_engines.Load(string(config.PayoutConfiguration.WalletMode))
+			}, EXIT_CONFIGURATION_LOAD_FAILURE, "failed to load signer")
+		}
+		unsignedPayouts := assertRunWithResult(func() (*common.UnsignedPayouts, error) {
+			return loadUnsignedPayoutsFromFile(fromFile)
+		}, EXIT_PAYOUTS_READ_FAILURE)
+		if config.PayoutConfiguration.SigningPolicy != nil {
+			policySigner := signer_engines.NewPolicySigner(signer, config.PayoutConfiguration.SigningPolicy)
+			policySigner.SetPayoutBlueprints(unsignedPayouts.PreparationResult.Blueprints, reporter_engines.NewFileSystemReporter(config, &common.ReporterEngineOptions{}))
+			signer = policySigner
+		}
+		key := signer.GetKey()
+		if !unsignedPayouts.PayoutKey.Equal(key) {
+			slog.Error("unsigned payouts were exported for different payout key", "expected", unsignedPayouts.PayoutKey.String(), "signer", key.String())
+			os.Exit(EXIT_IVNALID_ARGS)
+		}
+
+		for _, batch := range unsignedPayouts.Batches {
+			if state.Global.GetWantsOutputJson() {
+				slog.Info("batch to sign", "batch_id", batch.Id, "recipes", batch.Payouts)
+			} else {
+				utils.PrintPayouts(batch.Payouts, fmt.Sprintf("Batch %s", batch.Id), true)
+			}
+		}
+		if !confirmed {
+			assertRequireConfirmation("Do you want to sign above batches?")
+		}
+
+		signatures := make([]common.OperationSignature, 0, len(unsignedPayouts.Batches))
+		for _, batch := range unsignedPayouts.Batches {
+			op := assertRunWithResultAndErrorMessage(func() (*codec.Op, error) {
+				return batch.ToOp(key)
+			}, EXIT_OPERTION_FAILED, "failed to rebuild operation", "batch_id", batch.Id)
+			assertRunWithErrorMessage(func() error {
+				return signer.Sign(op)
+			}, EXIT_OPERTION_FAILED, "failed to sign operation", "batch_id", batch.Id)
+			signatures = append(signatures, common.OperationSignature{
+				Id:        batch.Id,
+				Signature: op.Signature,
+			})
+			slog.Info("batch signed", "batch_id", batch.Id, "tx_count", len(batch.Payouts))
+		}
+
+		assertRunWithErrorMessage(func() error {
+			return writeJsonToFile(toFile, signatures, constants.ErrOperationSignaturesSaveFailed)
+		}, EXIT_PAYOUT_WRITE_FAILURE, "failed to write operation signatures")
+		slog.Info("operations signed", "path", toFile, "batches", len(signatures), "phase", "result")
+	},
+}
+
+var offlineBroadcastCmd = &cobra.Command{
+	Use:   "broadcast",
+	Short: "broadcasts signed payout operations",
+	Long:  "attaches signatures made on the offline machine to exported operations, broadcasts them, waits for confirmation and writes reports",
+	Run: func(cmd *cobra.Command, args []string) {
+		config, collector, signer, transactor := assertRunWithResult(loadConfigurationEnginesExtensions, EXIT_CONFIGURATION_LOAD_FAILURE).Unwrap()
+		defer extension.CloseExtensions()
+
+		fromFile, _ := cmd.Flags().GetString(FROM_FILE_FLAG)
+		signaturesFile, _ := cmd.Flags().GetString(SIGNATURES_FLAG)
+		if fromFile == "" || signaturesFile == "" {
+			slog.Error("unsigned payouts and signatures files have to be specified", "flags", []string{FROM_FILE_FLAG, SIGNATURES_FLAG})
+			os.Exit(EXIT_IVNALID_ARGS)
+		}
+
+		unsignedPayouts := assertRunWithResult(func() (*common.UnsignedPayouts, error) {
+			return loadUnsignedPayoutsFromFile(fromFile)
+		}, EXIT_PAYOUTS_READ_FAILURE)
+		signatures := assertRunWithResult(func() ([]common.OperationSignature, error) {
+			return loadOperationSignaturesFromFile(signaturesFile)
+		}, EXIT_PAYOUTS_READ_FAILURE)
+
+		cycles := getPayoutsCycles(unsignedPayouts.PreparationResult.ValidPayouts)
+		slog.Info("acquiring lock", "cycles", cycles, "phase", "acquiring_lock")
+		unlock, err := lockCyclesWithTimeout(time.Minute*10, cycles...)
+		if err != nil {
+			slog.Error("failed to acquire lock", "error", err.Error())
+			os.Exit(EXIT_OPERTION_FAILED)
+		}
+		defer unlock()
+
+		fsReporter := reporter_engines.NewFileSystemReporter(config, &common.ReporterEngineOptions{})
+		journal := newPayoutJournal(false)
+		assertRunWithErrorMessage(func() error {
+			return reconcilePayoutJournal(journal, collector, transactor, fsReporter, cycles)
+		}, EXIT_OPERTION_FAILED, "failed to reconcile payout journal")
+		executionResult := assertRunWithResult(func() (*common.ExecutePayoutsResult, error) {
+			return core.ExecuteSignedPayouts(unsignedPayouts, signatures, config, common.NewExecutePayoutsEngineContext(signer, transactor, fsReporter, notifyAdminFactory(config)).WithJournal(journal), &common.ExecutePayoutsOptions{})
+		}, EXIT_OPERTION_FAILED)
+
+		switch {
+		case state.Global.GetWantsOutputJson():
+			slog.Info(constants.LOG_MESSAGE_PAYOUTS_EXECUTED, constants.LOG_FIELD_CYCLES, cycles, "phase", "result")
+		default:
+			utils.PrintBatchResults(executionResult.BatchResults, fmt.Sprintf("Results of #%s", utils.FormatCycleNumbers(cycles...)), config.Network.Explorer)
+		}
+
+		failedCount := lo.CountBy(executionResult.BatchResults, func(br common.BatchResult) bool { return !br.IsSuccess })
+		if len(executionResult.BatchResults) > 0 && failedCount > 0 {
+			slog.Error("failed operations detected", "failed", failedCount, "total", len(executionResult.BatchResults))
+			os.Exit(EXIT_OPERTION_FAILED)
+		}
+		if silent, _ := cmd.Flags().GetBool(SILENT_FLAG); !silent {
+			for _, blueprint := range unsignedPayouts.PreparationResult.Blueprints {
+				notifyPayoutsProcessedThroughAllNotificators(config, &blueprint.Summary)
+			}
+		}
+	},
+}
+
+func init() {
+	offlineExportCmd.Flags().Int64P(CYCLE_FLAG, "c", 0, "cycle to generate payouts for")
+	offlineExportCmd.Flags().String(TO_FILE_FLAG, "", "file to write unsigned payouts to")
+	offlineExportCmd.Flags().Bool(SKIP_BALANCE_CHECK_FLAG, false, "skips payout wallet balance check")
+	offlineExportCmd.Flags().Bool(DISABLE_SEPERATE_SC_PAYOUTS_FLAG, false, "disables smart contract separation (mixes txs and smart contract calls within batches)")
+	offlineExportCmd.Flags().Bool(DISABLE_SEPERATE_FA_PAYOUTS_FLAG, false, "disables fa transfers separation (mixes txs and fa transfers within batches)")
+
+	offlineSignCmd.Flags().String(FROM_FILE_FLAG, "", "file with unsigned payouts")
+	offlineSignCmd.Flags().String(TO_FILE_FLAG, "", "file to write signatures to")
+	offlineSignCmd.Flags().Bool(CONFIRM_FLAG, false, "automatically confirms signing")
+
+	offlineBroadcastCmd.Flags().String(FROM_FILE_FLAG, "", "file with unsigned payouts")
+	offlineBroadcastCmd.Flags().String(SIGNATURES_FLAG, "", "file with signatures made on the offline machine")
+	offlineBroadcastCmd.Flags().BoolP(SILENT_FLAG, "s", false, "suppresses notifications")
+
+	offlineCmd.AddCommand(offlineExportCmd)
+	offlineCmd.AddCommand(offlineSignCmd)
+	offlineCmd.AddCommand(offlineBroadcastCmd)
+	RootCmd.AddCommand(offlineCmd)
+}
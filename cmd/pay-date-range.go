@@ -68,6 +68,11 @@ var payDateRangeCmd = &cobra.Command{
 		}
 		defer unlock()
 
+		journal := newPayoutJournal(isDryRun)
+		assertRunWithErrorMessage(func() error {
+			return reconcilePayoutJournal(journal, collector, transactor, fsReporter, cycles)
+		}, EXIT_OPERTION_FAILED, "failed to reconcile payout journal")
+
 		slog.Info("generating payouts for cycles in the date range", "date_range", fmt.Sprintf("%s - %s", startDate.Format(time.RFC3339), endDate.Format(time.RFC3339)), "cycles", cycles)
 		generationResults := make(common.CyclePayoutBlueprints, 0, len(cycles))
 
@@ -102,7 +107,7 @@ var payDateRangeCmd = &cobra.Command{
 
 		slog.Info("checking reports of past payouts")
 		preparationResult := assertRunWithResult(func() (*common.PreparePayoutsResult, error) {
-			return core.PreparePayouts(generationResults, config, common.NewPreparePayoutsEngineContext(collector, signer, fsReporter, notifyAdminFactory(config)), &common.PreparePayoutsOptions{
+			return core.PreparePayouts(generationResults, config, common.NewPreparePayoutsEngineContext(collector, signer, fsReporter, notifyAdminFactory(config)).WithTransactor(transactor), &common.PreparePayoutsOptions{
 				Accumulate: true,
 			})
 		}, EXIT_OPERTION_FAILED)
@@ -122,6 +127,7 @@ var payDateRangeCmd = &cobra.Command{
 
 		if len(preparationResult.ValidPayouts) == 0 {
 			slog.Info("nothing to pay out")
+			reportCarryOverLedger(preparationResult, fsReporter)
 			notificator, _ := cmd.Flags().GetString(NOTIFICATOR_FLAG)
 			if notificator != "" { // rerun notification through notificator if specified manually
 				notifyPayoutsProcessed(config, generationResults.GetSummary(), notificator)
@@ -140,7 +146,7 @@ var payDateRangeCmd = &cobra.Command{
 			if reportToStdout, _ := cmd.Flags().GetBool(REPORT_TO_STDOUT); reportToStdout {
 				reporter = stdioReporter
 			}
-			return core.ExecutePayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(signer, transactor, reporter, notifyAdminFactory(config)), &common.ExecutePayoutsOptions{
+			return core.ExecutePayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(signer, transactor, reporter, notifyAdminFactory(config)).WithJournal(journal), &common.ExecutePayoutsOptions{
 				MixInContractCalls: mixInContractCalls,
 				MixInFATransfers:   mixInFATransfers,
 				DryRun:             isDryRun,
@@ -92,9 +92,14 @@ var payCmd = &cobra.Command{
 		}
 		defer unlock()
 
+		journal := newPayoutJournal(isDryRun)
+		assertRunWithErrorMessage(func() error {
+			return reconcilePayoutJournal(journal, collector, transactor, fsReporter, cycles)
+		}, EXIT_OPERTION_FAILED, "failed to reconcile payout journal")
+
 		slog.Info("checking past reports")
 		preparationResult := assertRunWithResult(func() (*common.PreparePayoutsResult, error) {
-			return core.PrepareCyclePayouts(generationResult, config, common.NewPreparePayoutsEngineContext(collector, signer, fsReporter, notifyAdminFactory(config)), &common.PreparePayoutsOptions{})
+			return core.PrepareCyclePayouts(generationResult, config, common.NewPreparePayoutsEngineContext(collector, signer, fsReporter, notifyAdminFactory(config)).WithTransactor(transactor), &common.PreparePayoutsOptions{})
 		}, EXIT_OPERTION_FAILED)
 
 		switch {
@@ -112,6 +117,7 @@ var payCmd = &cobra.Command{
 
 		if len(preparationResult.ValidPayouts) == 0 {
 			slog.Info("nothing to pay out", "phase", "result")
+			reportCarryOverLedger(preparationResult, fsReporter)
 			notificator, _ := cmd.Flags().GetString(NOTIFICATOR_FLAG)
 			if notificator != "" { // rerun notification through notificator if specified manually
 				notifyPayoutsProcessed(config, &generationResult.Summary, notificator)
@@ -130,7 +136,7 @@ var payCmd = &cobra.Command{
 			if reportToStdout, _ := cmd.Flags().GetBool(REPORT_TO_STDOUT); reportToStdout {
 				reporter = stdioReporter
 			}
-			return core.ExecutePayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(signer, transactor, reporter, notifyAdminFactory(config)), &common.ExecutePayoutsOptions{
+			return core.ExecutePayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(signer, transactor, reporter, notifyAdminFactory(config)).WithJournal(journal), &common.ExecutePayoutsOptions{
 				MixInContractCalls: mixInContractCalls,
 				MixInFATransfers:   mixInFATransfers,
 				DryRun:             isDryRun,
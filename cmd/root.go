@@ -25,6 +25,10 @@ const (
 	DISABLE_DONATION_PROMPT_FLAG = "disable-donation-prompt"
 	OUTPUT_FORMAT_FLAG           = "output-format"
 	PAY_ONLY_ADDRESS_PREFIX      = "pay-only-address-prefix"
+	DISABLE_CACHE_FLAG           = "no-cache"
+	RECORD_FLAG                  = "record"
+	REPLAY_FLAG                  = "replay"
+	PASSPHRASE_FD_FLAG           = "passphrase-fd"
 )
 
 var (
@@ -68,6 +72,10 @@ Copyright © %d alis.is
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			format, _ := cmd.Flags().GetString(OUTPUT_FORMAT_FLAG)
 			disableDonationPrompt, _ := cmd.Flags().GetBool(DISABLE_DONATION_PROMPT_FLAG)
+			disableCache, _ := cmd.Flags().GetBool(DISABLE_CACHE_FLAG)
+			recordFile, _ := cmd.Flags().GetString(RECORD_FLAG)
+			replayFile, _ := cmd.Flags().GetString(REPLAY_FLAG)
+			passphraseFd, _ := cmd.Flags().GetInt(PASSPHRASE_FD_FLAG)
 			level, _ := cmd.Flags().GetString(LOG_LEVEL_FLAG)
 			logServer, _ := cmd.Flags().GetString(LOG_SERVER_FLAG)
 
@@ -107,6 +115,10 @@ Copyright © %d alis.is
 				SignerOverride:        signerOverride,
 				DisableDonationPrompt: disableDonationPrompt,
 				PayOnlyAddressPrefix:  payOnlyAddressPrefix,
+				DisableCache:          disableCache,
+				RecordFilePath:        recordFile,
+				ReplayFilePath:        replayFile,
+				PassphraseFd:          passphraseFd,
 			}
 			if err := state.Init(workingDirectory, stateOptions); err != nil {
 				slog.Error("Failed to initialize state", "error", err.Error())
@@ -118,6 +130,9 @@ Copyright © %d alis.is
 				promptIfNewVersionAvailable()
 			}
 		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			closeFixtureRecorder()
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			version, _ := cmd.Flags().GetBool(VERSION_FLAG)
 			if version {
@@ -143,6 +158,10 @@ func init() {
 	RootCmd.PersistentFlags().String(SIGNER_FLAG, "", "Override signer")
 	RootCmd.PersistentFlags().Bool(SKIP_VERSION_CHECK_FLAG, false, "Skip version check")
 	RootCmd.PersistentFlags().Bool(DISABLE_DONATION_PROMPT_FLAG, false, "Disable donation prompt")
+	RootCmd.PersistentFlags().Bool(DISABLE_CACHE_FLAG, false, "Disable on-disk cache of completed cycles data")
+	RootCmd.PersistentFlags().String(RECORD_FLAG, "", "Records collector and transactor calls to the fixture file")
+	RootCmd.PersistentFlags().String(REPLAY_FLAG, "", "Replays collector and transactor calls from the fixture file instead of using network")
+	RootCmd.PersistentFlags().Int(PASSPHRASE_FD_FLAG, -1, "file descriptor to read keystore passphrase from (one passphrase per line), alternatively use KEYSTORE_PASSPHRASE environment variable")
 	RootCmd.PersistentFlags().String(PAY_ONLY_ADDRESS_PREFIX, "", "Pays only to addresses starting with the prefix (e.g. KT, usually you do not want to use this, just for recovering in case of issues)")
 	RootCmd.PersistentFlags().SetInterspersed(false)
 }
@@ -25,8 +25,7 @@ var statisticsCmd = &cobra.Command{
 		}
 		fsReporter := reporter_engines.NewFileSystemReporter(config, &common.ReporterEngineOptions{})
 
-		var total common.CyclePayoutSummary
-		ok := 0
+		summaries := make([]*common.CyclePayoutSummary, 0, n)
 		collectedCycles := make([]int64, 0, n)
 		for i := 0; i < n; i++ {
 			cycle := lastCycle - int64(i)
@@ -35,10 +34,10 @@ var statisticsCmd = &cobra.Command{
 				slog.Warn("failed to read report", "cycle", cycle, "error", err.Error())
 				continue
 			}
-			total = *total.CombineNumericData(summary)
+			summaries = append(summaries, summary)
 			collectedCycles = append(collectedCycles, cycle)
-			ok++
 		}
+		total := common.CombineCyclePayoutSummaries(summaries...)
 
 		firstCycle := lastCycle - int64(n-1)
 		header := fmt.Sprintf("Statistics #%d - #%d", firstCycle, lastCycle)
@@ -50,7 +49,7 @@ var statisticsCmd = &cobra.Command{
 			slog.Info("statistics generated", "result", total, "cycles", collectedCycles, "phase", "result")
 			return
 		}
-		utils.PrintCycleSummary(total, header)
+		utils.PrintCycleSummary(*total, header)
 	},
 }
 
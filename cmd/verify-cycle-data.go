@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/configuration"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	collector_engines "github.com/mavryk-network/mavpay/engines/collector"
+	"github.com/mavryk-network/mavpay/engines/mvkt"
+	reporter_engines "github.com/mavryk-network/mavpay/engines/reporter"
+	"github.com/mavryk-network/mavpay/state"
+	"github.com/mavryk-network/mavpay/transport"
+	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/spf13/cobra"
+)
+
+const (
+	RPC_CYCLE_DATA_SOURCE              = "rpc"
+	MVKT_CYCLE_DATA_SOURCE             = "mvkt"
+	PROTOCOL_REWARDS_CYCLE_DATA_SOURCE = "protocol-rewards"
+)
+
+type cycleDataVerificationSources struct {
+	rpc  *collector_engines.RpcColletor
+	mvkt *mvkt.Client
+}
+
+func loadCycleDataVerificationSources(config *configuration.RuntimeConfiguration) (*cycleDataVerificationSources, error) {
+	pools, err := config.Network.GetEndpointPools()
+	if err != nil {
+		return nil, errors.Join(constants.ErrCollectorLoadFailed, err)
+	}
+	mvktClient, err := mvkt.InitClient(config.Network.MvktUrl, config.Network.ProtocolRewardsUrl, &mvkt.MvktClientOptions{
+		HttpClient:                transport.NewHttpClient(10*time.Second, pools...),
+		BalanceCheckMode:          config.PayoutConfiguration.BalanceCheckMode,
+		DelegatorFetchConcurrency: config.Network.MvktConcurrency,
+		RequestsPerSecond:         config.Network.MvktRequestsPerSecond,
+	})
+	if err != nil {
+		return nil, errors.Join(constants.ErrCollectorLoadFailed, err)
+	}
+	rpcCollector, err := collector_engines.InitRpcColletor(config)
+	if err != nil {
+		return nil, errors.Join(constants.ErrCollectorLoadFailed, err)
+	}
+	return &cycleDataVerificationSources{
+		rpc:  rpcCollector,
+		mvkt: mvktClient,
+	}, nil
+}
+
+var verifyCycleDataCmd = &cobra.Command{
+	Use:   "verify-cycle-data",
+	Short: "compares cycle data of mvkt, protocol-rewards and rpc",
+	Long:  "fetches cycle data from mvkt, protocol-rewards and node rpc, compares delegators, balances and rewards and writes report of mismatches",
+	Run: func(cmd *cobra.Command, args []string) {
+		cycle, _ := cmd.Flags().GetInt64(CYCLE_FLAG)
+		tolerance, _ := cmd.Flags().GetInt64(TOLERANCE_FLAG)
+
+		config := assertRunWithResultAndErrorMessage(func() (*configuration.RuntimeConfiguration, error) {
+			config, err := configuration.Load()
+			if err != nil {
+				return nil, errors.Join(constants.ErrConfigurationLoadFailed, err)
+			}
+			return config, nil
+		}, EXIT_CONFIGURATION_LOAD_FAILURE, "failed to load configuration")
+		sources := assertRunWithResultAndErrorMessage(func() (*cycleDataVerificationSources, error) {
+			return loadCycleDataVerificationSources(config)
+		}, EXIT_CONFIGURATION_LOAD_FAILURE, "failed to load cycle data sources")
+
+		if cycle <= 0 {
+			cycle = assertRunWithResult(sources.rpc.GetLastCompletedCycle, EXIT_OPERTION_FAILED)
+		}
+		baker := config.BakerPKH
+		ctx := context.Background()
+
+		slog.Info("fetching cycle data", "source", RPC_CYCLE_DATA_SOURCE, "baker", baker.String(), "cycle", cycle)
+		rpcData := assertRunWithResultAndErrorMessage(func() (*common.BakersCycleData, error) {
+			return sources.rpc.GetCycleStakingData(baker, cycle)
+		}, EXIT_OPERTION_FAILED, "failed to fetch reference cycle data", "source", RPC_CYCLE_DATA_SOURCE)
+		reference := common.CycleDataSource{Id: RPC_CYCLE_DATA_SOURCE, Data: rpcData}
+
+		compared := make([]common.CycleDataSource, 0, 2)
+		fetchers := []struct {
+			id           string
+			balancesOnly bool
+			fetch        func() (*common.BakersCycleData, error)
+		}{
+			{MVKT_CYCLE_DATA_SOURCE, false, func() (*common.BakersCycleData, error) {
+				return sources.mvkt.GetCycleDataWithBalanceCheckMode(ctx, baker, cycle, enums.MVKT_BALANCE_CHECK_MODE)
+			}},
+			{PROTOCOL_REWARDS_CYCLE_DATA_SOURCE, true, func() (*common.BakersCycleData, error) {
+				return sources.mvkt.GetProtocolRewardsCycleData(ctx, baker, cycle)
+			}},
+		}
+		for _, fetcher := range fetchers {
+			slog.Info("fetching cycle data", "source", fetcher.id, "baker", baker.String(), "cycle", cycle)
+			data, err := fetcher.fetch()
+			if err != nil {
+				slog.Warn("failed to fetch cycle data, source skipped", "source", fetcher.id, "error", err.Error())
+				continue
+			}
+			compared = append(compared, common.CycleDataSource{Id: fetcher.id, Data: data, BalancesOnly: fetcher.balancesOnly})
+		}
+		if len(compared) == 0 {
+			slog.Error("no cycle data to compare with", "reference", RPC_CYCLE_DATA_SOURCE)
+			os.Exit(EXIT_OPERTION_FAILED)
+		}
+
+		mismatches := common.CompareCycleData(cycle, mavryk.NewZ(tolerance), reference, compared...)
+
+		fsReporter := reporter_engines.NewFileSystemReporter(config, &common.ReporterEngineOptions{})
+		reportFile := assertRunWithResultAndErrorMessage(func() (string, error) {
+			return fsReporter.ReportCycleDataMismatches(cycle, mismatches)
+		}, EXIT_OPERTION_FAILED, "failed to write cycle data mismatch report")
+
+		if state.Global.GetWantsOutputJson() {
+			slog.Info("cycle data verified", "cycle", cycle, "mismatches", mismatches, "report", reportFile, "phase", "result")
+		} else {
+			utils.PrintCycleDataMismatches(mismatches, fmt.Sprintf("Cycle Data Mismatches #%d", cycle))
+		}
+
+		if len(mismatches) > 0 {
+			slog.Warn("cycle data mismatches found", "cycle", cycle, "count", len(mismatches), "report", reportFile)
+			os.Exit(EXIT_CYCLE_DATA_MISMATCH)
+		}
+		slog.Info("cycle data of all sources match", "cycle", cycle, "report", reportFile)
+	},
+}
+
+func init() {
+	verifyCycleDataCmd.Flags().Int64(CYCLE_FLAG, 0, "cycle to verify (defaults to last completed cycle)")
+	verifyCycleDataCmd.Flags().Int64(TOLERANCE_FLAG, 0, "tolerated difference in mumav")
+	RootCmd.AddCommand(verifyCycleDataCmd)
+}
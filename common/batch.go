@@ -1,7 +1,10 @@
 package common
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 
 	"github.com/mavryk-network/mavpay/constants"
 	"github.com/mavryk-network/mvgo/codec"
@@ -55,10 +58,8 @@ func (b *batchBlueprint) ToBatch() RecipeBatch {
 
 type RecipeBatch []PayoutRecipe
 
-func (b *RecipeBatch) ToOpExecutionContext(signer SignerEngine, transactor TransactorEngine) (*OpExecutionContext, error) {
-	op := codec.NewOp().WithSource(signer.GetPKH())
-	op.WithTTL(constants.MAX_OPERATION_TTL)
-
+// injectContents adds transfer contents of the batch payouts to the operation
+func (b *RecipeBatch) injectContents(op *codec.Op) {
 	serializationGasLimit := lo.Reduce(*b, func(acc int64, p PayoutRecipe, _ int) int64 {
 		return acc + p.OpLimits.DeserializationGasLimit
 	}, int64(0))
@@ -74,8 +75,54 @@ func (b *RecipeBatch) ToOpExecutionContext(signer SignerEngine, transactor Trans
 			StorageLimit: p.OpLimits.StorageLimit,
 		})
 	}
+}
 
-	err := transactor.Complete(op, signer.GetKey())
+// ToCompletedOp builds the operation of the batch and completes it (branch, counters, reveal), the operation is not signed
+func (b *RecipeBatch) ToCompletedOp(key mavryk.Key, transactor TransactorEngine) (*codec.Op, error) {
+	return b.ToCompletedOpWithTTL(key, transactor, constants.MAX_OPERATION_TTL)
+}
+
+// ToCompletedOpWithTTL completes the operation on branch which remains valid for ttl blocks,
+// the branch is taken max_operations_ttl - ttl blocks below the head
+func (b *RecipeBatch) ToCompletedOpWithTTL(key mavryk.Key, transactor TransactorEngine, ttl int64) (*codec.Op, error) {
+	op := codec.NewOp().WithSource(key.Address())
+	op.WithTTL(ttl)
+	b.injectContents(op)
+
+	if err := transactor.Complete(op, key); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// ToCompletedOpWithCounter completes the operation and assigns consecutive counters starting from counter.
+// Used for operations injected before the previous ones are included, so the reveal is expected in the first operation.
+func (b *RecipeBatch) ToCompletedOpWithCounter(key mavryk.Key, transactor TransactorEngine, counter int64) (*codec.Op, error) {
+	op, err := b.ToCompletedOp(key, transactor)
+	if err != nil {
+		return nil, err
+	}
+	if len(op.Contents) > 0 {
+		if _, ok := op.Contents[0].(*codec.Reveal); ok {
+			op.Contents = op.Contents[1:]
+		}
+	}
+	for i := range op.Contents {
+		op.Contents[i].WithCounter(counter + int64(i))
+	}
+	return op, nil
+}
+
+// GetNextCounter returns counter the operation following the op has to use
+func GetNextCounter(op *codec.Op) int64 {
+	if len(op.Contents) == 0 {
+		return 0
+	}
+	return op.Contents[len(op.Contents)-1].GetCounter() + 1
+}
+
+func (b *RecipeBatch) ToOpExecutionContext(signer SignerEngine, transactor TransactorEngine) (*OpExecutionContext, error) {
+	op, err := b.ToCompletedOp(signer.GetKey(), transactor)
 	if err != nil {
 		return nil, err
 	}
@@ -87,3 +134,50 @@ func (b *RecipeBatch) ToOpExecutionContext(signer SignerEngine, transactor Trans
 	}
 	return InitOpExecutionContext(op, transactor), nil
 }
+
+func bumpFee(fee int64, feeBump float64) int64 {
+	return fee + max(1, int64(math.Ceil(float64(fee)*feeBump)))
+}
+
+func (b *RecipeBatch) ToOpExecutionContextWithCounter(signer SignerEngine, transactor TransactorEngine, counter int64) (*OpExecutionContext, error) {
+	op, err := b.ToCompletedOpWithCounter(signer.GetKey(), transactor, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("new op context", "op", op.Bytes(), "op_hash", op.Hash(), "counter", counter)
+	err = signer.Sign(op)
+	if err != nil {
+		return nil, err
+	}
+	return InitOpExecutionContext(op, transactor), nil
+}
+
+// ToReplacementOpExecutionContext re-forges the batch operation on a fresh branch with fees of the previous operation increased by feeBump portion.
+// Counters of the previous operation are kept, so only one operation of the replacement chain can ever be included.
+// If the payout wallet counter moved meanwhile, the previous operation (or other operation of the wallet) was included and
+// the replacement is refused to prevent double payment.
+func (b *RecipeBatch) ToReplacementOpExecutionContext(previous *codec.Op, feeBump float64, signer SignerEngine, transactor TransactorEngine) (*OpExecutionContext, error) {
+	op, err := b.ToCompletedOp(signer.GetKey(), transactor)
+	if err != nil {
+		return nil, err
+	}
+	if len(op.Contents) != len(previous.Contents) {
+		return nil, errors.Join(constants.ErrOperationReplacementNotPossible, fmt.Errorf("expected %d contents, got %d", len(previous.Contents), len(op.Contents)))
+	}
+	for i, content := range previous.Contents {
+		if op.Contents[i].GetCounter() != content.GetCounter() {
+			return nil, errors.Join(constants.ErrOperationReplacementNotPossible, fmt.Errorf("expected counter %d, got %d", content.GetCounter(), op.Contents[i].GetCounter()))
+		}
+		limits := op.Contents[i].Limits()
+		limits.Fee = bumpFee(content.Limits().Fee, feeBump)
+		op.Contents[i].WithLimits(limits)
+	}
+
+	slog.Debug("new replacement op context", "op", op.Bytes(), "op_hash", op.Hash())
+	err = signer.Sign(op)
+	if err != nil {
+		return nil, err
+	}
+	return InitOpExecutionContext(op, transactor), nil
+}
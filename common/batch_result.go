@@ -10,6 +10,8 @@ type BatchResult struct {
 	OpHash    mavryk.OpHash  `json:"op_hash"`
 	IsSuccess bool           `json:"is_success"`
 	Err       error          `json:"err"`
+	// operations dispatched for the batch in order, each replacing the previous one, set only if the operation was replaced
+	OpHashChain []mavryk.OpHash `json:"op_hash_chain,omitempty"`
 }
 
 func NewFailedBatchResult(payouts []PayoutRecipe, err error) *BatchResult {
@@ -34,6 +36,14 @@ func NewSuccessBatchResult(payouts []PayoutRecipe, opHash mavryk.OpHash) *BatchR
 	}
 }
 
+// WithOpHashChain records the replacement chain, it is kept only if the operation was actually replaced
+func (br *BatchResult) WithOpHashChain(chain []mavryk.OpHash) *BatchResult {
+	if len(chain) > 1 {
+		br.OpHashChain = chain
+	}
+	return br
+}
+
 func (br *BatchResult) ToReports() []PayoutReport {
 	result := make([]PayoutReport, len(br.Payouts))
 	for i, payout := range br.Payouts {
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplacementOpExecutionContext(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+	assert.Nil(err)
+	signer := &keySigner{key: key}
+	batch := RecipeBatch{
+		{Recipient: mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g"), TxKind: enums.PAYOUT_TX_KIND_MAV, Amount: mavryk.NewZ(1000), OpLimits: &OpLimits{GasLimit: 1000, StorageLimit: 0, TransactionFee: 500}},
+		{Recipient: mavryk.MustParseAddress("mv1DsVn1LCaMTS3DjpA3JRZWGcvAeFRqzaLa"), TxKind: enums.PAYOUT_TX_KIND_MAV, Amount: mavryk.NewZ(2000), OpLimits: &OpLimits{GasLimit: 1000, StorageLimit: 257, TransactionFee: 0}},
+	}
+
+	original, err := batch.ToOpExecutionContext(signer, &completingTransactor{})
+	assert.Nil(err)
+
+	replacement, err := batch.ToReplacementOpExecutionContext(original.Op, 0.5, signer, &completingTransactor{})
+	assert.Nil(err)
+	assert.Equal(len(original.Op.Contents), len(replacement.Op.Contents))
+	for i, content := range original.Op.Contents {
+		assert.Equal(content.GetCounter(), replacement.Op.Contents[i].GetCounter())
+	}
+	// reveal, 50% bump and minimal bump of zero fee
+	assert.Equal(int64(1), replacement.Op.Contents[0].Limits().Fee)
+	assert.Equal(int64(750), replacement.Op.Contents[1].Limits().Fee)
+	assert.Equal(int64(1), replacement.Op.Contents[2].Limits().Fee)
+	assert.Equal(original.Op.Contents[1].Limits().GasLimit, replacement.Op.Contents[1].Limits().GasLimit)
+
+	// counter moved, previous operation may have been included
+	_, err = batch.ToReplacementOpExecutionContext(original.Op, 0.5, signer, &completingTransactor{counterOffset: 1})
+	assert.ErrorIs(err, constants.ErrOperationReplacementNotPossible)
+}
+
+func TestCompletedOpWithCounter(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+	assert.Nil(err)
+	batch := RecipeBatch{
+		{Recipient: mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g"), TxKind: enums.PAYOUT_TX_KIND_MAV, Amount: mavryk.NewZ(1000), OpLimits: &OpLimits{GasLimit: 1000, TransactionFee: 500}},
+		{Recipient: mavryk.MustParseAddress("mv1DsVn1LCaMTS3DjpA3JRZWGcvAeFRqzaLa"), TxKind: enums.PAYOUT_TX_KIND_MAV, Amount: mavryk.NewZ(2000), OpLimits: &OpLimits{GasLimit: 1000, TransactionFee: 500}},
+	}
+
+	first, err := batch.ToCompletedOp(key.Public(), &completingTransactor{})
+	assert.Nil(err)
+	assert.Equal(int64(103), GetNextCounter(first))
+
+	// reveal is part of the first operation only
+	next, err := batch.ToCompletedOpWithCounter(key.Public(), &completingTransactor{}, GetNextCounter(first))
+	assert.Nil(err)
+	assert.Equal(len(batch), len(next.Contents))
+	assert.Equal(int64(103), next.Contents[0].GetCounter())
+	assert.Equal(int64(104), next.Contents[1].GetCounter())
+	assert.Equal(int64(105), GetNextCounter(next))
+}
+
+type keySigner struct {
+	SignerEngine
+	key mavryk.PrivateKey
+}
+
+func (signer *keySigner) GetKey() mavryk.Key {
+	return signer.key.Public()
+}
+
+func (signer *keySigner) Sign(op *codec.Op) error {
+	return op.Sign(signer.key)
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+// CarryOver is an amount of the delegator's payout of the cycle which was below the minimum payout amount
+type CarryOver struct {
+	Delegator mavryk.Address `json:"delegator"`
+	Recipient mavryk.Address `json:"recipient"`
+	// set only if the payout of the delegator is split among several recipients
+	RecipientShare float64  `json:"recipient_share,omitempty"`
+	Cycle          int64    `json:"cycle"`
+	Amount         mavryk.Z `json:"amount"`
+}
+
+// GetCarryOverKey identifies carried over amounts of the delegator, each recipient of a split payout has its own
+func GetCarryOverKey(delegator mavryk.Address, recipient mavryk.Address, recipientShare float64) string {
+	if recipientShare > 0 {
+		return fmt.Sprintf("%s/%s", delegator, recipient)
+	}
+	return delegator.String()
+}
+
+func (entry *CarryOver) GetKey() string {
+	return GetCarryOverKey(entry.Delegator, entry.Recipient, entry.RecipientShare)
+}
+
+// CarryOverLedger keeps carried over amounts until they are paid out
+type CarryOverLedger struct {
+	Entries []CarryOver `json:"entries"`
+}
+
+func NewCarryOverLedger() *CarryOverLedger {
+	return &CarryOverLedger{
+		Entries: make([]CarryOver, 0),
+	}
+}
+
+// Accrue records the amount unless the cycle is already recorded under the key of the entry
+func (ledger *CarryOverLedger) Accrue(entry CarryOver) bool {
+	if lo.ContainsBy(ledger.Entries, func(existing CarryOver) bool {
+		return existing.GetKey() == entry.GetKey() && existing.Cycle == entry.Cycle
+	}) {
+		return false
+	}
+	ledger.Entries = append(ledger.Entries, entry)
+	return true
+}
+
+// GetEntries returns entries recorded under the key ordered by cycle
+func (ledger *CarryOverLedger) GetEntries(key string) []CarryOver {
+	entries := lo.Filter(ledger.Entries, func(entry CarryOver, _ int) bool {
+		return entry.GetKey() == key
+	})
+	slices.SortFunc(entries, func(a, b CarryOver) int {
+		return int(a.Cycle - b.Cycle)
+	})
+	return entries
+}
+
+// Settle removes entries recorded under the key paid out with a payout
+func (ledger *CarryOverLedger) Settle(key string, cycles []int64) {
+	ledger.Entries = lo.Filter(ledger.Entries, func(entry CarryOver, _ int) bool {
+		return entry.GetKey() != key || !slices.Contains(cycles, entry.Cycle)
+	})
+}
+
+// SumCarryOvers returns total amount of the entries
+func SumCarryOvers(entries []CarryOver) mavryk.Z {
+	return lo.Reduce(entries, func(agg mavryk.Z, entry CarryOver, _ int) mavryk.Z {
+		return agg.Add(entry.Amount)
+	}, mavryk.Zero)
+}
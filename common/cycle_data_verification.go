@@ -0,0 +1,160 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mavryk-network/mvgo/mavryk"
+)
+
+const (
+	BAKER_SUBJECT = "baker"
+
+	PRESENCE_FIELD = "presence"
+	PRESENT_VALUE  = "present"
+	MISSING_VALUE  = "missing"
+)
+
+type CycleDataSource struct {
+	Id   string
+	Data *BakersCycleData
+	// source provides only balances, rewards are not compared
+	BalancesOnly bool
+}
+
+type CycleDataMismatch struct {
+	Cycle           int64  `json:"cycle" csv:"cycle"`
+	Subject         string `json:"subject" csv:"subject"`
+	Field           string `json:"field" csv:"field"`
+	ReferenceSource string `json:"reference_source" csv:"reference_source"`
+	ReferenceValue  string `json:"reference_value" csv:"reference_value"`
+	Source          string `json:"source" csv:"source"`
+	Value           string `json:"value" csv:"value"`
+	Difference      string `json:"difference,omitempty" csv:"difference"`
+}
+
+func (m *CycleDataMismatch) ToTableRowData() []string {
+	return []string{
+		m.Subject,
+		m.Field,
+		fmt.Sprintf("%s (%s)", m.ReferenceValue, m.ReferenceSource),
+		fmt.Sprintf("%s (%s)", m.Value, m.Source),
+		m.Difference,
+	}
+}
+
+func (m *CycleDataMismatch) GetTableHeaders() []string {
+	return []string{"Subject", "Field", "Reference", "Value", "Difference"}
+}
+
+type cycleDataField struct {
+	name     string
+	isReward bool
+	get      func(data *BakersCycleData) mavryk.Z
+}
+
+var (
+	comparedCycleDataFields = []cycleDataField{
+		{"own_delegated_balance", false, func(d *BakersCycleData) mavryk.Z { return d.OwnDelegatedBalance }},
+		{"external_delegated_balance", false, func(d *BakersCycleData) mavryk.Z { return d.ExternalDelegatedBalance }},
+		{"own_staked_balance", false, func(d *BakersCycleData) mavryk.Z { return d.OwnStakedBalance }},
+		{"external_staked_balance", false, func(d *BakersCycleData) mavryk.Z { return d.ExternalStakedBalance }},
+		{"delegators_count", false, func(d *BakersCycleData) mavryk.Z { return mavryk.NewZ(int64(d.DelegatorsCount)) }},
+		{"stakers_count", false, func(d *BakersCycleData) mavryk.Z { return mavryk.NewZ(int64(d.StakersCount)) }},
+		{"block_delegated_rewards", true, func(d *BakersCycleData) mavryk.Z { return d.BlockDelegatedRewards }},
+		{"ideal_block_delegated_rewards", true, func(d *BakersCycleData) mavryk.Z { return d.IdealBlockDelegatedRewards }},
+		{"endorsement_delegated_rewards", true, func(d *BakersCycleData) mavryk.Z { return d.EndorsementDelegatedRewards }},
+		{"ideal_endorsement_delegated_rewards", true, func(d *BakersCycleData) mavryk.Z { return d.IdealEndorsementDelegatedRewards }},
+		{"block_delegated_fees", true, func(d *BakersCycleData) mavryk.Z { return d.BlockDelegatedFees }},
+		{"block_staking_rewards_edge", true, func(d *BakersCycleData) mavryk.Z { return d.BlockStakingRewardsEdge }},
+		{"endorsement_staking_rewards_edge", true, func(d *BakersCycleData) mavryk.Z { return d.EndorsementStakingRewardsEdge }},
+		{"block_staking_rewards_shared", true, func(d *BakersCycleData) mavryk.Z { return d.BlockStakingRewardsShared }},
+		{"endorsement_staking_rewards_shared", true, func(d *BakersCycleData) mavryk.Z { return d.EndorsementStakingRewardsShared }},
+		{"block_staking_fees", true, func(d *BakersCycleData) mavryk.Z { return d.BlockStakingFees }},
+	}
+)
+
+func compareZ(cycle int64, subject string, field string, reference *CycleDataSource, referenceValue mavryk.Z, source *CycleDataSource, value mavryk.Z, tolerance mavryk.Z) *CycleDataMismatch {
+	difference := value.Sub(referenceValue)
+	absDifference := difference
+	if absDifference.IsNeg() {
+		absDifference = absDifference.Neg()
+	}
+	if !tolerance.IsLess(absDifference) {
+		return nil
+	}
+	return &CycleDataMismatch{
+		Cycle:           cycle,
+		Subject:         subject,
+		Field:           field,
+		ReferenceSource: reference.Id,
+		ReferenceValue:  referenceValue.String(),
+		Source:          source.Id,
+		Value:           value.String(),
+		Difference:      difference.String(),
+	}
+}
+
+// CompareCycleData compares cycle data of sources against the reference and returns
+// all baker and delegator level differences larger than tolerance (in mumav)
+func CompareCycleData(cycle int64, tolerance mavryk.Z, reference CycleDataSource, sources ...CycleDataSource) []CycleDataMismatch {
+	result := make([]CycleDataMismatch, 0)
+	for _, source := range sources {
+		for _, field := range comparedCycleDataFields {
+			if field.isReward && (source.BalancesOnly || reference.BalancesOnly) {
+				continue
+			}
+			if mismatch := compareZ(cycle, BAKER_SUBJECT, field.name, &reference, field.get(reference.Data), &source, field.get(source.Data), tolerance); mismatch != nil {
+				result = append(result, *mismatch)
+			}
+		}
+
+		referenceDelegators := make(map[string]Delegator, len(reference.Data.Delegators))
+		for _, delegator := range reference.Data.Delegators {
+			referenceDelegators[delegator.Address.String()] = delegator
+		}
+		sourceDelegators := make(map[string]Delegator, len(source.Data.Delegators))
+		for _, delegator := range source.Data.Delegators {
+			sourceDelegators[delegator.Address.String()] = delegator
+		}
+
+		addresses := make([]string, 0, len(referenceDelegators))
+		for address := range referenceDelegators {
+			addresses = append(addresses, address)
+		}
+		for address := range sourceDelegators {
+			if _, ok := referenceDelegators[address]; !ok {
+				addresses = append(addresses, address)
+			}
+		}
+		slices.Sort(addresses)
+
+		for _, address := range addresses {
+			referenceDelegator, inReference := referenceDelegators[address]
+			sourceDelegator, inSource := sourceDelegators[address]
+			if inReference != inSource {
+				referenceValue, value := PRESENT_VALUE, MISSING_VALUE
+				if !inReference {
+					referenceValue, value = MISSING_VALUE, PRESENT_VALUE
+				}
+				result = append(result, CycleDataMismatch{
+					Cycle:           cycle,
+					Subject:         address,
+					Field:           PRESENCE_FIELD,
+					ReferenceSource: reference.Id,
+					ReferenceValue:  referenceValue,
+					Source:          source.Id,
+					Value:           value,
+				})
+				continue
+			}
+			if mismatch := compareZ(cycle, address, "delegated_balance", &reference, referenceDelegator.DelegatedBalance, &source, sourceDelegator.DelegatedBalance, tolerance); mismatch != nil {
+				result = append(result, *mismatch)
+			}
+			if mismatch := compareZ(cycle, address, "staked_balance", &reference, referenceDelegator.StakedBalance, &source, sourceDelegator.StakedBalance, tolerance); mismatch != nil {
+				result = append(result, *mismatch)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareCycleData(t *testing.T) {
+	assert := assert.New(t)
+
+	first := mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g")
+	second := mavryk.MustParseAddress("mv1DsVn1LCaMTS3DjpA3JRZWGcvAeFRqzaLa")
+
+	reference := CycleDataSource{
+		Id: "rpc",
+		Data: &BakersCycleData{
+			OwnDelegatedBalance:   mavryk.NewZ(1000),
+			BlockDelegatedRewards: mavryk.NewZ(100),
+			Delegators: []Delegator{
+				{Address: first, DelegatedBalance: mavryk.NewZ(500), StakedBalance: mavryk.NewZ(10)},
+				{Address: second, DelegatedBalance: mavryk.NewZ(200)},
+			},
+		},
+	}
+	source := CycleDataSource{
+		Id: "protocol-rewards",
+		Data: &BakersCycleData{
+			OwnDelegatedBalance:   mavryk.NewZ(1001),
+			BlockDelegatedRewards: mavryk.NewZ(50),
+			Delegators: []Delegator{
+				{Address: first, DelegatedBalance: mavryk.NewZ(502), StakedBalance: mavryk.NewZ(10)},
+			},
+		},
+		BalancesOnly: true,
+	}
+
+	mismatches := CompareCycleData(1, mavryk.NewZ(0), reference, source)
+	assert.Len(mismatches, 3)
+	assert.Equal("own_delegated_balance", mismatches[0].Field)
+	assert.Equal("1", mismatches[0].Difference)
+	assert.Equal(PRESENCE_FIELD, mismatches[1].Field)
+	assert.Equal(second.String(), mismatches[1].Subject)
+	assert.Equal(MISSING_VALUE, mismatches[1].Value)
+	assert.Equal("delegated_balance", mismatches[2].Field)
+	assert.Equal("2", mismatches[2].Difference)
+
+	// differences within tolerance are ignored
+	mismatches = CompareCycleData(1, mavryk.NewZ(2), reference, source)
+	assert.Len(mismatches, 1)
+	assert.Equal(PRESENCE_FIELD, mismatches[0].Field)
+}
@@ -0,0 +1,399 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/mavryk"
+)
+
+const (
+	ELIGIBILITY_ADDRESS_TYPE_IMPLICIT = "implicit"
+	ELIGIBILITY_ADDRESS_TYPE_CONTRACT = "contract"
+)
+
+// EligibilityFacts are the candidate fields eligibility conditions are evaluated against
+type EligibilityFacts struct {
+	Address          mavryk.Address
+	DelegatedBalance mavryk.Z
+	StakedBalance    mavryk.Z
+	DelegationAge    int64
+	Cycle            int64
+}
+
+type eligibilityValue struct {
+	number float64
+	text   string
+}
+
+type eligibilityField struct {
+	isText bool
+	get    func(facts *EligibilityFacts) eligibilityValue
+}
+
+func mumavToMav(amount mavryk.Z) float64 {
+	return float64(amount.Int64()) / constants.MUMAV_FACTOR
+}
+
+// balances are compared in mav, same as balances in the configuration
+var eligibilityFields = map[string]eligibilityField{
+	"balance": {get: func(facts *EligibilityFacts) eligibilityValue {
+		return eligibilityValue{number: mumavToMav(facts.DelegatedBalance)}
+	}},
+	"staked_balance": {get: func(facts *EligibilityFacts) eligibilityValue {
+		return eligibilityValue{number: mumavToMav(facts.StakedBalance)}
+	}},
+	"address": {isText: true, get: func(facts *EligibilityFacts) eligibilityValue {
+		return eligibilityValue{text: facts.Address.String()}
+	}},
+	"address_type": {isText: true, get: func(facts *EligibilityFacts) eligibilityValue {
+		if facts.Address.IsContract() {
+			return eligibilityValue{text: ELIGIBILITY_ADDRESS_TYPE_CONTRACT}
+		}
+		return eligibilityValue{text: ELIGIBILITY_ADDRESS_TYPE_IMPLICIT}
+	}},
+	"delegation_age": {get: func(facts *EligibilityFacts) eligibilityValue {
+		return eligibilityValue{number: float64(facts.DelegationAge)}
+	}},
+	"cycle": {get: func(facts *EligibilityFacts) eligibilityValue {
+		return eligibilityValue{number: float64(facts.Cycle)}
+	}},
+}
+
+type eligibilityOperand struct {
+	field   string
+	literal *eligibilityValue
+	isText  bool
+}
+
+func (operand *eligibilityOperand) value(facts *EligibilityFacts) eligibilityValue {
+	if operand.literal != nil {
+		return *operand.literal
+	}
+	return eligibilityFields[operand.field].get(facts)
+}
+
+type eligibilityExpression interface {
+	evaluate(facts *EligibilityFacts) bool
+	// number of cycles of delegation history needed to evaluate the expression
+	delegationAgeHistoryLength() int64
+}
+
+type eligibilityOr struct{ left, right eligibilityExpression }
+type eligibilityAnd struct{ left, right eligibilityExpression }
+type eligibilityNot struct{ expression eligibilityExpression }
+type eligibilityComparison struct {
+	operator    string
+	left, right eligibilityOperand
+}
+
+func (node *eligibilityOr) evaluate(facts *EligibilityFacts) bool {
+	return node.left.evaluate(facts) || node.right.evaluate(facts)
+}
+
+func (node *eligibilityOr) delegationAgeHistoryLength() int64 {
+	return max(node.left.delegationAgeHistoryLength(), node.right.delegationAgeHistoryLength())
+}
+
+func (node *eligibilityAnd) evaluate(facts *EligibilityFacts) bool {
+	return node.left.evaluate(facts) && node.right.evaluate(facts)
+}
+
+func (node *eligibilityAnd) delegationAgeHistoryLength() int64 {
+	return max(node.left.delegationAgeHistoryLength(), node.right.delegationAgeHistoryLength())
+}
+
+func (node *eligibilityNot) evaluate(facts *EligibilityFacts) bool {
+	return !node.expression.evaluate(facts)
+}
+
+func (node *eligibilityNot) delegationAgeHistoryLength() int64 {
+	return node.expression.delegationAgeHistoryLength()
+}
+
+func (node *eligibilityComparison) evaluate(facts *EligibilityFacts) bool {
+	left, right := node.left.value(facts), node.right.value(facts)
+	if node.left.isText {
+		if node.operator == "==" {
+			return left.text == right.text
+		}
+		return left.text != right.text
+	}
+	switch node.operator {
+	case "==":
+		return left.number == right.number
+	case "!=":
+		return left.number != right.number
+	case "<":
+		return left.number < right.number
+	case "<=":
+		return left.number <= right.number
+	case ">":
+		return left.number > right.number
+	default:
+		return left.number >= right.number
+	}
+}
+
+// delegationAgeHistoryLength returns the age the delegation has to be tracked to for the comparison
+// with a number to be decided, older delegations compare the same way
+func (node *eligibilityComparison) delegationAgeHistoryLength() int64 {
+	if node.left.field == "delegation_age" && node.right.literal != nil {
+		return int64(math.Floor(node.right.literal.number)) + 1
+	}
+	if node.right.field == "delegation_age" && node.left.literal != nil {
+		return int64(math.Floor(node.left.literal.number)) + 1
+	}
+	return 0
+}
+
+type eligibilityTokenKind int
+
+const (
+	eligibilityTokenEnd eligibilityTokenKind = iota
+	eligibilityTokenIdentifier
+	eligibilityTokenNumber
+	eligibilityTokenString
+	eligibilityTokenOperator
+)
+
+type eligibilityToken struct {
+	kind     eligibilityTokenKind
+	text     string
+	position int
+}
+
+var (
+	// two character operators first so they are not split
+	eligibilityOperators           = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+	eligibilityComparisonOperators = []string{"==", "!=", "<", "<=", ">", ">="}
+)
+
+func tokenizeEligibilityCondition(source string) ([]eligibilityToken, error) {
+	tokens := make([]eligibilityToken, 0)
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, eligibilityToken{eligibilityTokenIdentifier, string(runes[start:i]), start})
+		case unicode.IsDigit(r) || r == '.':
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, eligibilityToken{eligibilityTokenNumber, string(runes[start:i]), start})
+		case r == '\'' || r == '"':
+			i++
+			for i < len(runes) && runes[i] != r {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+			tokens = append(tokens, eligibilityToken{eligibilityTokenString, string(runes[start+1 : i-1]), start})
+		default:
+			operator := ""
+			for _, candidate := range eligibilityOperators {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character '%c' at %d", r, start)
+			}
+			i += len(operator)
+			tokens = append(tokens, eligibilityToken{eligibilityTokenOperator, operator, start})
+		}
+	}
+	return append(tokens, eligibilityToken{eligibilityTokenEnd, "", len(runes)}), nil
+}
+
+type eligibilityParser struct {
+	tokens   []eligibilityToken
+	position int
+}
+
+func (parser *eligibilityParser) peek() eligibilityToken {
+	return parser.tokens[parser.position]
+}
+
+func (parser *eligibilityParser) next() eligibilityToken {
+	token := parser.tokens[parser.position]
+	if token.kind != eligibilityTokenEnd {
+		parser.position++
+	}
+	return token
+}
+
+func (parser *eligibilityParser) isOperator(operator string) bool {
+	token := parser.peek()
+	return token.kind == eligibilityTokenOperator && token.text == operator
+}
+
+func (parser *eligibilityParser) parseOr() (eligibilityExpression, error) {
+	left, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for parser.isOperator("||") {
+		parser.next()
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &eligibilityOr{left, right}
+	}
+	return left, nil
+}
+
+func (parser *eligibilityParser) parseAnd() (eligibilityExpression, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for parser.isOperator("&&") {
+		parser.next()
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &eligibilityAnd{left, right}
+	}
+	return left, nil
+}
+
+func (parser *eligibilityParser) parseUnary() (eligibilityExpression, error) {
+	switch {
+	case parser.isOperator("!"):
+		parser.next()
+		expression, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &eligibilityNot{expression}, nil
+	case parser.isOperator("("):
+		parser.next()
+		expression, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.isOperator(")") {
+			return nil, fmt.Errorf("expected ')' at %d", parser.peek().position)
+		}
+		parser.next()
+		return expression, nil
+	default:
+		return parser.parseComparison()
+	}
+}
+
+func (parser *eligibilityParser) parseComparison() (eligibilityExpression, error) {
+	left, err := parser.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	token := parser.next()
+	if token.kind != eligibilityTokenOperator || !slices.Contains(eligibilityComparisonOperators, token.text) {
+		return nil, fmt.Errorf("expected comparison operator at %d", token.position)
+	}
+	right, err := parser.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if left.isText != right.isText {
+		return nil, fmt.Errorf("can not compare text with number at %d", token.position)
+	}
+	if left.isText && token.text != "==" && token.text != "!=" {
+		return nil, fmt.Errorf("text can be compared only with '==' and '!=' at %d", token.position)
+	}
+	return &eligibilityComparison{token.text, left, right}, nil
+}
+
+func (parser *eligibilityParser) parseOperand() (eligibilityOperand, error) {
+	token := parser.next()
+	switch token.kind {
+	case eligibilityTokenIdentifier:
+		field, ok := eligibilityFields[token.text]
+		if !ok {
+			return eligibilityOperand{}, fmt.Errorf("unknown field '%s' at %d", token.text, token.position)
+		}
+		return eligibilityOperand{field: token.text, isText: field.isText}, nil
+	case eligibilityTokenNumber:
+		number, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return eligibilityOperand{}, fmt.Errorf("invalid number '%s' at %d", token.text, token.position)
+		}
+		return eligibilityOperand{literal: &eligibilityValue{number: number}}, nil
+	case eligibilityTokenString:
+		return eligibilityOperand{literal: &eligibilityValue{text: token.text}, isText: true}, nil
+	default:
+		return eligibilityOperand{}, fmt.Errorf("expected field or value at %d", token.position)
+	}
+}
+
+// EligibilityCondition is a condition over the candidate fields, e.g. "balance < 100 && address_type == 'contract'".
+// Fields are balance and staked_balance (in mav), address, address_type ('implicit' or 'contract'), delegation_age and cycle.
+type EligibilityCondition struct {
+	source     string
+	expression eligibilityExpression
+}
+
+func ParseEligibilityCondition(source string) (*EligibilityCondition, error) {
+	tokens, err := tokenizeEligibilityCondition(source)
+	if err != nil {
+		return nil, errors.Join(constants.ErrInvalidEligibilityCondition, err)
+	}
+	parser := eligibilityParser{tokens: tokens}
+	expression, err := parser.parseOr()
+	if err != nil {
+		return nil, errors.Join(constants.ErrInvalidEligibilityCondition, err)
+	}
+	if token := parser.peek(); token.kind != eligibilityTokenEnd {
+		return nil, errors.Join(constants.ErrInvalidEligibilityCondition, fmt.Errorf("unexpected '%s' at %d", token.text, token.position))
+	}
+	return &EligibilityCondition{
+		source:     source,
+		expression: expression,
+	}, nil
+}
+
+func (condition *EligibilityCondition) Evaluate(facts *EligibilityFacts) bool {
+	return condition.expression.evaluate(facts)
+}
+
+// GetDelegationAgeHistoryLength returns number of cycles of delegation history the condition needs
+func (condition *EligibilityCondition) GetDelegationAgeHistoryLength() int64 {
+	return condition.expression.delegationAgeHistoryLength()
+}
+
+func (condition *EligibilityCondition) String() string {
+	return condition.source
+}
+
+func (condition *EligibilityCondition) MarshalText() ([]byte, error) {
+	return []byte(condition.source), nil
+}
+
+func (condition *EligibilityCondition) UnmarshalText(data []byte) error {
+	parsed, err := ParseEligibilityCondition(string(data))
+	if err != nil {
+		return err
+	}
+	*condition = *parsed
+	return nil
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEligibilityCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	implicit := EligibilityFacts{
+		Address:          mavryk.MustParseAddress("mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3"),
+		DelegatedBalance: mavryk.NewZ(150_000_000),
+		StakedBalance:    mavryk.NewZ(50_000_000),
+		DelegationAge:    4,
+		Cycle:            100,
+	}
+	contract := EligibilityFacts{
+		Address:          mavryk.MustParseAddress("KT1Hkg6qgV3VykjgUXKbWcU3h6oJ1qVxUxZV"),
+		DelegatedBalance: mavryk.NewZ(5_000_000),
+		DelegationAge:    12,
+		Cycle:            100,
+	}
+
+	cases := []struct {
+		source   string
+		implicit bool
+		contract bool
+	}{
+		{"balance < 100", false, true},
+		{"balance >= 150 && staked_balance == 50", true, false},
+		{"address_type == 'contract'", false, true},
+		{"address_type != \"contract\" || balance < 10", true, true},
+		{"!(delegation_age > 10) && cycle >= 100", true, false},
+		{"address == 'mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3'", true, false},
+		{"balance < 1 || balance > 100 && delegation_age < 5", true, false},
+		{"(balance < 1 || balance > 100) && delegation_age > 5", false, false},
+		{"  balance<=5  ", false, true},
+	}
+	for _, c := range cases {
+		condition, err := ParseEligibilityCondition(c.source)
+		assert.Nil(err, c.source)
+		assert.Equal(c.implicit, condition.Evaluate(&implicit), c.source)
+		assert.Equal(c.contract, condition.Evaluate(&contract), c.source)
+		assert.Equal(c.source, condition.String())
+	}
+
+	for _, source := range []string{
+		"",
+		"balance",
+		"balance < ",
+		"unknown > 1",
+		"balance < 'text'",
+		"address < 'mv1'",
+		"(balance < 1",
+		"balance < 1 balance > 2",
+		"address == 'mv1",
+		"balance < 1 & balance > 2",
+		"balance < 1..2",
+	} {
+		_, err := ParseEligibilityCondition(source)
+		assert.NotNil(err, source)
+	}
+
+	condition, err := ParseEligibilityCondition("delegation_age >= 10 || (balance > 5 && 20.5 > delegation_age)")
+	assert.Nil(err)
+	assert.Equal(int64(21), condition.GetDelegationAgeHistoryLength())
+	condition, err = ParseEligibilityCondition("balance > 5")
+	assert.Nil(err)
+	assert.Equal(int64(0), condition.GetDelegationAgeHistoryLength())
+}
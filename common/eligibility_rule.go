@@ -0,0 +1,35 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/mavryk"
+)
+
+// EligibilityRule takes the action on candidates meeting the condition
+type EligibilityRule struct {
+	Condition *EligibilityCondition        `json:"if"`
+	Action    enums.EEligibilityRuleAction `json:"action"`
+	// reported as the invalid reason of candidates excluded by the rule
+	Reason         string         `json:"reason,omitempty"`
+	Fee            *float64       `json:"fee,omitempty"`
+	Recipient      mavryk.Address `json:"recipient,omitempty"`
+	MaximumBalance *mavryk.Z      `json:"maximum_balance,omitempty"`
+}
+
+func (rule *EligibilityRule) GetInvalidReason() enums.EPayoutInvalidReason {
+	if rule.Reason != "" {
+		return enums.EPayoutInvalidReason(rule.Reason)
+	}
+	return enums.EPayoutInvalidReason(fmt.Sprintf("%s (%s)", enums.INVALID_EXCLUDED_BY_RULE, rule.Condition))
+}
+
+// GetEligibilityRulesHistoryLength returns number of cycles of delegation history the rules need
+func GetEligibilityRulesHistoryLength(rules []EligibilityRule) int64 {
+	length := int64(0)
+	for _, rule := range rules {
+		length = max(length, rule.Condition.GetDelegationAgeHistoryLength())
+	}
+	return length
+}
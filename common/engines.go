@@ -24,7 +24,13 @@ type CollectorEngine interface {
 	GetCurrentCycleNumber() (int64, error)
 	GetLastCompletedCycle() (int64, error)
 	GetCycleStakingData(baker mavryk.Address, cycle int64) (*BakersCycleData, error)
+	// GetCycleDelegators returns addresses delegated to the baker in the cycle, without balances and rewards collected by GetCycleStakingData
+	GetCycleDelegators(baker mavryk.Address, cycle int64) ([]mavryk.Address, error)
 	GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error)
+	// GetCycleAtDate returns the cycle of the first block baked after the date, ErrNoBlockAfterDate if there is none yet
+	GetCycleAtDate(date time.Time) (int64, error)
+	// WasOperationApplied looks up the operation on chain. Collectors without an indexer search only recent blocks and
+	// report operations they do not find with OPERATION_STATUS_UNKNOWN, so it can not be used to tell an old operation does not exist.
 	WasOperationApplied(opHash mavryk.OpHash) (OperationStatus, error)
 	GetBranch(offset int64) (mavryk.BlockHash, error)
 	Simulate(o *codec.Op, publicKey mavryk.Key) (*rpc.Receipt, error)
@@ -43,6 +49,12 @@ type SignerEngine interface {
 	GetSigner() signer.Signer
 }
 
+// PayoutBlueprintsAwareSigner is implemented by signers which account signed amounts to cycles of the blueprints being executed,
+// the reporter keeps the accounted amounts between runs if it is able to
+type PayoutBlueprintsAwareSigner interface {
+	SetPayoutBlueprints(blueprints []*CyclePayoutBlueprint, reporter ReporterEngine)
+}
+
 type OpResult interface {
 	GetOpHash() mavryk.OpHash
 	WaitForApply() error
@@ -59,6 +71,26 @@ type TransactorEngine interface {
 	WaitOpConfirmation(opHash mavryk.OpHash, ttl int64, confirmations int64) (*rpc.Receipt, error)
 }
 
+// OperationStatusAwareTransactor is implemented by transactors able to look up status of previously dispatched operations
+type OperationStatusAwareTransactor interface {
+	GetOperationStatus(opHash mavryk.OpHash) (OperationStatus, error)
+}
+
+// BranchValidityAwareTransactor is implemented by transactors able to tell until when operations forged on a branch can be included
+type BranchValidityAwareTransactor interface {
+	// GetBranchValidity returns the last level operations forged on the branch can be included at and the level of the current head
+	GetBranchValidity(branch mavryk.BlockHash) (validUntil int64, head int64, err error)
+}
+
+// PayoutJournal is an append-only record of payout batch state transitions, used to reconcile batches interrupted by a crash.
+// Entries of runs written to reports are not needed anymore and are dropped by Compact.
+type PayoutJournal interface {
+	Record(entry JournalEntry) error
+	Load() ([]JournalEntry, error)
+	// Compact drops entries of runs whose results were written to reports
+	Compact() error
+}
+
 type NotificatorEngine interface {
 	PayoutSummaryNotify(summary *CyclePayoutSummary, additionalData map[string]string) error
 	AdminNotify(msg string) error
@@ -84,3 +116,15 @@ type ReporterEngine interface {
 	ReportCycleSummary(summary CyclePayoutSummary) error
 	GetExistingCycleSummary(cycle int64) (*CyclePayoutSummary, error)
 }
+
+// CarryOverLedgerAwareReporter is implemented by reporters able to store the ledger of carried over payouts with the reports
+type CarryOverLedgerAwareReporter interface {
+	GetCarryOverLedger() (*CarryOverLedger, error)
+	ReportCarryOverLedger(ledger *CarryOverLedger) error
+}
+
+// SigningPolicyLedgerAwareReporter is implemented by reporters able to store amounts signed under the signing policy with the reports
+type SigningPolicyLedgerAwareReporter interface {
+	GetSigningPolicyLedger() (*SigningPolicyLedger, error)
+	ReportSigningPolicyLedger(ledger *SigningPolicyLedger) error
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+// FeeRule sets the fee for the cycles within the cycle and date ranges, unset bounds are open.
+// Rule without delegators applies to everyone.
+type FeeRule struct {
+	Fee        float64          `json:"fee"`
+	FromCycle  *int64           `json:"from_cycle,omitempty"`
+	ToCycle    *int64           `json:"to_cycle,omitempty"`
+	FromDate   *time.Time       `json:"from_date,omitempty"`
+	ToDate     *time.Time       `json:"to_date,omitempty"`
+	Delegators []mavryk.Address `json:"delegators,omitempty"`
+}
+
+// IsActiveInCycle checks whether the rule applies to the cycle. Each date bound is resolved to the cycle of the first block
+// baked after it and the rule applies to cycles overlapping the date range. Paid cycles are finished, so a bound
+// no block was baked after yet lies after all of them, such end leaves the range open.
+func (rule *FeeRule) IsActiveInCycle(cycle int64, collector CollectorEngine) (bool, error) {
+	if rule.FromCycle != nil && cycle < *rule.FromCycle {
+		return false, nil
+	}
+	if rule.ToCycle != nil && cycle > *rule.ToCycle {
+		return false, nil
+	}
+	if rule.FromDate != nil {
+		fromCycle, err := collector.GetCycleAtDate(*rule.FromDate)
+		if errors.Is(err, constants.ErrNoBlockAfterDate) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if cycle < fromCycle {
+			return false, nil
+		}
+	}
+	if rule.ToDate != nil {
+		toCycle, err := collector.GetCycleAtDate(*rule.ToDate)
+		if errors.Is(err, constants.ErrNoBlockAfterDate) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if cycle > toCycle {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// AppliesTo checks whether the delegator belongs to the group of the rule
+func (rule *FeeRule) AppliesTo(delegator mavryk.Address) bool {
+	if len(rule.Delegators) == 0 {
+		return true
+	}
+	return lo.ContainsBy(rule.Delegators, func(address mavryk.Address) bool {
+		return address.Equal(delegator)
+	})
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/mavryk"
+)
+
+// FeeScheduleBracket applies the fee to balances bellow UpTo, nil UpTo means the bracket is unbounded
+type FeeScheduleBracket struct {
+	UpTo *mavryk.Z `json:"up_to,omitempty"`
+	Fee  float64   `json:"fee"`
+}
+
+// FeeSchedule determines the fee of a delegator from its balance, brackets are ordered by ascending UpTo
+type FeeSchedule struct {
+	Balance  enums.EFeeScheduleBalance `json:"balance"`
+	Mode     enums.EFeeScheduleMode    `json:"mode"`
+	Brackets []FeeScheduleBracket      `json:"brackets"`
+}
+
+func (schedule *FeeSchedule) getBalance(delegatedBalance mavryk.Z, stakedBalance mavryk.Z) mavryk.Z {
+	switch schedule.Balance {
+	case enums.FEE_SCHEDULE_BALANCE_STAKED:
+		return stakedBalance
+	case enums.FEE_SCHEDULE_BALANCE_TOTAL:
+		return delegatedBalance.Add(stakedBalance)
+	default:
+		return delegatedBalance
+	}
+}
+
+func (schedule *FeeSchedule) getBracket(balance mavryk.Z) FeeScheduleBracket {
+	for _, bracket := range schedule.Brackets {
+		if bracket.UpTo == nil || balance.IsLess(*bracket.UpTo) {
+			return bracket
+		}
+	}
+	return schedule.Brackets[len(schedule.Brackets)-1]
+}
+
+// GetFeeRate returns the fee rate for the balances. In marginal mode the rate is the effective one -
+// fees of the brackets weighted by the part of the balance within each bracket.
+func (schedule *FeeSchedule) GetFeeRate(delegatedBalance mavryk.Z, stakedBalance mavryk.Z) float64 {
+	if len(schedule.Brackets) == 0 {
+		return 0
+	}
+	balance := schedule.getBalance(delegatedBalance, stakedBalance)
+	if schedule.Mode != enums.FEE_SCHEDULE_MODE_MARGINAL || balance.IsZero() || balance.IsNeg() {
+		return schedule.getBracket(balance).Fee
+	}
+
+	fee := float64(0)
+	lowerBound := mavryk.Zero
+	for _, bracket := range schedule.Brackets {
+		upperBound := balance
+		if bracket.UpTo != nil && bracket.UpTo.IsLess(balance) {
+			upperBound = *bracket.UpTo
+		}
+		fee += bracket.Fee * float64(upperBound.Sub(lowerBound).Int64())
+		if !upperBound.IsLess(balance) {
+			break
+		}
+		lowerBound = upperBound
+	}
+	return fee / float64(balance.Int64())
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func mav(amount int64) mavryk.Z {
+	return mavryk.NewZ(amount).Mul64(constants.MUMAV_FACTOR)
+}
+
+func TestFeeScheduleGetFeeRate(t *testing.T) {
+	assert := assert.New(t)
+
+	firstBound := mav(1_000)
+	secondBound := mav(100_000)
+	schedule := FeeSchedule{
+		Balance: enums.FEE_SCHEDULE_BALANCE_DELEGATED,
+		Mode:    enums.FEE_SCHEDULE_MODE_FLAT,
+		Brackets: []FeeScheduleBracket{
+			{UpTo: &firstBound, Fee: .08},
+			{UpTo: &secondBound, Fee: .06},
+			{Fee: .04},
+		},
+	}
+
+	assert.Equal(.08, schedule.GetFeeRate(mavryk.Zero, mavryk.Zero))
+	assert.Equal(.08, schedule.GetFeeRate(mav(999), mavryk.Zero))
+	assert.Equal(.06, schedule.GetFeeRate(mav(1_000), mavryk.Zero))
+	assert.Equal(.04, schedule.GetFeeRate(mav(200_000), mavryk.Zero))
+
+	schedule.Balance = enums.FEE_SCHEDULE_BALANCE_STAKED
+	assert.Equal(.08, schedule.GetFeeRate(mav(200_000), mav(500)))
+	schedule.Balance = enums.FEE_SCHEDULE_BALANCE_TOTAL
+	assert.Equal(.06, schedule.GetFeeRate(mav(600), mav(500)))
+
+	schedule.Balance = enums.FEE_SCHEDULE_BALANCE_DELEGATED
+	schedule.Mode = enums.FEE_SCHEDULE_MODE_MARGINAL
+	assert.Equal(.08, schedule.GetFeeRate(mavryk.Zero, mavryk.Zero))
+	assert.InDelta(.08, schedule.GetFeeRate(mav(500), mavryk.Zero), 1e-9)
+	// 1k at 8% and 1k at 6%
+	assert.InDelta(.07, schedule.GetFeeRate(mav(2_000), mavryk.Zero), 1e-9)
+	// 1k at 8%, 99k at 6% and 100k at 4%
+	assert.InDelta((80+5_940+4_000)/200_000.0, schedule.GetFeeRate(mav(200_000), mavryk.Zero), 1e-9)
+}
@@ -0,0 +1,163 @@
+package common
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+// JournalEntry is a single state transition of a payout batch, entries are appended to the journal before and after every step
+type JournalEntry struct {
+	Timestamp time.Time                `json:"timestamp"`
+	Run       string                   `json:"run"`
+	Batch     string                   `json:"batch,omitempty"`
+	State     enums.EJournalBatchState `json:"state"`
+	Payouts   []PayoutRecipe           `json:"payouts,omitempty"`
+	// payouts combined into the batch payouts, reported separately for their own cycles
+	Accumulated []PayoutRecipe `json:"accumulated,omitempty"`
+	OpHash      mavryk.OpHash  `json:"op_hash"`
+	// hex encoded forged operation
+	Bytes string `json:"bytes,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func NewJournalEntry(run string, batch string, state enums.EJournalBatchState) JournalEntry {
+	return JournalEntry{
+		Timestamp: time.Now().UTC(),
+		Run:       run,
+		Batch:     batch,
+		State:     state,
+	}
+}
+
+// WithPayouts records payouts of the batch together with the accumulated payouts which were combined into them
+func (entry JournalEntry) WithPayouts(payouts []PayoutRecipe, accumulated []PayoutRecipe) JournalEntry {
+	entry.Payouts = payouts
+	notes := lo.SliceToMap(payouts, func(payout PayoutRecipe) (string, bool) {
+		return fmt.Sprintf("%s#%d", payout.GetShortIdentifier(), payout.Cycle), true
+	})
+	entry.Accumulated = lo.Filter(accumulated, func(payout PayoutRecipe, _ int) bool {
+		return payout.Kind == enums.PAYOUT_KIND_ACCUMULATED && notes[payout.Note]
+	})
+	return entry
+}
+
+func (entry JournalEntry) WithOp(op *codec.Op) JournalEntry {
+	if op == nil {
+		return entry
+	}
+	entry.OpHash = op.Hash()
+	entry.Bytes = hex.EncodeToString(op.Bytes())
+	return entry
+}
+
+func (entry JournalEntry) WithOpHash(opHash mavryk.OpHash) JournalEntry {
+	entry.OpHash = opHash
+	return entry
+}
+
+func (entry JournalEntry) WithError(err error) JournalEntry {
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// JournalBatch is the last known state of a batch replayed from the journal
+type JournalBatch struct {
+	Id          string
+	State       enums.EJournalBatchState
+	Payouts     []PayoutRecipe
+	Accumulated []PayoutRecipe
+	// all operations signed for the batch (replacements included)
+	OpHashes []mavryk.OpHash
+	OpHash   mavryk.OpHash
+	Error    string
+}
+
+func (batch *JournalBatch) IsFinished() bool {
+	return batch.State == enums.JOURNAL_BATCH_CONFIRMED || batch.State == enums.JOURNAL_BATCH_FAILED
+}
+
+type JournalRun struct {
+	Id      string
+	Batches []*JournalBatch
+}
+
+// GetCycles returns cycles of all payouts of the run, including the accumulated ones
+func (run *JournalRun) GetCycles() []int64 {
+	cycles := make([]int64, 0)
+	for _, batch := range run.Batches {
+		for _, payout := range append(batch.Payouts, batch.Accumulated...) {
+			cycles = append(cycles, payout.Cycle)
+		}
+	}
+	return lo.Uniq(cycles)
+}
+
+// GetUnfinishedJournalRuns replays the entries and returns runs whose results were not written to reports
+func GetUnfinishedJournalRuns(entries []JournalEntry) []JournalRun {
+	runs := make([]JournalRun, 0)
+	runIndexes := make(map[string]int)
+	reported := make(map[string]bool)
+	batches := make(map[string]*JournalBatch)
+
+	for _, entry := range entries {
+		if entry.State == enums.JOURNAL_RUN_REPORTED {
+			reported[entry.Run] = true
+			continue
+		}
+		runIndex, ok := runIndexes[entry.Run]
+		if !ok {
+			runIndex = len(runs)
+			runIndexes[entry.Run] = runIndex
+			runs = append(runs, JournalRun{Id: entry.Run, Batches: make([]*JournalBatch, 0)})
+		}
+
+		key := entry.Run + "|" + entry.Batch
+		batch, ok := batches[key]
+		if !ok {
+			batch = &JournalBatch{Id: entry.Batch, OpHashes: make([]mavryk.OpHash, 0)}
+			batches[key] = batch
+			runs[runIndex].Batches = append(runs[runIndex].Batches, batch)
+		}
+		batch.State = entry.State
+		batch.Error = entry.Error
+		if len(entry.Payouts) > 0 {
+			batch.Payouts = entry.Payouts
+			batch.Accumulated = entry.Accumulated
+		}
+		if entry.OpHash.IsValid() {
+			batch.OpHash = entry.OpHash
+			if !lo.ContainsBy(batch.OpHashes, func(opHash mavryk.OpHash) bool { return opHash.Equal(entry.OpHash) }) {
+				batch.OpHashes = append(batch.OpHashes, entry.OpHash)
+			}
+		}
+	}
+
+	result := make([]JournalRun, 0, len(runs))
+	for _, run := range runs {
+		if !reported[run.Id] {
+			result = append(result, run)
+		}
+	}
+	return result
+}
+
+// GetUnfinishedJournalEntries returns entries of runs whose results were not written to reports
+func GetUnfinishedJournalEntries(entries []JournalEntry) []JournalEntry {
+	reported := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.State == enums.JOURNAL_RUN_REPORTED {
+			reported[entry.Run] = true
+		}
+	}
+	return lo.Filter(entries, func(entry JournalEntry, _ int) bool {
+		return !reported[entry.Run]
+	})
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUnfinishedJournalRuns(t *testing.T) {
+	assert := assert.New(t)
+
+	opHash := mavryk.MustParseOpHash("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ")
+	payouts := []PayoutRecipe{
+		{Recipient: mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g"), Cycle: 10, Kind: enums.PAYOUT_KIND_DELEGATOR_REWARD, Amount: mavryk.NewZ(1000)},
+	}
+	accumulated := []PayoutRecipe{
+		{Recipient: mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g"), Cycle: 9, Kind: enums.PAYOUT_KIND_ACCUMULATED, Amount: mavryk.NewZ(500), Note: payouts[0].GetShortIdentifier() + "#10"},
+		{Recipient: mavryk.MustParseAddress("mv1DsVn1LCaMTS3DjpA3JRZWGcvAeFRqzaLa"), Cycle: 9, Kind: enums.PAYOUT_KIND_ACCUMULATED, Amount: mavryk.NewZ(500), Note: "other#10"},
+	}
+
+	entries := []JournalEntry{
+		NewJournalEntry("finished", "1/1", enums.JOURNAL_BATCH_BUILT).WithPayouts(payouts, nil),
+		NewJournalEntry("finished", "1/1", enums.JOURNAL_BATCH_CONFIRMED).WithOpHash(opHash),
+		NewJournalEntry("finished", "", enums.JOURNAL_RUN_REPORTED),
+		NewJournalEntry("interrupted", "1/2", enums.JOURNAL_BATCH_BUILT).WithPayouts(payouts, accumulated),
+		NewJournalEntry("interrupted", "1/2", enums.JOURNAL_BATCH_INJECTED).WithOpHash(opHash),
+		NewJournalEntry("interrupted", "2/2", enums.JOURNAL_BATCH_BUILT).WithPayouts(payouts, nil),
+	}
+
+	runs := GetUnfinishedJournalRuns(entries)
+	assert.Len(runs, 1)
+	assert.Equal("interrupted", runs[0].Id)
+	assert.Equal([]int64{10, 9}, runs[0].GetCycles())
+	assert.Len(runs[0].Batches, 2)
+
+	injected := runs[0].Batches[0]
+	assert.Equal(enums.JOURNAL_BATCH_INJECTED, injected.State)
+	assert.False(injected.IsFinished())
+	assert.Equal([]mavryk.OpHash{opHash}, injected.OpHashes)
+	assert.Len(injected.Accumulated, 1)
+	assert.Equal(int64(9), injected.Accumulated[0].Cycle)
+
+	built := runs[0].Batches[1]
+	assert.Equal(enums.JOURNAL_BATCH_BUILT, built.State)
+	assert.Empty(built.OpHashes)
+}
@@ -0,0 +1,27 @@
+package common
+
+// LoyaltyRebate lowers the fee of delegators delegating for at least AfterCycles consecutive cycles
+type LoyaltyRebate struct {
+	AfterCycles int64   `json:"after_cycles"`
+	Rebate      float64 `json:"rebate"`
+}
+
+// GetLoyaltyRebate returns the highest rebate reached with the delegation age
+func GetLoyaltyRebate(rebates []LoyaltyRebate, delegationAge int64) float64 {
+	result := float64(0)
+	for _, rebate := range rebates {
+		if delegationAge >= rebate.AfterCycles && rebate.Rebate > result {
+			result = rebate.Rebate
+		}
+	}
+	return result
+}
+
+// GetLoyaltyHistoryLength returns the number of cycles needed to reach the highest rebate
+func GetLoyaltyHistoryLength(rebates []LoyaltyRebate) int64 {
+	result := int64(0)
+	for _, rebate := range rebates {
+		result = max(result, rebate.AfterCycles)
+	}
+	return result
+}
@@ -37,6 +37,14 @@ func FloatToPercentage(f float64) string {
 	return fmt.Sprintf("%.2f%%", f*100)
 }
 
+// FormatFeeRate formats the fee rate, staked fee rate is appended if there were staked bonds
+func FormatFeeRate(feeRate float64, stakedFeeRate float64, stakedBonds mavryk.Z) string {
+	if stakedBonds.IsZero() {
+		return FloatToPercentage(feeRate)
+	}
+	return fmt.Sprintf("%s (staked %.2f%%)", FloatToPercentage(feeRate), stakedFeeRate*100)
+}
+
 func ShortenAddress(taddr mavryk.Address) string {
 	if taddr.Equal(mavryk.ZeroAddress) || taddr.Equal(mavryk.InvalidAddress) {
 		return ""
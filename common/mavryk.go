@@ -26,8 +26,11 @@ type BakersCycleData struct {
 	ExternalStakedBalance         mavryk.Z
 	BlockStakingRewardsEdge       mavryk.Z
 	EndorsementStakingRewardsEdge mavryk.Z
-	BlockStakingFees              mavryk.Z
-	StakersCount                  int32
+	// rewards of external stakers left to them after the baker took its edge
+	BlockStakingRewardsShared       mavryk.Z
+	EndorsementStakingRewardsShared mavryk.Z
+	BlockStakingFees                mavryk.Z
+	StakersCount                    int32
 
 	FrozenDepositLimit mavryk.Z
 	Delegators         []Delegator
@@ -56,6 +59,16 @@ func (cycleData *BakersCycleData) GetTotalDelegatedRewards(payoutMode enums.EPay
 	}
 }
 
+// GetTotalStakingRewardsEdge returns the edge baker earned from rewards of external stakers
+func (cycleData *BakersCycleData) GetTotalStakingRewardsEdge() mavryk.Z {
+	return cycleData.BlockStakingRewardsEdge.Add(cycleData.EndorsementStakingRewardsEdge)
+}
+
+// GetTotalStakingRewards returns rewards of external stakers before the baker took its edge from them
+func (cycleData *BakersCycleData) GetTotalStakingRewards() mavryk.Z {
+	return cycleData.GetTotalStakingRewardsEdge().Add(cycleData.BlockStakingRewardsShared).Add(cycleData.EndorsementStakingRewardsShared)
+}
+
 func (cycleData *BakersCycleData) GetBakerDelegatedBalance() mavryk.Z {
 	return cycleData.OwnDelegatedBalance
 }
@@ -68,4 +81,6 @@ type OperationLimits struct {
 	HardGasLimitPerOperation     int64
 	HardStorageLimitPerOperation int64
 	MaxOperationDataLength       int
+	// number of blocks operation branch remains valid for, 0 if not known
+	MaxOperationsTTL int64
 }
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	UNSIGNED_PAYOUTS_VERSION = 1
+
+	// watermark of generic operations
+	OPERATION_WATERMARK = 0x03
+)
+
+type UnsignedOperationContent struct {
+	Counter      int64 `json:"counter"`
+	Fee          int64 `json:"fee"`
+	GasLimit     int64 `json:"gas_limit"`
+	StorageLimit int64 `json:"storage_limit"`
+}
+
+// UnsignedBatch holds everything needed to rebuild the completed operation of the batch without network access
+type UnsignedBatch struct {
+	Id       string                     `json:"id"`
+	Payouts  RecipeBatch                `json:"payouts"`
+	Branch   mavryk.BlockHash           `json:"branch"`
+	Reveal   bool                       `json:"reveal,omitempty"`
+	Contents []UnsignedOperationContent `json:"contents"`
+	// last level the operation can be included at, the operation has to be broadcasted before the chain reaches it
+	ValidUntilLevel int64 `json:"valid_until_level,omitempty"`
+	// hex encoded forged operation
+	Bytes string `json:"bytes"`
+}
+
+// UnsignedPayouts is exported for signing on the offline machine
+type UnsignedPayouts struct {
+	Version           int                   `json:"version"`
+	PayoutKey         mavryk.Key            `json:"payout_key"`
+	PreparationResult *PreparePayoutsResult `json:"preparation_result"`
+	Batches           []UnsignedBatch       `json:"batches"`
+}
+
+type OperationSignature struct {
+	Id        string           `json:"id"`
+	Signature mavryk.Signature `json:"signature"`
+}
+
+func GetOperationDigest(op *codec.Op) []byte {
+	digest := blake2b.Sum256(append([]byte{OPERATION_WATERMARK}, op.Bytes()...))
+	return digest[:]
+}
+
+func NewUnsignedBatch(id string, batch RecipeBatch, op *codec.Op) UnsignedBatch {
+	contents := make([]UnsignedOperationContent, 0, len(op.Contents))
+	for _, content := range op.Contents {
+		limits := content.Limits()
+		contents = append(contents, UnsignedOperationContent{
+			Counter:      content.GetCounter(),
+			Fee:          limits.Fee,
+			GasLimit:     limits.GasLimit,
+			StorageLimit: limits.StorageLimit,
+		})
+	}
+	return UnsignedBatch{
+		Id:       id,
+		Payouts:  batch,
+		Branch:   op.Branch,
+		Reveal:   len(op.Contents) > len(batch),
+		Contents: contents,
+		Bytes:    hex.EncodeToString(op.Bytes()),
+	}
+}
+
+// ToOp rebuilds the operation from the batch payouts and verifies it matches the exported bytes,
+// so the payouts reviewed on the offline machine are what gets signed
+func (batch *UnsignedBatch) ToOp(key mavryk.Key) (*codec.Op, error) {
+	op := codec.NewOp().WithSource(key.Address()).WithBranch(batch.Branch)
+	op.WithTTL(constants.MAX_OPERATION_TTL)
+	batch.Payouts.injectContents(op)
+	if batch.Reveal {
+		op.WithContentsFront(&codec.Reveal{
+			Manager:   codec.Manager{Source: key.Address()},
+			PublicKey: key,
+		})
+	}
+	if len(op.Contents) != len(batch.Contents) {
+		return nil, errors.Join(constants.ErrUnsignedOperationMismatch, fmt.Errorf("batch %s: expected %d contents, got %d", batch.Id, len(batch.Contents), len(op.Contents)))
+	}
+	for i, content := range batch.Contents {
+		op.Contents[i].WithCounter(content.Counter)
+		op.Contents[i].WithLimits(mavryk.Limits{
+			Fee:          content.Fee,
+			GasLimit:     content.GasLimit,
+			StorageLimit: content.StorageLimit,
+		})
+	}
+	op.WithSource(key.Address())
+	if hex.EncodeToString(op.Bytes()) != batch.Bytes {
+		return nil, errors.Join(constants.ErrUnsignedOperationMismatch, fmt.Errorf("batch %s", batch.Id))
+	}
+	return op, nil
+}
+
+// ToSignedOp rebuilds the operation and attaches the signature after verifying it against the payout key
+func (batch *UnsignedBatch) ToSignedOp(key mavryk.Key, signature mavryk.Signature) (*codec.Op, error) {
+	op, err := batch.ToOp(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.Verify(GetOperationDigest(op), signature); err != nil {
+		return nil, errors.Join(constants.ErrInvalidOperationSignature, fmt.Errorf("batch %s", batch.Id), err)
+	}
+	op.WithSignature(signature)
+	return op, nil
+}
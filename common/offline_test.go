@@ -0,0 +1,66 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsignedBatchRoundtrip(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+	assert.Nil(err)
+	batch := RecipeBatch{
+		{Recipient: mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g"), TxKind: enums.PAYOUT_TX_KIND_MAV, Amount: mavryk.NewZ(1000), OpLimits: &OpLimits{GasLimit: 1000, StorageLimit: 0, TransactionFee: 500}},
+		{Recipient: mavryk.MustParseAddress("mv1DsVn1LCaMTS3DjpA3JRZWGcvAeFRqzaLa"), TxKind: enums.PAYOUT_TX_KIND_MAV, Amount: mavryk.NewZ(2000), OpLimits: &OpLimits{GasLimit: 1000, StorageLimit: 257, TransactionFee: 600}},
+	}
+
+	op, err := batch.ToCompletedOp(key.Public(), &completingTransactor{})
+	assert.Nil(err)
+
+	unsignedBatch := NewUnsignedBatch("1/1", batch, op)
+	rebuilt, err := unsignedBatch.ToOp(key.Public())
+	assert.Nil(err)
+	assert.Equal(op.Bytes(), rebuilt.Bytes())
+
+	assert.Nil(rebuilt.Sign(key))
+	signed, err := unsignedBatch.ToSignedOp(key.Public(), rebuilt.Signature)
+	assert.Nil(err)
+	assert.Equal(rebuilt.Signature, signed.Signature)
+
+	otherKey, err := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+	assert.Nil(err)
+	otherOp, _ := unsignedBatch.ToOp(key.Public())
+	assert.Nil(otherOp.Sign(otherKey))
+	_, err = unsignedBatch.ToSignedOp(key.Public(), otherOp.Signature)
+	assert.ErrorIs(err, constants.ErrInvalidOperationSignature)
+
+	// tampered payouts do not match the exported operation
+	unsignedBatch.Payouts[0].Amount = mavryk.NewZ(100000)
+	_, err = unsignedBatch.ToOp(key.Public())
+	assert.ErrorIs(err, constants.ErrUnsignedOperationMismatch)
+}
+
+// completingTransactor completes operations the way node rpc does, including reveal of the payout key
+type completingTransactor struct {
+	TransactorEngine
+	counterOffset int64
+}
+
+func (transactor *completingTransactor) Complete(op *codec.Op, key mavryk.Key) error {
+	op.WithBranch(mavryk.MustParseBlockHash("BM4VEjb3EGdgNgJhwfVUsUqPYvZWJUHdmKKgabuDkwy6SmUKDve"))
+	op.WithContentsFront(&codec.Reveal{
+		Manager:   codec.Manager{Source: key.Address()},
+		PublicKey: key,
+	})
+	for i := range op.Contents {
+		op.Contents[i].WithCounter(100 + transactor.counterOffset + int64(i))
+	}
+	op.WithSource(key.Address())
+	return nil
+}
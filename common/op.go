@@ -24,7 +24,7 @@ func InitOpExecutionContext(op *codec.Op, transactor TransactorEngine) *OpExecut
 }
 
 func (ctx *OpExecutionContext) GetOpHash() mavryk.OpHash {
-	if ctx.result == nil {
+	if ctx == nil || ctx.result == nil {
 		return mavryk.ZeroOpHash
 	}
 	return ctx.result.GetOpHash()
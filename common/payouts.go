@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/mavryk-network/mavpay/constants"
@@ -36,22 +37,30 @@ func (psr *OpLimits) GetOperationFeesWithoutAllocation() int64 {
 }
 
 type PayoutRecipe struct {
-	Baker            mavryk.Address               `json:"baker"`
-	Delegator        mavryk.Address               `json:"delegator,omitempty"`
-	Cycle            int64                        `json:"cycle,omitempty"`
-	Recipient        mavryk.Address               `json:"recipient,omitempty"`
+	Baker     mavryk.Address `json:"baker"`
+	Delegator mavryk.Address `json:"delegator,omitempty"`
+	Cycle     int64          `json:"cycle,omitempty"`
+	Recipient mavryk.Address `json:"recipient,omitempty"`
+	// share of the delegator's payout sent to the recipient, set only if the payout is split among several recipients
+	RecipientShare   float64                      `json:"recipient_share,omitempty"`
 	Kind             enums.EPayoutKind            `json:"kind,omitempty"`
 	TxKind           enums.EPayoutTransactionKind `json:"tx_kind,omitempty"`
 	FATokenId        mavryk.Z                     `json:"fa_token_id,omitempty"`
 	FAContract       mavryk.Address               `json:"fa_contract,omitempty"`
 	DelegatedBalance mavryk.Z                     `json:"delegator_balance,omitempty"`
-	StakedBalance    mavryk.Z                     `json:"-"` // enable in output when relevant (P)
+	StakedBalance    mavryk.Z                     `json:"staked_balance,omitempty"`
 	Amount           mavryk.Z                     `json:"amount,omitempty"`
 	FeeRate          float64                      `json:"fee_rate,omitempty"`
 	Fee              mavryk.Z                     `json:"fee,omitempty"`
-	OpLimits         *OpLimits                    `json:"op_limits,omitempty"`
-	Note             string                       `json:"note,omitempty"`
-	IsValid          bool                         `json:"valid,omitempty"`
+	// part of the amount refunded from the staking edge of the staked balance
+	StakedAmount  mavryk.Z  `json:"staked_amount,omitempty"`
+	StakedFeeRate float64   `json:"staked_fee_rate,omitempty"`
+	StakedFee     mavryk.Z  `json:"staked_fee,omitempty"`
+	OpLimits      *OpLimits `json:"op_limits,omitempty"`
+	Note          string    `json:"note,omitempty"`
+	IsValid       bool      `json:"valid,omitempty"`
+	// cycles of the carry-over ledger entries paid out with the payout
+	CarriedOverCycles []int64 `json:"carried_over_cycles,omitempty"`
 	// mainly for accumulation to be able to check if fee was collected and subtract it from the amount
 	TxFeeCollected bool `json:"tx_fee_collected,omitempty"`
 	// mainly for accumulation to be able to check if fee was collected and subtract it from the amount
@@ -145,6 +154,9 @@ func (recipe *PayoutRecipe) Combine(otherRecipe *PayoutRecipe) (*PayoutRecipe, e
 	recipe.StakedBalance = recipe.StakedBalance.Add(otherRecipe.StakedBalance).Div64(2)
 	recipe.Amount = recipe.Amount.Add(otherRecipe.Amount)
 	recipe.Fee = recipe.Fee.Add(otherRecipe.Fee)
+	recipe.StakedAmount = recipe.StakedAmount.Add(otherRecipe.StakedAmount)
+	recipe.StakedFee = recipe.StakedFee.Add(otherRecipe.StakedFee)
+	recipe.CarriedOverCycles = append(recipe.CarriedOverCycles, otherRecipe.CarriedOverCycles...)
 	recipe.OpLimits = &OpLimits{
 		StorageBurn:             recipe.OpLimits.StorageBurn + otherRecipe.OpLimits.StorageBurn,
 		AllocationBurn:          recipe.OpLimits.AllocationBurn + otherRecipe.OpLimits.AllocationBurn,
@@ -197,9 +209,13 @@ func (pr *PayoutRecipe) ToPayoutReport() PayoutReport {
 		DelegatedBalance: pr.DelegatedBalance,
 		StakedBalance:    pr.StakedBalance,
 		Recipient:        pr.Recipient,
+		RecipientShare:   pr.RecipientShare,
 		Amount:           pr.Amount,
 		FeeRate:          pr.FeeRate,
 		Fee:              pr.Fee,
+		StakedAmount:     pr.StakedAmount,
+		StakedFeeRate:    pr.StakedFeeRate,
+		StakedFee:        pr.StakedFee,
 		TransactionFee:   txFee,
 		OpHash:           mavryk.ZeroOpHash,
 		IsSuccess:        false,
@@ -207,6 +223,10 @@ func (pr *PayoutRecipe) ToPayoutReport() PayoutReport {
 	}
 }
 
+func (pr *PayoutRecipe) GetCarryOverKey() string {
+	return GetCarryOverKey(pr.Delegator, pr.Recipient, pr.RecipientShare)
+}
+
 func (pr *PayoutRecipe) GetTransactionFee() int64 {
 	if pr.OpLimits != nil {
 		return pr.OpLimits.TransactionFee
@@ -223,7 +243,7 @@ func (pr *PayoutRecipe) ToTableRowData() []string {
 		ShortenAddress(pr.FAContract),
 		ToStringEmptyIfZero(pr.FATokenId.Int64()),
 		FormatAmount(pr.TxKind, pr.Amount.Int64()),
-		FloatToPercentage(pr.FeeRate),
+		FormatFeeRate(pr.FeeRate, pr.StakedFeeRate, pr.StakedAmount.Add(pr.StakedFee)),
 		MumavToMavS(pr.Fee.Int64()),
 		MumavToMavS(pr.GetTransactionFee()),
 		pr.Note,
@@ -281,23 +301,28 @@ func GetRecipesFilteredTotals(recipes []PayoutRecipe, kind enums.EPayoutKind) ([
 }
 
 type CyclePayoutSummary struct {
-	Cycle                    int64     `json:"cycle"`
-	Delegators               int       `json:"delegators"`
-	PaidDelegators           int       `json:"paid_delegators"`
-	OwnStakedBalance         mavryk.Z  `json:"own_staked_balance"`
-	OwnDelegatedBalance      mavryk.Z  `json:"own_delegated_balance"`
-	ExternalStakedBalance    mavryk.Z  `json:"external_staked_balance"`
-	ExternalDelegatedBalance mavryk.Z  `json:"external_delegated_balance"`
-	EarnedFees               mavryk.Z  `json:"cycle_fees"`
-	EarnedRewards            mavryk.Z  `json:"cycle_rewards"`
-	DistributedRewards       mavryk.Z  `json:"distributed_rewards"`
-	BondIncome               mavryk.Z  `json:"bond_income"`
-	FeeIncome                mavryk.Z  `json:"fee_income"`
-	IncomeTotal              mavryk.Z  `json:"total_income"`
-	DonatedBonds             mavryk.Z  `json:"donated_bonds"`
-	DonatedFees              mavryk.Z  `json:"donated_fees"`
-	DonatedTotal             mavryk.Z  `json:"donated_total"`
-	Timestamp                time.Time `json:"timestamp"`
+	Cycle                    int64    `json:"cycle"`
+	Delegators               int      `json:"delegators"`
+	PaidDelegators           int      `json:"paid_delegators"`
+	OwnStakedBalance         mavryk.Z `json:"own_staked_balance"`
+	OwnDelegatedBalance      mavryk.Z `json:"own_delegated_balance"`
+	ExternalStakedBalance    mavryk.Z `json:"external_staked_balance"`
+	ExternalDelegatedBalance mavryk.Z `json:"external_delegated_balance"`
+	EarnedFees               mavryk.Z `json:"cycle_fees"`
+	EarnedRewards            mavryk.Z `json:"cycle_rewards"`
+	DistributedRewards       mavryk.Z `json:"distributed_rewards"`
+	BondIncome               mavryk.Z `json:"bond_income"`
+	FeeIncome                mavryk.Z `json:"fee_income"`
+	IncomeTotal              mavryk.Z `json:"total_income"`
+	DonatedBonds             mavryk.Z `json:"donated_bonds"`
+	DonatedFees              mavryk.Z `json:"donated_fees"`
+	DonatedTotal             mavryk.Z `json:"donated_total"`
+	// staking side - edge earned from external stakers and fees attributed to staked balance
+	Stakers            int       `json:"stakers"`
+	StakingRewardsEdge mavryk.Z  `json:"staking_rewards_edge"`
+	StakingFees        mavryk.Z  `json:"staking_fees"`
+	StakingIncome      mavryk.Z  `json:"staking_income"`
+	Timestamp          time.Time `json:"timestamp"`
 }
 
 func (summary *CyclePayoutSummary) GetTotalStakedBalance() mavryk.Z {
@@ -308,6 +333,11 @@ func (summary *CyclePayoutSummary) GetTotalDelegatedBalance() mavryk.Z {
 	return summary.OwnDelegatedBalance.Add(summary.ExternalDelegatedBalance)
 }
 
+// GetBakerIncomeTotal returns income of the baker from both delegation and staking
+func (summary *CyclePayoutSummary) GetBakerIncomeTotal() mavryk.Z {
+	return summary.IncomeTotal.Add(summary.StakingIncome)
+}
+
 func (summary *CyclePayoutSummary) CombineNumericData(another *CyclePayoutSummary) *CyclePayoutSummary {
 	return &CyclePayoutSummary{
 		OwnStakedBalance:         summary.OwnStakedBalance.Add(another.OwnStakedBalance),
@@ -323,6 +353,9 @@ func (summary *CyclePayoutSummary) CombineNumericData(another *CyclePayoutSummar
 		DonatedBonds:             summary.DonatedBonds.Add(another.DonatedBonds),
 		DonatedFees:              summary.DonatedFees.Add(another.DonatedFees),
 		DonatedTotal:             summary.DonatedTotal.Add(another.DonatedTotal),
+		StakingRewardsEdge:       summary.StakingRewardsEdge.Add(another.StakingRewardsEdge),
+		StakingFees:              summary.StakingFees.Add(another.StakingFees),
+		StakingIncome:            summary.StakingIncome.Add(another.StakingIncome),
 	}
 }
 
@@ -380,20 +413,34 @@ type GeneratePayoutsOptions struct {
 type CyclePayoutBlueprints []*CyclePayoutBlueprint
 
 func (results CyclePayoutBlueprints) GetSummary() *CyclePayoutSummary {
-	summary := &CyclePayoutSummary{}
+	return CombineCyclePayoutSummaries(lo.Map(results, func(result *CyclePayoutBlueprint, _ int) *CyclePayoutSummary {
+		return &result.Summary
+	})...)
+}
+
+// CombineCyclePayoutSummaries sums numeric data of the summaries, delegators and stakers are averaged
+func CombineCyclePayoutSummaries(summaries ...*CyclePayoutSummary) *CyclePayoutSummary {
+	result := &CyclePayoutSummary{}
+	if len(summaries) == 0 {
+		return result
+	}
 	delegators := 0
-	for _, result := range results {
-		delegators += result.Summary.Delegators
-		summary = summary.CombineNumericData(&result.Summary)
+	stakers := 0
+	for _, summary := range summaries {
+		delegators += summary.Delegators
+		stakers += summary.Stakers
+		result = result.CombineNumericData(summary)
 	}
-	summary.Delegators = delegators / len(results) // average
-	return summary
+	result.Delegators = delegators / len(summaries) // average
+	result.Stakers = stakers / len(summaries)       // average
+	return result
 }
 
 type PreparePayoutsEngineContext struct {
 	collector   CollectorEngine
 	signer      SignerEngine
 	reporter    ReporterEngine
+	transactor  TransactorEngine
 	adminNotify func(msg string)
 }
 
@@ -418,6 +465,52 @@ func (engines *PreparePayoutsEngineContext) GetReporter() ReporterEngine {
 	return engines.reporter
 }
 
+// WithTransactor enables checking status of operations from past reports on the node the payouts are injected through
+func (engines *PreparePayoutsEngineContext) WithTransactor(transactor TransactorEngine) *PreparePayoutsEngineContext {
+	engines.transactor = transactor
+	return engines
+}
+
+// GetOperationStatusChecker returns nil if transactor is not set or it is not able to look up status of operations,
+// operations the transactor does not find are looked up through the collector
+func (engines *PreparePayoutsEngineContext) GetOperationStatusChecker() OperationStatusAwareTransactor {
+	if transactor, ok := engines.transactor.(OperationStatusAwareTransactor); ok {
+		return NewOperationStatusChecker(transactor, engines.collector)
+	}
+	return nil
+}
+
+// operationStatusChecker looks up operations on the node the payouts are injected through first, the node is searched only
+// for recent operations, so operations it does not find are looked up through the collector (indexer)
+type operationStatusChecker struct {
+	transactor OperationStatusAwareTransactor
+	collector  CollectorEngine
+}
+
+// NewOperationStatusChecker combines status lookup of the transactor with the collector, transactor may be nil
+func NewOperationStatusChecker(transactor OperationStatusAwareTransactor, collector CollectorEngine) OperationStatusAwareTransactor {
+	return &operationStatusChecker{
+		transactor: transactor,
+		collector:  collector,
+	}
+}
+
+func (checker *operationStatusChecker) GetOperationStatus(opHash mavryk.OpHash) (OperationStatus, error) {
+	if checker.transactor != nil {
+		status, err := checker.transactor.GetOperationStatus(opHash)
+		if err == nil && (status == OPERATION_STATUS_APPLIED || status == OPERATION_STATUS_FAILED) {
+			return status, nil
+		}
+		if checker.collector == nil {
+			return status, err
+		}
+		if err != nil {
+			slog.Debug("operation status check through transactor failed, falling back to collector", "op_hash", opHash.String(), "error", err.Error())
+		}
+	}
+	return checker.collector.WasOperationApplied(opHash)
+}
+
 func (engines *PreparePayoutsEngineContext) AdminNotify(msg string) {
 	if engines.adminNotify != nil {
 		engines.adminNotify(msg)
@@ -444,12 +537,15 @@ type PreparePayoutsResult struct {
 	AccumulatedPayouts            []PayoutRecipe          `json:"accumulated_payouts,omitempty"`
 	InvalidPayouts                []PayoutRecipe          `json:"invalid_payouts,omitempty"`
 	ReportsOfPastSuccesfulPayouts []PayoutReport          `json:"reports_of_past_succesful_payouts,omitempty"`
+	// set only if carry-over is enabled, stored after execution with the paid entries settled
+	CarryOverLedger *CarryOverLedger `json:"carry_over_ledger,omitempty"`
 }
 
 type ExecutePayoutsEngineContext struct {
 	signer      SignerEngine
 	transactor  TransactorEngine
 	reporter    ReporterEngine
+	journal     PayoutJournal
 	adminNotify func(msg string)
 }
 
@@ -474,6 +570,17 @@ func (engines *ExecutePayoutsEngineContext) GetReporter() ReporterEngine {
 	return engines.reporter
 }
 
+// WithJournal enables recording of batch state transitions, so batches interrupted by a crash can be reconciled
+func (engines *ExecutePayoutsEngineContext) WithJournal(journal PayoutJournal) *ExecutePayoutsEngineContext {
+	engines.journal = journal
+	return engines
+}
+
+// GetJournal returns nil if journal is not enabled
+func (engines *ExecutePayoutsEngineContext) GetJournal() PayoutJournal {
+	return engines.journal
+}
+
 func (engines *ExecutePayoutsEngineContext) AdminNotify(msg string) {
 	if engines.adminNotify != nil {
 		engines.adminNotify(msg)
@@ -493,6 +600,54 @@ func (engines *ExecutePayoutsEngineContext) Validate() error {
 	return nil
 }
 
+type ReconcilePayoutsEngineContext struct {
+	journal    PayoutJournal
+	collector  CollectorEngine
+	transactor TransactorEngine
+	reporter   ReporterEngine
+}
+
+func NewReconcilePayoutsEngineContext(journal PayoutJournal, collector CollectorEngine, transactor TransactorEngine, reporter ReporterEngine) *ReconcilePayoutsEngineContext {
+	return &ReconcilePayoutsEngineContext{
+		journal:    journal,
+		collector:  collector,
+		transactor: transactor,
+		reporter:   reporter,
+	}
+}
+
+func (engines *ReconcilePayoutsEngineContext) GetJournal() PayoutJournal {
+	return engines.journal
+}
+
+func (engines *ReconcilePayoutsEngineContext) GetCollector() CollectorEngine {
+	return engines.collector
+}
+
+func (engines *ReconcilePayoutsEngineContext) GetTransactor() TransactorEngine {
+	return engines.transactor
+}
+
+func (engines *ReconcilePayoutsEngineContext) GetReporter() ReporterEngine {
+	return engines.reporter
+}
+
+func (engines *ReconcilePayoutsEngineContext) Validate() error {
+	if engines.journal == nil {
+		return errors.Join(constants.ErrMissingEngine, constants.ErrMissingPayoutJournal)
+	}
+	if engines.collector == nil {
+		return errors.Join(constants.ErrMissingEngine, constants.ErrMissingCollectorEngine)
+	}
+	if engines.transactor == nil {
+		return errors.Join(constants.ErrMissingEngine, constants.ErrMissingTransactorEngine)
+	}
+	if engines.reporter == nil {
+		return errors.Join(constants.ErrMissingEngine, constants.ErrMissingReporterEngine)
+	}
+	return nil
+}
+
 type ExecutePayoutsOptions struct {
 	MixInContractCalls bool `json:"mix_in_contract_calls,omitempty"`
 	MixInFATransfers   bool `json:"mix_in_fa_transfers,omitempty"`
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombineCyclePayoutSummaries(t *testing.T) {
+	assert := assert.New(t)
+
+	summaries := []*CyclePayoutSummary{
+		{Cycle: 1, Delegators: 10, Stakers: 2, EarnedRewards: mavryk.NewZ(100), StakingIncome: mavryk.NewZ(5)},
+		{Cycle: 2, Delegators: 20, Stakers: 5, EarnedRewards: mavryk.NewZ(200), StakingIncome: mavryk.NewZ(7)},
+	}
+	combined := CombineCyclePayoutSummaries(summaries...)
+	assert.Equal(15, combined.Delegators)
+	assert.Equal(3, combined.Stakers)
+	assert.Equal(mavryk.NewZ(300), combined.EarnedRewards)
+	assert.Equal(mavryk.NewZ(12), combined.StakingIncome)
+	assert.Equal(mavryk.NewZ(312), combined.GetBakerIncomeTotal())
+
+	blueprints := CyclePayoutBlueprints{{Summary: *summaries[0]}, {Summary: *summaries[1]}}
+	assert.Equal(combined, blueprints.GetSummary())
+
+	assert.Equal(&CyclePayoutSummary{}, CombineCyclePayoutSummaries())
+}
+
+type statusTransactor struct {
+	status OperationStatus
+	err    error
+}
+
+func (transactor *statusTransactor) GetOperationStatus(opHash mavryk.OpHash) (OperationStatus, error) {
+	return transactor.status, transactor.err
+}
+
+type statusCollector struct {
+	CollectorEngine
+	status OperationStatus
+}
+
+func (collector *statusCollector) WasOperationApplied(opHash mavryk.OpHash) (OperationStatus, error) {
+	return collector.status, nil
+}
+
+func TestOperationStatusCheckerFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	opHash := mavryk.MustParseOpHash("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ")
+	collector := &statusCollector{status: OPERATION_STATUS_APPLIED}
+
+	// operation older than the node lookup is found through the collector
+	for _, transactor := range []*statusTransactor{
+		{status: OPERATION_STATUS_NOT_EXISTS},
+		{status: OPERATION_STATUS_UNKNOWN, err: errors.New("node unavailable")},
+	} {
+		status, err := NewOperationStatusChecker(transactor, collector).GetOperationStatus(opHash)
+		assert.Nil(err)
+		assert.Equal(OPERATION_STATUS_APPLIED, status)
+	}
+
+	status, err := NewOperationStatusChecker(&statusTransactor{status: OPERATION_STATUS_FAILED}, collector).GetOperationStatus(opHash)
+	assert.Nil(err)
+	assert.Equal(OPERATION_STATUS_FAILED, status)
+
+	status, err = NewOperationStatusChecker(nil, collector).GetOperationStatus(opHash)
+	assert.Nil(err)
+	assert.Equal(OPERATION_STATUS_APPLIED, status)
+}
@@ -19,17 +19,25 @@ type PayoutReport struct {
 	FATokenId        mavryk.Z                     `json:"token_id,omitempty" csv:"token_id"`
 	Delegator        mavryk.Address               `json:"delegator,omitempty" csv:"delegator"`
 	DelegatedBalance mavryk.Z                     `json:"delegator_balance,omitempty" csv:"delegator_balance"`
-	StakedBalance    mavryk.Z                     `json:"-" csv:"-"` // enable when relevant
+	StakedBalance    mavryk.Z                     `json:"staked_balance,omitempty" csv:"staked_balance"`
 	Recipient        mavryk.Address               `json:"recipient,omitempty" csv:"recipient"`
+	RecipientShare   float64                      `json:"recipient_share,omitempty" csv:"recipient_share"`
 	Amount           mavryk.Z                     `json:"amount,omitempty" csv:"amount"`
 	FeeRate          float64                      `json:"fee_rate,omitempty" csv:"fee_rate"`
 	Fee              mavryk.Z                     `json:"fee,omitempty" csv:"fee"`
+	StakedAmount     mavryk.Z                     `json:"staked_amount,omitempty" csv:"staked_amount"`
+	StakedFeeRate    float64                      `json:"staked_fee_rate,omitempty" csv:"staked_fee_rate"`
+	StakedFee        mavryk.Z                     `json:"staked_fee,omitempty" csv:"staked_fee"`
 	TransactionFee   int64                        `json:"tx_fee,omitempty" csv:"tx_fee"`
 	OpHash           mavryk.OpHash                `json:"op_hash,omitempty" csv:"op_hash"`
 	IsSuccess        bool                         `json:"success" csv:"success"`
 	Note             string                       `json:"note,omitempty" csv:"note"`
 }
 
+func (pr *PayoutReport) GetCarryOverKey() string {
+	return GetCarryOverKey(pr.Delegator, pr.Recipient, pr.RecipientShare)
+}
+
 func (pr *PayoutReport) GetTransactionFee() int64 {
 	return pr.TransactionFee
 }
@@ -43,7 +51,7 @@ func (pr *PayoutReport) ToTableRowData() []string {
 		ShortenAddress(pr.FAContract),
 		ToStringEmptyIfZero(pr.FATokenId.Int64()),
 		FormatAmount(pr.TxKind, pr.Amount.Int64()),
-		FloatToPercentage(pr.FeeRate),
+		FormatFeeRate(pr.FeeRate, pr.StakedFeeRate, pr.StakedAmount.Add(pr.StakedFee)),
 		MumavToMavS(pr.Fee.Int64()),
 		MumavToMavS(pr.GetTransactionFee()),
 		pr.OpHash.String(),
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/mavryk-network/mvgo/mavryk"
+)
+
+const (
+	SIGNING_POLICY_MAV_ASSET = "mav"
+	// counters of past operations kept in the ledger to recognize replacements
+	SIGNING_POLICY_LEDGER_COUNTERS_KEPT = 1000
+)
+
+// SigningPolicy limits what the payout wallet signs, zero amounts mean no limit
+type SigningPolicy struct {
+	MaximumOperationAmount mavryk.Z `json:"max_operation_amount,omitempty"`
+	MaximumCycleAmount     mavryk.Z `json:"max_cycle_amount,omitempty"`
+	// if set, mav and tokens can be sent only to these addresses
+	AllowedDestinations []mavryk.Address          `json:"allowed_destinations,omitempty"`
+	TokenLimits         []SigningPolicyTokenLimit `json:"token_limits,omitempty"`
+}
+
+// SigningPolicyTokenLimit allows transfers of the token, amounts are in the smallest units of the token
+type SigningPolicyTokenLimit struct {
+	Contract               mavryk.Address `json:"contract"`
+	TokenId                int64          `json:"token_id,omitempty"`
+	MaximumOperationAmount mavryk.Z       `json:"max_operation_amount,omitempty"`
+	MaximumCycleAmount     mavryk.Z       `json:"max_cycle_amount,omitempty"`
+}
+
+// GetSigningPolicyTokenAsset identifies the token in limits and signed amounts
+func GetSigningPolicyTokenAsset(contract mavryk.Address, tokenId int64) string {
+	return fmt.Sprintf("%s/%d", contract, tokenId)
+}
+
+// SigningPolicyLedger keeps amounts signed for each cycle by asset (mav or token), so cycle limits hold across runs
+type SigningPolicyLedger struct {
+	Cycles map[int64]map[string]mavryk.Z `json:"cycles"`
+	// amounts signed by cycle and asset of the operations by counter of their first content,
+	// replacements reuse counters and only one of them can be applied
+	Counters map[int64]map[int64]map[string]mavryk.Z `json:"counters,omitempty"`
+}
+
+func NewSigningPolicyLedger() *SigningPolicyLedger {
+	return &SigningPolicyLedger{
+		Cycles:   make(map[int64]map[string]mavryk.Z),
+		Counters: make(map[int64]map[int64]map[string]mavryk.Z),
+	}
+}
+
+// GetSpent returns amount of the asset signed for the cycle, amount of the operation the counter belongs to is excluded
+func (ledger *SigningPolicyLedger) GetSpent(cycle int64, asset string, counter int64) mavryk.Z {
+	return ledger.Cycles[cycle][asset].Sub(ledger.Counters[counter][cycle][asset])
+}
+
+// Record adds amounts of the signed operation, amounts of the replaced operation with the same counter are removed
+func (ledger *SigningPolicyLedger) Record(counter int64, amounts map[int64]map[string]mavryk.Z) {
+	if ledger.Cycles == nil {
+		ledger.Cycles = make(map[int64]map[string]mavryk.Z)
+	}
+	if ledger.Counters == nil {
+		ledger.Counters = make(map[int64]map[int64]map[string]mavryk.Z)
+	}
+	for cycle, assets := range ledger.Counters[counter] {
+		for asset, amount := range assets {
+			ledger.Cycles[cycle][asset] = ledger.Cycles[cycle][asset].Sub(amount)
+		}
+	}
+	for cycle, assets := range amounts {
+		if _, ok := ledger.Cycles[cycle]; !ok {
+			ledger.Cycles[cycle] = make(map[string]mavryk.Z)
+		}
+		for asset, amount := range assets {
+			ledger.Cycles[cycle][asset] = ledger.Cycles[cycle][asset].Add(amount)
+		}
+	}
+	if counter <= 0 {
+		return
+	}
+	ledger.Counters[counter] = amounts
+	for past := range ledger.Counters {
+		if past <= counter-SIGNING_POLICY_LEDGER_COUNTERS_KEPT {
+			delete(ledger.Counters, past)
+		}
+	}
+}
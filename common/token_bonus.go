@@ -0,0 +1,46 @@
+package common
+
+import (
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+// TokenBonus distributes an amount of FA token to delegators in proportion to their delegated balance.
+// Amounts are in the smallest units of the token.
+type TokenBonus struct {
+	Kind           enums.EPayoutTransactionKind `json:"kind"`
+	Contract       mavryk.Address               `json:"contract"`
+	TokenId        mavryk.Z                     `json:"token_id,omitempty"`
+	Amount         mavryk.Z                     `json:"amount,omitempty"`
+	CycleAmounts   map[int64]mavryk.Z           `json:"cycle_amounts,omitempty"`
+	MinimumBalance mavryk.Z                     `json:"minimum_balance,omitempty"`
+	Delegators     []mavryk.Address             `json:"delegators,omitempty"`
+	Ignore         []mavryk.Address             `json:"ignore,omitempty"`
+}
+
+// GetCycleAmount returns the amount distributed in the cycle, amount set for the cycle takes precedence
+func (bonus *TokenBonus) GetCycleAmount(cycle int64) mavryk.Z {
+	if amount, ok := bonus.CycleAmounts[cycle]; ok {
+		return amount
+	}
+	return bonus.Amount
+}
+
+// IsEligible checks whether the delegator with the delegated balance receives the bonus
+func (bonus *TokenBonus) IsEligible(delegator mavryk.Address, delegatedBalance mavryk.Z) bool {
+	isSameAddress := func(address mavryk.Address) bool { return address.Equal(delegator) }
+	if lo.ContainsBy(bonus.Ignore, isSameAddress) {
+		return false
+	}
+	if len(bonus.Delegators) > 0 && !lo.ContainsBy(bonus.Delegators, isSameAddress) {
+		return false
+	}
+	return !delegatedBalance.IsZero() && !delegatedBalance.IsLess(bonus.MinimumBalance)
+}
+
+// IsSameToken checks whether the transfer moves the token of the bonus
+func (bonus *TokenBonus) IsSameToken(transfer TransferArgs) bool {
+	return transfer.GetTxKind() == bonus.Kind && transfer.GetFAContract().Equal(bonus.Contract) &&
+		(bonus.Kind != enums.PAYOUT_TX_KIND_FA2 || transfer.GetFATokenId().Equal(bonus.TokenId))
+}
@@ -0,0 +1,117 @@
+package common
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/micheline"
+)
+
+// TokenTransfer is a single transfer of FA1.2 or FA2 token decoded from parameters of the transfer entrypoint
+type TokenTransfer struct {
+	From    mavryk.Address
+	To      mavryk.Address
+	TokenId int64
+	Amount  mavryk.Z
+}
+
+// flattenPair unfolds right combs, Pair a (Pair b c) and Pair a b c are both returned as [a b c]
+func flattenPair(prim micheline.Prim) []micheline.Prim {
+	if prim.OpCode != micheline.D_PAIR || len(prim.Args) == 0 {
+		return []micheline.Prim{prim}
+	}
+	result := make([]micheline.Prim, 0, len(prim.Args))
+	result = append(result, prim.Args[:len(prim.Args)-1]...)
+	return append(result, flattenPair(prim.Args[len(prim.Args)-1])...)
+}
+
+func decodeAddress(prim micheline.Prim) (mavryk.Address, error) {
+	if prim.String != "" {
+		return mavryk.ParseAddress(prim.String)
+	}
+	var address mavryk.Address
+	err := address.UnmarshalBinary(prim.Bytes)
+	return address, err
+}
+
+func decodeNat(prim micheline.Prim) (*big.Int, error) {
+	if prim.Int == nil {
+		return nil, errors.New("expected int")
+	}
+	if prim.Int.Sign() < 0 {
+		return nil, errors.New("int out of range")
+	}
+	return prim.Int, nil
+}
+
+// DecodeFA1TransferParameters decodes Pair from (Pair to amount)
+func DecodeFA1TransferParameters(value micheline.Prim) ([]TokenTransfer, error) {
+	args := flattenPair(value)
+	if len(args) != 3 {
+		return nil, constants.ErrInvalidTransferParameters
+	}
+	from, err := decodeAddress(args[0])
+	if err != nil {
+		return nil, errors.Join(constants.ErrInvalidTransferParameters, err)
+	}
+	to, err := decodeAddress(args[1])
+	if err != nil {
+		return nil, errors.Join(constants.ErrInvalidTransferParameters, err)
+	}
+	amount, err := decodeNat(args[2])
+	if err != nil {
+		return nil, errors.Join(constants.ErrInvalidTransferParameters, err)
+	}
+	return []TokenTransfer{{From: from, To: to, Amount: mavryk.NewBigZ(amount)}}, nil
+}
+
+// DecodeFA2TransferParameters decodes list of Pair from (list of Pair to (Pair token_id amount))
+func DecodeFA2TransferParameters(value micheline.Prim) ([]TokenTransfer, error) {
+	if value.Type != micheline.PrimSequence {
+		return nil, constants.ErrInvalidTransferParameters
+	}
+	result := make([]TokenTransfer, 0)
+	for _, batch := range value.Args {
+		args := flattenPair(batch)
+		if len(args) != 2 || args[1].Type != micheline.PrimSequence {
+			return nil, constants.ErrInvalidTransferParameters
+		}
+		from, err := decodeAddress(args[0])
+		if err != nil {
+			return nil, errors.Join(constants.ErrInvalidTransferParameters, err)
+		}
+		for _, tx := range args[1].Args {
+			txArgs := flattenPair(tx)
+			if len(txArgs) != 3 {
+				return nil, constants.ErrInvalidTransferParameters
+			}
+			to, err := decodeAddress(txArgs[0])
+			if err != nil {
+				return nil, errors.Join(constants.ErrInvalidTransferParameters, err)
+			}
+			tokenId, err := decodeNat(txArgs[1])
+			if err != nil || !tokenId.IsInt64() {
+				return nil, errors.Join(constants.ErrInvalidTransferParameters, err)
+			}
+			amount, err := decodeNat(txArgs[2])
+			if err != nil {
+				return nil, errors.Join(constants.ErrInvalidTransferParameters, err)
+			}
+			result = append(result, TokenTransfer{From: from, To: to, TokenId: tokenId.Int64(), Amount: mavryk.NewBigZ(amount)})
+		}
+	}
+	return result, nil
+}
+
+// DecodeTokenTransfers decodes transfers of FA1.2 or FA2 transfer entrypoint call, the standard is recognized by the shape of parameters
+func DecodeTokenTransfers(params *micheline.Parameters) ([]TokenTransfer, error) {
+	if params == nil || params.Entrypoint != "transfer" {
+		return nil, constants.ErrInvalidTransferParameters
+	}
+	if params.Value.Type == micheline.PrimSequence {
+		return DecodeFA2TransferParameters(params.Value)
+	}
+	return DecodeFA1TransferParameters(params.Value)
+}
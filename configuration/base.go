@@ -2,10 +2,13 @@ package configuration
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"math"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/hjson/hjson-go/v4"
 	"github.com/mavryk-network/mavpay/common"
@@ -58,7 +61,9 @@ func ConfigurationToRuntimeConfiguration(configuration *LatestConfigurationType)
 		}
 		return k, RuntimeDelegatorOverride{
 			Recipient:                    delegatorOverride.Recipient,
+			Recipients:                   delegatorOverride.Recipients,
 			Fee:                          delegatorOverride.Fee,
+			StakedFee:                    delegatorOverride.StakedFee,
 			MinimumBalance:               FloatAmountToMumav(delegatorOverride.MinimumBalance),
 			IsBakerPayingTxFee:           delegatorOverride.IsBakerPayingTxFee,
 			IsBakerPayingAllocationTxFee: delegatorOverride.IsBakerPayingAllocationTxFee,
@@ -90,6 +95,10 @@ func ConfigurationToRuntimeConfiguration(configuration *LatestConfigurationType)
 	if balanceCheckMode == "" {
 		balanceCheckMode = enums.PROTOCOL_BALANCE_CHECK_MODE
 	}
+	network := configuration.Network
+	if network.CollectorMode == "" {
+		network.CollectorMode = enums.RPC_AND_MVKT_COLLECTOR_MODE
+	}
 
 	gasLimitBuffer := int64(constants.DEFAULT_TX_GAS_LIMIT_BUFFER)
 	if configuration.PayoutConfiguration.TxGasLimitBuffer != nil {
@@ -146,6 +155,161 @@ func ConfigurationToRuntimeConfiguration(configuration *LatestConfigurationType)
 		simulationBatchSize = *configuration.PayoutConfiguration.SimulationBatchSize
 	}
 
+	var signingPolicy *common.SigningPolicy
+	if configuration.PayoutConfiguration.SigningPolicy != nil {
+		signingPolicy = &common.SigningPolicy{
+			MaximumOperationAmount: FloatAmountToMumav(configuration.PayoutConfiguration.SigningPolicy.MaximumOperationAmount),
+			MaximumCycleAmount:     FloatAmountToMumav(configuration.PayoutConfiguration.SigningPolicy.MaximumCycleAmount),
+			AllowedDestinations:    configuration.PayoutConfiguration.SigningPolicy.AllowedDestinations,
+			TokenLimits:            make([]common.SigningPolicyTokenLimit, 0, len(configuration.PayoutConfiguration.SigningPolicy.TokenLimits)),
+		}
+		for _, limit := range configuration.PayoutConfiguration.SigningPolicy.TokenLimits {
+			tokenLimit := common.SigningPolicyTokenLimit{
+				Contract:               limit.Contract,
+				TokenId:                limit.TokenId,
+				MaximumOperationAmount: mavryk.Zero,
+				MaximumCycleAmount:     mavryk.Zero,
+			}
+			if limit.MaximumOperationAmount != "" {
+				amount, err := mavryk.ParseZ(limit.MaximumOperationAmount)
+				if err != nil {
+					return nil, errors.Join(constants.ErrInvalidSigningPolicyAmount, fmt.Errorf("token: %s", common.GetSigningPolicyTokenAsset(limit.Contract, limit.TokenId)), err)
+				}
+				tokenLimit.MaximumOperationAmount = amount
+			}
+			if limit.MaximumCycleAmount != "" {
+				amount, err := mavryk.ParseZ(limit.MaximumCycleAmount)
+				if err != nil {
+					return nil, errors.Join(constants.ErrInvalidSigningPolicyAmount, fmt.Errorf("token: %s", common.GetSigningPolicyTokenAsset(limit.Contract, limit.TokenId)), err)
+				}
+				tokenLimit.MaximumCycleAmount = amount
+			}
+			signingPolicy.TokenLimits = append(signingPolicy.TokenLimits, tokenLimit)
+		}
+	}
+
+	feeBumping := RuntimeFeeBumpingConfiguration{
+		MaximumReplacements: constants.DEFAULT_MAX_OPERATION_REPLACEMENTS,
+		FeeBump:             constants.DEFAULT_OPERATION_REPLACEMENT_FEE_BUMP,
+	}
+	if configuration.PayoutConfiguration.FeeBumping != nil {
+		if configuration.PayoutConfiguration.FeeBumping.MaximumReplacements != nil {
+			feeBumping.MaximumReplacements = *configuration.PayoutConfiguration.FeeBumping.MaximumReplacements
+		}
+		if configuration.PayoutConfiguration.FeeBumping.FeeBump > 0 {
+			feeBumping.FeeBump = configuration.PayoutConfiguration.FeeBumping.FeeBump
+		}
+	}
+
+	parallelBatches := constants.DEFAULT_PARALLEL_BATCHES
+	if configuration.PayoutConfiguration.ParallelBatches != nil {
+		parallelBatches = *configuration.PayoutConfiguration.ParallelBatches
+	}
+
+	var feeSchedule *common.FeeSchedule
+	if configuration.PayoutConfiguration.FeeSchedule != nil {
+		feeSchedule = &common.FeeSchedule{
+			Balance: enums.FEE_SCHEDULE_BALANCE_DELEGATED,
+			Mode:    enums.FEE_SCHEDULE_MODE_FLAT,
+			Brackets: lo.Map(configuration.PayoutConfiguration.FeeSchedule.Brackets, func(bracket mavpay_configuration.FeeScheduleBracketV0, _ int) common.FeeScheduleBracket {
+				result := common.FeeScheduleBracket{Fee: bracket.Fee}
+				if bracket.UpTo != nil {
+					upTo := FloatAmountToMumav(*bracket.UpTo)
+					result.UpTo = &upTo
+				}
+				return result
+			}),
+		}
+		if configuration.PayoutConfiguration.FeeSchedule.Balance != "" {
+			feeSchedule.Balance = configuration.PayoutConfiguration.FeeSchedule.Balance
+		}
+		if configuration.PayoutConfiguration.FeeSchedule.Mode != "" {
+			feeSchedule.Mode = configuration.PayoutConfiguration.FeeSchedule.Mode
+		}
+	}
+
+	feeRules := make([]common.FeeRule, 0, len(configuration.PayoutConfiguration.FeeRules))
+	for _, rule := range configuration.PayoutConfiguration.FeeRules {
+		feeRule := common.FeeRule{
+			Fee:        rule.Fee,
+			FromCycle:  rule.FromCycle,
+			ToCycle:    rule.ToCycle,
+			Delegators: rule.Delegators,
+		}
+		if rule.FromDate != "" {
+			fromDate, err := time.Parse("2006-01-02", rule.FromDate)
+			if err != nil {
+				return nil, errors.Join(constants.ErrInvalidFeeRuleDate, err)
+			}
+			feeRule.FromDate = &fromDate
+		}
+		if rule.ToDate != "" {
+			toDate, err := time.Parse("2006-01-02", rule.ToDate)
+			if err != nil {
+				return nil, errors.Join(constants.ErrInvalidFeeRuleDate, err)
+			}
+			// whole day is included
+			toDate = toDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			feeRule.ToDate = &toDate
+		}
+		feeRules = append(feeRules, feeRule)
+	}
+
+	eligibilityRules := make([]common.EligibilityRule, 0, len(configuration.Delegators.Rules))
+	for _, rule := range configuration.Delegators.Rules {
+		condition, err := common.ParseEligibilityCondition(rule.If)
+		if err != nil {
+			return nil, errors.Join(err, fmt.Errorf("condition: %s", rule.If))
+		}
+		eligibilityRule := common.EligibilityRule{
+			Condition: condition,
+			Action:    rule.Action,
+			Reason:    rule.Reason,
+			Fee:       rule.Fee,
+			Recipient: rule.Recipient,
+		}
+		if rule.MaximumBalance != nil {
+			eligibilityRule.MaximumBalance = lo.ToPtr(FloatAmountToMumav(*rule.MaximumBalance))
+		}
+		eligibilityRules = append(eligibilityRules, eligibilityRule)
+	}
+
+	var carryOver *RuntimeCarryOverConfiguration
+	if configuration.PayoutConfiguration.CarryOver != nil {
+		carryOver = &RuntimeCarryOverConfiguration{
+			MaximumCycles: configuration.PayoutConfiguration.CarryOver.MaximumCycles,
+		}
+	}
+
+	tokenBonuses := make([]common.TokenBonus, 0, len(configuration.TokenBonuses))
+	for _, bonus := range configuration.TokenBonuses {
+		tokenBonus := common.TokenBonus{
+			Kind:           bonus.Kind,
+			Contract:       bonus.Contract,
+			TokenId:        mavryk.NewZ(bonus.TokenId),
+			Amount:         mavryk.Zero,
+			CycleAmounts:   make(map[int64]mavryk.Z, len(bonus.CycleAmounts)),
+			MinimumBalance: FloatAmountToMumav(bonus.MinimumBalance),
+			Delegators:     bonus.Delegators,
+			Ignore:         bonus.Ignore,
+		}
+		if bonus.Amount != "" {
+			amount, err := mavryk.ParseZ(bonus.Amount)
+			if err != nil {
+				return nil, errors.Join(constants.ErrInvalidTokenBonusAmount, err)
+			}
+			tokenBonus.Amount = amount
+		}
+		for cycle, cycleAmount := range bonus.CycleAmounts {
+			amount, err := mavryk.ParseZ(cycleAmount)
+			if err != nil {
+				return nil, errors.Join(constants.ErrInvalidTokenBonusAmount, fmt.Errorf("cycle: %d", cycle), err)
+			}
+			tokenBonus.CycleAmounts[cycle] = amount
+		}
+		tokenBonuses = append(tokenBonuses, tokenBonus)
+	}
+
 	return &RuntimeConfiguration{
 		BakerPKH: configuration.BakerPKH,
 		PayoutConfiguration: RuntimePayoutConfiguration{
@@ -153,6 +317,7 @@ func ConfigurationToRuntimeConfiguration(configuration *LatestConfigurationType)
 			PayoutMode:                 payoutMode,
 			BalanceCheckMode:           balanceCheckMode,
 			Fee:                        configuration.PayoutConfiguration.Fee,
+			StakedFee:                  configuration.PayoutConfiguration.StakedFee,
 			IsPayingTxFee:              configuration.PayoutConfiguration.IsPayingTxFee,
 			IsPayingAllocationTxFee:    configuration.PayoutConfiguration.IsPayingAllocationTxFee,
 			MinimumAmount:              FloatAmountToMumav(configuration.PayoutConfiguration.MinimumAmount),
@@ -164,6 +329,15 @@ func ConfigurationToRuntimeConfiguration(configuration *LatestConfigurationType)
 			MinimumDelayBlocks:         minimumPayoutDelayBlocks,
 			MaximumDelayBlocks:         maximumPayoutDelayBlocks,
 			SimulationBatchSize:        simulationBatchSize,
+			SigningPolicy:              signingPolicy,
+			FeeBumping:                 feeBumping,
+			ParallelBatches:            parallelBatches,
+			FeeSchedule:                feeSchedule,
+			FeeRules:                   feeRules,
+			LoyaltyRebates: lo.Map(configuration.PayoutConfiguration.LoyaltyRebates, func(rebate mavpay_configuration.LoyaltyRebateV0, _ int) common.LoyaltyRebate {
+				return common.LoyaltyRebate{AfterCycles: rebate.AfterCycles, Rebate: rebate.Rebate}
+			}),
+			CarryOver: carryOver,
 		},
 		Delegators: RuntimeDelegatorsConfiguration{
 			Requirements: RuntimeDelegatorRequirements{
@@ -173,6 +347,7 @@ func ConfigurationToRuntimeConfiguration(configuration *LatestConfigurationType)
 			Overrides: delegatorOverrides,
 			Ignore:    configuration.Delegators.Ignore,
 			Prefilter: configuration.Delegators.Prefilter,
+			Rules:     eligibilityRules,
 		},
 		IncomeRecipients: RuntimeIncomeRecipients{
 			Bonds:       configuration.IncomeRecipients.Bonds,
@@ -181,7 +356,7 @@ func ConfigurationToRuntimeConfiguration(configuration *LatestConfigurationType)
 			DonateFees:  donateFees,
 			DonateBonds: donateBonds,
 		},
-		Network:        configuration.Network,
+		Network:        network,
 		Overdelegation: configuration.Overdelegation,
 		NotificationConfigurations: lo.Map(configuration.NotificationConfigurations, func(item json.RawMessage, index int) RuntimeNotificatorConfiguration {
 			var isValid bool
@@ -198,6 +373,7 @@ func ConfigurationToRuntimeConfiguration(configuration *LatestConfigurationType)
 			}
 		}),
 		Extensions:       configuration.Extensions,
+		TokenBonuses:     tokenBonuses,
 		SourceBytes:      []byte{},
 		DisableAnalytics: configuration.DisableAnalytics,
 	}, nil
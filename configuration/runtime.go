@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"math"
 
+	"github.com/mavryk-network/mavpay/common"
 	mavpay_configuration "github.com/mavryk-network/mavpay/configuration/v"
 	"github.com/mavryk-network/mavpay/constants"
 	"github.com/mavryk-network/mavpay/constants/enums"
@@ -17,12 +18,14 @@ type RuntimeDelegatorRequirements struct {
 }
 
 type RuntimeDelegatorOverride struct {
-	Recipient                    mavryk.Address `json:"recipient,omitempty"`
-	Fee                          *float64       `json:"fee,omitempty"`
-	MinimumBalance               mavryk.Z       `json:"minimum_balance,omitempty"`
-	IsBakerPayingTxFee           *bool          `json:"baker_pays_transaction_fee,omitempty"`
-	IsBakerPayingAllocationTxFee *bool          `json:"baker_pays_allocation_fee,omitempty"`
-	MaximumBalance               *mavryk.Z      `json:"maximum_balance,omitempty"`
+	Recipient                    mavryk.Address     `json:"recipient,omitempty"`
+	Recipients                   map[string]float64 `json:"recipients,omitempty"`
+	Fee                          *float64           `json:"fee,omitempty"`
+	StakedFee                    *float64           `json:"staked_fee,omitempty"`
+	MinimumBalance               mavryk.Z           `json:"minimum_balance,omitempty"`
+	IsBakerPayingTxFee           *bool              `json:"baker_pays_transaction_fee,omitempty"`
+	IsBakerPayingAllocationTxFee *bool              `json:"baker_pays_allocation_fee,omitempty"`
+	MaximumBalance               *mavryk.Z          `json:"maximum_balance,omitempty"`
 }
 
 type RuntimeDelegatorsConfiguration struct {
@@ -30,6 +33,7 @@ type RuntimeDelegatorsConfiguration struct {
 	Overrides    map[string]RuntimeDelegatorOverride `json:"overrides,omitempty"`
 	Ignore       []mavryk.Address                    `json:"ignore,omitempty"`
 	Prefilter    []mavryk.Address                    `json:"prefilter,omitempty"`
+	Rules        []common.EligibilityRule            `json:"rules,omitempty"`
 }
 
 type RuntimeNotificatorConfiguration struct {
@@ -39,22 +43,39 @@ type RuntimeNotificatorConfiguration struct {
 	IsAdmin       bool                          `json:"admin"`
 }
 
+type RuntimeFeeBumpingConfiguration struct {
+	MaximumReplacements int     `json:"max_replacements"`
+	FeeBump             float64 `json:"fee_bump,omitempty"`
+}
+
+type RuntimeCarryOverConfiguration struct {
+	MaximumCycles int64 `json:"max_cycles,omitempty"`
+}
+
 type RuntimePayoutConfiguration struct {
-	WalletMode                 enums.EWalletMode       `json:"wallet_mode,omitempty"`
-	PayoutMode                 enums.EPayoutMode       `json:"payout_mode,omitempty"`
-	BalanceCheckMode           enums.EBalanceCheckMode `json:"balance_check_mode,omitempty"`
-	Fee                        float64                 `json:"fee,omitempty"`
-	IsPayingTxFee              bool                    `json:"baker_pays_transaction_fee,omitempty"`
-	IsPayingAllocationTxFee    bool                    `json:"baker_pays_allocation_fee,omitempty"`
-	MinimumAmount              mavryk.Z                `json:"minimum_payout_amount,omitempty"`
-	IgnoreEmptyAccounts        bool                    `json:"ignore_empty_accounts,omitempty"`
-	TxGasLimitBuffer           int64                   `json:"transaction_gas_limit_buffer,omitempty"`
-	TxDeserializationGasBuffer int64                   `json:"transaction_deserialization_gas_buffer,omitempty"`
-	TxFeeBuffer                int64                   `json:"transaction_fee_buffer,omitempty"`
-	KtTxFeeBuffer              int64                   `json:"kt_transaction_fee_buffer,omitempty"`
-	MinimumDelayBlocks         int64                   `json:"minimum_delay_blocks,omitempty"`
-	MaximumDelayBlocks         int64                   `json:"maximum_delay_blocks,omitempty"`
-	SimulationBatchSize        int                     `json:"simulation_batch_size,omitempty"`
+	WalletMode                 enums.EWalletMode              `json:"wallet_mode,omitempty"`
+	PayoutMode                 enums.EPayoutMode              `json:"payout_mode,omitempty"`
+	BalanceCheckMode           enums.EBalanceCheckMode        `json:"balance_check_mode,omitempty"`
+	Fee                        float64                        `json:"fee,omitempty"`
+	StakedFee                  *float64                       `json:"staked_fee,omitempty"`
+	IsPayingTxFee              bool                           `json:"baker_pays_transaction_fee,omitempty"`
+	IsPayingAllocationTxFee    bool                           `json:"baker_pays_allocation_fee,omitempty"`
+	MinimumAmount              mavryk.Z                       `json:"minimum_payout_amount,omitempty"`
+	IgnoreEmptyAccounts        bool                           `json:"ignore_empty_accounts,omitempty"`
+	TxGasLimitBuffer           int64                          `json:"transaction_gas_limit_buffer,omitempty"`
+	TxDeserializationGasBuffer int64                          `json:"transaction_deserialization_gas_buffer,omitempty"`
+	TxFeeBuffer                int64                          `json:"transaction_fee_buffer,omitempty"`
+	KtTxFeeBuffer              int64                          `json:"kt_transaction_fee_buffer,omitempty"`
+	MinimumDelayBlocks         int64                          `json:"minimum_delay_blocks,omitempty"`
+	MaximumDelayBlocks         int64                          `json:"maximum_delay_blocks,omitempty"`
+	SimulationBatchSize        int                            `json:"simulation_batch_size,omitempty"`
+	SigningPolicy              *common.SigningPolicy          `json:"signing_policy,omitempty"`
+	FeeBumping                 RuntimeFeeBumpingConfiguration `json:"fee_bumping"`
+	ParallelBatches            int                            `json:"parallel_batches,omitempty"`
+	FeeSchedule                *common.FeeSchedule            `json:"fee_schedule,omitempty"`
+	FeeRules                   []common.FeeRule               `json:"fee_rules,omitempty"`
+	LoyaltyRebates             []common.LoyaltyRebate         `json:"loyalty_rebates,omitempty"`
+	CarryOver                  *RuntimeCarryOverConfiguration `json:"carry_over,omitempty"`
 }
 
 type RuntimeIncomeRecipients struct {
@@ -74,6 +95,7 @@ type RuntimeConfiguration struct {
 	Overdelegation             mavpay_configuration.OverdelegationConfigurationV0
 	NotificationConfigurations []RuntimeNotificatorConfiguration
 	Extensions                 []mavpay_configuration.ExtensionConfigurationV0
+	TokenBonuses               []common.TokenBonus
 	SourceBytes                []byte `json:"-"`
 	DisableAnalytics           bool   `json:"disable_analytics,omitempty"`
 }
@@ -97,6 +119,11 @@ func GetDefaultRuntimeConfiguration() RuntimeConfiguration {
 			MinimumDelayBlocks:         constants.DEFAULT_CYCLE_MONITOR_MINIMUM_DELAY,
 			MaximumDelayBlocks:         constants.DEFAULT_CYCLE_MONITOR_MAXIMUM_DELAY,
 			SimulationBatchSize:        constants.DEFAULT_SIMULATION_TX_BATCH_SIZE,
+			FeeBumping: RuntimeFeeBumpingConfiguration{
+				MaximumReplacements: constants.DEFAULT_MAX_OPERATION_REPLACEMENTS,
+				FeeBump:             constants.DEFAULT_OPERATION_REPLACEMENT_FEE_BUMP,
+			},
+			ParallelBatches: constants.DEFAULT_PARALLEL_BATCHES,
 		},
 		Delegators: RuntimeDelegatorsConfiguration{
 			Requirements: RuntimeDelegatorRequirements{
@@ -114,6 +141,7 @@ func GetDefaultRuntimeConfiguration() RuntimeConfiguration {
 			Explorer:               constants.DEFAULT_EXPLORER_URL,
 			DoNotPaySmartContracts: false,
 			IgnoreProtocolChanges:  false,
+			CollectorMode:          enums.RPC_AND_MVKT_COLLECTOR_MODE,
 		},
 		Overdelegation: mavpay_configuration.OverdelegationConfigurationV0{
 			IsProtectionEnabled: true,
@@ -7,6 +7,7 @@ import (
 	"github.com/mavryk-network/mavpay/constants"
 	"github.com/mavryk-network/mavpay/constants/enums"
 	"github.com/mavryk-network/mavpay/notifications"
+	"github.com/mavryk-network/mavpay/transport"
 	"github.com/mavryk-network/mvgo/mavryk"
 )
 
@@ -25,12 +26,23 @@ type DelegatorRequirementsV0 struct {
 }
 
 type DelegatorOverrideV0 struct {
-	Recipient                    mavryk.Address `json:"recipient,omitempty" comment:"Redirects payout to the recipient 'address'"`
-	Fee                          *float64       `json:"fee,omitempty" comment:"Overrides the fee for the delegator"`
-	MinimumBalance               float64        `json:"minimum_balance,omitempty" comment:"Overrides the minimum balance requirement for the delegator"`
-	IsBakerPayingTxFee           *bool          `json:"baker_pays_transaction_fee,omitempty" comment:"Overrides the baker paying the transaction fee"`
-	IsBakerPayingAllocationTxFee *bool          `json:"baker_pays_allocation_fee,omitempty" comment:"Overrides the baker paying the allocation transaction fee"`
-	MaximumBalance               *float64       `json:"maximum_balance,omitempty" comment:"The maximum balance for the delegator (for overdelegation situation you can limit how much of a delegator balance is taken into account)"`
+	Recipient                    mavryk.Address     `json:"recipient,omitempty" comment:"Redirects payout to the recipient 'address'"`
+	Recipients                   map[string]float64 `json:"recipients,omitempty" comment:"Splits payout among the recipients, 'address': share (portion as decimal, e.g. 0.7 for 70%), shares have to add up to 1, can not be combined with 'recipient'"`
+	Fee                          *float64           `json:"fee,omitempty" comment:"Overrides the fee for the delegator"`
+	StakedFee                    *float64           `json:"staked_fee,omitempty" comment:"Overrides the fee on the full staking rewards of the delegator"`
+	MinimumBalance               float64            `json:"minimum_balance,omitempty" comment:"Overrides the minimum balance requirement for the delegator"`
+	IsBakerPayingTxFee           *bool              `json:"baker_pays_transaction_fee,omitempty" comment:"Overrides the baker paying the transaction fee"`
+	IsBakerPayingAllocationTxFee *bool              `json:"baker_pays_allocation_fee,omitempty" comment:"Overrides the baker paying the allocation transaction fee"`
+	MaximumBalance               *float64           `json:"maximum_balance,omitempty" comment:"The maximum balance for the delegator (for overdelegation situation you can limit how much of a delegator balance is taken into account)"`
+}
+
+type EligibilityRuleV0 struct {
+	If             string                       `json:"if" comment:"condition over fields of the delegator - balance, staked_balance (in mav), address, address_type ('implicit' or 'contract'), delegation_age (consecutive cycles delegated), cycle - with operators ==, !=, <, <=, >, >=, &&, ||, ! and parentheses, e.g. 'balance < 100 && delegation_age < 5'"`
+	Action         enums.EEligibilityRuleAction `json:"action" comment:"action taken if the condition is met, can be 'exclude', 'fee', 'redirect' or 'cap'"`
+	Reason         string                       `json:"reason,omitempty" comment:"reported as the reason of exclusion ('exclude' action)"`
+	Fee            *float64                     `json:"fee,omitempty" comment:"fee charged to the delegator ('fee' action), loyalty rebate does not apply"`
+	Recipient      mavryk.Address               `json:"recipient,omitempty" comment:"address the payout is redirected to ('redirect' action)"`
+	MaximumBalance *float64                     `json:"maximum_balance,omitempty" comment:"maximum balance of the delegator taken into account ('cap' action)"`
 }
 
 type DelegatorsConfigurationV0 struct {
@@ -39,27 +51,103 @@ type DelegatorsConfigurationV0 struct {
 	Ignore       []mavryk.Address               `json:"ignore,omitempty" comment:"List of delegator addresses to ignore - wont be included in reward set, rewards will be redistributed"`
 	Overrides    map[string]DelegatorOverrideV0 `json:"overrides,omitempty" comment:"Overrides for specific delegators"`
 	FeeOverrides map[string][]mavryk.Address    `json:"fee_overrides,omitempty" comment:"Shortcuts for overriding fees for specific delegators"`
+	Rules        []EligibilityRuleV0            `json:"rules,omitempty" comment:"Eligibility rules evaluated in order for each delegator, the first met 'exclude' rule excludes the delegator, actions of other met rules apply in order"`
 }
 
 type MavrykNetworkConfigurationV0 struct {
 	// RpcUrl represents the URL to the RPC node.
-	RpcUrl                 string `json:"rpc_url,omitempty" comment:"Url to rpc endpoint"`
-	MvktUrl                string `json:"mvkt_url,omitempty" comment:"Url to mvkt endpoint"`
-	ProtocolRewardsUrl     string `json:"protocol_rewards_url,omitempty" comment:"Url to protocol rewards endpoint"`
-	Explorer               string `json:"explorer,omitempty" comment:"Url to block explorer"`
-	DoNotPaySmartContracts bool   `json:"ignore_kt,omitempty" comment:"if true, smart contracts will not be paid out (used for testing)"`
-	IgnoreProtocolChanges  bool   `json:"ignore_protocol_changes,omitempty" comment:"if true, protocol changes will be ignored, otherwise the payout will be stopped if the protocol changes"`
+	RpcUrl                   string                         `json:"rpc_url,omitempty" comment:"Url to rpc endpoint"`
+	MvktUrl                  string                         `json:"mvkt_url,omitempty" comment:"Url to mvkt endpoint"`
+	ProtocolRewardsUrl       string                         `json:"protocol_rewards_url,omitempty" comment:"Url to protocol rewards endpoint"`
+	Explorer                 string                         `json:"explorer,omitempty" comment:"Url to block explorer"`
+	DoNotPaySmartContracts   bool                           `json:"ignore_kt,omitempty" comment:"if true, smart contracts will not be paid out (used for testing)"`
+	IgnoreProtocolChanges    bool                           `json:"ignore_protocol_changes,omitempty" comment:"if true, protocol changes will be ignored, otherwise the payout will be stopped if the protocol changes"`
+	RpcEndpoints             []transport.EndpointDefinition `json:"rpc_endpoints,omitempty" comment:"additional rpc endpoints to fail over to"`
+	MvktEndpoints            []transport.EndpointDefinition `json:"mvkt_endpoints,omitempty" comment:"additional mvkt endpoints to fail over to"`
+	ProtocolRewardsEndpoints []transport.EndpointDefinition `json:"protocol_rewards_endpoints,omitempty" comment:"additional protocol rewards endpoints to fail over to"`
+	RequiredConfirmations    *int64                         `json:"required_confirmations,omitempty" comment:"number of blocks on top of the block including payout operation before it is considered paid, operations in reorganized blocks are waited for again"`
+	BroadcastEndpoints       []transport.EndpointDefinition `json:"broadcast_endpoints,omitempty" comment:"additional rpc nodes payout operations are injected to at the same time as to rpc_url, the first accepted operation hash is used"`
+	CollectorMode            enums.ECollectorMode           `json:"collector_mode,omitempty" comment:"source of cycle data, can be 'rpc-and-mvkt' or 'rpc' (rpc requires an archive node, does not support 'ideal' payout_mode and looks up payout operations only within the last 120 blocks, status of older operations is unknown)"`
+	MvktConcurrency          int                            `json:"mvkt_concurrency,omitempty" comment:"number of delegator pages fetched from mvkt in parallel"`
+	MvktRequestsPerSecond    float64                        `json:"mvkt_requests_per_second,omitempty" comment:"limit of requests per second to mvkt and protocol rewards, negative value disables the limit"`
+	Http                     *transport.HttpOptions         `json:"http,omitempty" comment:"http options (timeout, proxy, headers, tls) of all rpc, mvkt and protocol rewards endpoints, list an endpoint in *_endpoints to configure it separately"`
+}
+
+// GetEndpointPools returns failover pools of rpc, mvkt and protocol rewards endpoints
+func (network *MavrykNetworkConfigurationV0) GetEndpointPools() ([]*transport.EndpointPool, error) {
+	rpcPool, err := transport.GetEndpointPool("rpc", network.RpcUrl, network.Http, network.RpcEndpoints)
+	if err != nil {
+		return nil, err
+	}
+	mvktPool, err := transport.GetEndpointPool("mvkt", network.MvktUrl, network.Http, network.MvktEndpoints)
+	if err != nil {
+		return nil, err
+	}
+	protocolRewardsPool, err := transport.GetEndpointPool("protocol_rewards", network.ProtocolRewardsUrl, network.Http, network.ProtocolRewardsEndpoints)
+	if err != nil {
+		return nil, err
+	}
+	return []*transport.EndpointPool{rpcPool, mvktPool, protocolRewardsPool}, nil
 }
 
 type OverdelegationConfigurationV0 struct {
 	IsProtectionEnabled bool `json:"protect,omitempty"`
 }
 
+type SigningPolicyV0 struct {
+	MaximumOperationAmount float64                     `json:"max_operation_amount,omitempty" comment:"maximum amount of mav sent by a single operation, 0 means no limit"`
+	MaximumCycleAmount     float64                     `json:"max_cycle_amount,omitempty" comment:"maximum amount of mav sent for a single cycle, 0 means no limit"`
+	AllowedDestinations    []mavryk.Address            `json:"allowed_destinations,omitempty" comment:"if set, mav and tokens can be sent only to these addresses, payouts to other recipients are refused"`
+	TokenLimits            []SigningPolicyTokenLimitV0 `json:"token_limits,omitempty" comment:"tokens allowed to be transferred, transfers of other tokens and other contract calls are refused"`
+}
+
+type SigningPolicyTokenLimitV0 struct {
+	Contract               mavryk.Address `json:"contract" comment:"address of the token contract"`
+	TokenId                int64          `json:"token_id,omitempty" comment:"id of the token, FA2 only"`
+	MaximumOperationAmount string         `json:"max_operation_amount,omitempty" comment:"maximum amount of the token (in its smallest units) sent by a single operation, 0 means no limit"`
+	MaximumCycleAmount     string         `json:"max_cycle_amount,omitempty" comment:"maximum amount of the token (in its smallest units) sent for a single cycle, 0 means no limit"`
+}
+
+type FeeBumpingV0 struct {
+	MaximumReplacements *int    `json:"max_replacements,omitempty" comment:"how many times an operation which was not included before it expired is re-forged with a higher fee and re-injected, 0 (default) disables fee bumping. NOTE: replacements were enabled by default before, set this explicitly to keep them"`
+	FeeBump             float64 `json:"fee_bump,omitempty" comment:"portion the fee is increased by with each replacement (e.g. 0.5 for 50%), the increase is paid by the baker"`
+}
+
+type FeeScheduleBracketV0 struct {
+	UpTo *float64 `json:"up_to,omitempty" comment:"upper bound of the bracket in mav (exclusive), omit for the last, unbounded bracket"`
+	Fee  float64  `json:"fee" comment:"fee of the bracket (portion of the reward as decimal, e.g. 0.06 for 6%)"`
+}
+
+type FeeScheduleV0 struct {
+	Balance  enums.EFeeScheduleBalance `json:"balance,omitempty" comment:"balance the brackets are keyed on, can be 'delegated', 'staked' or 'total' (default 'delegated')"`
+	Mode     enums.EFeeScheduleMode    `json:"mode,omitempty" comment:"how the brackets apply, 'flat' charges the fee of the bracket the balance falls into, 'marginal' charges each bracket's fee on the part of the balance within it (default 'flat')"`
+	Brackets []FeeScheduleBracketV0    `json:"brackets" comment:"brackets ordered by ascending upper bound"`
+}
+
+type FeeRuleV0 struct {
+	Fee        float64          `json:"fee" comment:"fee charged while the rule applies (portion of the reward as decimal, e.g. 0 for a 0% promotion)"`
+	FromCycle  *int64           `json:"from_cycle,omitempty" comment:"first cycle the rule applies to"`
+	ToCycle    *int64           `json:"to_cycle,omitempty" comment:"last cycle the rule applies to"`
+	FromDate   string           `json:"from_date,omitempty" comment:"rule applies to cycles running on or after the date (YYYY-MM-DD, UTC)"`
+	ToDate     string           `json:"to_date,omitempty" comment:"rule applies to cycles running on or before the date (YYYY-MM-DD, UTC), date in the future leaves the rule open"`
+	Delegators []mavryk.Address `json:"delegators,omitempty" comment:"delegators the rule applies to, all delegators if empty"`
+}
+
+type LoyaltyRebateV0 struct {
+	AfterCycles int64   `json:"after_cycles" comment:"number of consecutive cycles (including the paid one) the delegator has to delegate to the baker"`
+	Rebate      float64 `json:"rebate" comment:"portion subtracted from the fee (e.g. 0.01 to lower 5% fee to 4%)"`
+}
+
+type CarryOverV0 struct {
+	MaximumCycles int64 `json:"max_cycles,omitempty" comment:"number of cycles after which the carried over amount is paid out even if it is still below the minimum payout amount, 0 waits for the minimum"`
+}
+
 type PayoutConfigurationV0 struct {
-	WalletMode                 enums.EWalletMode       `json:"wallet_mode" comment:"wallet mode to use for signing transactions, can be 'local-private-key' or 'remote-signer'"`
+	WalletMode                 enums.EWalletMode       `json:"wallet_mode" comment:"wallet mode to use for signing transactions, can be 'local-private-key', 'local-keystore', 'remote-signer' or 'offline'"`
 	PayoutMode                 enums.EPayoutMode       `json:"payout_mode" comment:"payout mode to use, can be 'actual' or 'ideal'"`
 	BalanceCheckMode           enums.EBalanceCheckMode `json:"balance_check_mode" comment:"balance check mode to use, can be 'protocol' or 'mvkt'"`
 	Fee                        float64                 `json:"fee,omitempty" comment:"fee to charge delegators for the payout (portion of the reward as decimal, e.g. 0.075 for 7.5%)" validate:"required,min=0,max=1"`
+	StakedFee                  *float64                `json:"staked_fee,omitempty" comment:"fee on the full staking rewards of each staker (portion as decimal), staking rewards are paid by the protocol and the baker receives its edge from them, the fee is kept from the edge and the rest of the edge is refunded with the payout, a fee above the edge keeps the whole edge, the edge is kept whole if not set"`
 	IsPayingTxFee              bool                    `json:"baker_pays_transaction_fee,omitempty" comment:"if true, baker pays the transaction fee"`
 	IsPayingAllocationTxFee    bool                    `json:"baker_pays_allocation_fee,omitempty" comment:"if true, baker pays the allocation transaction fee"`
 	MinimumAmount              float64                 `json:"minimum_payout_amount,omitempty" comment:"minimum amount to pay out to delegators, if the amount is less, the payout will be ignored"`
@@ -71,6 +159,24 @@ type PayoutConfigurationV0 struct {
 	MinimumDelayBlocks         *int64                  `json:"minimum_delay_blocks,omitempty" comment:"minimum delay in blocks before the payout is executed"`
 	MaximumDelayBlocks         *int64                  `json:"maximum_delay_blocks,omitempty" comment:"maximum delay in blocks before the payout is executed"`
 	SimulationBatchSize        *int                    `json:"simulation_batch_size,omitempty" comment:"size of the batch for simulation (number of transactions, higher usually means faster simulation but in case of failure, more transactions will be lost and need to be simulated again)"`
+	SigningPolicy              *SigningPolicyV0        `json:"signing_policy,omitempty" comment:"if set, signer refuses operations exceeding the limits, sending funds outside of allowed destinations or containing anything else than transfers, amounts signed for each cycle are kept with reports"`
+	FeeBumping                 *FeeBumpingV0           `json:"fee_bumping,omitempty" comment:"re-injection of expired payout operations with higher fee, disabled unless max_replacements is set"`
+	ParallelBatches            *int                    `json:"parallel_batches,omitempty" comment:"number of batches injected together with consecutive counters and confirmed together, 1 executes batches one after another, can not be combined with fee bumping"`
+	FeeSchedule                *FeeScheduleV0          `json:"fee_schedule,omitempty" comment:"if set, the fee is determined by the balance of the delegator instead of 'fee', fee overrides of delegators take precedence"`
+	FeeRules                   []FeeRuleV0             `json:"fee_rules,omitempty" comment:"fee rules limited to cycle or date ranges and optionally to a group of delegators (e.g. promotions or announced fee changes), the first rule applying to the paid cycle and delegator is used instead of 'fee' and 'fee_schedule', fee overrides of delegators take precedence"`
+	LoyaltyRebates             []LoyaltyRebateV0       `json:"loyalty_rebates,omitempty" comment:"fee discounts for long term delegators, the highest reached rebate applies, delegators with fee override are not eligible"`
+	CarryOver                  *CarryOverV0            `json:"carry_over,omitempty" comment:"if set, payouts below 'minimum_payout_amount' are recorded in a ledger stored with the reports and paid out together with later payouts of the delegator once the accrued amount passes the minimum"`
+}
+
+type TokenBonusV0 struct {
+	Kind           enums.EPayoutTransactionKind `json:"kind" comment:"token standard, can be 'fa1' (FA1.2) or 'fa2'"`
+	Contract       mavryk.Address               `json:"contract" comment:"address of the token contract"`
+	TokenId        int64                        `json:"token_id,omitempty" comment:"id of the token, FA2 only"`
+	Amount         string                       `json:"amount,omitempty" comment:"amount of the token (in its smallest units) distributed each cycle"`
+	CycleAmounts   map[int64]string             `json:"cycle_amounts,omitempty" comment:"amounts of the token distributed in specific cycles instead of 'amount'"`
+	MinimumBalance float64                      `json:"minimum_balance,omitempty" comment:"minimum delegated balance in mav to receive the bonus"`
+	Delegators     []mavryk.Address             `json:"delegators,omitempty" comment:"if set, only these delegators receive the bonus"`
+	Ignore         []mavryk.Address             `json:"ignore,omitempty" comment:"delegators not receiving the bonus"`
 }
 
 type ExtensionConfigurationV0 = common.ExtensionDefinition
@@ -85,6 +191,7 @@ type ConfigurationV0 struct {
 	Overdelegation             OverdelegationConfigurationV0 `json:"overdelegation,omitempty" comment:"overdelegation protection configuration"`
 	NotificationConfigurations []json.RawMessage             `json:"notifications,omitempty" comment:"notification configurations"`
 	Extensions                 []ExtensionConfigurationV0    `json:"extensions,omitempty" comment:"extensions (for custom functionality)"`
+	TokenBonuses               []TokenBonusV0                `json:"token_bonuses,omitempty" comment:"FA tokens distributed to delegators alongside mav rewards in proportion to their delegated balance, the payout wallet has to hold the tokens"`
 	SourceBytes                []byte                        `json:"-"`
 	DisableAnalytics           bool                          `json:"disable_analytics,omitempty" comment:"disables analytics, please consider leaving it enabled🙏"`
 }
@@ -121,6 +228,7 @@ func GetDefaultV0() ConfigurationV0 {
 			Explorer:               constants.DEFAULT_EXPLORER_URL,
 			DoNotPaySmartContracts: false,
 			IgnoreProtocolChanges:  false,
+			CollectorMode:          enums.RPC_AND_MVKT_COLLECTOR_MODE,
 		},
 		Overdelegation: OverdelegationConfigurationV0{
 			IsProtectionEnabled: true,
@@ -3,10 +3,13 @@ package configuration
 import (
 	"errors"
 	"fmt"
+	"math"
+	"net/url"
 
 	"github.com/mavryk-network/mavpay/constants"
 	"github.com/mavryk-network/mavpay/constants/enums"
 	"github.com/mavryk-network/mavpay/notifications"
+	"github.com/mavryk-network/mavpay/transport"
 	"github.com/mavryk-network/mavpay/utils"
 	"github.com/mavryk-network/mvgo/mavryk"
 	"github.com/samber/lo"
@@ -35,14 +38,109 @@ func (configuration *RuntimeConfiguration) Validate() (err error) {
 		fmt.Sprintf("configuration.payouts.wallet_mode - '%s' not supported", configuration.PayoutConfiguration.WalletMode))
 	_assert(lo.Contains(enums.SUPPORTED_PAYOUT_MODES, configuration.PayoutConfiguration.PayoutMode),
 		fmt.Sprintf("configuration.payouts.payout_mode - '%s' not supported", configuration.PayoutConfiguration.PayoutMode))
+	_assert(lo.Contains(enums.SUPPORTED_COLLECTOR_MODES, configuration.Network.CollectorMode),
+		fmt.Sprintf("configuration.network.collector_mode - '%s' not supported", configuration.Network.CollectorMode))
+	// node does not expose missed baking rewards, ideal rewards can not be computed without an indexer
+	_assert(configuration.Network.CollectorMode != enums.RPC_COLLECTOR_MODE || configuration.PayoutConfiguration.PayoutMode != enums.PAYOUT_MODE_IDEAL,
+		fmt.Sprintf("configuration.payouts.payout_mode - '%s' is not supported with '%s' collector_mode", enums.PAYOUT_MODE_IDEAL, enums.RPC_COLLECTOR_MODE))
+	for id, endpoints := range map[string][]transport.EndpointDefinition{
+		"rpc_endpoints":              configuration.Network.RpcEndpoints,
+		"mvkt_endpoints":             configuration.Network.MvktEndpoints,
+		"protocol_rewards_endpoints": configuration.Network.ProtocolRewardsEndpoints,
+		"broadcast_endpoints":        configuration.Network.BroadcastEndpoints,
+	} {
+		for _, endpoint := range endpoints {
+			u, err := url.Parse(endpoint.Url)
+			_assert(err == nil && u.Host != "", fmt.Sprintf("configuration.network.%s - '%s' is not valid url", id, endpoint.Url))
+			err = endpoint.Http.Validate()
+			_assert(err == nil, fmt.Sprintf("configuration.network.%s - '%s' has invalid http options: %v", id, endpoint.Url, err))
+		}
+	}
+	_assert(configuration.Network.RequiredConfirmations == nil || *configuration.Network.RequiredConfirmations >= 0, "configuration.network.required_confirmations must not be negative")
+	httpOptionsErr := configuration.Network.Http.Validate()
+	_assert(httpOptionsErr == nil, fmt.Sprintf("configuration.network.http - %v", httpOptionsErr))
+	if signingPolicy := configuration.PayoutConfiguration.SigningPolicy; signingPolicy != nil {
+		_assert(!signingPolicy.MaximumOperationAmount.IsNeg(), "configuration.payouts.signing_policy.max_operation_amount must not be negative")
+		_assert(!signingPolicy.MaximumCycleAmount.IsNeg(), "configuration.payouts.signing_policy.max_cycle_amount must not be negative")
+		for _, destination := range signingPolicy.AllowedDestinations {
+			_assert(destination.IsValid(), fmt.Sprintf("configuration.payouts.signing_policy.allowed_destinations - '%s' is not valid address", destination))
+		}
+		for i, limit := range signingPolicy.TokenLimits {
+			_assert(limit.Contract.IsValid() && limit.Contract.IsContract(), fmt.Sprintf("configuration.payouts.signing_policy.token_limits[%d].contract - '%s' is not valid contract address", i, limit.Contract))
+			_assert(limit.TokenId >= 0, fmt.Sprintf("configuration.payouts.signing_policy.token_limits[%d].token_id must not be negative", i))
+			_assert(!limit.MaximumOperationAmount.IsNeg(), fmt.Sprintf("configuration.payouts.signing_policy.token_limits[%d].max_operation_amount must not be negative", i))
+			_assert(!limit.MaximumCycleAmount.IsNeg(), fmt.Sprintf("configuration.payouts.signing_policy.token_limits[%d].max_cycle_amount must not be negative", i))
+		}
+	}
+	_assert(configuration.PayoutConfiguration.FeeBumping.MaximumReplacements >= 0, "configuration.payouts.fee_bumping.max_replacements must not be negative")
+	_assert(configuration.PayoutConfiguration.FeeBumping.FeeBump >= 0, "configuration.payouts.fee_bumping.fee_bump must not be negative")
+	_assert(configuration.PayoutConfiguration.ParallelBatches >= 1, "configuration.payouts.parallel_batches must be at least 1")
+	_assert(configuration.PayoutConfiguration.ParallelBatches == 1 || configuration.PayoutConfiguration.FeeBumping.MaximumReplacements == 0,
+		"configuration.payouts.parallel_batches can not be combined with configuration.payouts.fee_bumping, set fee_bumping.max_replacements to 0 or parallel_batches to 1")
+	if feeSchedule := configuration.PayoutConfiguration.FeeSchedule; feeSchedule != nil {
+		_assert(lo.Contains(enums.SUPPORTED_FEE_SCHEDULE_BALANCES, feeSchedule.Balance),
+			fmt.Sprintf("configuration.payouts.fee_schedule.balance - '%s' not supported", feeSchedule.Balance))
+		_assert(lo.Contains(enums.SUPPORTED_FEE_SCHEDULE_MODES, feeSchedule.Mode),
+			fmt.Sprintf("configuration.payouts.fee_schedule.mode - '%s' not supported", feeSchedule.Mode))
+		_assert(len(feeSchedule.Brackets) > 0, "configuration.payouts.fee_schedule.brackets must contain at least one bracket")
+		for i, bracket := range feeSchedule.Brackets {
+			_assert(utils.IsPortionWithin0n1(bracket.Fee), getPortionRangeError(fmt.Sprintf("configuration.payouts.fee_schedule.brackets[%d].fee", i), bracket.Fee))
+			if i == len(feeSchedule.Brackets)-1 {
+				_assert(bracket.UpTo == nil, "configuration.payouts.fee_schedule.brackets - last bracket must not have up_to")
+				continue
+			}
+			_assert(bracket.UpTo != nil, fmt.Sprintf("configuration.payouts.fee_schedule.brackets[%d].up_to is required, only the last bracket is unbounded", i))
+			_assert(bracket.UpTo.IsGreater(mavryk.Zero), fmt.Sprintf("configuration.payouts.fee_schedule.brackets[%d].up_to must be positive", i))
+			if i > 0 {
+				_assert(feeSchedule.Brackets[i-1].UpTo.IsLess(*bracket.UpTo), fmt.Sprintf("configuration.payouts.fee_schedule.brackets[%d].up_to must be greater than up_to of the previous bracket", i))
+			}
+		}
+	}
+	for i, rule := range configuration.PayoutConfiguration.FeeRules {
+		_assert(utils.IsPortionWithin0n1(rule.Fee), getPortionRangeError(fmt.Sprintf("configuration.payouts.fee_rules[%d].fee", i), rule.Fee))
+		_assert(rule.FromCycle != nil || rule.ToCycle != nil || rule.FromDate != nil || rule.ToDate != nil,
+			fmt.Sprintf("configuration.payouts.fee_rules[%d] has to be limited by cycle or date range", i))
+		_assert(rule.FromCycle == nil || rule.ToCycle == nil || *rule.FromCycle <= *rule.ToCycle,
+			fmt.Sprintf("configuration.payouts.fee_rules[%d].from_cycle must be less or equal to to_cycle", i))
+		_assert(rule.FromDate == nil || rule.ToDate == nil || rule.FromDate.Before(*rule.ToDate),
+			fmt.Sprintf("configuration.payouts.fee_rules[%d].from_date must not be after to_date", i))
+		for _, delegator := range rule.Delegators {
+			_assert(delegator.IsValid(), fmt.Sprintf("configuration.payouts.fee_rules[%d].delegators - '%s' is not valid address", i, delegator))
+		}
+	}
+	for i, rebate := range configuration.PayoutConfiguration.LoyaltyRebates {
+		_assert(rebate.AfterCycles > 0, fmt.Sprintf("configuration.payouts.loyalty_rebates[%d].after_cycles must be positive", i))
+		_assert(utils.IsPortionWithin0n1(rebate.Rebate), getPortionRangeError(fmt.Sprintf("configuration.payouts.loyalty_rebates[%d].rebate", i), rebate.Rebate))
+	}
+	if configuration.PayoutConfiguration.CarryOver != nil {
+		_assert(configuration.PayoutConfiguration.CarryOver.MaximumCycles >= 0, "configuration.payouts.carry_over.max_cycles must not be negative")
+	}
 	_assert(configuration.PayoutConfiguration.MinimumDelayBlocks <= configuration.PayoutConfiguration.MaximumDelayBlocks,
 		"configuration.payouts.minimum_delay_blocks must be less or equal to configuration.payouts.maximum_delay_blocks")
 
 	_assert(lo.Contains(enums.SUPPORTED_DELEGATOR_MINIMUM_BALANCE_REWARD_DESTINATIONS, configuration.Delegators.Requirements.BellowMinimumBalanceRewardDestination),
 		fmt.Sprintf("configuration.delegators.requirements.below_minimum_reward_destination - '%s' not supported", configuration.Delegators.Requirements.BellowMinimumBalanceRewardDestination))
 
+	for i, rule := range configuration.Delegators.Rules {
+		_assert(lo.Contains(enums.SUPPORTED_ELIGIBILITY_RULE_ACTIONS, rule.Action),
+			fmt.Sprintf("configuration.delegators.rules[%d].action - '%s' not supported", i, rule.Action))
+		switch rule.Action {
+		case enums.ELIGIBILITY_RULE_ACTION_FEE:
+			_assert(rule.Fee != nil, fmt.Sprintf("configuration.delegators.rules[%d].fee is required for 'fee' action", i))
+			_assert(utils.IsPortionWithin0n1(*rule.Fee), getPortionRangeError(fmt.Sprintf("configuration.delegators.rules[%d].fee", i), *rule.Fee))
+		case enums.ELIGIBILITY_RULE_ACTION_REDIRECT:
+			_assert(rule.Recipient.IsValid(), fmt.Sprintf("configuration.delegators.rules[%d].recipient is required for 'redirect' action", i))
+		case enums.ELIGIBILITY_RULE_ACTION_CAP:
+			_assert(rule.MaximumBalance != nil, fmt.Sprintf("configuration.delegators.rules[%d].maximum_balance is required for 'cap' action", i))
+			_assert(!rule.MaximumBalance.IsNeg(), fmt.Sprintf("configuration.delegators.rules[%d].maximum_balance must not be negative", i))
+		}
+	}
+
 	_assert(utils.IsPortionWithin0n1(configuration.PayoutConfiguration.Fee),
 		getPortionRangeError("configuration.payouts.fee", configuration.PayoutConfiguration.Fee))
+	if stakedFee := configuration.PayoutConfiguration.StakedFee; stakedFee != nil {
+		_assert(utils.IsPortionWithin0n1(*stakedFee), getPortionRangeError("configuration.payouts.staked_fee", *stakedFee))
+	}
 	_assert(utils.IsPortionWithin0n1(configuration.IncomeRecipients.DonateFees),
 		getPortionRangeError("configuration.income_recipients.donate/fees", configuration.IncomeRecipients.DonateFees))
 	_assert(utils.IsPortionWithin0n1(configuration.IncomeRecipients.DonateBonds),
@@ -82,6 +180,30 @@ func (configuration *RuntimeConfiguration) Validate() (err error) {
 		_assert(err == nil, fmt.Sprintf("configuration.delegators.overrides.%s has to be valid PKH", k))
 		_assert(v.Fee == nil || utils.IsPortionWithin0n1(*v.Fee),
 			getPortionRangeError(fmt.Sprintf("configuration.delegators.overrides.%s fee", k), *v.Fee))
+		if len(v.Recipients) > 0 {
+			_assert(v.Recipient.Equal(mavryk.InvalidAddress), fmt.Sprintf("configuration.delegators.overrides.%s can not have both recipient and recipients", k))
+			for recipient, share := range v.Recipients {
+				_, err := mavryk.ParseAddress(recipient)
+				_assert(err == nil, fmt.Sprintf("configuration.delegators.overrides.%s.recipients - '%s' is not valid address", k, recipient))
+				_assert(share > 0 && share <= 1, fmt.Sprintf("configuration.delegators.overrides.%s.recipients.%s share has to be within (0, 1]", k, recipient))
+			}
+			_assert(math.Abs(lo.Sum(lo.Values(v.Recipients))-1) < 1e-9, fmt.Sprintf("configuration.delegators.overrides.%s.recipients shares have to add up to 1", k))
+		}
+		if v.StakedFee != nil {
+			_assert(utils.IsPortionWithin0n1(*v.StakedFee),
+				getPortionRangeError(fmt.Sprintf("configuration.delegators.overrides.%s staked_fee", k), *v.StakedFee))
+		}
+	}
+
+	for i, bonus := range configuration.TokenBonuses {
+		_assert(lo.Contains(enums.FA_OPERATION_KINDS, bonus.Kind), fmt.Sprintf("configuration.token_bonuses[%d].kind - '%s' not supported", i, bonus.Kind))
+		_assert(bonus.Contract.IsValid() && bonus.Contract.IsContract(), fmt.Sprintf("configuration.token_bonuses[%d].contract - '%s' is not valid contract address", i, bonus.Contract))
+		_assert(!bonus.TokenId.IsNeg(), fmt.Sprintf("configuration.token_bonuses[%d].token_id must not be negative", i))
+		_assert(!bonus.Amount.IsNeg(), fmt.Sprintf("configuration.token_bonuses[%d].amount must not be negative", i))
+		for cycle, amount := range bonus.CycleAmounts {
+			_assert(!amount.IsNeg(), fmt.Sprintf("configuration.token_bonuses[%d].cycle_amounts.%d must not be negative", i, cycle))
+		}
+		_assert(!bonus.MinimumBalance.IsNeg(), fmt.Sprintf("configuration.token_bonuses[%d].minimum_balance must not be negative", i))
 	}
 
 	for _, v := range configuration.NotificationConfigurations {
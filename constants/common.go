@@ -7,19 +7,22 @@ const (
 
 	DELEGATION_CAPACITY_FACTOR = 9
 
-	DEFAULT_BAKER_FEE                     = float64(.05)
-	DEFAULT_DELEGATOR_MINIMUM_BALANCE     = float64(0)
-	DEFAULT_PAYOUT_MINIMUM_AMOUNT         = float64(0)
-	DEFAULT_RPC_URL                       = "https://rpc.mavryk.network/"
-	DEFAULT_MVKT_URL                      = "https://api.mavryk.network/"
-	DEFAULT_PROTOCOL_REWARDS_URL          = "https://protocol-rewards.mavryk.network/"
-	DEFAULT_EXPLORER_URL                  = "https://mvkt.io/"
-	DEFAULT_REQUIRED_CONFIRMATIONS        = int64(2)
-	DEFAULT_TX_GAS_LIMIT_BUFFER           = int64(100)
-	DEFAULT_TX_DESERIALIZATION_GAS_BUFFER = int64(2) // just because of integer division
-	DEFAULT_TX_FEE_BUFFER                 = int64(0)
-	DEFAULT_KT_TX_FEE_BUFFER              = int64(0)
-	DEFAULT_SIMULATION_TX_BATCH_SIZE      = 50
+	DEFAULT_BAKER_FEE                      = float64(.05)
+	DEFAULT_DELEGATOR_MINIMUM_BALANCE      = float64(0)
+	DEFAULT_PAYOUT_MINIMUM_AMOUNT          = float64(0)
+	DEFAULT_RPC_URL                        = "https://rpc.mavryk.network/"
+	DEFAULT_MVKT_URL                       = "https://api.mavryk.network/"
+	DEFAULT_PROTOCOL_REWARDS_URL           = "https://protocol-rewards.mavryk.network/"
+	DEFAULT_EXPLORER_URL                   = "https://mvkt.io/"
+	DEFAULT_REQUIRED_CONFIRMATIONS         = int64(2)
+	DEFAULT_TX_GAS_LIMIT_BUFFER            = int64(100)
+	DEFAULT_TX_DESERIALIZATION_GAS_BUFFER  = int64(2) // just because of integer division
+	DEFAULT_TX_FEE_BUFFER                  = int64(0)
+	DEFAULT_KT_TX_FEE_BUFFER               = int64(0)
+	DEFAULT_SIMULATION_TX_BATCH_SIZE       = 50
+	DEFAULT_MAX_OPERATION_REPLACEMENTS     = 0 // fee bumping is opt-in
+	DEFAULT_OPERATION_REPLACEMENT_FEE_BUMP = float64(.5)
+	DEFAULT_PARALLEL_BATCHES               = 1
 
 	// buffer for signature, branch etc.
 	DEFAULT_BATCHING_OPERATION_DATA_BUFFER = 3000
@@ -28,14 +31,22 @@ const (
 	MAX_OPERATION_TTL  = 12   // 12 blocks
 	ALLOCATION_STORAGE = 257
 
+	// blocks to wait for inclusion before operation is considered expired, little reserve over MAX_OPERATION_TTL
+	OPERATION_INCLUSION_TTL = MAX_OPERATION_TTL + 2
+
 	DEFAULT_CYCLE_MONITOR_MAXIMUM_DELAY = int64(1500)
 	DEFAULT_CYCLE_MONITOR_MINIMUM_DELAY = int64(500)
 
-	CONFIG_FILE_BACKUP_SUFFIX = ".backup"
-	PAYOUT_REPORT_FILE_NAME   = "payouts.csv"
-	INVALID_REPORT_FILE_NAME  = "invalid.csv"
-	REPORT_SUMMARY_FILE_NAME  = "summary.json"
-	REPORTS_DIRECTORY         = "reports"
+	CONFIG_FILE_BACKUP_SUFFIX            = ".backup"
+	PAYOUT_REPORT_FILE_NAME              = "payouts.csv"
+	INVALID_REPORT_FILE_NAME             = "invalid.csv"
+	REPORT_SUMMARY_FILE_NAME             = "summary.json"
+	CYCLE_DATA_MISMATCH_REPORT_FILE_NAME = "cycle_data_mismatches.csv"
+	PAYOUT_JOURNAL_FILE_NAME             = "payouts.journal"
+	CARRY_OVER_LEDGER_FILE_NAME          = "carry_over.json"
+	SIGNING_POLICY_LEDGER_FILE_NAME      = "signing_policy.json"
+	REPORTS_DIRECTORY                    = "reports"
+	CACHE_DIRECTORY                      = ".cache"
 
 	DEFAULT_DONATION_ADDRESS    = "mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g"
 	DEFAULT_DONATION_PERCENTAGE = 0.05
@@ -7,6 +7,9 @@ const (
 	WALLET_MODE_LOCAL_PRIVATE_KEY2 EWalletMode = "local_private_key"
 	WALLET_MODE_REMOTE_SIGNER      EWalletMode = "remote-signer"
 	WALLET_MODE_REMOTE_SIGNER2     EWalletMode = "remote_signer"
+	WALLET_MODE_LOCAL_KEYSTORE     EWalletMode = "local-keystore"
+	WALLET_MODE_LOCAL_KEYSTORE2    EWalletMode = "local_keystore"
+	WALLET_MODE_OFFLINE            EWalletMode = "offline"
 )
 
 var (
@@ -15,6 +18,9 @@ var (
 		WALLET_MODE_LOCAL_PRIVATE_KEY2,
 		WALLET_MODE_REMOTE_SIGNER,
 		WALLET_MODE_REMOTE_SIGNER2,
+		WALLET_MODE_LOCAL_KEYSTORE,
+		WALLET_MODE_LOCAL_KEYSTORE2,
+		WALLET_MODE_OFFLINE,
 	}
 )
 
@@ -48,12 +54,14 @@ const (
 	INVALID_UNSUPPORTED_TX_KIND          EPayoutInvalidReason = "UNSUPPORTED_TX_KIND"
 	INVALID_MANUALLY_EXCLUDED_BY_PREFIX  EPayoutInvalidReason = "MANUALLY_EXCLUDED_BY_PREFIX"
 	ITERMEDIATE_FAILED_TO_ESTIMATE_BATCH EPayoutInvalidReason = "FAILED_TO_ESTIMATE_BATCH"
+	INVALID_EXCLUDED_BY_RULE             EPayoutInvalidReason = "EXCLUDED_BY_RULE"
 )
 
 type EPayoutKind string
 
 const (
 	PAYOUT_KIND_DELEGATOR_REWARD EPayoutKind = "delegator reward"
+	PAYOUT_KIND_TOKEN_BONUS      EPayoutKind = "token bonus"
 	PAYOUT_KIND_BAKER_REWARD     EPayoutKind = "baker reward"
 	PAYOUT_KIND_DONATION         EPayoutKind = "donation"
 	PAYOUT_KIND_FEE_INCOME       EPayoutKind = "fee income"
@@ -64,7 +72,7 @@ const (
 func (kind EPayoutKind) ToPriority() int {
 	// for odering
 	switch kind {
-	case PAYOUT_KIND_DELEGATOR_REWARD:
+	case PAYOUT_KIND_DELEGATOR_REWARD, PAYOUT_KIND_TOKEN_BONUS:
 		return 10
 	case PAYOUT_KIND_BAKER_REWARD:
 		return 9
@@ -116,3 +124,82 @@ var (
 	PROTOCOL_BALANCE_CHECK_MODE = EBalanceCheckMode("protocol")
 	MVKT_BALANCE_CHECK_MODE     = EBalanceCheckMode("mvkt")
 )
+
+type ECollectorMode string
+
+var (
+	RPC_AND_MVKT_COLLECTOR_MODE = ECollectorMode("rpc-and-mvkt")
+	RPC_COLLECTOR_MODE          = ECollectorMode("rpc")
+)
+
+var (
+	SUPPORTED_COLLECTOR_MODES = []ECollectorMode{
+		RPC_AND_MVKT_COLLECTOR_MODE,
+		RPC_COLLECTOR_MODE,
+	}
+)
+
+type EJournalBatchState string
+
+const (
+	// batch payouts recorded before the operation is forged
+	JOURNAL_BATCH_BUILT     EJournalBatchState = "built"
+	JOURNAL_BATCH_SIGNED    EJournalBatchState = "signed"
+	JOURNAL_BATCH_INJECTED  EJournalBatchState = "injected"
+	JOURNAL_BATCH_CONFIRMED EJournalBatchState = "confirmed"
+	JOURNAL_BATCH_FAILED    EJournalBatchState = "failed"
+	// results of all batches of the run were written to reports
+	JOURNAL_RUN_REPORTED EJournalBatchState = "reported"
+)
+
+type EFeeScheduleMode string
+
+const (
+	// fee of the bracket the balance falls into applies to the whole reward
+	FEE_SCHEDULE_MODE_FLAT EFeeScheduleMode = "flat"
+	// fee of each bracket applies to the portion of the balance within the bracket
+	FEE_SCHEDULE_MODE_MARGINAL EFeeScheduleMode = "marginal"
+)
+
+var (
+	SUPPORTED_FEE_SCHEDULE_MODES = []EFeeScheduleMode{
+		FEE_SCHEDULE_MODE_FLAT,
+		FEE_SCHEDULE_MODE_MARGINAL,
+	}
+)
+
+type EFeeScheduleBalance string
+
+const (
+	FEE_SCHEDULE_BALANCE_DELEGATED EFeeScheduleBalance = "delegated"
+	FEE_SCHEDULE_BALANCE_STAKED    EFeeScheduleBalance = "staked"
+	FEE_SCHEDULE_BALANCE_TOTAL     EFeeScheduleBalance = "total"
+)
+
+var (
+	SUPPORTED_FEE_SCHEDULE_BALANCES = []EFeeScheduleBalance{
+		FEE_SCHEDULE_BALANCE_DELEGATED,
+		FEE_SCHEDULE_BALANCE_STAKED,
+		FEE_SCHEDULE_BALANCE_TOTAL,
+	}
+)
+
+type EEligibilityRuleAction string
+
+const (
+	// candidate is not paid, reason of the rule is reported as the invalid reason
+	ELIGIBILITY_RULE_ACTION_EXCLUDE  EEligibilityRuleAction = "exclude"
+	ELIGIBILITY_RULE_ACTION_FEE      EEligibilityRuleAction = "fee"
+	ELIGIBILITY_RULE_ACTION_REDIRECT EEligibilityRuleAction = "redirect"
+	// delegated balance taken into account is limited
+	ELIGIBILITY_RULE_ACTION_CAP EEligibilityRuleAction = "cap"
+)
+
+var (
+	SUPPORTED_ELIGIBILITY_RULE_ACTIONS = []EEligibilityRuleAction{
+		ELIGIBILITY_RULE_ACTION_EXCLUDE,
+		ELIGIBILITY_RULE_ACTION_FEE,
+		ELIGIBILITY_RULE_ACTION_REDIRECT,
+		ELIGIBILITY_RULE_ACTION_CAP,
+	}
+)
@@ -28,15 +28,50 @@ var (
 	// configuration - migration
 	ErrConfigurationMigrationFailed = errors.New("failed to migrate configuration")
 
+	// configuration - conversion
+	ErrInvalidFeeRuleDate          = errors.New("invalid fee rule date, expected YYYY-MM-DD")
+	ErrInvalidTokenBonusAmount     = errors.New("invalid token bonus amount")
+	ErrInvalidSigningPolicyAmount  = errors.New("invalid signing policy amount")
+	ErrInvalidEligibilityCondition = errors.New("invalid eligibility rule condition")
+
 	// collector engines
 
 	// baker did not have any rewards in cycle
 	ErrNoCycleDataAvailable                = errors.New("no cycle data available")
 	ErrCycleDataFetchFailed                = errors.New("failed to fetch cycle data")
+	ErrNoBlockAfterDate                    = errors.New("no block baked after the date yet")
 	ErrCycleDataProtocolRewardsFetchFailed = errors.New("failed to fetch protocol-rewards cycle data")
 	ErrCycleDataProtocolRewardsMismatch    = errors.New("protocol-rewards cycle data mismatch")
 	ErrCycleDataUnmarshalFailed            = errors.New("failed to unmarshal cycle data")
 	ErrOperationStatusCheckFailed          = errors.New("failed to check operation status")
+	ErrCacheClearFailed                    = errors.New("failed to clear cache")
+
+	// replay
+
+	ErrFixtureRecordFailed = errors.New("failed to record fixture")
+	ErrFixtureLoadFailed   = errors.New("failed to load fixtures")
+	ErrFixtureNotFound     = errors.New("fixture not found")
+
+	// keystore
+
+	ErrKeystoreLoadFailed   = errors.New("failed to load keystore")
+	ErrKeystoreSaveFailed   = errors.New("failed to save keystore")
+	ErrInvalidKeystore      = errors.New("invalid keystore")
+	ErrInvalidPassphrase    = errors.New("invalid passphrase")
+	ErrEmptyPassphrase      = errors.New("passphrase must not be empty")
+	ErrPassphraseReadFailed = errors.New("failed to read passphrase")
+
+	// signing policy
+
+	ErrSigningPolicyViolation             = errors.New("signing policy violation")
+	ErrSigningPolicyNonTransferContent    = errors.New("operation contains non-transfer content")
+	ErrSigningPolicyDestinationNotAllowed = errors.New("destination is not in allowed destinations")
+	ErrSigningPolicyTokenNotAllowed       = errors.New("token transfers are allowed only for tokens with limits")
+	ErrSigningPolicyOperationCapExceeded  = errors.New("operation amount exceeds the limit")
+	ErrSigningPolicyCycleCapExceeded      = errors.New("cycle amount exceeds the limit")
+	ErrSigningPolicyLedgerLoadFailed      = errors.New("failed to load amounts signed in past cycles")
+	ErrSigningPolicyLedgerWriteFailed     = errors.New("failed to write signed amounts")
+	ErrInvalidTransferParameters          = errors.New("invalid transfer parameters")
 
 	// cycle monitor
 
@@ -49,6 +84,7 @@ var (
 	ErrMissingCollectorEngine  = errors.New("undefined collector engine")
 	ErrMissingReporterEngine   = errors.New("undefined reporter engine")
 	ErrMissingTransactorEngine = errors.New("undefined transactor engine")
+	ErrMissingPayoutJournal    = errors.New("undefined payout journal")
 	ErrMissingConfiguration    = errors.New("undefined configuration")
 	ErrMissingPayoutBlueprint  = errors.New("undefined payout blueprint")
 
@@ -63,12 +99,38 @@ var (
 	ErrPayoutsSaveToFileFailed               = errors.New("failed to save payouts to file")
 	ErrInsufficientBalance                   = errors.New("insufficient balance")
 	ErrFailedToEstimateSerializationGasLimit = errors.New("failed to estimate batch serialization gas limit")
+	ErrFeeRuleResolutionFailed               = errors.New("failed to resolve fee rules of the cycle")
+	ErrDelegationHistoryCollectionFailed     = errors.New("failed to collect delegation history")
+	ErrTokenBalanceCheckFailed               = errors.New("failed to check token balance")
+	ErrCarryOverLedgerLoadFailed             = errors.New("failed to load carry-over ledger")
 
 	// execute payouts
 
 	ErrExecutePayoutsUserTerminated = errors.New("user terminated execution")
 	ErrGetChainLimitsFailed         = errors.New("failed to get chain limits")
 
+	// offline signing
+
+	ErrOfflineSignerCannotSign       = errors.New("offline signer cannot sign, sign exported operations on the offline machine")
+	ErrUnsignedPayoutsLoadFailed     = errors.New("failed to load unsigned payouts")
+	ErrUnsignedPayoutsSaveFailed     = errors.New("failed to save unsigned payouts")
+	ErrOperationSignaturesLoadFailed = errors.New("failed to load operation signatures")
+	ErrOperationSignaturesSaveFailed = errors.New("failed to save operation signatures")
+	ErrUnsignedOperationMismatch     = errors.New("rebuilt operation does not match the exported one")
+	ErrOperationSignatureMissing     = errors.New("operation signature missing")
+	ErrInvalidOperationSignature     = errors.New("invalid operation signature")
+	ErrOperationBranchExpired        = errors.New("branch of exported operation expired, export the payouts again")
+	ErrBranchValidityCheckFailed     = errors.New("failed to check validity of exported operation branch")
+	ErrOperationBranchLookupFailed   = errors.New("failed to look up until when the operation branch is valid")
+
+	// payout journal
+
+	ErrPayoutJournalWriteFailed      = errors.New("failed to write payout journal")
+	ErrPayoutJournalLoadFailed       = errors.New("failed to load payout journal")
+	ErrPayoutJournalCompactionFailed = errors.New("failed to compact payout journal")
+	ErrPayoutJournalReconcileFailed  = errors.New("failed to reconcile payout journal")
+	ErrPayoutJournalBatchNotSigned   = errors.New("batch was interrupted before it was signed")
+
 	// notifications
 
 	ErrUnsupportedNotificator          = errors.New("unsupported notificator")
@@ -84,6 +146,8 @@ var (
 	ErrOperationInvalidContractAddress = errors.New("invalid contract address")
 	ErrOperationInvalidLimits          = errors.New("invalid limits")
 	ErrOperationFailed                 = errors.New("operation failed")
+	ErrOperationNotIncluded            = errors.New("operation not included")
+	ErrOperationReplacementNotPossible = errors.New("operation can not be replaced, payout wallet counter changed (operation may have been included)")
 
 	// extensions
 
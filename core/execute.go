@@ -1,6 +1,8 @@
 package core
 
 import (
+	"errors"
+
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/configuration"
 	"github.com/mavryk-network/mavpay/constants"
@@ -25,3 +27,56 @@ func ExecutePayouts(preparationResult *common.PreparePayoutsResult, config *conf
 		PaidDelegators: ctx.StageData.PaidDelegators,
 	}, err
 }
+
+// ExportUnsignedPayouts splits payouts into batches and completes their operations for signing on the offline machine
+func ExportUnsignedPayouts(preparationResult *common.PreparePayoutsResult, config *configuration.RuntimeConfiguration, engineContext *common.ExecutePayoutsEngineContext, options *common.ExecutePayoutsOptions) (*common.UnsignedPayouts, error) {
+	if config == nil {
+		return nil, constants.ErrMissingConfiguration
+	}
+
+	ctx, err := execute.NewPayoutExecutionContext(preparationResult, config, engineContext, options)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = WrapContext[*execute.PayoutExecutionContext, *common.ExecutePayoutsOptions](ctx).ExecuteStages(options,
+		execute.SplitIntoBatches,
+		execute.ExportUnsignedBatches).Unwrap()
+	if err != nil {
+		return nil, err
+	}
+	return &common.UnsignedPayouts{
+		Version:           common.UNSIGNED_PAYOUTS_VERSION,
+		PayoutKey:         engineContext.GetSigner().GetKey(),
+		PreparationResult: preparationResult,
+		Batches:           ctx.StageData.UnsignedBatches,
+	}, nil
+}
+
+// ExecuteSignedPayouts broadcasts operations signed on the offline machine, waits for confirmation and writes reports
+func ExecuteSignedPayouts(unsignedPayouts *common.UnsignedPayouts, signatures []common.OperationSignature, config *configuration.RuntimeConfiguration, engineContext *common.ExecutePayoutsEngineContext, options *common.ExecutePayoutsOptions) (*common.ExecutePayoutsResult, error) {
+	if config == nil {
+		return nil, constants.ErrMissingConfiguration
+	}
+	if !unsignedPayouts.PayoutKey.Equal(engineContext.GetSigner().GetKey()) {
+		return nil, errors.Join(constants.ErrInvalidOperationSignature, errors.New("unsigned payouts were exported for different payout key"))
+	}
+
+	ctx, err := execute.NewPayoutExecutionContext(unsignedPayouts.PreparationResult, config, engineContext, options)
+	if err != nil {
+		return nil, err
+	}
+	ctx.StageData.UnsignedBatches = unsignedPayouts.Batches
+	ctx.StageData.Signatures = execute.SignaturesToMap(signatures)
+
+	ctx, err = WrapContext[*execute.PayoutExecutionContext, *common.ExecutePayoutsOptions](ctx).ExecuteStages(options,
+		execute.LoadSignedBatches,
+		execute.ExecutePayouts).Unwrap()
+	if ctx == nil {
+		return nil, err
+	}
+	return &common.ExecutePayoutsResult{
+		BatchResults:   ctx.StageData.BatchResults,
+		PaidDelegators: ctx.StageData.PaidDelegators,
+	}, err
+}
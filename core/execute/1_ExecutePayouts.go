@@ -8,20 +8,30 @@ import (
 
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
 	"github.com/mavryk-network/mavpay/state"
 	"github.com/mavryk-network/mavpay/utils"
 	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
 	"github.com/samber/lo"
 )
 
-func druRunExecutePayoutBatch(ctx *PayoutExecutionContext, logger *slog.Logger, batchId string, batch common.RecipeBatch) *common.BatchResult {
+// getOpExecutionContext returns operation of the batch signed on the offline machine if available, otherwise builds and signs it
+func getOpExecutionContext(ctx *PayoutExecutionContext, index int, batch common.RecipeBatch) (*common.OpExecutionContext, error) {
+	if ctx.StageData.SignedOps != nil {
+		return getSignedOpExecutionContext(ctx, index)
+	}
+	return batch.ToOpExecutionContext(ctx.GetSigner(), ctx.GetTransactor())
+}
+
+func druRunExecutePayoutBatch(ctx *PayoutExecutionContext, logger *slog.Logger, index int, batchId string, batch common.RecipeBatch) *common.BatchResult {
 	logger = logger.With("batch_id", batchId)
 	if state.Global.GetWantsOutputJson() {
 		logger.Info("creating batch", "recipes", batch, "phase", "executing_batch")
 	} else {
 		logger.Info("creating batch", "tx_count", len(batch), "phase", "executing_batch")
 	}
-	opExecCtx, err := batch.ToOpExecutionContext(ctx.GetSigner(), ctx.GetTransactor())
+	opExecCtx, err := getOpExecutionContext(ctx, index, batch)
 	if err != nil {
 		logger.Warn("failed to create operation execution context", "id", batchId, "error", err.Error(), "phase", "batch_execution_finished")
 		return common.NewFailedBatchResultWithOpHash(batch, opExecCtx.GetOpHash(), errors.Join(constants.ErrOperationContextCreationFailed, err))
@@ -34,37 +44,127 @@ func druRunExecutePayoutBatch(ctx *PayoutExecutionContext, logger *slog.Logger,
 	return common.NewSuccessBatchResult(batch, mavryk.ZeroOpHash)
 }
 
-func executePayoutBatch(ctx *PayoutExecutionContext, logger *slog.Logger, batchId string, batch common.RecipeBatch) *common.BatchResult {
+// getOpHashChainStatus looks up whether any operation of the replacement chain was included, if transactor is able to tell
+func getOpHashChainStatus(ctx *PayoutExecutionContext, chain []mavryk.OpHash) (mavryk.OpHash, common.OperationStatus) {
+	transactor, ok := ctx.GetTransactor().(common.OperationStatusAwareTransactor)
+	if !ok {
+		return mavryk.ZeroOpHash, common.OPERATION_STATUS_UNKNOWN
+	}
+	for _, opHash := range chain {
+		status, err := transactor.GetOperationStatus(opHash)
+		if err != nil {
+			return mavryk.ZeroOpHash, common.OPERATION_STATUS_UNKNOWN
+		}
+		if status == common.OPERATION_STATUS_APPLIED || status == common.OPERATION_STATUS_FAILED {
+			return opHash, status
+		}
+	}
+	return mavryk.ZeroOpHash, common.OPERATION_STATUS_NOT_EXISTS
+}
+
+func executePayoutBatch(ctx *PayoutExecutionContext, logger *slog.Logger, index int, batchId string, batch common.RecipeBatch) *common.BatchResult {
 	logger = logger.With("batch_id", batchId)
 	if state.Global.GetWantsOutputJson() {
 		logger.Info("creating batch", "recipes", batch, "phase", "executing_batch")
 	} else {
 		logger.Info("creating batch", "tx_count", len(batch), "phase", "executing_batch")
 	}
-	opExecCtx, err := batch.ToOpExecutionContext(ctx.GetSigner(), ctx.GetTransactor())
+	if err := ctx.recordJournal(batchId, enums.JOURNAL_BATCH_BUILT, func(entry common.JournalEntry) common.JournalEntry {
+		return entry.WithPayouts(batch, ctx.AccumulatedPayouts)
+	}); err != nil {
+		logger.Warn("batch not executed, payout journal is not writable", "phase", "batch_execution_finished")
+		return common.NewFailedBatchResult(batch, errors.Join(constants.ErrPayoutJournalWriteFailed, err))
+	}
+	opExecCtx, err := getOpExecutionContext(ctx, index, batch)
 	if err != nil {
 		logger.Warn("failed to create operation execution context", "error", err.Error(), "phase", "batch_execution_finished")
-		return common.NewFailedBatchResultWithOpHash(batch, opExecCtx.GetOpHash(), errors.Join(constants.ErrOperationContextCreationFailed, err))
+		return ctx.recordJournalBatchResult(batchId, common.NewFailedBatchResultWithOpHash(batch, opExecCtx.GetOpHash(), errors.Join(constants.ErrOperationContextCreationFailed, err)))
+	}
+	if err := ctx.recordJournal(batchId, enums.JOURNAL_BATCH_SIGNED, func(entry common.JournalEntry) common.JournalEntry { return entry.WithOp(opExecCtx.Op) }); err != nil {
+		logger.Warn("batch not broadcasted, payout journal is not writable", "phase", "batch_execution_finished")
+		return ctx.recordJournalBatchResult(batchId, common.NewFailedBatchResult(batch, errors.Join(constants.ErrPayoutJournalWriteFailed, err)))
 	}
 
-	logger.Info("broadcasting batch")
-	err = opExecCtx.Dispatch(nil)
-	if err != nil {
-		logger.Warn("failed to broadcast batch", "error", err.Error(), "phase", "batch_execution_finished")
-		return common.NewFailedBatchResultWithOpHash(batch, opExecCtx.GetOpHash(), errors.Join(constants.ErrOperationBroadcastFailed, err))
+	// operations signed on the offline machine can not be re-forged
+	feeBumping := ctx.GetConfiguration().PayoutConfiguration.FeeBumping
+	isReplaceable := feeBumping.MaximumReplacements > 0 && ctx.StageData.SignedOps == nil
+	var dispatchOptions *rpc.CallOptions
+	if isReplaceable {
+		// stop waiting once the operation expires, so it can be replaced
+		options := rpc.DefaultOptions
+		options.TTL = constants.OPERATION_INCLUSION_TTL
+		dispatchOptions = &options
 	}
 
-	logger.Info("waiting for confirmation", "op_reference", utils.GetOpReference(opExecCtx.GetOpHash(), ctx.GetConfiguration().Network.Explorer), "op_hash", opExecCtx.GetOpHash(), "phase", "batch_waiting_for_confirmation")
-	ctx.protectedSection.Pause() // pause protected section to allow confirmation canceling
-	err = opExecCtx.WaitForApply()
-	ctx.protectedSection.Resume() // resume protected section
-	if err != nil {
-		logger.Warn("failed to apply batch", "error", err.Error(), "phase", "batch_execution_finished")
-		return common.NewFailedBatchResultWithOpHash(batch, opExecCtx.GetOpHash(), errors.Join(constants.ErrOperationConfirmationFailed, err))
+	chain := make([]mavryk.OpHash, 0, 1)
+	for replacements := 0; ; replacements++ {
+		logger.Info("broadcasting batch")
+		err = opExecCtx.Dispatch(dispatchOptions)
+		if err != nil {
+			logger.Warn("failed to broadcast batch", "error", err.Error(), "phase", "batch_execution_finished")
+			opHash := opExecCtx.GetOpHash()
+			if len(chain) > 0 {
+				opHash = chain[len(chain)-1]
+			}
+			return ctx.recordJournalBatchResult(batchId, common.NewFailedBatchResultWithOpHash(batch, opHash, errors.Join(constants.ErrOperationBroadcastFailed, err)).WithOpHashChain(chain))
+		}
+		chain = append(chain, opExecCtx.GetOpHash())
+		// the operation is out already, the signed entry holds its hash, so the batch is waited for regardless
+		ctx.recordJournal(batchId, enums.JOURNAL_BATCH_INJECTED, func(entry common.JournalEntry) common.JournalEntry { return entry.WithOpHash(opExecCtx.GetOpHash()) })
+
+		logger.Info("waiting for confirmation", "op_reference", utils.GetOpReference(opExecCtx.GetOpHash(), ctx.GetConfiguration().Network.Explorer), "op_hash", opExecCtx.GetOpHash(), "phase", "batch_waiting_for_confirmation")
+		ctx.protectedSection.Pause() // pause protected section to allow confirmation canceling
+		err = opExecCtx.WaitForApply()
+		ctx.protectedSection.Resume() // resume protected section
+		if err == nil {
+			logger.Info("batch successful", "phase", "batch_execution_finished")
+			return ctx.recordJournalBatchResult(batchId, common.NewSuccessBatchResult(batch, opExecCtx.GetOpHash()).WithOpHashChain(chain))
+		}
+		if !isReplaceable || replacements >= feeBumping.MaximumReplacements || ctx.protectedSection.Signaled() {
+			logger.Warn("failed to apply batch", "error", err.Error(), "phase", "batch_execution_finished")
+			return ctx.recordJournalBatchResult(batchId, common.NewFailedBatchResultWithOpHash(batch, opExecCtx.GetOpHash(), errors.Join(constants.ErrOperationConfirmationFailed, err)).WithOpHashChain(chain))
+		}
+
+		// previous operations of the chain might have landed meanwhile
+		switch opHash, status := getOpHashChainStatus(ctx, chain); status {
+		case common.OPERATION_STATUS_APPLIED:
+			logger.Info("batch successful", "op_hash", opHash, "phase", "batch_execution_finished")
+			return ctx.recordJournalBatchResult(batchId, common.NewSuccessBatchResult(batch, opHash).WithOpHashChain(chain))
+		case common.OPERATION_STATUS_FAILED:
+			logger.Warn("failed to apply batch", "op_hash", opHash, "error", constants.ErrOperationFailed.Error(), "phase", "batch_execution_finished")
+			return ctx.recordJournalBatchResult(batchId, common.NewFailedBatchResultWithOpHash(batch, opHash, errors.Join(constants.ErrOperationConfirmationFailed, constants.ErrOperationFailed)).WithOpHashChain(chain))
+		}
+
+		logger.Warn("batch was not included, replacing it with higher fee", "op_hash", opExecCtx.GetOpHash(), "error", err.Error(), "replacement", replacements+1)
+		replacement, replacementErr := batch.ToReplacementOpExecutionContext(opExecCtx.Op, feeBumping.FeeBump, ctx.GetSigner(), ctx.GetTransactor())
+		if replacementErr != nil {
+			logger.Warn("failed to replace batch", "error", replacementErr.Error(), "phase", "batch_execution_finished")
+			return ctx.recordJournalBatchResult(batchId, common.NewFailedBatchResultWithOpHash(batch, opExecCtx.GetOpHash(), errors.Join(constants.ErrOperationConfirmationFailed, err, replacementErr)).WithOpHashChain(chain))
+		}
+		if err := ctx.recordJournal(batchId, enums.JOURNAL_BATCH_SIGNED, func(entry common.JournalEntry) common.JournalEntry { return entry.WithOp(replacement.Op) }); err != nil {
+			logger.Warn("replacement not broadcasted, payout journal is not writable", "phase", "batch_execution_finished")
+			return ctx.recordJournalBatchResult(batchId, common.NewFailedBatchResultWithOpHash(batch, opExecCtx.GetOpHash(), errors.Join(constants.ErrOperationConfirmationFailed, err, constants.ErrPayoutJournalWriteFailed)).WithOpHashChain(chain))
+		}
+		opExecCtx = replacement
 	}
+}
 
-	logger.Info("batch successful", "phase", "batch_execution_finished")
-	return common.NewSuccessBatchResult(batch, opExecCtx.GetOpHash())
+// reportCarryOverLedger settles ledger entries paid out by successful batches and stores the ledger
+func reportCarryOverLedger(ctx *PayoutExecutionContext) error {
+	ledger := ctx.CarryOverLedger
+	reporter, ok := ctx.GetReporter().(common.CarryOverLedgerAwareReporter)
+	if ledger == nil || !ok {
+		return nil
+	}
+	for _, result := range ctx.StageData.BatchResults {
+		if !result.IsSuccess {
+			continue
+		}
+		for _, payout := range result.Payouts {
+			ledger.Settle(payout.GetCarryOverKey(), payout.CarriedOverCycles)
+		}
+	}
+	return reporter.ReportCarryOverLedger(ledger)
 }
 
 func executePayouts(ctx *PayoutExecutionContext, options *common.ExecutePayoutsOptions) *PayoutExecutionContext {
@@ -72,25 +172,35 @@ func executePayouts(ctx *PayoutExecutionContext, options *common.ExecutePayoutsO
 	batchCount := len(ctx.StageData.Batches)
 	batchesResults := make(common.BatchResults, 0)
 
+	if signer, ok := ctx.GetSigner().(common.PayoutBlueprintsAwareSigner); ok {
+		signer.SetPayoutBlueprints(ctx.PayoutBlueprints, ctx.GetReporter())
+	}
+
 	ctx.protectedSection.Start()
 	logger.Info("paying out", "batches_count", batchCount, "phase", "batch_execution_start")
 	reporter := ctx.GetReporter()
-	for i, batch := range ctx.StageData.Batches {
-		if err := reporter.ReportPayouts(append(batchesResults.ToReports(), ctx.StageData.ReportsOfPastSuccesfulPayouts...)); err != nil {
-			logger.Warn("failed to write partial report of payouts", "error", err.Error())
-		}
+	// operations signed on the offline machine have their counters fixed already
+	parallelBatches := ctx.GetConfiguration().PayoutConfiguration.ParallelBatches
+	if parallelBatches > 1 && !options.DryRun && ctx.StageData.SignedOps == nil {
+		batchesResults = executePayoutBatchesInParallel(ctx, logger, parallelBatches)
+	} else {
+		for i, batch := range ctx.StageData.Batches {
+			if err := reporter.ReportPayouts(append(batchesResults.ToReports(), ctx.StageData.ReportsOfPastSuccesfulPayouts...)); err != nil {
+				logger.Warn("failed to write partial report of payouts", "error", err.Error())
+			}
 
-		if ctx.protectedSection.Signaled() {
-			batchesResults = append(batchesResults, *common.NewFailedBatchResult(batch, constants.ErrExecutePayoutsUserTerminated))
-			ctx.AdminNotify("Payouts execution terminated by user")
-			continue
-		}
+			if ctx.protectedSection.Signaled() {
+				batchesResults = append(batchesResults, *common.NewFailedBatchResult(batch, constants.ErrExecutePayoutsUserTerminated))
+				ctx.AdminNotify("Payouts execution terminated by user")
+				continue
+			}
 
-		batchId := fmt.Sprintf("%d/%d", i+1, batchCount)
-		if options.DryRun {
-			batchesResults = append(batchesResults, *druRunExecutePayoutBatch(ctx, logger, batchId, batch))
-		} else {
-			batchesResults = append(batchesResults, *executePayoutBatch(ctx, logger, batchId, batch))
+			batchId := fmt.Sprintf("%d/%d", i+1, batchCount)
+			if options.DryRun {
+				batchesResults = append(batchesResults, *druRunExecutePayoutBatch(ctx, logger, i, batchId, batch))
+			} else {
+				batchesResults = append(batchesResults, *executePayoutBatch(ctx, logger, i, batchId, batch))
+			}
 		}
 	}
 
@@ -132,8 +242,15 @@ func executePayouts(ctx *PayoutExecutionContext, options *common.ExecutePayoutsO
 			failureDetected = true
 		}
 	}
+	if err := reportCarryOverLedger(ctx); err != nil {
+		logger.Warn("failed to report carry-over ledger", "error", err.Error())
+		failureDetected = true
+	}
 	if !failureDetected {
 		logger.Info("all payouts reports written successfully")
+		if !options.DryRun {
+			ctx.recordJournal("", enums.JOURNAL_RUN_REPORTED, nil)
+		}
 	}
 
 	ctx.protectedSection.Stop()
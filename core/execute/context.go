@@ -2,10 +2,14 @@ package execute
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/configuration"
+	"github.com/mavryk-network/mavpay/constants/enums"
 	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
 )
 
 type StageData struct {
@@ -14,6 +18,11 @@ type StageData struct {
 	Batches                       []common.RecipeBatch
 	BatchResults                  common.BatchResults
 	PaidDelegators                int
+
+	// offline signing
+	UnsignedBatches []common.UnsignedBatch
+	Signatures      map[string]mavryk.Signature
+	SignedOps       []*codec.Op
 }
 
 type PayoutExecutionContext struct {
@@ -27,14 +36,48 @@ type PayoutExecutionContext struct {
 	InvalidPayouts     []common.PayoutRecipe
 	AccumulatedPayouts []common.PayoutRecipe
 	PayoutBlueprints   []*common.CyclePayoutBlueprint
+	CarryOverLedger    *common.CarryOverLedger
 
-	logger *slog.Logger
+	// identifies entries of this execution in the payout journal
+	journalRunId string
+	logger       *slog.Logger
 }
 
 func (ctx *PayoutExecutionContext) GetConfiguration() *configuration.RuntimeConfiguration {
 	return ctx.configuration
 }
 
+// recordJournal writes the entry to the payout journal if enabled. Batches must not be signed or dispatched
+// unless their entry was written, otherwise a crash would leave an operation the journal does not know about.
+func (ctx *PayoutExecutionContext) recordJournal(batchId string, state enums.EJournalBatchState, build func(entry common.JournalEntry) common.JournalEntry) error {
+	journal := ctx.GetJournal()
+	if journal == nil {
+		return nil
+	}
+	entry := common.NewJournalEntry(ctx.journalRunId, batchId, state)
+	if build != nil {
+		entry = build(entry)
+	}
+	if err := journal.Record(entry); err != nil {
+		ctx.logger.Error("failed to write payout journal", "batch_id", batchId, "state", state, "error", err.Error())
+		return err
+	}
+	return nil
+}
+
+// recordJournalBatchResult writes the final state of the batch to the payout journal and returns the result
+func (ctx *PayoutExecutionContext) recordJournalBatchResult(batchId string, result *common.BatchResult) *common.BatchResult {
+	state := enums.JOURNAL_BATCH_CONFIRMED
+	if !result.IsSuccess {
+		state = enums.JOURNAL_BATCH_FAILED
+	}
+	// the batch is finished, on failure the journal still holds its operation and the result is resolved by reconciliation if needed
+	ctx.recordJournal(batchId, state, func(entry common.JournalEntry) common.JournalEntry {
+		return entry.WithOpHash(result.OpHash).WithError(result.Err)
+	})
+	return result
+}
+
 func NewPayoutExecutionContext(preparationResult *common.PreparePayoutsResult, configuration *configuration.RuntimeConfiguration, engineContext *common.ExecutePayoutsEngineContext, options *common.ExecutePayoutsOptions) (*PayoutExecutionContext, error) {
 	if err := engineContext.Validate(); err != nil {
 		return nil, err
@@ -53,7 +96,9 @@ func NewPayoutExecutionContext(preparationResult *common.PreparePayoutsResult, c
 		InvalidPayouts:     preparationResult.InvalidPayouts,
 		AccumulatedPayouts: preparationResult.AccumulatedPayouts,
 		PayoutBlueprints:   preparationResult.Blueprints,
+		CarryOverLedger:    preparationResult.CarryOverLedger,
 
-		logger: slog.Default().With("stage", "execute"),
+		journalRunId: time.Now().UTC().Format(time.RFC3339Nano),
+		logger:       slog.Default().With("stage", "execute"),
 	}, nil
 }
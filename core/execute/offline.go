@@ -0,0 +1,115 @@
+package execute
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+)
+
+// ExportUnsignedBatches completes operations of all batches without signing them.
+// Operations are forged on the current head with the max_operations_ttl of the network, so they can be
+// broadcasted until the chain advances by max_operations_ttl blocks from the export.
+func ExportUnsignedBatches(ctx *PayoutExecutionContext, options *common.ExecutePayoutsOptions) (*PayoutExecutionContext, error) {
+	logger := ctx.logger.With("phase", "export_unsigned_batches")
+	batchCount := len(ctx.StageData.Batches)
+	key := ctx.GetSigner().GetKey()
+
+	limits, err := ctx.GetTransactor().GetLimits()
+	if err != nil {
+		return nil, errors.Join(constants.ErrGetChainLimitsFailed, err)
+	}
+	ttl := limits.MaxOperationsTTL
+	if ttl <= 0 {
+		logger.Warn("max operations ttl of the network not known, exported operations expire early", "ttl", constants.MAX_OPERATION_TTL)
+		ttl = constants.MAX_OPERATION_TTL
+	}
+
+	unsignedBatches := make([]common.UnsignedBatch, 0, batchCount)
+	for i, batch := range ctx.StageData.Batches {
+		batchId := fmt.Sprintf("%d/%d", i+1, batchCount)
+		logger.Info("completing batch", "batch_id", batchId, "tx_count", len(batch))
+		op, err := batch.ToCompletedOpWithTTL(key, ctx.GetTransactor(), ttl)
+		if err != nil {
+			return nil, errors.Join(constants.ErrOperationContextCreationFailed, fmt.Errorf("batch %s", batchId), err)
+		}
+		unsignedBatch := common.NewUnsignedBatch(batchId, batch, op)
+		if transactor, ok := ctx.GetTransactor().(common.BranchValidityAwareTransactor); ok {
+			validUntil, _, err := transactor.GetBranchValidity(op.Branch)
+			if err != nil {
+				return nil, errors.Join(constants.ErrBranchValidityCheckFailed, fmt.Errorf("batch %s", batchId), err)
+			}
+			unsignedBatch.ValidUntilLevel = validUntil
+		}
+		unsignedBatches = append(unsignedBatches, unsignedBatch)
+	}
+	ctx.StageData.UnsignedBatches = unsignedBatches
+	return ctx, nil
+}
+
+// checkBranchValidity refuses batches forged on branch which already expired, such operations would be rejected by the node
+func checkBranchValidity(ctx *PayoutExecutionContext, unsignedBatch *common.UnsignedBatch) error {
+	transactor, ok := ctx.GetTransactor().(common.BranchValidityAwareTransactor)
+	if !ok {
+		return nil
+	}
+	validUntil, head, err := transactor.GetBranchValidity(unsignedBatch.Branch)
+	if err != nil {
+		return errors.Join(constants.ErrBranchValidityCheckFailed, fmt.Errorf("batch %s, branch %s", unsignedBatch.Id, unsignedBatch.Branch), err)
+	}
+	if head >= validUntil {
+		return errors.Join(constants.ErrOperationBranchExpired, fmt.Errorf("batch %s, branch %s valid until level %d, head is at level %d", unsignedBatch.Id, unsignedBatch.Branch, validUntil, head))
+	}
+	return nil
+}
+
+// LoadSignedBatches rebuilds operations of the unsigned batches and attaches the signatures made on the offline machine.
+// Batches without signature are kept so they are reported as failed. Batches with expired branch are refused.
+func LoadSignedBatches(ctx *PayoutExecutionContext, options *common.ExecutePayoutsOptions) (*PayoutExecutionContext, error) {
+	logger := ctx.logger.With("phase", "load_signed_batches")
+	key := ctx.GetSigner().GetKey()
+
+	for i := range ctx.StageData.UnsignedBatches {
+		if err := checkBranchValidity(ctx, &ctx.StageData.UnsignedBatches[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	batches := make([]common.RecipeBatch, 0, len(ctx.StageData.UnsignedBatches))
+	signedOps := make([]*codec.Op, 0, len(ctx.StageData.UnsignedBatches))
+	for _, unsignedBatch := range ctx.StageData.UnsignedBatches {
+		batches = append(batches, unsignedBatch.Payouts)
+		signature, ok := ctx.StageData.Signatures[unsignedBatch.Id]
+		if !ok {
+			logger.Warn("batch is not signed", "batch_id", unsignedBatch.Id)
+			signedOps = append(signedOps, nil)
+			continue
+		}
+		op, err := unsignedBatch.ToSignedOp(key, signature)
+		if err != nil {
+			return nil, err
+		}
+		signedOps = append(signedOps, op)
+	}
+	ctx.StageData.Batches = batches
+	ctx.StageData.SignedOps = signedOps
+	return ctx, nil
+}
+
+func getSignedOpExecutionContext(ctx *PayoutExecutionContext, index int) (*common.OpExecutionContext, error) {
+	if index >= len(ctx.StageData.SignedOps) || ctx.StageData.SignedOps[index] == nil {
+		return nil, constants.ErrOperationSignatureMissing
+	}
+	return common.InitOpExecutionContext(ctx.StageData.SignedOps[index], ctx.GetTransactor()), nil
+}
+
+func SignaturesToMap(signatures []common.OperationSignature) map[string]mavryk.Signature {
+	result := make(map[string]mavryk.Signature, len(signatures))
+	for _, signature := range signatures {
+		result[signature.Id] = signature.Signature
+	}
+	return result
+}
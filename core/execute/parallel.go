@@ -0,0 +1,204 @@
+package execute
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+)
+
+// checkReforgeable makes sure the operation which was not included can not be included anymore - its branch expired,
+// it is not on the chain and the payout wallet counter did not move past its counter. Re-forging the batch otherwise could pay it twice.
+func checkReforgeable(ctx *PayoutExecutionContext, batch common.RecipeBatch, op *codec.Op) error {
+	transactor, ok := ctx.GetTransactor().(common.BranchValidityAwareTransactor)
+	if !ok {
+		return errors.Join(constants.ErrOperationReplacementNotPossible, errors.New("transactor can not check branch expiration"))
+	}
+	validUntil, head, err := transactor.GetBranchValidity(op.Branch)
+	if err != nil {
+		return errors.Join(constants.ErrOperationReplacementNotPossible, err)
+	}
+	if head < validUntil {
+		return errors.Join(constants.ErrOperationReplacementNotPossible, fmt.Errorf("branch valid until level %d, head is at level %d", validUntil, head))
+	}
+	if _, status := getOpHashChainStatus(ctx, []mavryk.OpHash{op.Hash()}); status != common.OPERATION_STATUS_NOT_EXISTS {
+		return errors.Join(constants.ErrOperationReplacementNotPossible, fmt.Errorf("operation status %s", status))
+	}
+	fresh, err := batch.ToCompletedOp(ctx.GetSigner().GetKey(), ctx.GetTransactor())
+	if err != nil {
+		return errors.Join(constants.ErrOperationReplacementNotPossible, err)
+	}
+	if len(op.Contents) == 0 || len(fresh.Contents) == 0 || fresh.Contents[0].GetCounter() > op.Contents[0].GetCounter() {
+		return errors.Join(constants.ErrOperationReplacementNotPossible, errors.New("payout wallet counter moved past the operation counter"))
+	}
+	return nil
+}
+
+// executePayoutBatchWindow forges batches of the window with consecutive counters, injects them together and waits for all of them.
+// The first batch of the window is final. Later batches which were not injected or which provably can not be included anymore
+// (e.g. because a batch before them failed to land) return nil result and have to be re-forged, other failures are final.
+func executePayoutBatchWindow(ctx *PayoutExecutionContext, logger *slog.Logger, window []int) []*common.BatchResult {
+	batchCount := len(ctx.StageData.Batches)
+	results := make([]*common.BatchResult, len(window))
+	batchIds := make([]string, len(window))
+	opExecCtxs := make([]*common.OpExecutionContext, 0, len(window))
+
+	counter := int64(0)
+	for i, index := range window {
+		batch := ctx.StageData.Batches[index]
+		batchIds[i] = fmt.Sprintf("%d/%d", index+1, batchCount)
+		logger.Info("creating batch", "batch_id", batchIds[i], "tx_count", len(batch), "counter", counter, "phase", "executing_batch")
+
+		if err := ctx.recordJournal(batchIds[i], enums.JOURNAL_BATCH_BUILT, func(entry common.JournalEntry) common.JournalEntry {
+			return entry.WithPayouts(batch, ctx.AccumulatedPayouts)
+		}); err != nil {
+			logger.Warn("batch not executed, payout journal is not writable", "batch_id", batchIds[i])
+			if i == 0 {
+				results[0] = common.NewFailedBatchResult(batch, errors.Join(constants.ErrPayoutJournalWriteFailed, err))
+			}
+			break
+		}
+		var opExecCtx *common.OpExecutionContext
+		var err error
+		if i == 0 {
+			opExecCtx, err = batch.ToOpExecutionContext(ctx.GetSigner(), ctx.GetTransactor())
+		} else {
+			opExecCtx, err = batch.ToOpExecutionContextWithCounter(ctx.GetSigner(), ctx.GetTransactor(), counter)
+		}
+		if err != nil {
+			logger.Warn("failed to create operation execution context", "batch_id", batchIds[i], "error", err.Error())
+			if i == 0 {
+				results[0] = ctx.recordJournalBatchResult(batchIds[0], common.NewFailedBatchResult(batch, errors.Join(constants.ErrOperationContextCreationFailed, err)))
+			}
+			break
+		}
+		if err := ctx.recordJournal(batchIds[i], enums.JOURNAL_BATCH_SIGNED, func(entry common.JournalEntry) common.JournalEntry { return entry.WithOp(opExecCtx.Op) }); err != nil {
+			// batches after the first one are re-forged, they were never dispatched
+			logger.Warn("batch not broadcasted, payout journal is not writable", "batch_id", batchIds[i])
+			if i == 0 {
+				results[0] = ctx.recordJournalBatchResult(batchIds[0], common.NewFailedBatchResult(batch, errors.Join(constants.ErrPayoutJournalWriteFailed, err)))
+			}
+			break
+		}
+		counter = common.GetNextCounter(opExecCtx.Op)
+		opExecCtxs = append(opExecCtxs, opExecCtx)
+	}
+
+	// wait until operations expire, so the ones which were not included can be re-forged safely
+	dispatchOptions := rpc.DefaultOptions
+	dispatchOptions.TTL = constants.OPERATION_INCLUSION_TTL
+	dispatched := 0
+	for i, opExecCtx := range opExecCtxs {
+		logger.Info("broadcasting batch", "batch_id", batchIds[i])
+		if err := opExecCtx.Dispatch(&dispatchOptions); err != nil {
+			// the node might have accepted the operation despite the error, so it is not re-forged
+			logger.Warn("failed to broadcast batch", "batch_id", batchIds[i], "error", err.Error())
+			results[i] = ctx.recordJournalBatchResult(batchIds[i], common.NewFailedBatchResult(ctx.StageData.Batches[window[i]], errors.Join(constants.ErrOperationBroadcastFailed, err)))
+			break
+		}
+		// the operation is out already, the signed entry holds its hash, so the batch is waited for regardless
+		ctx.recordJournal(batchIds[i], enums.JOURNAL_BATCH_INJECTED, func(entry common.JournalEntry) common.JournalEntry { return entry.WithOpHash(opExecCtx.GetOpHash()) })
+		logger.Info("waiting for confirmation", "batch_id", batchIds[i], "op_reference", utils.GetOpReference(opExecCtx.GetOpHash(), ctx.GetConfiguration().Network.Explorer), "op_hash", opExecCtx.GetOpHash(), "phase", "batch_waiting_for_confirmation")
+		dispatched++
+	}
+
+	errs := make([]error, dispatched)
+	var wg sync.WaitGroup
+	ctx.protectedSection.Pause() // pause protected section to allow confirmation canceling
+	for i := 0; i < dispatched; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = opExecCtxs[i].WaitForApply()
+		}(i)
+	}
+	wg.Wait()
+	ctx.protectedSection.Resume() // resume protected section
+
+	for i := 0; i < dispatched; i++ {
+		batch := ctx.StageData.Batches[window[i]]
+		opHash := opExecCtxs[i].GetOpHash()
+		if errs[i] == nil {
+			logger.Info("batch successful", "batch_id", batchIds[i], "phase", "batch_execution_finished")
+			results[i] = ctx.recordJournalBatchResult(batchIds[i], common.NewSuccessBatchResult(batch, opHash))
+			continue
+		}
+		if i > 0 {
+			// re-forging operation which was included would pay the batch twice
+			switch _, status := getOpHashChainStatus(ctx, []mavryk.OpHash{opHash}); status {
+			case common.OPERATION_STATUS_APPLIED:
+				logger.Info("batch successful", "batch_id", batchIds[i], "phase", "batch_execution_finished")
+				results[i] = ctx.recordJournalBatchResult(batchIds[i], common.NewSuccessBatchResult(batch, opHash))
+				continue
+			case common.OPERATION_STATUS_FAILED:
+				// included but failed, re-forging would not help
+			default:
+				if err := checkReforgeable(ctx, batch, opExecCtxs[i].Op); err != nil {
+					logger.Warn("batch was not confirmed and can not be re-forged", "batch_id", batchIds[i], "op_hash", opHash, "error", err.Error())
+					errs[i] = errors.Join(errs[i], err)
+					break
+				}
+				logger.Warn("batch was not included, it will be re-forged", "batch_id", batchIds[i], "op_hash", opHash, "error", errs[i].Error())
+				continue
+			}
+		}
+		logger.Warn("failed to apply batch", "batch_id", batchIds[i], "error", errs[i].Error(), "phase", "batch_execution_finished")
+		results[i] = ctx.recordJournalBatchResult(batchIds[i], common.NewFailedBatchResultWithOpHash(batch, opHash, errors.Join(constants.ErrOperationConfirmationFailed, errs[i])))
+	}
+	return results
+}
+
+// executePayoutBatchesInParallel executes batches in windows of parallelBatches, batches which have to be re-forged
+// are moved to the beginning of the next window
+func executePayoutBatchesInParallel(ctx *PayoutExecutionContext, logger *slog.Logger, parallelBatches int) common.BatchResults {
+	reporter := ctx.GetReporter()
+	results := make([]*common.BatchResult, len(ctx.StageData.Batches))
+	collectResults := func() common.BatchResults {
+		batchesResults := make(common.BatchResults, 0, len(results))
+		for _, result := range results {
+			if result != nil {
+				batchesResults = append(batchesResults, *result)
+			}
+		}
+		return batchesResults
+	}
+
+	pending := make([]int, len(ctx.StageData.Batches))
+	for i := range pending {
+		pending[i] = i
+	}
+	for len(pending) > 0 {
+		if err := reporter.ReportPayouts(append(collectResults().ToReports(), ctx.StageData.ReportsOfPastSuccesfulPayouts...)); err != nil {
+			logger.Warn("failed to write partial report of payouts", "error", err.Error())
+		}
+
+		if ctx.protectedSection.Signaled() {
+			for _, index := range pending {
+				results[index] = common.NewFailedBatchResult(ctx.StageData.Batches[index], constants.ErrExecutePayoutsUserTerminated)
+			}
+			ctx.AdminNotify("Payouts execution terminated by user")
+			break
+		}
+
+		window := pending[:min(parallelBatches, len(pending))]
+		windowResults := executePayoutBatchWindow(ctx, logger, window)
+		reforge := make([]int, 0, len(window))
+		for i, index := range window {
+			if windowResults[i] == nil {
+				reforge = append(reforge, index)
+				continue
+			}
+			results[index] = windowResults[i]
+		}
+		pending = append(reforge, pending[len(window):]...)
+	}
+	return collectResults()
+}
@@ -29,6 +29,8 @@ func GeneratePayouts(config *configuration.RuntimeConfiguration, engineContext *
 		// hooks
 		generate.DistributeBonds,
 		generate.CollectBakerFee,
+		generate.SplitPayouts,
+		generate.DistributeTokenBonuses,
 		generate.CheckSufficientBalance,
 		generate.CollectTransactionFees,
 		generate.ValidateSimulatedPayouts,
@@ -40,9 +40,25 @@ func GeneratePayoutCandidates(ctx *PayoutGenerationContext, options *common.Gene
 		return ctx, errors.Join(constants.ErrCycleDataCollectionFailed, fmt.Errorf("collector: %s", ctx.GetCollector().GetId()), err)
 	}
 
+	delegationAges := map[string]int64{}
+	if len(configuration.PayoutConfiguration.LoyaltyRebates) > 0 || common.GetEligibilityRulesHistoryLength(configuration.Delegators.Rules) > 0 {
+		logger.Debug("collecting delegation history")
+		delegationAges, err = getDelegationAges(ctx, options.Cycle, ctx.StageData.CycleData.Delegators)
+		if err != nil {
+			return ctx, err
+		}
+	}
+
 	logger.Debug("generating payout candidates")
 	payoutCandidates := lo.Map(ctx.StageData.CycleData.Delegators, func(delegator common.Delegator, _ int) PayoutCandidate {
 		payoutCandidate := DelegatorToPayoutCandidate(delegator, configuration)
+		payoutCandidate.DelegationAge = delegationAges[delegator.Address.String()]
+		if len(configuration.PayoutConfiguration.LoyaltyRebates) > 0 {
+			payoutCandidate = ApplyLoyaltyRebate(payoutCandidate, delegationAges[delegator.Address.String()], configuration)
+		}
+		if len(configuration.Delegators.Rules) > 0 {
+			payoutCandidate = ApplyEligibilityRules(payoutCandidate, configuration.Delegators.Rules, options.Cycle)
+		}
 		validationContext := payoutCandidate.ToValidationContext(ctx)
 		return *validationContext.Validate(
 			IsIgnoredValidator,
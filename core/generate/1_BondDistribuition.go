@@ -58,6 +58,10 @@ func DistributeBonds(ctx *PayoutGenerationContext, options *common.GeneratePayou
 
 	bakerBonds := getBakerBondsAmount(ctx.StageData.CycleData, totalDelegatorsDelegatedBalance, configuration)
 	availableRewards := ctx.StageData.CycleData.GetTotalDelegatedRewards(configuration.PayoutConfiguration.PayoutMode).Sub(bakerBonds)
+	// staking rewards are paid by the protocol, only the edge the baker received from them is distributed
+	stakingRewardsEdge := ctx.StageData.CycleData.GetTotalStakingRewardsEdge()
+	stakingRewards := ctx.StageData.CycleData.GetTotalStakingRewards()
+	externalStakedBalance := ctx.StageData.CycleData.ExternalStakedBalance
 
 	ctx.StageData.PayoutCandidatesWithBondAmount = lo.Map(candidates, func(candidate PayoutCandidate, _ int) PayoutCandidateWithBondAmount {
 		if candidate.IsInvalid {
@@ -66,10 +70,17 @@ func DistributeBonds(ctx *PayoutGenerationContext, options *common.GeneratePayou
 				BondsAmount:     mavryk.Zero,
 			}
 		}
+		stakedBondsAmount, stakedRewardsAmount := mavryk.Zero, mavryk.Zero
+		if candidate.StakedFeeRate != nil && externalStakedBalance.IsGreater(mavryk.Zero) {
+			stakedBondsAmount = stakingRewardsEdge.Mul(candidate.StakedBalance).Div(externalStakedBalance)
+			stakedRewardsAmount = stakingRewards.Mul(candidate.StakedBalance).Div(externalStakedBalance)
+		}
 		return PayoutCandidateWithBondAmount{
-			PayoutCandidate: candidate,
-			BondsAmount:     availableRewards.Mul(candidate.GetDelegatedBalance()).Div(totalDelegatorsDelegatedBalance),
-			TxKind:          enums.PAYOUT_TX_KIND_MAV,
+			PayoutCandidate:     candidate,
+			BondsAmount:         availableRewards.Mul(candidate.GetDelegatedBalance()).Div(totalDelegatorsDelegatedBalance),
+			StakedBondsAmount:   stakedBondsAmount,
+			StakedRewardsAmount: stakedRewardsAmount,
+			TxKind:              enums.PAYOUT_TX_KIND_MAV,
 		}
 	})
 
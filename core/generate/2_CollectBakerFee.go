@@ -1,7 +1,10 @@
 package generate
 
 import (
+	"errors"
+
 	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
 	"github.com/mavryk-network/mavpay/constants/enums"
 	"github.com/mavryk-network/mavpay/extension"
 	"github.com/mavryk-network/mavpay/utils"
@@ -18,12 +21,32 @@ func ExecuteOnFeesCollection(data *OnFeesCollectionHookData) error {
 	return extension.ExecuteHook(enums.EXTENSION_HOOK_ON_FEES_COLLECTION, "0.2", data)
 }
 
+// getCycleFeeRules returns fee rules applying to the cycle, in order of the configuration
+func getCycleFeeRules(ctx *PayoutGenerationContext, cycle int64) ([]common.FeeRule, error) {
+	rules := make([]common.FeeRule, 0, len(ctx.GetConfiguration().PayoutConfiguration.FeeRules))
+	for _, rule := range ctx.GetConfiguration().PayoutConfiguration.FeeRules {
+		isActive, err := rule.IsActiveInCycle(cycle, ctx.GetCollector())
+		if err != nil {
+			return nil, errors.Join(constants.ErrFeeRuleResolutionFailed, err)
+		}
+		if isActive {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
 func CollectBakerFee(ctx *PayoutGenerationContext, options *common.GeneratePayoutsOptions) (*PayoutGenerationContext, error) {
 	configuration := ctx.GetConfiguration()
 	logger := ctx.logger.With("phase", "collect_baker_fee")
 	logger.Debug("collecting baker fee")
 	candidates := ctx.StageData.PayoutCandidatesWithBondAmount
 
+	feeRules, err := getCycleFeeRules(ctx, options.Cycle)
+	if err != nil {
+		return ctx, err
+	}
+
 	candidatesWithBondsAndFees := lo.Map(candidates, func(candidateWithBondsAmount PayoutCandidateWithBondAmount, _ int) PayoutCandidateWithBondAmountAndFee {
 		if candidateWithBondsAmount.IsInvalid {
 			return PayoutCandidateWithBondAmountAndFee{
@@ -38,8 +61,27 @@ func CollectBakerFee(ctx *PayoutGenerationContext, options *common.GeneratePayou
 			}
 		}
 
+		// fee overrides of delegators and eligibility rules take precedence over the fee rules and the schedule
+		if configuration.Delegators.Overrides[candidateWithBondsAmount.Source.String()].Fee == nil && !candidateWithBondsAmount.IsFeeOverridden {
+			if rule, found := lo.Find(feeRules, func(rule common.FeeRule) bool { return rule.AppliesTo(candidateWithBondsAmount.Source) }); found {
+				candidateWithBondsAmount.FeeRate = rule.Fee
+			} else if schedule := configuration.PayoutConfiguration.FeeSchedule; schedule != nil {
+				candidateWithBondsAmount.FeeRate = schedule.GetFeeRate(candidateWithBondsAmount.DelegatedBalance, candidateWithBondsAmount.StakedBalance)
+			}
+		}
+		if candidateWithBondsAmount.FeeRebate > 0 {
+			candidateWithBondsAmount.FeeRate = max(0, candidateWithBondsAmount.FeeRate-candidateWithBondsAmount.FeeRebate)
+		}
+
 		fee := utils.GetZPortion(candidateWithBondsAmount.BondsAmount, candidateWithBondsAmount.FeeRate)
 		candidateWithBondsAmount.BondsAmount = candidateWithBondsAmount.BondsAmount.Sub(fee)
+		// staked fee is a share of the staker's rewards kept from the edge the baker already received, the rest of the edge
+		// is refunded. Fee above the edge can not be collected, the edge is kept whole then. It is not part of the collected fees.
+		stakedFee := utils.GetZPortion(candidateWithBondsAmount.StakedRewardsAmount, candidateWithBondsAmount.GetStakedFeeRate())
+		if candidateWithBondsAmount.StakedBondsAmount.IsLess(stakedFee) {
+			stakedFee = candidateWithBondsAmount.StakedBondsAmount
+		}
+		candidateWithBondsAmount.BondsAmount = candidateWithBondsAmount.BondsAmount.Add(candidateWithBondsAmount.StakedBondsAmount.Sub(stakedFee))
 		if candidateWithBondsAmount.BondsAmount.IsZero() || candidateWithBondsAmount.BondsAmount.IsNeg() {
 			candidateWithBondsAmount.IsInvalid = true
 			candidateWithBondsAmount.InvalidBecause = enums.INVALID_PAYOUT_BELLOW_MINIMUM
@@ -47,6 +89,7 @@ func CollectBakerFee(ctx *PayoutGenerationContext, options *common.GeneratePayou
 		return PayoutCandidateWithBondAmountAndFee{
 			PayoutCandidateWithBondAmount: candidateWithBondsAmount,
 			Fee:                           fee,
+			StakedFee:                     stakedFee,
 		}
 	})
 
@@ -54,7 +97,7 @@ func CollectBakerFee(ctx *PayoutGenerationContext, options *common.GeneratePayou
 		Cycle:      options.Cycle,
 		Candidates: candidatesWithBondsAndFees,
 	}
-	err := ExecuteOnFeesCollection(hookData)
+	err = ExecuteOnFeesCollection(hookData)
 	if err != nil {
 		return ctx, err
 	}
@@ -1,10 +1,14 @@
 package generate
 
 import (
+	"errors"
 	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/configuration"
+	"github.com/mavryk-network/mavpay/constants"
 	"github.com/mavryk-network/mavpay/constants/enums"
 	"github.com/mavryk-network/mavpay/test/mock"
 	"github.com/mavryk-network/mavpay/utils"
@@ -156,3 +160,176 @@ func TestCollectBakerFees(t *testing.T) {
 		}
 	}
 }
+
+// dateRangeColletor runs cycles of one hour with the head baked at the time of its creation in the current cycle
+// of the simple collector. Like real collectors it fails to resolve dates no block was baked after yet.
+type dateRangeColletor struct {
+	*mock.SimpleColletor
+	genesis time.Time
+	head    time.Time
+}
+
+func newDateRangeColletor() *dateRangeColletor {
+	collector := mock.InitSimpleColletor()
+	current, _ := collector.GetCurrentCycleNumber()
+	head := time.Now()
+	return &dateRangeColletor{
+		SimpleColletor: collector,
+		genesis:        head.Add(-time.Duration(current)*time.Hour - 30*time.Minute),
+		head:           head,
+	}
+}
+
+func (engine *dateRangeColletor) getMidCycleDate(cycle int64) *time.Time {
+	return lo.ToPtr(engine.genesis.Add(time.Duration(cycle)*time.Hour + 30*time.Minute))
+}
+
+func (engine *dateRangeColletor) GetCycleAtDate(date time.Time) (int64, error) {
+	if !date.Before(engine.head) {
+		return 0, errors.Join(constants.ErrCycleDataFetchFailed, constants.ErrNoBlockAfterDate)
+	}
+	return int64(date.Sub(engine.genesis) / time.Hour), nil
+}
+
+func (engine *dateRangeColletor) GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error) {
+	return nil, errors.New("unexpected cycles in date range lookup")
+}
+
+func TestCollectBakerFeeRules(t *testing.T) {
+	assert := assert.New(t)
+
+	ruleConfig := configuration.GetDefaultRuntimeConfiguration()
+	ctx := &PayoutGenerationContext{
+		GeneratePayoutsEngineContext: *common.NewGeneratePayoutsEngines(collector, nil, nil),
+		StageData:                    &StageData{PayoutCandidatesWithBondAmount: payoutCandidatesWithBondAmount},
+		configuration:                &ruleConfig,
+
+		logger: slog.Default(),
+	}
+	adjustFee(ctx, 0.05)
+
+	promotedDelegator := payoutCandidatesWithBondAmount[0].Source
+	overriddenDelegator := payoutCandidatesWithBondAmount[1].Source
+	overriddenFee := 0.05
+	ruleConfig.Delegators.Overrides = map[string]configuration.RuntimeDelegatorOverride{
+		overriddenDelegator.String(): {Fee: &overriddenFee},
+	}
+	ruleConfig.PayoutConfiguration.FeeRules = []common.FeeRule{
+		{Fee: 0, FromCycle: lo.ToPtr(int64(10)), ToCycle: lo.ToPtr(int64(12)), Delegators: []mavryk.Address{promotedDelegator, overriddenDelegator}},
+		{Fee: 0.1, FromCycle: lo.ToPtr(int64(10))},
+	}
+
+	t.Log("check rules outside of the cycle range")
+	result, err := CollectBakerFee(ctx, &common.GeneratePayoutsOptions{Cycle: 9})
+	assert.Nil(err)
+	for _, v := range result.StageData.PayoutCandidatesWithBondAmountAndFees {
+		if v.TxKind == enums.PAYOUT_TX_KIND_MAV {
+			assert.Equal(0.05, v.FeeRate)
+		}
+	}
+
+	t.Log("check first matching rule applies and overrides take precedence")
+	result, err = CollectBakerFee(ctx, &common.GeneratePayoutsOptions{Cycle: 11})
+	assert.Nil(err)
+	assert.Equal(0.0, result.StageData.PayoutCandidatesWithBondAmountAndFees[0].FeeRate)
+	assert.True(result.StageData.PayoutCandidatesWithBondAmountAndFees[0].Fee.IsZero())
+	assert.Equal(overriddenFee, result.StageData.PayoutCandidatesWithBondAmountAndFees[1].FeeRate)
+
+	t.Log("check rule after the promotion")
+	result, err = CollectBakerFee(ctx, &common.GeneratePayoutsOptions{Cycle: 13})
+	assert.Nil(err)
+	assert.Equal(0.1, result.StageData.PayoutCandidatesWithBondAmountAndFees[0].FeeRate)
+	assert.Equal(overriddenFee, result.StageData.PayoutCandidatesWithBondAmountAndFees[1].FeeRate)
+
+	t.Log("check date ranges apply to overlapping cycles")
+	dateCollector := newDateRangeColletor()
+	ctx.GeneratePayoutsEngineContext = *common.NewGeneratePayoutsEngines(dateCollector, nil, nil)
+	ruleConfig.PayoutConfiguration.FeeRules = []common.FeeRule{
+		{Fee: 0.2, FromDate: dateCollector.getMidCycleDate(497), ToDate: dateCollector.getMidCycleDate(498)},
+		{Fee: 0.3, ToDate: dateCollector.getMidCycleDate(495)},
+		// end in the future does not cap the range at the time of the run
+		{Fee: 0.4, FromDate: dateCollector.getMidCycleDate(499), ToDate: lo.ToPtr(time.Now().Add(24 * time.Hour))},
+		// rule starting in the future applies to none of the finished cycles
+		{Fee: 0.5, FromDate: lo.ToPtr(time.Now().Add(time.Hour))},
+	}
+	for cycle, fee := range map[int64]float64{494: 0.3, 495: 0.3, 496: 0.05, 497: 0.2, 498: 0.2, 499: 0.4, 500: 0.4} {
+		result, err = CollectBakerFee(ctx, &common.GeneratePayoutsOptions{Cycle: cycle})
+		assert.Nil(err)
+		assert.Equal(fee, result.StageData.PayoutCandidatesWithBondAmountAndFees[0].FeeRate, "cycle %d", cycle)
+	}
+}
+
+func TestCollectBakerStakedFee(t *testing.T) {
+	assert := assert.New(t)
+
+	// staker earned 20 mav from its stake, 2 mav of it (10%) is the edge the baker already received
+	stakedFeeRate := 0.05
+	cappedStakedFeeRate := 0.5
+	candidates := []PayoutCandidateWithBondAmount{
+		{
+			PayoutCandidate: PayoutCandidate{
+				Source:        mock.GetRandomAddress(),
+				Recipient:     mock.GetRandomAddress(),
+				FeeRate:       0.05,
+				StakedFeeRate: &stakedFeeRate,
+			},
+			BondsAmount:         mavryk.NewZ(10000000),
+			StakedBondsAmount:   mavryk.NewZ(2000000),
+			StakedRewardsAmount: mavryk.NewZ(20000000),
+			TxKind:              enums.PAYOUT_TX_KIND_MAV,
+		},
+		{
+			PayoutCandidate: PayoutCandidate{
+				Source:    mock.GetRandomAddress(),
+				Recipient: mock.GetRandomAddress(),
+				FeeRate:   0.05,
+			},
+			BondsAmount: mavryk.NewZ(10000000),
+			TxKind:      enums.PAYOUT_TX_KIND_MAV,
+		},
+		{
+			PayoutCandidate: PayoutCandidate{
+				Source:        mock.GetRandomAddress(),
+				Recipient:     mock.GetRandomAddress(),
+				FeeRate:       0.05,
+				StakedFeeRate: &cappedStakedFeeRate,
+			},
+			BondsAmount:         mavryk.NewZ(10000000),
+			StakedBondsAmount:   mavryk.NewZ(2000000),
+			StakedRewardsAmount: mavryk.NewZ(20000000),
+			TxKind:              enums.PAYOUT_TX_KIND_MAV,
+		},
+	}
+	stakedConfig := configuration.GetDefaultRuntimeConfiguration()
+	ctx := &PayoutGenerationContext{
+		GeneratePayoutsEngineContext: *common.NewGeneratePayoutsEngines(collector, nil, nil),
+		StageData:                    &StageData{PayoutCandidatesWithBondAmount: candidates},
+		configuration:                &stakedConfig,
+
+		logger: slog.Default(),
+	}
+
+	result, err := CollectBakerFee(ctx, &common.GeneratePayoutsOptions{})
+	assert.Nil(err)
+	// 5% of the 20 mav rewards is kept from the edge, the other half of the edge is refunded
+	staker := result.StageData.PayoutCandidatesWithBondAmountAndFees[0]
+	assert.Equal(int64(500000), staker.Fee.Int64())
+	assert.Equal(int64(1000000), staker.StakedFee.Int64())
+	assert.Equal(int64(9500000+1000000), staker.BondsAmount.Int64())
+
+	delegator := result.StageData.PayoutCandidatesWithBondAmountAndFees[1]
+	assert.True(delegator.StakedFee.IsZero())
+	assert.Equal(int64(9500000), delegator.BondsAmount.Int64())
+
+	// 50% of the rewards is above the edge, the whole edge is kept and nothing is refunded
+	capped := result.StageData.PayoutCandidatesWithBondAmountAndFees[2]
+	assert.Equal(int64(2000000), capped.StakedFee.Int64())
+	assert.Equal(int64(9500000), capped.BondsAmount.Int64())
+
+	// staked fee is not part of the collected fees
+	assert.Equal(int64(1500000), result.StageData.BakerFeesAmount.Add(result.StageData.DonateFeesAmount).Int64())
+
+	recipe := (&PayoutCandidateSimulated{PayoutCandidateWithBondAmountAndFee: staker}).ToPayoutRecipe(mock.GetRandomAddress(), 1, enums.PAYOUT_KIND_DELEGATOR_REWARD)
+	assert.Equal(int64(1000000), recipe.StakedAmount.Int64())
+	assert.Equal(stakedFeeRate, recipe.StakedFeeRate)
+}
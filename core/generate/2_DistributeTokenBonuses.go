@@ -0,0 +1,65 @@
+package generate
+
+import (
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+// getTokenBonusCandidates splits the bonus of the cycle among eligible delegators in proportion to their delegated balance
+func getTokenBonusCandidates(bonus *common.TokenBonus, candidates []PayoutCandidate, cycle int64) []PayoutCandidateWithBondAmountAndFee {
+	amount := bonus.GetCycleAmount(cycle)
+	if amount.IsZero() {
+		return []PayoutCandidateWithBondAmountAndFee{}
+	}
+
+	eligible := lo.Filter(candidates, func(candidate PayoutCandidate, _ int) bool {
+		return !candidate.IsInvalid && bonus.IsEligible(candidate.Source, candidate.GetDelegatedBalance())
+	})
+	totalBalance := lo.Reduce(eligible, func(agg mavryk.Z, candidate PayoutCandidate, _ int) mavryk.Z {
+		return agg.Add(candidate.GetDelegatedBalance())
+	}, mavryk.Zero)
+	if totalBalance.IsZero() {
+		return []PayoutCandidateWithBondAmountAndFee{}
+	}
+
+	result := make([]PayoutCandidateWithBondAmountAndFee, 0, len(eligible))
+	for _, candidate := range eligible {
+		share := amount.Mul(candidate.GetDelegatedBalance()).Div(totalBalance)
+		if share.IsZero() {
+			continue
+		}
+		// bonus is not subject to the fee, it is distributed as is
+		candidate.FeeRate = 0
+		candidate.FeeRebate = 0
+		candidate.StakedFeeRate = nil
+		result = append(result, PayoutCandidateWithBondAmountAndFee{
+			PayoutCandidateWithBondAmount: PayoutCandidateWithBondAmount{
+				PayoutCandidate: candidate,
+				BondsAmount:     share,
+				TxKind:          bonus.Kind,
+				FATokenId:       bonus.TokenId,
+				FAContract:      bonus.Contract,
+				IsTokenBonus:    true,
+			},
+		})
+	}
+	return result
+}
+
+func DistributeTokenBonuses(ctx *PayoutGenerationContext, options *common.GeneratePayoutsOptions) (*PayoutGenerationContext, error) {
+	configuration := ctx.GetConfiguration()
+	logger := ctx.logger.With("phase", "distribute_token_bonuses")
+	if len(configuration.TokenBonuses) == 0 {
+		return ctx, nil
+	}
+	logger.Debug("distributing token bonuses")
+
+	for _, bonus := range configuration.TokenBonuses {
+		bonusCandidates := getTokenBonusCandidates(&bonus, ctx.StageData.PayoutCandidates, options.Cycle)
+		logger.Debug("token bonus distributed", "contract", bonus.Contract, "token_id", bonus.TokenId, "recipients", len(bonusCandidates))
+		ctx.StageData.PayoutCandidatesWithBondAmountAndFees = append(ctx.StageData.PayoutCandidatesWithBondAmountAndFees, bonusCandidates...)
+	}
+
+	return ctx, nil
+}
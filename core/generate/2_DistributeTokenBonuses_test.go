@@ -0,0 +1,59 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mavpay/test/mock"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTokenBonusCandidates(t *testing.T) {
+	assert := assert.New(t)
+
+	ignored := mock.GetRandomAddress()
+	candidates := []PayoutCandidate{
+		{Source: mock.GetRandomAddress(), Recipient: mock.GetRandomAddress(), DelegatedBalance: mavryk.NewZ(300_000_000), FeeRate: .05},
+		{Source: mock.GetRandomAddress(), Recipient: mock.GetRandomAddress(), DelegatedBalance: mavryk.NewZ(100_000_000), FeeRate: .05},
+		{Source: mock.GetRandomAddress(), Recipient: mock.GetRandomAddress(), DelegatedBalance: mavryk.NewZ(1_000_000), FeeRate: .05},
+		{Source: ignored, Recipient: ignored, DelegatedBalance: mavryk.NewZ(500_000_000), FeeRate: .05},
+		{Source: mock.GetRandomAddress(), Recipient: mock.GetRandomAddress(), DelegatedBalance: mavryk.NewZ(500_000_000), IsInvalid: true},
+	}
+	bonus := common.TokenBonus{
+		Kind:           enums.PAYOUT_TX_KIND_FA2,
+		Contract:       mavryk.MustParseAddress("KT1Hkg6qgV3VykjgUXKbWcU3h6oJ1qVxUxZV"),
+		TokenId:        mavryk.NewZ(1),
+		Amount:         mavryk.NewZ(1000),
+		CycleAmounts:   map[int64]mavryk.Z{11: mavryk.NewZ(4000), 12: mavryk.Zero},
+		MinimumBalance: mavryk.NewZ(10_000_000),
+		Ignore:         []mavryk.Address{ignored},
+	}
+
+	result := getTokenBonusCandidates(&bonus, candidates, 10)
+	assert.Len(result, 2)
+	assert.Equal(mavryk.NewZ(750), result[0].BondsAmount)
+	assert.Equal(mavryk.NewZ(250), result[1].BondsAmount)
+	for i, candidate := range result {
+		assert.True(candidate.IsTokenBonus)
+		assert.Equal(enums.PAYOUT_TX_KIND_FA2, candidate.TxKind)
+		assert.True(bonus.Contract.Equal(candidate.FAContract))
+		assert.Equal(bonus.TokenId, candidate.FATokenId)
+		assert.Equal(candidates[i].Recipient, candidate.Recipient)
+		assert.Equal(float64(0), candidate.FeeRate)
+		assert.True(candidate.Fee.IsZero())
+		assert.True(bonus.IsSameToken(&candidate))
+	}
+
+	result = getTokenBonusCandidates(&bonus, candidates, 11)
+	assert.Equal(mavryk.NewZ(3000), result[0].BondsAmount)
+	assert.Equal(mavryk.NewZ(1000), result[1].BondsAmount)
+
+	assert.Empty(getTokenBonusCandidates(&bonus, candidates, 12))
+
+	bonus.Delegators = []mavryk.Address{candidates[1].Source}
+	result = getTokenBonusCandidates(&bonus, candidates, 10)
+	assert.Len(result, 1)
+	assert.Equal(mavryk.NewZ(1000), result[0].BondsAmount)
+}
@@ -0,0 +1,77 @@
+package generate
+
+import (
+	"slices"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+// splitCandidate splits the payout of the delegator among the recipients by their shares,
+// the remainder after rounding is assigned to the last recipient in the address order
+func splitCandidate(candidate PayoutCandidateWithBondAmountAndFee, recipients map[string]float64) []PayoutCandidateWithBondAmountAndFee {
+	addresses := lo.Keys(recipients)
+	slices.Sort(addresses)
+
+	result := make([]PayoutCandidateWithBondAmountAndFee, 0, len(addresses))
+	bondsAmount, fee, stakedBondsAmount, stakedRewardsAmount, stakedFee := candidate.BondsAmount, candidate.Fee, candidate.StakedBondsAmount, candidate.StakedRewardsAmount, candidate.StakedFee
+	for i, address := range addresses {
+		share := recipients[address]
+		split := candidate
+		split.Recipient, _ = mavryk.ParseAddress(address)
+		split.RecipientShare = share
+		if i < len(addresses)-1 {
+			split.BondsAmount = utils.GetZPortion(candidate.BondsAmount, share)
+			split.Fee = utils.GetZPortion(candidate.Fee, share)
+			split.StakedBondsAmount = utils.GetZPortion(candidate.StakedBondsAmount, share)
+			split.StakedRewardsAmount = utils.GetZPortion(candidate.StakedRewardsAmount, share)
+			split.StakedFee = utils.GetZPortion(candidate.StakedFee, share)
+		} else {
+			split.BondsAmount, split.Fee, split.StakedBondsAmount, split.StakedRewardsAmount, split.StakedFee = bondsAmount, fee, stakedBondsAmount, stakedRewardsAmount, stakedFee
+		}
+		bondsAmount = bondsAmount.Sub(split.BondsAmount)
+		fee = fee.Sub(split.Fee)
+		stakedBondsAmount = stakedBondsAmount.Sub(split.StakedBondsAmount)
+		stakedRewardsAmount = stakedRewardsAmount.Sub(split.StakedRewardsAmount)
+		stakedFee = stakedFee.Sub(split.StakedFee)
+
+		if split.BondsAmount.IsZero() || split.BondsAmount.IsNeg() {
+			split.IsInvalid = true
+			split.InvalidBecause = enums.INVALID_PAYOUT_BELLOW_MINIMUM
+		}
+		result = append(result, split)
+	}
+	return result
+}
+
+// SplitPayouts splits payouts of delegators with multiple recipients configured in their overrides
+func SplitPayouts(ctx *PayoutGenerationContext, options *common.GeneratePayoutsOptions) (*PayoutGenerationContext, error) {
+	configuration := ctx.GetConfiguration()
+	logger := ctx.logger.With("phase", "split_payouts")
+
+	candidates := make([]PayoutCandidateWithBondAmountAndFee, 0, len(ctx.StageData.PayoutCandidatesWithBondAmountAndFees))
+	for _, candidate := range ctx.StageData.PayoutCandidatesWithBondAmountAndFees {
+		recipients := configuration.Delegators.Overrides[candidate.Source.String()].Recipients
+		if candidate.IsInvalid || candidate.TxKind != enums.PAYOUT_TX_KIND_MAV || len(recipients) == 0 {
+			candidates = append(candidates, candidate)
+			continue
+		}
+		logger.Debug("splitting payout", "delegator", candidate.Source, "recipients", len(recipients))
+		for _, split := range splitCandidate(candidate, recipients) {
+			// recipients were not known when the candidate was validated
+			validationContext := split.PayoutCandidate.ToValidationContext(ctx)
+			split.PayoutCandidate = *validationContext.Validate(
+				IgnoreKtValidator,
+				RecipientNotBaker,
+				NotExcludedByAddressPrefix,
+			).ToPayoutCandidate()
+			candidates = append(candidates, split)
+		}
+	}
+
+	ctx.StageData.PayoutCandidatesWithBondAmountAndFees = candidates
+	return ctx, nil
+}
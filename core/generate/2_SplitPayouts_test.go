@@ -0,0 +1,58 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mavpay/test/mock"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCandidate(t *testing.T) {
+	assert := assert.New(t)
+
+	delegator := mock.GetRandomAddress()
+	first := mavryk.MustParseAddress("mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3")
+	second := mavryk.MustParseAddress("mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb")
+	third := mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g")
+	candidate := PayoutCandidateWithBondAmountAndFee{
+		PayoutCandidateWithBondAmount: PayoutCandidateWithBondAmount{
+			PayoutCandidate: PayoutCandidate{Source: delegator, Recipient: delegator, DelegatedBalance: mavryk.NewZ(100_000_000), FeeRate: .05},
+			BondsAmount:     mavryk.NewZ(1001),
+			TxKind:          enums.PAYOUT_TX_KIND_MAV,
+		},
+		Fee: mavryk.NewZ(53),
+	}
+
+	result := splitCandidate(candidate, map[string]float64{
+		third.String():  0.2,
+		first.String():  0.5,
+		second.String(): 0.3,
+	})
+	assert.Len(result, 3)
+	assert.True(first.Equal(result[0].Recipient))
+	assert.True(second.Equal(result[1].Recipient))
+	assert.True(third.Equal(result[2].Recipient))
+	assert.Equal(0.5, result[0].RecipientShare)
+	assert.Equal(0.2, result[2].RecipientShare)
+
+	totalBonds, totalFee := mavryk.Zero, mavryk.Zero
+	for _, split := range result {
+		assert.True(delegator.Equal(split.Source))
+		assert.False(split.IsInvalid)
+		totalBonds = totalBonds.Add(split.BondsAmount)
+		totalFee = totalFee.Add(split.Fee)
+	}
+	assert.Equal(mavryk.NewZ(500), result[0].BondsAmount)
+	assert.Equal(mavryk.NewZ(300), result[1].BondsAmount)
+	assert.Equal(mavryk.NewZ(201), result[2].BondsAmount)
+	assert.Equal(candidate.BondsAmount, totalBonds)
+	assert.Equal(candidate.Fee, totalFee)
+
+	candidate.BondsAmount = mavryk.NewZ(1)
+	result = splitCandidate(candidate, map[string]float64{first.String(): 0.5, second.String(): 0.5})
+	assert.True(result[0].IsInvalid)
+	assert.Equal(enums.INVALID_PAYOUT_BELLOW_MINIMUM, result[0].InvalidBecause)
+	assert.False(result[1].IsInvalid)
+}
@@ -12,15 +12,17 @@ import (
 	"github.com/mavryk-network/mavpay/constants/enums"
 	"github.com/mavryk-network/mavpay/extension"
 	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/codec"
 	"github.com/mavryk-network/mvgo/mavryk"
 	"github.com/samber/lo"
 )
 
 type CheckBalanceHookData struct {
-	SkipMavCheck bool                                  `json:"skip_mav_check"`
-	IsSufficient bool                                  `json:"is_sufficient"`
-	Message      string                                `json:"message"`
-	Payouts      []PayoutCandidateWithBondAmountAndFee `json:"payouts"`
+	SkipMavCheck   bool                                  `json:"skip_mav_check"`
+	SkipTokenCheck bool                                  `json:"skip_token_check"`
+	IsSufficient   bool                                  `json:"is_sufficient"`
+	Message        string                                `json:"message"`
+	Payouts        []PayoutCandidateWithBondAmountAndFee `json:"payouts"`
 }
 
 func checkBalanceWithHook(data *CheckBalanceHookData) error {
@@ -76,6 +78,49 @@ func checkBalanceWithCollector(data *CheckBalanceHookData, ctx *PayoutGeneration
 	return nil
 }
 
+// checkTokenBalancesWithCollector simulates transfer of the total of each token bonus to the payout address itself,
+// token contracts reject the transfer if the payout address does not hold enough tokens
+func checkTokenBalancesWithCollector(data *CheckBalanceHookData, ctx *PayoutGenerationContext) error {
+	if data.SkipTokenCheck {
+		return nil
+	}
+	payoutAddress := ctx.PayoutKey.Address()
+	for _, bonus := range ctx.GetConfiguration().TokenBonuses {
+		required := lo.Reduce(data.Payouts, func(agg mavryk.Z, candidate PayoutCandidateWithBondAmountAndFee, _ int) mavryk.Z {
+			if candidate.IsInvalid || !candidate.IsTokenBonus || !bonus.IsSameToken(&candidate) {
+				return agg
+			}
+			return agg.Add(candidate.BondsAmount)
+		}, mavryk.Zero)
+		if required.IsZero() {
+			continue
+		}
+
+		transfer := PayoutCandidateWithBondAmount{
+			PayoutCandidate: PayoutCandidate{Recipient: payoutAddress},
+			BondsAmount:     required,
+			TxKind:          bonus.Kind,
+			FATokenId:       bonus.TokenId,
+			FAContract:      bonus.Contract,
+		}
+		op := codec.NewOp().WithSource(payoutAddress)
+		op.WithTTL(constants.MAX_OPERATION_TTL)
+		if err := common.InjectTransferContents(op, payoutAddress, &transfer); err != nil {
+			return errors.Join(constants.ErrTokenBalanceCheckFailed, err)
+		}
+		receipt, err := ctx.GetCollector().Simulate(op, ctx.PayoutKey)
+		if err != nil && receipt == nil {
+			return errors.Join(constants.ErrTokenBalanceCheckFailed, err)
+		}
+		if receipt != nil && !receipt.IsSuccess() {
+			data.IsSufficient = false
+			data.Message = fmt.Sprintf("required: %s of token %s (id %s)", required, bonus.Contract, bonus.TokenId)
+			return nil
+		}
+	}
+	return nil
+}
+
 func runBalanceCheck(ctx *PayoutGenerationContext, logger *slog.Logger, check func(*CheckBalanceHookData) error, data *CheckBalanceHookData, options *common.GeneratePayoutsOptions) error {
 	notificatorTrigger := 0
 	for {
@@ -136,6 +181,10 @@ func CheckSufficientBalance(ctx *PayoutGenerationContext, options *common.Genera
 			logger.Debug("checking mav balance with collector")
 			return checkBalanceWithCollector(data, ctx)
 		},
+		func(data *CheckBalanceHookData) error {
+			logger.Debug("checking token balances with collector")
+			return checkTokenBalancesWithCollector(data, ctx)
+		},
 	}
 
 	for _, check := range checks {
@@ -88,6 +88,9 @@ func FinalizePayouts(ctx *PayoutGenerationContext, options *common.GeneratePayou
 	simulated := ctx.StageData.PayoutCandidatesSimulated
 
 	delegatorPayouts := lo.Map(simulated, func(candidate PayoutCandidateSimulated, _ int) common.PayoutRecipe {
+		if candidate.IsTokenBonus {
+			return candidate.ToPayoutRecipe(ctx.GetConfiguration().BakerPKH, options.Cycle, enums.PAYOUT_KIND_TOKEN_BONUS)
+		}
 		return candidate.ToPayoutRecipe(ctx.GetConfiguration().BakerPKH, options.Cycle, enums.PAYOUT_KIND_DELEGATOR_REWARD)
 	})
 
@@ -123,9 +126,10 @@ func FinalizePayouts(ctx *PayoutGenerationContext, options *common.GeneratePayou
 	payouts = append(payouts, donationPayouts...)
 
 	ctx.StageData.Payouts = payouts
-	ctx.StageData.PaidDelegators = len(lo.Filter(delegatorPayouts, func(recipe common.PayoutRecipe, _ int) bool {
-		return recipe.Kind != enums.PAYOUT_KIND_INVALID
-	}))
+	// split payouts of a delegator are counted once
+	ctx.StageData.PaidDelegators = len(lo.Uniq(lo.FilterMap(delegatorPayouts, func(recipe common.PayoutRecipe, _ int) (string, bool) {
+		return recipe.Delegator.String(), recipe.Kind == enums.PAYOUT_KIND_DELEGATOR_REWARD
+	})))
 
 	return ctx, nil
 }
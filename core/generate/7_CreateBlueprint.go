@@ -12,13 +12,22 @@ import (
 
 func sumValidPayoutsAmount(payouts []common.PayoutRecipe) mavryk.Z {
 	return lo.Reduce(payouts, func(agg mavryk.Z, payout common.PayoutRecipe, _ int) mavryk.Z {
-		if !payout.IsValid {
+		if !payout.IsValid || payout.TxKind != enums.PAYOUT_TX_KIND_MAV {
 			return agg
 		}
 		return agg.Add(payout.Amount)
 	}, mavryk.Zero)
 }
 
+func sumValidPayoutsStakedAmount(payouts []common.PayoutRecipe) mavryk.Z {
+	return lo.Reduce(payouts, func(agg mavryk.Z, payout common.PayoutRecipe, _ int) mavryk.Z {
+		if !payout.IsValid {
+			return agg
+		}
+		return agg.Add(payout.StakedAmount)
+	}, mavryk.Zero)
+}
+
 type AfterPayoutsBlueprintGeneratedHookData = common.CyclePayoutBlueprint
 
 // NOTE: do we want to allow rewriting of blueprint?
@@ -51,6 +60,10 @@ func CreateBlueprint(ctx *PayoutGenerationContext, options *common.GeneratePayou
 			DonatedBonds:             stageData.DonateBondsAmount,
 			DonatedFees:              stageData.DonateFeesAmount,
 			DonatedTotal:             stageData.DonateFeesAmount.Add(stageData.DonateBondsAmount),
+			Stakers:                  int(stageData.CycleData.StakersCount),
+			StakingRewardsEdge:       stageData.CycleData.GetTotalStakingRewardsEdge(),
+			StakingFees:              stageData.CycleData.BlockStakingFees,
+			StakingIncome:            stageData.CycleData.GetTotalStakingRewardsEdge().Add(stageData.CycleData.BlockStakingFees).Sub(sumValidPayoutsStakedAmount(stageData.Payouts)),
 			Timestamp:                time.Now(),
 		},
 		BatchMetadataDeserializationGasLimit: stageData.BatchMetadataDeserializationGasLimit,
@@ -0,0 +1,41 @@
+package generate
+
+import (
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants/enums"
+)
+
+// ApplyEligibilityRules evaluates the rules in order against the candidate. The first met 'exclude' rule
+// invalidates the candidate with the reason of the rule, actions of other met rules are applied in order.
+func ApplyEligibilityRules(candidate PayoutCandidate, rules []common.EligibilityRule, cycle int64) PayoutCandidate {
+	facts := common.EligibilityFacts{
+		Address:          candidate.Source,
+		DelegatedBalance: candidate.DelegatedBalance,
+		StakedBalance:    candidate.StakedBalance,
+		DelegationAge:    candidate.DelegationAge,
+		Cycle:            cycle,
+	}
+	for _, rule := range rules {
+		if !rule.Condition.Evaluate(&facts) {
+			continue
+		}
+		switch rule.Action {
+		case enums.ELIGIBILITY_RULE_ACTION_EXCLUDE:
+			candidate.IsInvalid = true
+			candidate.InvalidBecause = rule.GetInvalidReason()
+			return candidate
+		case enums.ELIGIBILITY_RULE_ACTION_FEE:
+			// same as with fee overrides, the loyalty rebate does not apply
+			candidate.FeeRate = *rule.Fee
+			candidate.FeeRebate = 0
+			candidate.IsFeeOverridden = true
+		case enums.ELIGIBILITY_RULE_ACTION_REDIRECT:
+			candidate.Recipient = rule.Recipient
+		case enums.ELIGIBILITY_RULE_ACTION_CAP:
+			if rule.MaximumBalance.IsLess(candidate.DelegatedBalance) {
+				candidate.DelegatedBalance = *rule.MaximumBalance
+			}
+		}
+	}
+	return candidate
+}
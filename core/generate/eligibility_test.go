@@ -0,0 +1,61 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyEligibilityRules(t *testing.T) {
+	assert := assert.New(t)
+
+	mustParseCondition := func(source string) *common.EligibilityCondition {
+		condition, err := common.ParseEligibilityCondition(source)
+		assert.Nil(err)
+		return condition
+	}
+	redirectTo := mavryk.MustParseAddress("mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb")
+	rules := []common.EligibilityRule{
+		{Condition: mustParseCondition("address_type == 'contract'"), Action: enums.ELIGIBILITY_RULE_ACTION_EXCLUDE, Reason: "NO_CONTRACTS"},
+		{Condition: mustParseCondition("balance < 10"), Action: enums.ELIGIBILITY_RULE_ACTION_EXCLUDE},
+		{Condition: mustParseCondition("delegation_age > 5"), Action: enums.ELIGIBILITY_RULE_ACTION_FEE, Fee: lo.ToPtr(0.02)},
+		{Condition: mustParseCondition("balance > 1000"), Action: enums.ELIGIBILITY_RULE_ACTION_CAP, MaximumBalance: lo.ToPtr(mavryk.NewZ(1_000_000_000))},
+		{Condition: mustParseCondition("cycle >= 100 && balance > 2000"), Action: enums.ELIGIBILITY_RULE_ACTION_REDIRECT, Recipient: redirectTo},
+	}
+
+	delegator := mavryk.MustParseAddress("mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3")
+	candidate := PayoutCandidate{Source: delegator, Recipient: delegator, DelegatedBalance: mavryk.NewZ(500_000_000), FeeRate: .05, FeeRebate: .01, DelegationAge: 3}
+	result := ApplyEligibilityRules(candidate, rules, 100)
+	assert.Equal(candidate, result)
+
+	candidate.DelegationAge = 6
+	result = ApplyEligibilityRules(candidate, rules, 100)
+	assert.False(result.IsInvalid)
+	assert.Equal(0.02, result.FeeRate)
+	assert.Equal(0.0, result.FeeRebate)
+	assert.True(result.IsFeeOverridden)
+
+	candidate.DelegatedBalance = mavryk.NewZ(3_000_000_000)
+	result = ApplyEligibilityRules(candidate, rules, 100)
+	assert.Equal(mavryk.NewZ(1_000_000_000), result.DelegatedBalance)
+	// conditions are evaluated against the balance of the delegator, not the capped one
+	assert.True(redirectTo.Equal(result.Recipient))
+	result = ApplyEligibilityRules(candidate, rules, 99)
+	assert.True(delegator.Equal(result.Recipient))
+
+	candidate.DelegatedBalance = mavryk.NewZ(5_000_000)
+	result = ApplyEligibilityRules(candidate, rules, 100)
+	assert.True(result.IsInvalid)
+	assert.Equal(enums.EPayoutInvalidReason("EXCLUDED_BY_RULE (balance < 10)"), result.InvalidBecause)
+	assert.False(result.IsFeeOverridden)
+
+	contract := mavryk.MustParseAddress("KT1Hkg6qgV3VykjgUXKbWcU3h6oJ1qVxUxZV")
+	candidate.Source, candidate.Recipient = contract, contract
+	result = ApplyEligibilityRules(candidate, rules, 100)
+	assert.True(result.IsInvalid)
+	assert.Equal(enums.EPayoutInvalidReason("NO_CONTRACTS"), result.InvalidBecause)
+}
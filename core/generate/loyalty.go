@@ -0,0 +1,47 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+// getDelegationAges returns number of consecutive cycles (including the paid one) each delegator of the cycle
+// delegated to the baker. History is looked up only as far as the highest loyalty rebate or eligibility rule requires,
+// only delegators of past cycles are collected. Cycles without data (e.g. the baker had no rights) do not break the delegation.
+func getDelegationAges(ctx *PayoutGenerationContext, cycle int64, delegators []common.Delegator) (map[string]int64, error) {
+	configuration := ctx.GetConfiguration()
+	historyLength := max(common.GetLoyaltyHistoryLength(configuration.PayoutConfiguration.LoyaltyRebates),
+		common.GetEligibilityRulesHistoryLength(configuration.Delegators.Rules))
+
+	ages := make(map[string]int64, len(delegators))
+	for _, delegator := range delegators {
+		ages[delegator.Address.String()] = 1
+	}
+	active := lo.Keys(ages)
+
+	for pastCycle := cycle - 1; pastCycle >= 0 && pastCycle > cycle-historyLength && len(active) > 0; pastCycle-- {
+		pastDelegators, err := ctx.GetCollector().GetCycleDelegators(configuration.BakerPKH, pastCycle)
+		if errors.Is(err, constants.ErrNoCycleDataAvailable) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Join(constants.ErrDelegationHistoryCollectionFailed, fmt.Errorf("cycle: %d", pastCycle), err)
+		}
+		present := lo.SliceToMap(pastDelegators, func(address mavryk.Address) (string, bool) {
+			return address.String(), true
+		})
+		active = lo.Filter(active, func(address string, _ int) bool {
+			if !present[address] {
+				return false
+			}
+			ages[address]++
+			return true
+		})
+	}
+	return ages, nil
+}
@@ -0,0 +1,64 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/configuration"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/test/mock"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+// delegatorsHistoryColletor serves only delegators of past cycles, cycles without them have no data
+type delegatorsHistoryColletor struct {
+	*mock.SimpleColletor
+	delegators map[int64][]mavryk.Address
+}
+
+func (engine *delegatorsHistoryColletor) GetCycleStakingData(baker mavryk.Address, cycle int64) (*common.BakersCycleData, error) {
+	return nil, errors.New("unexpected cycle data lookup")
+}
+
+func (engine *delegatorsHistoryColletor) GetCycleDelegators(baker mavryk.Address, cycle int64) ([]mavryk.Address, error) {
+	delegators, ok := engine.delegators[cycle]
+	if !ok {
+		return nil, errors.Join(constants.ErrNoCycleDataAvailable, fmt.Errorf("cycle: %d", cycle))
+	}
+	return delegators, nil
+}
+
+func TestGetDelegationAgesWithGapCycle(t *testing.T) {
+	assert := assert.New(t)
+
+	loyal := mavryk.MustParseAddress("mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3")
+	recent := mavryk.MustParseAddress("mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb")
+	historyCollector := &delegatorsHistoryColletor{
+		SimpleColletor: mock.InitSimpleColletor(),
+		delegators: map[int64][]mavryk.Address{
+			99: {loyal, recent},
+			// 98 has no data, e.g. the baker had no rights
+			97: {loyal},
+			96: {loyal, recent},
+		},
+	}
+
+	config := configuration.GetDefaultRuntimeConfiguration()
+	config.PayoutConfiguration.LoyaltyRebates = []common.LoyaltyRebate{{AfterCycles: 10, Rebate: 0.01}}
+	ctx := &PayoutGenerationContext{
+		GeneratePayoutsEngineContext: *common.NewGeneratePayoutsEngines(historyCollector, nil, nil),
+		configuration:                &config,
+
+		logger: slog.Default(),
+	}
+
+	ages, err := getDelegationAges(ctx, 100, []common.Delegator{{Address: loyal}, {Address: recent}})
+	assert.Nil(err)
+	// the gap cycle does not break the delegation
+	assert.Equal(int64(4), ages[loyal.String()])
+	assert.Equal(int64(2), ages[recent.String()])
+}
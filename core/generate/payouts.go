@@ -1,6 +1,8 @@
 package generate
 
 import (
+	"fmt"
+
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/configuration"
 	"github.com/mavryk-network/mavpay/constants/enums"
@@ -11,6 +13,7 @@ type PayoutCandidate struct {
 	Source                       mavryk.Address             `json:"source,omitempty"`
 	Recipient                    mavryk.Address             `json:"recipient,omitempty"`
 	FeeRate                      float64                    `json:"fee_rate,omitempty"`
+	StakedFeeRate                *float64                   `json:"staked_fee_rate,omitempty"`
 	StakedBalance                mavryk.Z                   `json:"staked_balance,omitempty"`
 	DelegatedBalance             mavryk.Z                   `json:"delegated_balance,omitempty"`
 	IsInvalid                    bool                       `json:"is_invalid,omitempty"`
@@ -18,6 +21,12 @@ type PayoutCandidate struct {
 	IsBakerPayingTxFee           bool                       `json:"is_baker_paying_tx_fee,omitempty"`
 	IsBakerPayingAllocationTxFee bool                       `json:"is_baker_paying_allocation_tx_fee,omitempty"`
 	InvalidBecause               enums.EPayoutInvalidReason `json:"invalid_because,omitempty"`
+	DelegationAge                int64                      `json:"delegation_age,omitempty"`
+	FeeRebate                    float64                    `json:"fee_rebate,omitempty"`
+	// share of the delegator's payout sent to the recipient, set only if the payout is split among several recipients
+	RecipientShare float64 `json:"recipient_share,omitempty"`
+	// fee set by an eligibility rule, fee rules and the fee schedule do not apply
+	IsFeeOverridden bool `json:"is_fee_overridden,omitempty"`
 	// mainly for accumulation to be able to check if fee was collected and subtract it from the amount
 	TxFeeCollected bool `json:"tx_fee_collected,omitempty"`
 	// mainly for accumulation to be able to check if fee was collected and subtract it from the amount
@@ -28,6 +37,14 @@ func (candidate *PayoutCandidate) GetDelegatedBalance() mavryk.Z {
 	return candidate.DelegatedBalance
 }
 
+// GetStakedFeeRate returns the fee on the staking rewards, the whole edge is kept if staked fee is not configured
+func (candidate *PayoutCandidate) GetStakedFeeRate() float64 {
+	if candidate.StakedFeeRate == nil {
+		return 1
+	}
+	return *candidate.StakedFeeRate
+}
+
 func (candidate *PayoutCandidate) ToValidationContext(ctx *PayoutGenerationContext) PayoutValidationContext {
 	pkh, _ := candidate.Recipient.MarshalText()
 	var overrides *configuration.RuntimeDelegatorOverride
@@ -44,10 +61,16 @@ func (candidate *PayoutCandidate) ToValidationContext(ctx *PayoutGenerationConte
 
 type PayoutCandidateWithBondAmount struct {
 	PayoutCandidate
-	BondsAmount mavryk.Z                     `json:"bonds_amount,omitempty"`
-	TxKind      enums.EPayoutTransactionKind `json:"tx_kind,omitempty"`
-	FATokenId   mavryk.Z                     `json:"fa_token_id,omitempty"` // required only if fa12 or fa2
-	FAContract  mavryk.Address               `json:"fa_contract"`           // required only if fa12 or fa2
+	BondsAmount mavryk.Z `json:"bonds_amount,omitempty"`
+	// share of the staking edge received by the baker from the staked balance, set only if staked fee is configured
+	StakedBondsAmount mavryk.Z `json:"staked_bonds_amount,omitempty"`
+	// rewards of the staked balance before the edge was taken from them, staked fee is charged from these
+	StakedRewardsAmount mavryk.Z                     `json:"staked_rewards_amount,omitempty"`
+	TxKind              enums.EPayoutTransactionKind `json:"tx_kind,omitempty"`
+	FATokenId           mavryk.Z                     `json:"fa_token_id,omitempty"` // required only if fa12 or fa2
+	FAContract          mavryk.Address               `json:"fa_contract"`           // required only if fa12 or fa2
+	// token bonus distributed alongside the mav reward of the delegator
+	IsTokenBonus bool `json:"is_token_bonus,omitempty"`
 }
 
 func (candidate *PayoutCandidateWithBondAmount) GetDestination() mavryk.Address {
@@ -77,6 +100,8 @@ func (candidate *PayoutCandidateWithBondAmount) GetFeeRate() float64 {
 type PayoutCandidateWithBondAmountAndFee struct {
 	PayoutCandidateWithBondAmount
 	Fee mavryk.Z `json:"fee,omitempty"`
+	// part of the staked bonds kept by the baker, the rest is included in bonds amount
+	StakedFee mavryk.Z `json:"staked_fee,omitempty"`
 }
 
 func (candidate *PayoutCandidateWithBondAmountAndFee) ToValidationContext(ctx *PayoutGenerationContext) PresimPayoutCandidateValidationContext {
@@ -109,6 +134,12 @@ func (payout *PayoutCandidateSimulated) ToPayoutRecipe(baker mavryk.Address, cyc
 	if payout.IsInvalid {
 		kind = enums.PAYOUT_KIND_INVALID
 		note = string(payout.InvalidBecause)
+	} else if payout.FeeRebate > 0 && payout.TxKind == enums.PAYOUT_TX_KIND_MAV {
+		note = fmt.Sprintf("loyalty rebate %s after %d cycles", common.FloatToPercentage(payout.FeeRebate), payout.DelegationAge)
+	}
+	stakedFeeRate := float64(0)
+	if !payout.StakedBondsAmount.IsZero() {
+		stakedFeeRate = payout.GetStakedFeeRate()
 	}
 
 	return common.PayoutRecipe{
@@ -118,6 +149,7 @@ func (payout *PayoutCandidateSimulated) ToPayoutRecipe(baker mavryk.Address, cyc
 		TxKind:                 payout.TxKind,
 		Delegator:              payout.Source,
 		Recipient:              payout.Recipient,
+		RecipientShare:         payout.RecipientShare,
 		DelegatedBalance:       payout.DelegatedBalance,
 		StakedBalance:          payout.StakedBalance,
 		FATokenId:              payout.FATokenId,
@@ -125,6 +157,9 @@ func (payout *PayoutCandidateSimulated) ToPayoutRecipe(baker mavryk.Address, cyc
 		Amount:                 payout.BondsAmount,
 		FeeRate:                payout.FeeRate,
 		Fee:                    payout.Fee,
+		StakedAmount:           payout.StakedBondsAmount.Sub(payout.StakedFee),
+		StakedFeeRate:          stakedFeeRate,
+		StakedFee:              payout.StakedFee,
 		OpLimits:               payout.SimulationResult,
 		TxFeeCollected:         payout.TxFeeCollected,
 		AllocationFeeCollected: payout.AllocationFeeCollected,
@@ -137,6 +172,7 @@ func DelegatorToPayoutCandidate(delegator common.Delegator, configuration *confi
 	pkh, _ := delegator.Address.MarshalText()
 	delegatorOverrides := configuration.Delegators.Overrides
 	payoutFeeRate := configuration.PayoutConfiguration.Fee
+	stakedFeeRate := configuration.PayoutConfiguration.StakedFee
 	payoutRecipient := delegator.Address
 	isBakerPayingTxFee := configuration.PayoutConfiguration.IsPayingTxFee
 	IsBakerPayingAllocationTxFee := configuration.PayoutConfiguration.IsPayingAllocationTxFee
@@ -148,6 +184,9 @@ func DelegatorToPayoutCandidate(delegator common.Delegator, configuration *confi
 		if delegatorOverride.Fee != nil {
 			payoutFeeRate = *delegatorOverride.Fee
 		}
+		if delegatorOverride.StakedFee != nil {
+			stakedFeeRate = delegatorOverride.StakedFee
+		}
 		if delegatorOverride.IsBakerPayingTxFee != nil {
 			isBakerPayingTxFee = *delegatorOverride.IsBakerPayingTxFee
 		}
@@ -163,6 +202,7 @@ func DelegatorToPayoutCandidate(delegator common.Delegator, configuration *confi
 		Source:                       delegator.Address,
 		Recipient:                    payoutRecipient,
 		FeeRate:                      payoutFeeRate,
+		StakedFeeRate:                stakedFeeRate,
 		DelegatedBalance:             delegator.DelegatedBalance,
 		StakedBalance:                delegator.StakedBalance,
 		IsEmptied:                    delegator.Emptied,
@@ -170,3 +210,14 @@ func DelegatorToPayoutCandidate(delegator common.Delegator, configuration *confi
 		IsBakerPayingAllocationTxFee: IsBakerPayingAllocationTxFee,
 	}
 }
+
+// ApplyLoyaltyRebate records the delegation age and the rebate it earns, the rebate is subtracted
+// from the fee when the baker fee is collected. Delegators with fee override are not eligible.
+func ApplyLoyaltyRebate(candidate PayoutCandidate, delegationAge int64, configuration *configuration.RuntimeConfiguration) PayoutCandidate {
+	candidate.DelegationAge = delegationAge
+	if configuration.Delegators.Overrides[candidate.Source.String()].Fee != nil {
+		return candidate
+	}
+	candidate.FeeRebate = common.GetLoyaltyRebate(configuration.PayoutConfiguration.LoyaltyRebates, delegationAge)
+	return candidate
+}
@@ -53,3 +53,39 @@ func TestDelegatorToPayoutCandidate(t *testing.T) {
 	candidate = DelegatorToPayoutCandidate(delegator, &config)
 	assert.True(candidate.GetDelegatedBalance().Equal(delegator.DelegatedBalance))
 }
+
+func TestApplyLoyaltyRebate(t *testing.T) {
+	assert := assert.New(t)
+
+	config := configuration.GetDefaultRuntimeConfiguration()
+	config.PayoutConfiguration.Fee = 0.05
+	config.PayoutConfiguration.LoyaltyRebates = []common.LoyaltyRebate{
+		{AfterCycles: 10, Rebate: 0.01},
+		{AfterCycles: 50, Rebate: 0.02},
+	}
+	overriddenFee := 0.1
+	config.Delegators.Overrides = map[string]configuration.RuntimeDelegatorOverride{
+		"mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb": {
+			Fee: &overriddenFee,
+		},
+	}
+
+	delegator := common.Delegator{
+		Address:          mavryk.MustParseAddress("mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3"),
+		DelegatedBalance: mavryk.NewZ(100000000),
+	}
+	candidate := ApplyLoyaltyRebate(DelegatorToPayoutCandidate(delegator, &config), 9, &config)
+	assert.Equal(int64(9), candidate.DelegationAge)
+	assert.Equal(0.0, candidate.FeeRebate)
+
+	candidate = ApplyLoyaltyRebate(DelegatorToPayoutCandidate(delegator, &config), 10, &config)
+	assert.Equal(0.01, candidate.FeeRebate)
+
+	candidate = ApplyLoyaltyRebate(DelegatorToPayoutCandidate(delegator, &config), 80, &config)
+	assert.Equal(0.02, candidate.FeeRebate)
+
+	delegator.Address = mavryk.MustParseAddress("mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb")
+	candidate = ApplyLoyaltyRebate(DelegatorToPayoutCandidate(delegator, &config), 80, &config)
+	assert.Equal(0.0, candidate.FeeRebate)
+	assert.Equal(overriddenFee, candidate.FeeRate)
+}
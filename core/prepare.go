@@ -19,13 +19,15 @@ func PreparePayouts(blueprints []*common.CyclePayoutBlueprint, config *configura
 
 	ctx, err = WrapContext[*prepare.PayoutPrepareContext, *common.PreparePayoutsOptions](ctx).ExecuteStages(options,
 		prepare.PreparePayouts,
-		prepare.AccumulatePayouts).Unwrap()
+		prepare.AccumulatePayouts,
+		prepare.CarryOverPayouts).Unwrap()
 	return &common.PreparePayoutsResult{
 		Blueprints:                    ctx.PayoutBlueprints,
 		ValidPayouts:                  ctx.StageData.ValidPayouts,
 		AccumulatedPayouts:            ctx.StageData.AccumulatedPayouts,
 		InvalidPayouts:                ctx.StageData.InvalidPayouts,
 		ReportsOfPastSuccesfulPayouts: ctx.StageData.ReportsOfPastSuccesfulPayouts,
+		CarryOverLedger:               ctx.StageData.CarryOverLedger,
 	}, err
 }
 
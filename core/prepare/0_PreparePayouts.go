@@ -45,7 +45,7 @@ func PreparePayouts(ctx *PayoutPrepareContext, options *common.PreparePayoutsOpt
 		}
 		reportResidues := utils.FilterReportsByBaker(reports, ctx.configuration.BakerPKH)
 		// we match already paid even against invalid set of payouts in case they were paid under different conditions
-		bluePrintPayouts, blueprintReportsOfPastSuccesfulPayouts := utils.FilterRecipesByReports(blueprint.Payouts, reportResidues, ctx.GetCollector())
+		bluePrintPayouts, blueprintReportsOfPastSuccesfulPayouts := utils.FilterRecipesByReports(blueprint.Payouts, reportResidues, ctx.GetOperationStatusChecker())
 
 		payouts = append(payouts, bluePrintPayouts...)
 		reportsOfPastSuccesfulPayouts = append(reportsOfPastSuccesfulPayouts, blueprintReportsOfPastSuccesfulPayouts...)
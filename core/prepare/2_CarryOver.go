@@ -0,0 +1,176 @@
+package prepare
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+func isCarryOverCandidate(payout common.PayoutRecipe) bool {
+	return !payout.IsValid && payout.TxKind == enums.PAYOUT_TX_KIND_MAV &&
+		payout.Note == string(enums.INVALID_PAYOUT_BELLOW_MINIMUM) && !payout.Amount.IsNeg() && !payout.Amount.IsZero()
+}
+
+// getCollectedTxFees returns transaction fees subtracted from the amount of the payout
+func getCollectedTxFees(payout common.PayoutRecipe) int64 {
+	if payout.OpLimits == nil {
+		return 0
+	}
+	fees := int64(0)
+	if payout.TxFeeCollected {
+		fees += payout.OpLimits.GetOperationFeesWithoutAllocation()
+	}
+	if payout.AllocationFeeCollected {
+		fees += payout.OpLimits.GetAllocationFee()
+	}
+	return fees
+}
+
+// getCarryOverNote appends cycles the carried over amounts come from to the note of the payout
+func getCarryOverNote(note string, cycle int64, entries []common.CarryOver) string {
+	cycles := lo.FilterMap(entries, func(entry common.CarryOver, _ int) (int64, bool) {
+		return entry.Cycle, entry.Cycle != cycle
+	})
+	if len(cycles) == 0 {
+		return note
+	}
+	carryOverNote := fmt.Sprintf("carried over from %s", utils.FormatCycleNumbers(cycles...))
+	if note != "" {
+		return fmt.Sprintf("%s; %s", note, carryOverNote)
+	}
+	return carryOverNote
+}
+
+func getCarriedOverCycles(entries []common.CarryOver) []int64 {
+	return lo.Map(entries, func(entry common.CarryOver, _ int) int64 {
+		return entry.Cycle
+	})
+}
+
+// getPaidDelegators returns carry-over keys of delegators already paid a delegator reward in the cycles of the blueprints,
+// their payouts bellow minimum were paid out from the ledger and must not be carried over again
+func getPaidDelegators(ctx *PayoutPrepareContext) (map[string]bool, error) {
+	paid := make(map[string]bool)
+	for _, blueprint := range ctx.PayoutBlueprints {
+		reports, err := ctx.GetReporter().GetExistingReports(blueprint.Cycle)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Join(constants.ErrPayoutsFromFileLoadFailed, fmt.Errorf("cycle: %d", blueprint.Cycle), err)
+		}
+		for _, report := range utils.FilterReportsByBaker(reports, ctx.configuration.BakerPKH) {
+			if report.IsSuccess && report.Kind == enums.PAYOUT_KIND_DELEGATOR_REWARD {
+				paid[fmt.Sprintf("%s#%d", report.GetCarryOverKey(), report.Cycle)] = true
+			}
+		}
+	}
+	return paid, nil
+}
+
+// CarryOverPayouts records payouts bellow minimum in the carry-over ledger and pays the carried over amounts
+// with the delegator's payout, or on its own once it passes the minimum or is carried over for too long
+func CarryOverPayouts(ctx *PayoutPrepareContext, options *common.PreparePayoutsOptions) (*PayoutPrepareContext, error) {
+	if ctx.PayoutBlueprints == nil {
+		return nil, constants.ErrMissingPayoutBlueprint
+	}
+	configuration := ctx.GetConfiguration()
+	carryOverConfiguration := configuration.PayoutConfiguration.CarryOver
+	if carryOverConfiguration == nil {
+		return ctx, nil
+	}
+
+	logger := ctx.logger.With("phase", "carry_over_payouts")
+	reporter, ok := ctx.GetReporter().(common.CarryOverLedgerAwareReporter)
+	if !ok {
+		logger.Warn("reporter does not support carry-over ledger, payouts bellow minimum are not carried over")
+		return ctx, nil
+	}
+	logger.Info("carrying over payouts")
+
+	ledger, err := reporter.GetCarryOverLedger()
+	if err != nil {
+		return ctx, errors.Join(constants.ErrCarryOverLedgerLoadFailed, err)
+	}
+	paidDelegators, err := getPaidDelegators(ctx)
+	if err != nil {
+		return ctx, err
+	}
+
+	invalidPayouts := make([]common.PayoutRecipe, 0, len(ctx.StageData.InvalidPayouts))
+	// only the payout of the latest cycle of the delegator (or recipient of the split payout) can pay out the carried over amount
+	latestPayouts := make(map[string]common.PayoutRecipe)
+	keys := make([]string, 0)
+	for _, payout := range ctx.StageData.InvalidPayouts {
+		key := payout.GetCarryOverKey()
+		if !isCarryOverCandidate(payout) || paidDelegators[fmt.Sprintf("%s#%d", key, payout.Cycle)] {
+			invalidPayouts = append(invalidPayouts, payout)
+			continue
+		}
+		ledger.Accrue(common.CarryOver{
+			Delegator:      payout.Delegator,
+			Recipient:      payout.Recipient,
+			RecipientShare: payout.RecipientShare,
+			Cycle:          payout.Cycle,
+			// payout was not sent, so the transaction fees subtracted from it are owed as well
+			Amount: payout.Amount.Add64(getCollectedTxFees(payout)),
+		})
+		if latest, ok := latestPayouts[key]; ok {
+			if latest.Cycle > payout.Cycle {
+				invalidPayouts = append(invalidPayouts, payout)
+				continue
+			}
+			invalidPayouts = append(invalidPayouts, latest)
+		} else {
+			keys = append(keys, key)
+		}
+		latestPayouts[key] = payout
+	}
+
+	settledKeys := make(map[string]bool)
+	validPayouts := lo.Map(ctx.StageData.ValidPayouts, func(payout common.PayoutRecipe, _ int) common.PayoutRecipe {
+		key := payout.GetCarryOverKey()
+		if payout.Kind != enums.PAYOUT_KIND_DELEGATOR_REWARD || payout.TxKind != enums.PAYOUT_TX_KIND_MAV || settledKeys[key] {
+			return payout
+		}
+		entries := ledger.GetEntries(key)
+		if len(entries) == 0 {
+			return payout
+		}
+		settledKeys[key] = true
+		payout.Amount = payout.Amount.Add(common.SumCarryOvers(entries))
+		payout.Note = getCarryOverNote(payout.Note, payout.Cycle, entries)
+		payout.CarriedOverCycles = getCarriedOverCycles(entries)
+		return payout
+	})
+
+	for _, key := range keys {
+		payout := latestPayouts[key]
+		if settledKeys[key] {
+			invalidPayouts = append(invalidPayouts, payout)
+			continue
+		}
+		entries := ledger.GetEntries(key)
+		amount := common.SumCarryOvers(entries).Sub64(getCollectedTxFees(payout))
+		isDue := carryOverConfiguration.MaximumCycles > 0 && int64(len(entries)) >= carryOverConfiguration.MaximumCycles
+		if payout.OpLimits == nil || (!amount.IsGreater(configuration.PayoutConfiguration.MinimumAmount) && !(isDue && amount.IsGreater(mavryk.Zero))) {
+			invalidPayouts = append(invalidPayouts, payout)
+			continue
+		}
+		payout.Kind = enums.PAYOUT_KIND_DELEGATOR_REWARD
+		payout.IsValid = true
+		payout.Amount = amount
+		payout.Note = getCarryOverNote("", payout.Cycle, entries)
+		payout.CarriedOverCycles = getCarriedOverCycles(entries)
+		validPayouts = append(validPayouts, payout)
+	}
+
+	ctx.StageData.ValidPayouts = validPayouts
+	ctx.StageData.InvalidPayouts = invalidPayouts
+	ctx.StageData.CarryOverLedger = ledger
+	return ctx, nil
+}
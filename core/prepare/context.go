@@ -12,6 +12,7 @@ type StageData struct {
 	InvalidPayouts                []common.PayoutRecipe
 	AccumulatedPayouts            []common.PayoutRecipe
 	ReportsOfPastSuccesfulPayouts []common.PayoutReport
+	CarryOverLedger               *common.CarryOverLedger
 }
 
 type PayoutPrepareContext struct {
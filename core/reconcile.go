@@ -0,0 +1,186 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+// getJournalOpHashesStatus looks up operations of the batch on chain through the node the batch was injected through,
+// indexer might lag behind it. Operations older than the node lookup are looked up through the collector.
+// If none of them is included yet, it waits until the last one is either included or expired, the earlier ones expire before it.
+func getJournalOpHashesStatus(engines *common.ReconcilePayoutsEngineContext, opHashes []mavryk.OpHash) (mavryk.OpHash, common.OperationStatus, error) {
+	transactor, _ := engines.GetTransactor().(common.OperationStatusAwareTransactor)
+	getOperationStatus := common.NewOperationStatusChecker(transactor, engines.GetCollector()).GetOperationStatus
+	lookup := func() (mavryk.OpHash, common.OperationStatus, error) {
+		for _, opHash := range opHashes {
+			status, err := getOperationStatus(opHash)
+			if err != nil {
+				return mavryk.ZeroOpHash, common.OPERATION_STATUS_UNKNOWN, errors.Join(constants.ErrOperationStatusCheckFailed, err)
+			}
+			if status == common.OPERATION_STATUS_APPLIED || status == common.OPERATION_STATUS_FAILED {
+				return opHash, status, nil
+			}
+		}
+		return mavryk.ZeroOpHash, common.OPERATION_STATUS_NOT_EXISTS, nil
+	}
+
+	opHash, status, err := lookup()
+	if err != nil || status != common.OPERATION_STATUS_NOT_EXISTS || len(opHashes) == 0 {
+		return opHash, status, err
+	}
+
+	last := opHashes[len(opHashes)-1]
+	slog.Info("waiting for interrupted batch operation to be included or expire", "op_hash", last)
+	if receipt, err := engines.GetTransactor().WaitOpConfirmation(last, constants.MAX_OPERATION_TTL, 0); err == nil {
+		if receipt != nil && !receipt.IsSuccess() {
+			return last, common.OPERATION_STATUS_FAILED, nil
+		}
+		return last, common.OPERATION_STATUS_APPLIED, nil
+	}
+	return lookup()
+}
+
+// reconcileJournalBatch resolves the final result of the batch interrupted by a crash
+func reconcileJournalBatch(engines *common.ReconcilePayoutsEngineContext, batch *common.JournalBatch) (*common.BatchResult, error) {
+	switch batch.State {
+	case enums.JOURNAL_BATCH_CONFIRMED:
+		return common.NewSuccessBatchResult(batch.Payouts, batch.OpHash), nil
+	case enums.JOURNAL_BATCH_FAILED:
+		err := constants.ErrOperationFailed
+		if batch.Error != "" {
+			err = errors.New(batch.Error)
+		}
+		return common.NewFailedBatchResultWithOpHash(batch.Payouts, batch.OpHash, err), nil
+	}
+
+	// operation might have been broadcasted even if the crash came before it was recorded as injected
+	opHash, status, err := getJournalOpHashesStatus(engines, batch.OpHashes)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case common.OPERATION_STATUS_APPLIED:
+		return common.NewSuccessBatchResult(batch.Payouts, opHash).WithOpHashChain(batch.OpHashes), nil
+	case common.OPERATION_STATUS_FAILED:
+		return common.NewFailedBatchResultWithOpHash(batch.Payouts, opHash, errors.Join(constants.ErrOperationConfirmationFailed, constants.ErrOperationFailed)).WithOpHashChain(batch.OpHashes), nil
+	}
+	if len(batch.OpHashes) == 0 {
+		return common.NewFailedBatchResult(batch.Payouts, constants.ErrPayoutJournalBatchNotSigned), nil
+	}
+	return common.NewFailedBatchResultWithOpHash(batch.Payouts, batch.OpHash, errors.Join(constants.ErrOperationConfirmationFailed, constants.ErrOperationNotIncluded)).WithOpHashChain(batch.OpHashes), nil
+}
+
+// reportReconciledResults merges reports of the reconciled batches into existing reports of their cycles
+func reportReconciledResults(reporter common.ReporterEngine, reports []common.PayoutReport) error {
+	cycles := lo.Uniq(lo.Map(reports, func(report common.PayoutReport, _ int) int64 { return report.Cycle }))
+	merged := make([]common.PayoutReport, 0, len(reports))
+	for _, cycle := range cycles {
+		existing, err := reporter.GetExistingReports(cycle)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Join(constants.ErrPayoutsFromFileLoadFailed, fmt.Errorf("cycle: %d", cycle), err)
+		}
+		merged = append(merged, lo.Filter(existing, func(report common.PayoutReport, _ int) bool {
+			return !lo.ContainsBy(reports, func(reconciled common.PayoutReport) bool {
+				return reconciled.Cycle == report.Cycle && reconciled.Id == report.Id
+			})
+		})...)
+	}
+	return reporter.ReportPayouts(append(merged, reports...))
+}
+
+// settleReconciledCarryOvers removes amounts carried over to payouts of the successful batches from the carry-over ledger.
+// The run was interrupted before it stored the ledger, so the amounts would be paid out again otherwise.
+func settleReconciledCarryOvers(reporter common.ReporterEngine, results []*common.BatchResult) error {
+	ledgerReporter, ok := reporter.(common.CarryOverLedgerAwareReporter)
+	if !ok {
+		return nil
+	}
+	ledger, err := ledgerReporter.GetCarryOverLedger()
+	if err != nil {
+		return errors.Join(constants.ErrCarryOverLedgerLoadFailed, err)
+	}
+	for _, result := range results {
+		if !result.IsSuccess {
+			continue
+		}
+		for _, payout := range result.Payouts {
+			ledger.Settle(payout.GetCarryOverKey(), payout.CarriedOverCycles)
+		}
+	}
+	return ledgerReporter.ReportCarryOverLedger(ledger)
+}
+
+// ReconcilePayoutJournal resolves batches of runs interrupted by a crash against the chain and writes their results to reports.
+// It has to run before new payouts of the cycles are prepared, otherwise payouts of interrupted batches could be paid again.
+// Only runs paying any of the cycles are reconciled, cycles have to be locked, so none of the runs is still executing.
+func ReconcilePayoutJournal(engines *common.ReconcilePayoutsEngineContext, cycles []int64) error {
+	if err := engines.Validate(); err != nil {
+		return err
+	}
+	entries, err := engines.GetJournal().Load()
+	if err != nil {
+		return err
+	}
+
+	for _, run := range common.GetUnfinishedJournalRuns(entries) {
+		if len(lo.Intersect(run.GetCycles(), cycles)) == 0 {
+			continue
+		}
+		logger := slog.With("run", run.Id)
+		logger.Warn("found interrupted payout run, reconciling its batches with chain", "batches", len(run.Batches))
+
+		reports := make([]common.PayoutReport, 0)
+		results := make([]*common.BatchResult, 0, len(run.Batches))
+		for _, batch := range run.Batches {
+			result, err := reconcileJournalBatch(engines, batch)
+			if err != nil {
+				return errors.Join(constants.ErrPayoutJournalReconcileFailed, fmt.Errorf("run: %s, batch: %s", run.Id, batch.Id), err)
+			}
+			results = append(results, result)
+			if !batch.IsFinished() {
+				state := enums.JOURNAL_BATCH_CONFIRMED
+				if !result.IsSuccess {
+					state = enums.JOURNAL_BATCH_FAILED
+				}
+				logger.Info("interrupted batch reconciled", "batch_id", batch.Id, "state", state, "op_hash", result.OpHash)
+				entry := common.NewJournalEntry(run.Id, batch.Id, state).WithOpHash(result.OpHash).WithError(result.Err)
+				if err := engines.GetJournal().Record(entry); err != nil {
+					return errors.Join(constants.ErrPayoutJournalReconcileFailed, err)
+				}
+			}
+
+			reports = append(reports, result.ToReports()...)
+			for _, payout := range batch.Accumulated {
+				report := payout.ToPayoutReport()
+				report.OpHash = result.OpHash
+				report.IsSuccess = result.IsSuccess
+				reports = append(reports, report)
+			}
+		}
+
+		if err := reportReconciledResults(engines.GetReporter(), reports); err != nil {
+			return errors.Join(constants.ErrPayoutJournalReconcileFailed, err)
+		}
+		if err := settleReconciledCarryOvers(engines.GetReporter(), results); err != nil {
+			return errors.Join(constants.ErrPayoutJournalReconcileFailed, err)
+		}
+		if err := engines.GetJournal().Record(common.NewJournalEntry(run.Id, "", enums.JOURNAL_RUN_REPORTED)); err != nil {
+			return errors.Join(constants.ErrPayoutJournalReconcileFailed, err)
+		}
+		logger.Info("interrupted payout run reconciled")
+	}
+
+	// journal is read whole on every run, entries of reported runs are not needed anymore
+	if err := engines.GetJournal().Compact(); err != nil {
+		slog.Warn("failed to compact payout journal", "error", err.Error())
+	}
+	return nil
+}
@@ -44,6 +44,14 @@ func genrateSample() *mavpay_configuration.ConfigurationV0 {
 	maximumBalance := float64(1000.0)
 	minimumDelayBlocks := int64(10)
 	maximumDelayBlocks := int64(250)
+	maximumReplacements := 2
+	parallelBatches := 1
+	stakedFee := 0.05
+	feeScheduleFirstBracket := float64(1000)
+	feeScheduleSecondBracket := float64(100000)
+	promotionFromCycle := int64(100)
+	promotionToCycle := int64(110)
+	ruleMaximumBalance := float64(100000)
 
 	return &mavpay_configuration.ConfigurationV0{
 		Version:  0,
@@ -62,11 +70,29 @@ func genrateSample() *mavpay_configuration.ConfigurationV0 {
 				"mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb": {
 					MaximumBalance: &maximumBalance,
 				},
+				"mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g": {
+					Recipients: map[string]float64{
+						"mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3": 0.7,
+						"mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb": 0.3,
+					},
+				},
 			},
 			FeeOverrides: map[string][]mavryk.Address{
 				"1":  {mavryk.ZeroAddress, mavryk.BurnAddress},
 				".5": {mavryk.InvalidAddress},
 			},
+			Rules: []mavpay_configuration.EligibilityRuleV0{
+				{
+					If:     "address_type == 'contract' && balance < 10",
+					Action: enums.ELIGIBILITY_RULE_ACTION_EXCLUDE,
+					Reason: "SMALL_CONTRACT",
+				},
+				{
+					If:             "delegation_age < 3 && balance > 100000",
+					Action:         enums.ELIGIBILITY_RULE_ACTION_CAP,
+					MaximumBalance: &ruleMaximumBalance,
+				},
+			},
 			Ignore:    []mavryk.Address{mavryk.ZeroAddress, mavryk.BurnAddress},
 			Prefilter: []mavryk.Address{mavryk.MustParseAddress("mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3"), mavryk.MustParseAddress("mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb")},
 		},
@@ -85,6 +111,7 @@ func genrateSample() *mavpay_configuration.ConfigurationV0 {
 			PayoutMode:                 enums.PAYOUT_MODE_IDEAL,
 			BalanceCheckMode:           enums.PROTOCOL_BALANCE_CHECK_MODE,
 			Fee:                        .075,
+			StakedFee:                  &stakedFee,
 			IsPayingTxFee:              true,
 			IsPayingAllocationTxFee:    true,
 			MinimumAmount:              10.5,
@@ -94,6 +121,50 @@ func genrateSample() *mavpay_configuration.ConfigurationV0 {
 			KtTxFeeBuffer:              &ktFeeBuffer,
 			MinimumDelayBlocks:         &minimumDelayBlocks,
 			MaximumDelayBlocks:         &maximumDelayBlocks,
+			SigningPolicy: &mavpay_configuration.SigningPolicyV0{
+				MaximumOperationAmount: 5000,
+				MaximumCycleAmount:     20000,
+				TokenLimits: []mavpay_configuration.SigningPolicyTokenLimitV0{
+					{
+						Contract:           mavryk.MustParseAddress("KT1Hkg6qgV3VykjgUXKbWcU3h6oJ1qVxUxZV"),
+						TokenId:            1,
+						MaximumCycleAmount: "10000000",
+					},
+				},
+			},
+			FeeBumping: &mavpay_configuration.FeeBumpingV0{
+				MaximumReplacements: &maximumReplacements,
+				FeeBump:             .5,
+			},
+			ParallelBatches: &parallelBatches,
+			FeeSchedule: &mavpay_configuration.FeeScheduleV0{
+				Balance: enums.FEE_SCHEDULE_BALANCE_DELEGATED,
+				Mode:    enums.FEE_SCHEDULE_MODE_FLAT,
+				Brackets: []mavpay_configuration.FeeScheduleBracketV0{
+					{UpTo: &feeScheduleFirstBracket, Fee: .08},
+					{UpTo: &feeScheduleSecondBracket, Fee: .06},
+					{Fee: .04},
+				},
+			},
+			FeeRules: []mavpay_configuration.FeeRuleV0{
+				{
+					Fee:        0,
+					FromCycle:  &promotionFromCycle,
+					ToCycle:    &promotionToCycle,
+					Delegators: []mavryk.Address{mavryk.MustParseAddress("mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3")},
+				},
+				{
+					Fee:      .06,
+					FromDate: "2025-01-01",
+				},
+			},
+			LoyaltyRebates: []mavpay_configuration.LoyaltyRebateV0{
+				{AfterCycles: 10, Rebate: .01},
+				{AfterCycles: 50, Rebate: .02},
+			},
+			CarryOver: &mavpay_configuration.CarryOverV0{
+				MaximumCycles: 10,
+			},
 		},
 		NotificationConfigurations: []json.RawMessage{
 			json.RawMessage(`{
@@ -184,6 +255,17 @@ func genrateSample() *mavpay_configuration.ConfigurationV0 {
 				Configuration: &feeExtensionConfiguration,
 			},
 		},
+		TokenBonuses: []mavpay_configuration.TokenBonusV0{
+			{
+				Kind:           enums.PAYOUT_TX_KIND_FA2,
+				Contract:       mavryk.MustParseAddress("KT1Hkg6qgV3VykjgUXKbWcU3h6oJ1qVxUxZV"),
+				TokenId:        1,
+				Amount:         "1000000",
+				CycleAmounts:   map[int64]string{750: "5000000"},
+				MinimumBalance: 100,
+				Ignore:         []mavryk.Address{mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g")},
+			},
+		},
 		DisableAnalytics: true,
 	}
 }
@@ -88,6 +88,10 @@ func GenerateHookSampleData() {
 			DonatedBonds:       mavryk.NewZ(1000000000),
 			DonatedFees:        mavryk.NewZ(1000000000),
 			DonatedTotal:       mavryk.NewZ(1000000000),
+			Stakers:            1,
+			StakingRewardsEdge: mavryk.NewZ(1000000000),
+			StakingFees:        mavryk.NewZ(1000000000),
+			StakingIncome:      mavryk.NewZ(2000000000),
 			Timestamp:          t,
 		},
 	}
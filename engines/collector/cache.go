@@ -0,0 +1,175 @@
+package collector_engines
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+const (
+	// versioned, so data cached before staking rewards shared with stakers were collected is not used
+	CYCLE_DATA_CACHE_DIRECTORY      = "cycle-data-v2"
+	CYCLES_IN_RANGE_CACHE_DIRECTORY = "cycles-in-range"
+)
+
+// CachedColletor persists immutable data of completed cycles on disk
+// and serves them without asking the underlying collector again.
+type CachedColletor struct {
+	common.CollectorEngine
+
+	directory        string
+	balanceCheckMode enums.EBalanceCheckMode
+}
+
+func NewCachedColletor(engine common.CollectorEngine, directory string, balanceCheckMode enums.EBalanceCheckMode) *CachedColletor {
+	return &CachedColletor{
+		CollectorEngine:  engine,
+		directory:        directory,
+		balanceCheckMode: balanceCheckMode,
+	}
+}
+
+func (engine *CachedColletor) GetId() string {
+	return fmt.Sprintf("Cached%s", engine.CollectorEngine.GetId())
+}
+
+func (engine *CachedColletor) getCycleDataPath(baker mavryk.Address, cycle int64) string {
+	return path.Join(engine.directory, CYCLE_DATA_CACHE_DIRECTORY, engine.CollectorEngine.GetId(), string(engine.balanceCheckMode), baker.String(), fmt.Sprintf("%d.json", cycle))
+}
+
+func (engine *CachedColletor) getCyclesInRangePath(startDate time.Time, endDate time.Time) string {
+	return path.Join(engine.directory, CYCLES_IN_RANGE_CACHE_DIRECTORY, engine.CollectorEngine.GetId(), fmt.Sprintf("%d-%d.json", startDate.Unix(), endDate.Unix()))
+}
+
+func readCacheEntry[T any](entryPath string) (*T, bool) {
+	data, err := os.ReadFile(entryPath)
+	if err != nil {
+		return nil, false
+	}
+	var result T
+	if err := json.Unmarshal(data, &result); err != nil {
+		slog.Warn("invalid cache entry, ignoring", "path", entryPath, "error", err.Error())
+		return nil, false
+	}
+	return &result, true
+}
+
+func writeCacheEntry[T any](entryPath string, value T) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		slog.Warn("failed to marshal cache entry", "path", entryPath, "error", err.Error())
+		return
+	}
+	if err := os.MkdirAll(path.Dir(entryPath), 0700); err != nil {
+		slog.Warn("failed to create cache directory", "path", entryPath, "error", err.Error())
+		return
+	}
+	// write to temporary file first so interrupted writes never leave corrupted entries behind
+	tmpPath := entryPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		slog.Warn("failed to write cache entry", "path", entryPath, "error", err.Error())
+		return
+	}
+	if err := os.Rename(tmpPath, entryPath); err != nil {
+		slog.Warn("failed to write cache entry", "path", entryPath, "error", err.Error())
+	}
+}
+
+func (engine *CachedColletor) GetCycleStakingData(baker mavryk.Address, cycle int64) (*common.BakersCycleData, error) {
+	entryPath := engine.getCycleDataPath(baker, cycle)
+	if cached, ok := readCacheEntry[common.BakersCycleData](entryPath); ok {
+		slog.Debug("using cached cycle data", "baker", baker.String(), "cycle", cycle)
+		return cached, nil
+	}
+
+	data, err := engine.CollectorEngine.GetCycleStakingData(baker, cycle)
+	if err != nil {
+		return nil, err
+	}
+
+	lastCompletedCycle, err := engine.CollectorEngine.GetLastCompletedCycle()
+	if err != nil {
+		slog.Debug("failed to get last completed cycle, not caching cycle data", "error", err.Error())
+		return data, nil
+	}
+	if cycle <= lastCompletedCycle {
+		writeCacheEntry(entryPath, data)
+	}
+	return data, nil
+}
+
+// GetCycleDelegators serves delegators of cached cycle data, delegators of cycles not cached are not cached on their own
+func (engine *CachedColletor) GetCycleDelegators(baker mavryk.Address, cycle int64) ([]mavryk.Address, error) {
+	if cached, ok := readCacheEntry[common.BakersCycleData](engine.getCycleDataPath(baker, cycle)); ok {
+		slog.Debug("using cached cycle data delegators", "baker", baker.String(), "cycle", cycle)
+		return lo.Map(cached.Delegators, func(delegator common.Delegator, _ int) mavryk.Address {
+			return delegator.Address
+		}), nil
+	}
+	return engine.CollectorEngine.GetCycleDelegators(baker, cycle)
+}
+
+func (engine *CachedColletor) GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error) {
+	entryPath := engine.getCyclesInRangePath(startDate, endDate)
+	if cached, ok := readCacheEntry[[]int64](entryPath); ok {
+		slog.Debug("using cached cycles in date range", "start", startDate, "end", endDate)
+		return *cached, nil
+	}
+
+	cycles, err := engine.CollectorEngine.GetCyclesInDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	lastCompletedCycle, err := engine.CollectorEngine.GetLastCompletedCycle()
+	if err != nil {
+		slog.Debug("failed to get last completed cycle, not caching cycles in date range", "error", err.Error())
+		return cycles, nil
+	}
+	if len(cycles) > 0 && cycles[len(cycles)-1] <= lastCompletedCycle {
+		writeCacheEntry(entryPath, cycles)
+	}
+	return cycles, nil
+}
+
+// ClearCache removes cached entries from the cache directory and returns number of removed entries.
+// If cycle is set only cycle data of the given cycle are removed, otherwise all entries are removed.
+func ClearCache(directory string, cycle *int64) (int, error) {
+	root := directory
+	if cycle != nil {
+		root = path.Join(directory, CYCLE_DATA_CACHE_DIRECTORY)
+	}
+
+	removed := 0
+	err := filepath.WalkDir(root, func(entryPath string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || (cycle != nil && d.Name() != fmt.Sprintf("%d.json", *cycle)) {
+			return nil
+		}
+		if err := os.Remove(entryPath); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, errors.Join(constants.ErrCacheClearFailed, err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,107 @@
+package collector_engines
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mavpay/test/mock"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingColletor counts requests reaching the underlying collector
+type countingColletor struct {
+	*mock.SimpleColletor
+	cycleDataRequests     map[int64]int
+	cyclesInRangeRequests int
+}
+
+func (engine *countingColletor) GetCycleStakingData(baker mavryk.Address, cycle int64) (*common.BakersCycleData, error) {
+	engine.cycleDataRequests[cycle]++
+	return engine.SimpleColletor.GetCycleStakingData(baker, cycle)
+}
+
+func (engine *countingColletor) GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error) {
+	engine.cyclesInRangeRequests++
+	return engine.SimpleColletor.GetCyclesInDateRange(startDate, endDate)
+}
+
+func TestCachedCollectorGetCycleStakingData(t *testing.T) {
+	assert := assert.New(t)
+
+	directory := t.TempDir()
+	underlying := &countingColletor{SimpleColletor: mock.InitSimpleColletor(), cycleDataRequests: map[int64]int{}}
+	collector := NewCachedColletor(underlying, directory, enums.PROTOCOL_BALANCE_CHECK_MODE)
+	baker := mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g")
+
+	// completed cycle is fetched once and served from cache afterwards
+	for i := 0; i < 2; i++ {
+		data, err := collector.GetCycleStakingData(baker, 500)
+		assert.Nil(err)
+		assert.Equal(int32(2), data.DelegatorsCount)
+	}
+	assert.Equal(1, underlying.cycleDataRequests[500])
+	_, err := os.Stat(path.Join(directory, CYCLE_DATA_CACHE_DIRECTORY, underlying.GetId(), string(enums.PROTOCOL_BALANCE_CHECK_MODE), baker.String(), "500.json"))
+	assert.Nil(err)
+
+	// current cycle is never cached
+	for i := 0; i < 2; i++ {
+		_, err := collector.GetCycleStakingData(baker, 501)
+		assert.Nil(err)
+	}
+	assert.Equal(2, underlying.cycleDataRequests[501])
+	_, err = os.Stat(path.Join(directory, CYCLE_DATA_CACHE_DIRECTORY, underlying.GetId(), string(enums.PROTOCOL_BALANCE_CHECK_MODE), baker.String(), "501.json"))
+	assert.True(os.IsNotExist(err))
+
+	// range ending with the current cycle is not cached either
+	start, end := time.Unix(1_700_000_000, 0), time.Unix(1_700_100_000, 0)
+	for i := 0; i < 2; i++ {
+		cycles, err := collector.GetCyclesInDateRange(start, end)
+		assert.Nil(err)
+		assert.Equal([]int64{500, 501}, cycles)
+	}
+	assert.Equal(2, underlying.cyclesInRangeRequests)
+}
+
+func TestClearCache(t *testing.T) {
+	assert := assert.New(t)
+
+	directory := t.TempDir()
+	underlying := &countingColletor{SimpleColletor: mock.InitSimpleColletor(), cycleDataRequests: map[int64]int{}}
+	collector := NewCachedColletor(underlying, directory, enums.PROTOCOL_BALANCE_CHECK_MODE)
+	baker := mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g")
+	for _, cycle := range []int64{0, 10, 500} {
+		_, err := collector.GetCycleStakingData(baker, cycle)
+		assert.Nil(err)
+	}
+	writeCacheEntry(collector.getCyclesInRangePath(time.Unix(0, 0), time.Unix(100, 0)), []int64{0})
+
+	// cycle 0 can be cleared on its own
+	cycle := int64(0)
+	removed, err := ClearCache(directory, &cycle)
+	assert.Nil(err)
+	assert.Equal(1, removed)
+	_, err = collector.GetCycleStakingData(baker, 0)
+	assert.Nil(err)
+	_, err = collector.GetCycleStakingData(baker, 10)
+	assert.Nil(err)
+	assert.Equal(2, underlying.cycleDataRequests[0])
+	assert.Equal(1, underlying.cycleDataRequests[10])
+
+	// without cycle everything is removed
+	removed, err = ClearCache(directory, nil)
+	assert.Nil(err)
+	assert.Equal(4, removed)
+	entries, err := os.ReadDir(path.Join(directory, CYCLES_IN_RANGE_CACHE_DIRECTORY, underlying.GetId()))
+	assert.Nil(err)
+	assert.Empty(entries)
+
+	// clearing empty cache is not an error
+	removed, err = ClearCache(t.TempDir(), &cycle)
+	assert.Nil(err)
+	assert.Equal(0, removed)
+}
@@ -3,12 +3,12 @@ package collector_engines
 import (
 	"context"
 	"log/slog"
-	"net/http"
 	"time"
 
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/configuration"
 	"github.com/mavryk-network/mavpay/engines/mvkt"
+	"github.com/mavryk-network/mavpay/transport"
 	"github.com/mavryk-network/mavpay/utils"
 	"github.com/mavryk-network/mvgo/codec"
 	"github.com/mavryk-network/mvgo/mavryk"
@@ -25,17 +25,22 @@ var (
 )
 
 func InitDefaultRpcAndMvktColletor(config *configuration.RuntimeConfiguration) (*DefaultRpcAndMvktColletor, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	pools, err := config.Network.GetEndpointPools()
+	if err != nil {
+		return nil, err
 	}
+	client := transport.NewHttpClient(10*time.Second, pools...)
+
 	rpcClient, err := rpc.NewClient(config.Network.RpcUrl, client)
 	if err != nil {
 		return nil, err
 	}
 
 	mvktClient, err := mvkt.InitClient(config.Network.MvktUrl, config.Network.ProtocolRewardsUrl, &mvkt.MvktClientOptions{
-		HttpClient:       client,
-		BalanceCheckMode: config.PayoutConfiguration.BalanceCheckMode,
+		HttpClient:                client,
+		BalanceCheckMode:          config.PayoutConfiguration.BalanceCheckMode,
+		DelegatorFetchConcurrency: config.Network.MvktConcurrency,
+		RequestsPerSecond:         config.Network.MvktRequestsPerSecond,
 	})
 	if err != nil {
 		return nil, err
@@ -92,10 +97,18 @@ func (engine *DefaultRpcAndMvktColletor) GetCycleStakingData(baker mavryk.Addres
 	return engine.mvkt.GetCycleData(context.Background(), baker, cycle)
 }
 
+func (engine *DefaultRpcAndMvktColletor) GetCycleDelegators(baker mavryk.Address, cycle int64) ([]mavryk.Address, error) {
+	return engine.mvkt.GetCycleDelegators(context.Background(), baker, cycle)
+}
+
 func (engine *DefaultRpcAndMvktColletor) GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error) {
 	return engine.mvkt.GetCyclesInDateRange(context.Background(), startDate, endDate)
 }
 
+func (engine *DefaultRpcAndMvktColletor) GetCycleAtDate(date time.Time) (int64, error) {
+	return engine.mvkt.GetCycleAtDate(context.Background(), date)
+}
+
 func (engine *DefaultRpcAndMvktColletor) WasOperationApplied(op mavryk.OpHash) (common.OperationStatus, error) {
 	return engine.mvkt.WasOperationApplied(context.Background(), op)
 }
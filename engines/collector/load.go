@@ -0,0 +1,22 @@
+package collector_engines
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/configuration"
+	"github.com/mavryk-network/mavpay/constants/enums"
+)
+
+func Load(config *configuration.RuntimeConfiguration) (common.CollectorEngine, error) {
+	switch config.Network.CollectorMode {
+	case "", enums.RPC_AND_MVKT_COLLECTOR_MODE:
+		slog.Debug("creating DefaultRpcAndMvktColletor")
+		return InitDefaultRpcAndMvktColletor(config)
+	case enums.RPC_COLLECTOR_MODE:
+		slog.Debug("creating RpcColletor")
+		return InitRpcColletor(config)
+	}
+	return nil, fmt.Errorf("invalid collector mode: '%s'", config.Network.CollectorMode)
+}
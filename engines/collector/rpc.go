@@ -0,0 +1,565 @@
+package collector_engines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/configuration"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/transport"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+	"github.com/samber/lo"
+)
+
+const (
+	RPC_COLLECTOR_CONCURRENCY        = 16
+	RPC_OPERATION_LOOKUP_DEPTH       = int64(120)
+	RPC_DELEGATION_SHARE_PRECISION   = int64(10000)
+	RPC_BLOCK_REWARDS_CATEGORY       = "baking rewards"
+	RPC_BLOCK_BONUSES_CATEGORY       = "baking bonuses"
+	RPC_ATTESTING_REWARDS_CATEGORY   = "attesting rewards"
+	RPC_ENDORSING_REWARDS_CATEGORY   = "endorsing rewards"
+	RPC_LOST_ATTESTING_CATEGORY      = "lost attesting rewards"
+	RPC_LOST_ENDORSING_CATEGORY      = "lost endorsing rewards"
+	RPC_BLOCK_FEES_CATEGORY          = "block fees"
+	RPC_BALANCE_UPDATE_KIND_CONTRACT = "contract"
+	RPC_BALANCE_UPDATE_KIND_FREEZER  = "freezer"
+	RPC_BALANCE_UPDATE_KIND_BURNED   = "burned"
+)
+
+type rpcConstants struct {
+	BlocksPerCycle       int64  `json:"blocks_per_cycle"`
+	ConsensusRightsDelay *int64 `json:"consensus_rights_delay,omitempty"`
+	PreservedCycles      *int64 `json:"preserved_cycles,omitempty"`
+}
+
+type rpcLevelsInCycle struct {
+	First int64 `json:"first"`
+	Last  int64 `json:"last"`
+}
+
+type rpcCurrentLevel struct {
+	Level int64 `json:"level"`
+	Cycle int64 `json:"cycle"`
+}
+
+type rpcBlockHeader struct {
+	Level     int64     `json:"level"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type rpcStaker struct {
+	Contract      string `json:"contract,omitempty"`
+	Delegate      string `json:"delegate,omitempty"`
+	BakerOwnStake string `json:"baker_own_stake,omitempty"`
+	BakerEdge     string `json:"baker_edge,omitempty"`
+}
+
+type rpcBalanceUpdate struct {
+	Kind     string     `json:"kind"`
+	Category string     `json:"category,omitempty"`
+	Contract string     `json:"contract,omitempty"`
+	Delegate string     `json:"delegate,omitempty"`
+	Staker   *rpcStaker `json:"staker,omitempty"`
+	Change   int64      `json:"change,string"`
+}
+
+type rpcBlockMetadata struct {
+	BalanceUpdates []rpcBalanceUpdate `json:"balance_updates"`
+}
+
+type rpcOperationResult struct {
+	Status string `json:"status"`
+}
+
+type rpcOperation struct {
+	Hash     string `json:"hash"`
+	Contents []struct {
+		Metadata struct {
+			OperationResult *rpcOperationResult `json:"operation_result,omitempty"`
+		} `json:"metadata"`
+	} `json:"contents"`
+}
+
+// rewards the baker received within a cycle, split by where the protocol credited them
+type rpcCycleRewards struct {
+	BlockLiquid            int64
+	BlockStakedOwn         int64
+	BlockStakedEdge        int64
+	BlockStakedShared      int64
+	EndorsementLiquid      int64
+	EndorsementStakedOwn   int64
+	EndorsementStakedEdge  int64
+	EndorsementStakedShare int64
+	MissedEndorsement      int64
+	Fees                   int64
+}
+
+func (r *rpcCycleRewards) add(other rpcCycleRewards) {
+	r.BlockLiquid += other.BlockLiquid
+	r.BlockStakedOwn += other.BlockStakedOwn
+	r.BlockStakedEdge += other.BlockStakedEdge
+	r.BlockStakedShared += other.BlockStakedShared
+	r.EndorsementLiquid += other.EndorsementLiquid
+	r.EndorsementStakedOwn += other.EndorsementStakedOwn
+	r.EndorsementStakedEdge += other.EndorsementStakedEdge
+	r.EndorsementStakedShare += other.EndorsementStakedShare
+	r.MissedEndorsement += other.MissedEndorsement
+	r.Fees += other.Fees
+}
+
+func (r *rpcCycleRewards) total() int64 {
+	return r.BlockLiquid + r.BlockStakedOwn + r.BlockStakedEdge + r.BlockStakedShared +
+		r.EndorsementLiquid + r.EndorsementStakedOwn + r.EndorsementStakedEdge + r.EndorsementStakedShare
+}
+
+// RpcColletor collects all data straight from the node RPC, no indexer is involved.
+// Historical cycles require an archive node.
+type RpcColletor struct {
+	*DefaultRpcAndMvktColletor
+}
+
+func InitRpcColletor(config *configuration.RuntimeConfiguration) (*RpcColletor, error) {
+	pools, err := config.Network.GetEndpointPools()
+	if err != nil {
+		return nil, err
+	}
+	client := transport.NewHttpClient(10*time.Second, pools...)
+
+	rpcClient, err := rpc.NewClient(config.Network.RpcUrl, client)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RpcColletor{
+		DefaultRpcAndMvktColletor: &DefaultRpcAndMvktColletor{
+			rpc: rpcClient,
+		},
+	}
+
+	return result, result.RefreshParams()
+}
+
+func (engine *RpcColletor) GetId() string {
+	return "RpcColletor"
+}
+
+func (engine *RpcColletor) getConstants(ctx context.Context) (*rpcConstants, error) {
+	var result rpcConstants
+	if err := engine.rpc.Get(ctx, "chains/main/blocks/head/context/constants", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (engine *RpcColletor) getCurrentCycle(ctx context.Context) (int64, error) {
+	var level rpcCurrentLevel
+	if err := engine.rpc.Get(ctx, "chains/main/blocks/head/helpers/current_level", &level); err != nil {
+		return 0, err
+	}
+	return level.Cycle, nil
+}
+
+func (engine *RpcColletor) getCycleLevels(ctx context.Context, cycle int64) (*rpcLevelsInCycle, error) {
+	currentCycle, err := engine.getCurrentCycle(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cycle > currentCycle {
+		return nil, errors.Join(constants.ErrNoCycleDataAvailable, fmt.Errorf("cycle %d is in the future", cycle))
+	}
+
+	var result rpcLevelsInCycle
+	path := fmt.Sprintf("chains/main/blocks/head/helpers/levels_in_current_cycle?offset=%d", cycle-currentCycle)
+	if err := engine.rpc.Get(ctx, path, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (engine *RpcColletor) getZ(ctx context.Context, path string) (mavryk.Z, error) {
+	var result mavryk.Z
+	if err := engine.rpc.Get(ctx, path, &result); err != nil {
+		return mavryk.Zero, err
+	}
+	return result, nil
+}
+
+// returns (delegated, staked) balance of the contract at level
+func (engine *RpcColletor) getContractBalances(ctx context.Context, addr string, level int64) (mavryk.Z, mavryk.Z, error) {
+	fullBalance, err := engine.getZ(ctx, fmt.Sprintf("chains/main/blocks/%d/context/contracts/%s/full_balance", level, addr))
+	if err != nil {
+		return mavryk.Zero, mavryk.Zero, err
+	}
+	stakedBalance, err := engine.getZ(ctx, fmt.Sprintf("chains/main/blocks/%d/context/contracts/%s/staked_balance", level, addr))
+	if err != nil {
+		return mavryk.Zero, mavryk.Zero, err
+	}
+	return fullBalance.Sub(stakedBalance), stakedBalance, nil
+}
+
+// returns contracts delegated to the baker at level, the baker itself excluded
+func (engine *RpcColletor) getDelegatedContracts(ctx context.Context, baker mavryk.Address, level int64) ([]string, error) {
+	var contracts []string
+	path := fmt.Sprintf("chains/main/blocks/%d/context/delegates/%s/delegated_contracts", level, baker.String())
+	if err := engine.rpc.Get(ctx, path, &contracts); err != nil {
+		return nil, err
+	}
+	return lo.Filter(contracts, func(contract string, _ int) bool {
+		return contract != baker.String()
+	}), nil
+}
+
+func (engine *RpcColletor) getDelegators(ctx context.Context, baker mavryk.Address, level int64) ([]common.Delegator, error) {
+	contracts, err := engine.getDelegatedContracts(ctx, baker, level)
+	if err != nil {
+		return nil, err
+	}
+
+	delegators := make([]common.Delegator, len(contracts))
+	err = forEachConcurrently(len(contracts), func(i int) error {
+		addr, err := mavryk.ParseAddress(contracts[i])
+		if err != nil {
+			return err
+		}
+		delegated, staked, err := engine.getContractBalances(ctx, contracts[i], level)
+		if err != nil {
+			return err
+		}
+		delegators[i] = common.Delegator{
+			Address:          addr,
+			DelegatedBalance: delegated,
+			StakedBalance:    staked,
+			Emptied:          delegated.Add(staked).IsZero(),
+		}
+		return nil
+	})
+	return delegators, err
+}
+
+func collectBakerRewards(baker string, updates []rpcBalanceUpdate) rpcCycleRewards {
+	result := rpcCycleRewards{}
+	source := ""
+	for _, update := range updates {
+		if update.Kind == RPC_BALANCE_UPDATE_KIND_BURNED && update.Delegate == baker && update.Change > 0 {
+			switch update.Category {
+			case RPC_LOST_ATTESTING_CATEGORY, RPC_LOST_ENDORSING_CATEGORY:
+				result.MissedEndorsement += update.Change
+			}
+			continue
+		}
+		// every credit is preceded by the debit of its source
+		if update.Change < 0 {
+			source = update.Category
+			continue
+		}
+
+		var liquid, own, edge, shared *int64
+		switch source {
+		case RPC_BLOCK_REWARDS_CATEGORY, RPC_BLOCK_BONUSES_CATEGORY:
+			liquid, own, edge, shared = &result.BlockLiquid, &result.BlockStakedOwn, &result.BlockStakedEdge, &result.BlockStakedShared
+		case RPC_ATTESTING_REWARDS_CATEGORY, RPC_ENDORSING_REWARDS_CATEGORY:
+			liquid, own, edge, shared = &result.EndorsementLiquid, &result.EndorsementStakedOwn, &result.EndorsementStakedEdge, &result.EndorsementStakedShare
+		case RPC_BLOCK_FEES_CATEGORY:
+			if update.Kind == RPC_BALANCE_UPDATE_KIND_CONTRACT && update.Contract == baker {
+				result.Fees += update.Change
+			}
+			continue
+		default:
+			continue
+		}
+
+		switch {
+		case update.Kind == RPC_BALANCE_UPDATE_KIND_CONTRACT && update.Contract == baker:
+			*liquid += update.Change
+		case update.Kind == RPC_BALANCE_UPDATE_KIND_FREEZER && update.Staker != nil:
+			staker := update.Staker
+			switch {
+			case staker.BakerOwnStake == baker, staker.Contract == baker && staker.Delegate == baker:
+				*own += update.Change
+			case staker.BakerEdge == baker:
+				*edge += update.Change
+			case staker.Contract == "" && staker.Delegate == baker:
+				*shared += update.Change
+			}
+		}
+	}
+	return result
+}
+
+func (engine *RpcColletor) getCycleRewards(ctx context.Context, baker mavryk.Address, levels *rpcLevelsInCycle) (rpcCycleRewards, error) {
+	result := rpcCycleRewards{}
+	lock := sync.Mutex{}
+	err := forEachConcurrently(int(levels.Last-levels.First+1), func(i int) error {
+		var metadata rpcBlockMetadata
+		if err := engine.rpc.Get(ctx, fmt.Sprintf("chains/main/blocks/%d/metadata", levels.First+int64(i)), &metadata); err != nil {
+			return err
+		}
+		rewards := collectBakerRewards(baker.String(), metadata.BalanceUpdates)
+		lock.Lock()
+		result.add(rewards)
+		lock.Unlock()
+		return nil
+	})
+	return result, err
+}
+
+// returns level of the stake snapshot baking rights of the cycle were computed from
+func (engine *RpcColletor) getSnapshotLevel(ctx context.Context, cycle int64) (int64, error) {
+	chainConstants, err := engine.getConstants(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rightsDelay := int64(2)
+	switch {
+	case chainConstants.ConsensusRightsDelay != nil:
+		rightsDelay = *chainConstants.ConsensusRightsDelay
+	case chainConstants.PreservedCycles != nil:
+		rightsDelay = *chainConstants.PreservedCycles
+	}
+	// baking rights of the cycle were computed from the stake at the end of this cycle
+	snapshotLevels, err := engine.getCycleLevels(ctx, cycle-rightsDelay-1)
+	if err != nil {
+		return 0, err
+	}
+	return snapshotLevels.Last, nil
+}
+
+func (engine *RpcColletor) GetCycleDelegators(baker mavryk.Address, cycle int64) ([]mavryk.Address, error) {
+	ctx := context.Background()
+	snapshotLevel, err := engine.getSnapshotLevel(ctx, cycle)
+	if err != nil {
+		return nil, errors.Join(constants.ErrCycleDataFetchFailed, err)
+	}
+	contracts, err := engine.getDelegatedContracts(ctx, baker, snapshotLevel)
+	if err != nil {
+		return nil, errors.Join(constants.ErrCycleDataFetchFailed, err)
+	}
+	result := make([]mavryk.Address, 0, len(contracts))
+	for _, contract := range contracts {
+		addr, err := mavryk.ParseAddress(contract)
+		if err != nil {
+			return nil, errors.Join(constants.ErrCycleDataFetchFailed, err)
+		}
+		result = append(result, addr)
+	}
+	return result, nil
+}
+
+func (engine *RpcColletor) GetCycleStakingData(baker mavryk.Address, cycle int64) (*common.BakersCycleData, error) {
+	ctx := context.Background()
+
+	levels, err := engine.getCycleLevels(ctx, cycle)
+	if err != nil {
+		return nil, errors.Join(constants.ErrCycleDataFetchFailed, err)
+	}
+	snapshotLevel, err := engine.getSnapshotLevel(ctx, cycle)
+	if err != nil {
+		return nil, errors.Join(constants.ErrCycleDataFetchFailed, err)
+	}
+
+	ownDelegated, ownStaked, err := engine.getContractBalances(ctx, baker.String(), snapshotLevel)
+	if err != nil {
+		return nil, errors.Join(constants.ErrCycleDataFetchFailed, err)
+	}
+	frozenDepositLimit := mavryk.Zero
+	var limit *mavryk.Z
+	if err := engine.rpc.Get(ctx, fmt.Sprintf("chains/main/blocks/%d/context/delegates/%s/frozen_deposits_limit", snapshotLevel, baker.String()), &limit); err == nil && limit != nil {
+		frozenDepositLimit = *limit
+	}
+
+	delegators, err := engine.getDelegators(ctx, baker, snapshotLevel)
+	if err != nil {
+		return nil, errors.Join(constants.ErrCycleDataFetchFailed, err)
+	}
+	slog.Debug("fetched baker data", "delegators_count", len(delegators), "snapshot_level", snapshotLevel)
+
+	rewards, err := engine.getCycleRewards(ctx, baker, levels)
+	if err != nil {
+		return nil, errors.Join(constants.ErrCycleDataFetchFailed, err)
+	}
+	if rewards.total() == 0 && rewards.Fees == 0 && rewards.MissedEndorsement == 0 {
+		return nil, errors.Join(constants.ErrNoCycleDataAvailable, fmt.Errorf("baker: %s", baker.String()))
+	}
+
+	externalDelegated, externalStaked, stakersCount := mavryk.Zero, mavryk.Zero, int32(0)
+	for _, delegator := range delegators {
+		externalDelegated = externalDelegated.Add(delegator.DelegatedBalance)
+		externalStaked = externalStaked.Add(delegator.StakedBalance)
+		if !delegator.StakedBalance.IsZero() {
+			stakersCount++
+		}
+	}
+
+	precision := RPC_DELEGATION_SHARE_PRECISION
+	var blockDelegatedRewards, endorsingDelegatedRewards, delegationShare mavryk.Z
+	if cycle >= constants.FIRST_BOREAS_AI_ACTIVATED_CYCLE {
+		blockDelegatedRewards = mavryk.NewZ(rewards.BlockLiquid)
+		endorsingDelegatedRewards = mavryk.NewZ(rewards.EndorsementLiquid)
+		// rewards are split by the protocol proportionally to baking power,
+		// so the liquid part of the rewards matches the delegated part of the baking power
+		delegationShare = mavryk.NewZ(precision)
+		if total := rewards.total(); total > 0 {
+			delegationShare = mavryk.NewZ(rewards.BlockLiquid + rewards.EndorsementLiquid).Mul64(precision).Div64(total)
+		}
+	} else {
+		blockDelegatedRewards = mavryk.NewZ(rewards.BlockLiquid + rewards.BlockStakedOwn)
+		endorsingDelegatedRewards = mavryk.NewZ(rewards.EndorsementLiquid + rewards.EndorsementStakedOwn)
+		delegationShare = mavryk.NewZ(1)
+		precision = 1
+	}
+
+	blockDelegatedFees := delegationShare.Mul64(rewards.Fees).Div64(precision)
+	blockStakingFees := mavryk.NewZ(rewards.Fees).Sub(blockDelegatedFees)
+
+	return &common.BakersCycleData{
+		DelegatorsCount:          int32(len(delegators)),
+		OwnDelegatedBalance:      ownDelegated,
+		ExternalDelegatedBalance: externalDelegated,
+		BlockDelegatedRewards:    blockDelegatedRewards,
+		// missed baking rewards are not exposed by the node
+		IdealBlockDelegatedRewards:       blockDelegatedRewards,
+		EndorsementDelegatedRewards:      endorsingDelegatedRewards,
+		IdealEndorsementDelegatedRewards: endorsingDelegatedRewards.Add(delegationShare.Mul64(rewards.MissedEndorsement).Div64(precision)),
+		BlockDelegatedFees:               blockDelegatedFees,
+
+		StakersCount:                    stakersCount,
+		OwnStakedBalance:                ownStaked,
+		ExternalStakedBalance:           externalStaked,
+		BlockStakingRewardsEdge:         mavryk.NewZ(rewards.BlockStakedEdge),
+		EndorsementStakingRewardsEdge:   mavryk.NewZ(rewards.EndorsementStakedEdge),
+		BlockStakingRewardsShared:       mavryk.NewZ(rewards.BlockStakedShared),
+		EndorsementStakingRewardsShared: mavryk.NewZ(rewards.EndorsementStakedShare),
+		BlockStakingFees:                blockStakingFees,
+
+		FrozenDepositLimit: frozenDepositLimit,
+		Delegators:         delegators,
+	}, nil
+}
+
+func (engine *RpcColletor) getBlockHeader(ctx context.Context, level int64) (*rpcBlockHeader, error) {
+	var header rpcBlockHeader
+	if err := engine.rpc.Get(ctx, fmt.Sprintf("chains/main/blocks/%d/header", level), &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// binary searches for the cycle of the first block with timestamp not before the given one
+func (engine *RpcColletor) getFirstBlockCycleAfterTimestamp(ctx context.Context, timestamp time.Time) (int64, error) {
+	genesis, err := engine.getBlockHeader(ctx, 0)
+	if err != nil {
+		return 0, err
+	}
+	var latest rpcBlockHeader
+	if err := engine.rpc.Get(ctx, "chains/main/blocks/head/header", &latest); err != nil {
+		return 0, err
+	}
+	low, high := genesis.Level+1, latest.Level
+	if latest.Timestamp.Before(timestamp) {
+		return 0, errors.Join(constants.ErrCycleDataFetchFailed, constants.ErrNoBlockAfterDate, fmt.Errorf("timestamp: %s", timestamp))
+	}
+	for low < high {
+		mid := low + (high-low)/2
+		header, err := engine.getBlockHeader(ctx, mid)
+		if err != nil {
+			return 0, err
+		}
+		if header.Timestamp.Before(timestamp) {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+
+	var level rpcCurrentLevel
+	if err := engine.rpc.Get(ctx, fmt.Sprintf("chains/main/blocks/%d/helpers/current_level", low), &level); err != nil {
+		return 0, err
+	}
+	return level.Cycle, nil
+}
+
+func (engine *RpcColletor) GetCycleAtDate(date time.Time) (int64, error) {
+	return engine.getFirstBlockCycleAfterTimestamp(context.Background(), date)
+}
+
+func (engine *RpcColletor) GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error) {
+	ctx := context.Background()
+	firstCycle, err := engine.getFirstBlockCycleAfterTimestamp(ctx, startDate)
+	if err != nil {
+		return nil, err
+	}
+	firstCycleAfterTheRange, err := engine.getFirstBlockCycleAfterTimestamp(ctx, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	cycles := make([]int64, 0, 20)
+	for cycle := firstCycle; cycle < firstCycleAfterTheRange; cycle++ {
+		cycles = append(cycles, cycle)
+	}
+	return cycles, nil
+}
+
+// Without an indexer only the last RPC_OPERATION_LOOKUP_DEPTH blocks can be searched,
+// older operations are reported with unknown status.
+func (engine *RpcColletor) WasOperationApplied(opHash mavryk.OpHash) (common.OperationStatus, error) {
+	ctx := context.Background()
+	for offset := int64(0); offset < RPC_OPERATION_LOOKUP_DEPTH; offset++ {
+		var operations []rpcOperation
+		// manager operations are in the 4th validation pass
+		path := fmt.Sprintf("chains/main/blocks/head~%d/operations/3", offset)
+		if err := engine.rpc.Get(ctx, path, &operations); err != nil {
+			return common.OPERATION_STATUS_UNKNOWN, errors.Join(constants.ErrOperationStatusCheckFailed, err)
+		}
+		for _, op := range operations {
+			if op.Hash != opHash.String() {
+				continue
+			}
+			for _, content := range op.Contents {
+				if result := content.Metadata.OperationResult; result != nil && result.Status != "applied" {
+					return common.OPERATION_STATUS_FAILED, nil
+				}
+			}
+			return common.OPERATION_STATUS_APPLIED, nil
+		}
+	}
+	return common.OPERATION_STATUS_UNKNOWN, nil
+}
+
+// runs fn for 0..n-1 with at most RPC_COLLECTOR_CONCURRENCY calls at once, stops dispatching on the first error and returns it
+func forEachConcurrently(n int, fn func(i int) error) error {
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	failed := make(chan struct{})
+	semaphore := make(chan struct{}, RPC_COLLECTOR_CONCURRENCY)
+dispatch:
+	for i := 0; i < n; i++ {
+		semaphore <- struct{}{}
+		select {
+		case <-failed:
+			break dispatch
+		default:
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if err := fn(i); err != nil {
+				once.Do(func() {
+					firstErr = err
+					close(failed)
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	return firstErr
+}
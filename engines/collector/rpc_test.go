@@ -0,0 +1,262 @@
+package collector_engines
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+const rpcTestBaker = "mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g"
+
+// fakeRpcNode serves the node RPC endpoints used by the rpc collector, balances do not change with level
+type fakeRpcNode struct {
+	mtx            sync.Mutex
+	constants      map[string]any
+	blocksPerCycle int64
+	currentCycle   int64
+	// balance updates served for the first block of the rewarded cycle
+	metadata      []byte
+	metadataLevel int64
+	delegators    []string
+	balances      map[string][2]int64 // full and staked balance by address
+	operations    map[int64][]rpcOperation
+	// levels balances and delegators were requested at
+	requestedLevels map[int64]bool
+}
+
+func (node *fakeRpcNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	node.mtx.Lock()
+	defer node.mtx.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/chains/main/blocks/")
+	switch path {
+	case "head/context/constants":
+		json.NewEncoder(w).Encode(node.constants)
+		return
+	case "head/helpers/current_level":
+		json.NewEncoder(w).Encode(rpcCurrentLevel{Level: node.currentCycle*node.blocksPerCycle + 1, Cycle: node.currentCycle})
+		return
+	case "head/helpers/levels_in_current_cycle":
+		offset, _ := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+		cycle := node.currentCycle + offset
+		json.NewEncoder(w).Encode(rpcLevelsInCycle{First: cycle * node.blocksPerCycle, Last: (cycle+1)*node.blocksPerCycle - 1})
+		return
+	}
+
+	block, rest, _ := strings.Cut(path, "/")
+	if strings.HasPrefix(block, "head~") {
+		offset, _ := strconv.ParseInt(strings.TrimPrefix(block, "head~"), 10, 64)
+		operations, ok := node.operations[offset]
+		if !ok {
+			operations = []rpcOperation{}
+		}
+		json.NewEncoder(w).Encode(operations)
+		return
+	}
+	level, err := strconv.ParseInt(block, 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	switch {
+	case rest == "metadata":
+		if level == node.metadataLevel {
+			w.Write(node.metadata)
+			return
+		}
+		json.NewEncoder(w).Encode(rpcBlockMetadata{BalanceUpdates: []rpcBalanceUpdate{}})
+	case len(parts) == 4 && parts[1] == "delegates" && parts[3] == "delegated_contracts":
+		node.requestedLevels[level] = true
+		json.NewEncoder(w).Encode(node.delegators)
+	case len(parts) == 4 && parts[1] == "contracts" && parts[3] == "full_balance":
+		node.requestedLevels[level] = true
+		json.NewEncoder(w).Encode(fmt.Sprintf("%d", node.balances[parts[2]][0]))
+	case len(parts) == 4 && parts[1] == "contracts" && parts[3] == "staked_balance":
+		node.requestedLevels[level] = true
+		json.NewEncoder(w).Encode(fmt.Sprintf("%d", node.balances[parts[2]][1]))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeRpcNode(t *testing.T, constants map[string]any) *fakeRpcNode {
+	metadata, err := os.ReadFile("testdata/rpc_block_metadata.json")
+	assert.Nil(t, err)
+	return &fakeRpcNode{
+		constants:      constants,
+		blocksPerCycle: 4,
+		currentCycle:   802,
+		metadata:       metadata,
+		metadataLevel:  800 * 4,
+		delegators:     []string{rpcTestBaker, "mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3", "KT1Hkg6qgV3VykjgUXKbWcU3h6oJ1qVxUxZV"},
+		balances: map[string][2]int64{
+			rpcTestBaker:                           {1_000_000_000, 400_000_000},
+			"mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3": {150_000_000, 50_000_000},
+			"KT1Hkg6qgV3VykjgUXKbWcU3h6oJ1qVxUxZV": {20_000_000, 0},
+		},
+		operations:      map[int64][]rpcOperation{},
+		requestedLevels: map[int64]bool{},
+	}
+}
+
+func newTestRpcCollector(t *testing.T, node *fakeRpcNode) (*RpcColletor, func()) {
+	server := httptest.NewServer(node)
+	client, err := rpc.NewClient(server.URL, http.DefaultClient)
+	assert.Nil(t, err)
+	return &RpcColletor{
+		DefaultRpcAndMvktColletor: &DefaultRpcAndMvktColletor{
+			rpc: client,
+		},
+	}, server.Close
+}
+
+func TestCollectBakerRewards(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := os.ReadFile("testdata/rpc_block_metadata.json")
+	assert.Nil(err)
+	var metadata rpcBlockMetadata
+	assert.Nil(json.Unmarshal(data, &metadata))
+
+	rewards := collectBakerRewards(rpcTestBaker, metadata.BalanceUpdates)
+	assert.Equal(rpcCycleRewards{
+		BlockLiquid:       900,
+		BlockStakedOwn:    100,
+		BlockStakedEdge:   50,
+		BlockStakedShared: 250,
+		EndorsementLiquid: 2000,
+		MissedEndorsement: 500,
+		Fees:              70,
+	}, rewards)
+	assert.Equal(int64(3300), rewards.total())
+
+	assert.Equal(rpcCycleRewards{}, collectBakerRewards("mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb", metadata.BalanceUpdates))
+}
+
+func TestRpcCollectorGetCycleStakingData(t *testing.T) {
+	assert := assert.New(t)
+
+	node := newFakeRpcNode(t, map[string]any{"blocks_per_cycle": 4, "consensus_rights_delay": 2})
+	collector, closeServer := newTestRpcCollector(t, node)
+	defer closeServer()
+
+	data, err := collector.GetCycleStakingData(mavryk.MustParseAddress(rpcTestBaker), 800)
+	assert.Nil(err)
+	// rights of cycle 800 were computed from the stake at the end of cycle 797
+	assert.Equal(map[int64]bool{797*4 + 3: true}, node.requestedLevels)
+
+	assert.Equal(int32(2), data.DelegatorsCount)
+	assert.Equal(int32(1), data.StakersCount)
+	assert.Equal(mavryk.NewZ(600_000_000), data.OwnDelegatedBalance)
+	assert.Equal(mavryk.NewZ(400_000_000), data.OwnStakedBalance)
+	assert.Equal(mavryk.NewZ(120_000_000), data.ExternalDelegatedBalance)
+	assert.Equal(mavryk.NewZ(50_000_000), data.ExternalStakedBalance)
+	assert.Equal(mavryk.NewZ(900), data.BlockDelegatedRewards)
+	assert.Equal(mavryk.NewZ(2000), data.EndorsementDelegatedRewards)
+	// delegated share of the baking power is 2900/3300
+	assert.Equal(mavryk.NewZ(2000+439), data.IdealEndorsementDelegatedRewards)
+	assert.Equal(mavryk.NewZ(61), data.BlockDelegatedFees)
+	assert.Equal(mavryk.NewZ(9), data.BlockStakingFees)
+	assert.Equal(mavryk.NewZ(50), data.BlockStakingRewardsEdge)
+	assert.Equal(mavryk.NewZ(250), data.BlockStakingRewardsShared)
+	assert.Len(data.Delegators, 2)
+	assert.True(data.Delegators[1].Address.IsContract())
+
+	// protocols before consensus_rights_delay expose preserved_cycles
+	node = newFakeRpcNode(t, map[string]any{"blocks_per_cycle": 4, "preserved_cycles": 3})
+	collector, closeServer = newTestRpcCollector(t, node)
+	defer closeServer()
+
+	_, err = collector.GetCycleStakingData(mavryk.MustParseAddress(rpcTestBaker), 800)
+	assert.Nil(err)
+	assert.Equal(map[int64]bool{796*4 + 3: true}, node.requestedLevels)
+
+	// cycle without rewards has no data yet
+	node = newFakeRpcNode(t, map[string]any{"blocks_per_cycle": 4, "consensus_rights_delay": 2})
+	node.metadataLevel = 0
+	collector, closeServer = newTestRpcCollector(t, node)
+	defer closeServer()
+
+	_, err = collector.GetCycleStakingData(mavryk.MustParseAddress(rpcTestBaker), 800)
+	assert.NotNil(err)
+}
+
+func TestRpcCollectorWasOperationApplied(t *testing.T) {
+	assert := assert.New(t)
+
+	applied := mavryk.MustParseOpHash("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ")
+	failed := mavryk.MustParseOpHash("ooUkGSF79MoFXdKFjw514YKxP4VHqMhoQUN29s5hkqwLNDGSXDc")
+	tooOld := mavryk.MustParseOpHash("ooDfVtthBZiv3exbkbic2iQN3r7MiVA2zW954zrTjfPmy7EBc6W")
+
+	var operations map[int64][]rpcOperation
+	assert.Nil(json.Unmarshal([]byte(fmt.Sprintf(`{
+		"3": [{"hash": "%s", "contents": [{"metadata": {"operation_result": {"status": "applied"}}}]}],
+		"5": [{"hash": "%s", "contents": [
+			{"metadata": {"operation_result": {"status": "applied"}}},
+			{"metadata": {"operation_result": {"status": "backtracked"}}}
+		]}],
+		"%d": [{"hash": "%s", "contents": [{"metadata": {"operation_result": {"status": "applied"}}}]}]
+	}`, applied, failed, RPC_OPERATION_LOOKUP_DEPTH, tooOld)), &operations))
+
+	node := newFakeRpcNode(t, map[string]any{})
+	node.operations = operations
+	collector, closeServer := newTestRpcCollector(t, node)
+	defer closeServer()
+
+	status, err := collector.WasOperationApplied(applied)
+	assert.Nil(err)
+	assert.Equal(common.OPERATION_STATUS_APPLIED, status)
+
+	status, err = collector.WasOperationApplied(failed)
+	assert.Nil(err)
+	assert.Equal(common.OPERATION_STATUS_FAILED, status)
+
+	// operations older than the lookup depth can not be found without an indexer
+	status, err = collector.WasOperationApplied(tooOld)
+	assert.Nil(err)
+	assert.Equal(common.OPERATION_STATUS_UNKNOWN, status)
+}
+
+func TestForEachConcurrentlyStopsOnError(t *testing.T) {
+	assert := assert.New(t)
+
+	var mtx sync.Mutex
+	calls, running, maxRunning := 0, 0, 0
+	failure := errors.New("rpc failed")
+	err := forEachConcurrently(1000, func(i int) error {
+		mtx.Lock()
+		calls++
+		running++
+		maxRunning = max(maxRunning, running)
+		mtx.Unlock()
+		defer func() {
+			mtx.Lock()
+			running--
+			mtx.Unlock()
+		}()
+		if i == 0 {
+			return failure
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	assert.ErrorIs(err, failure)
+	assert.LessOrEqual(maxRunning, RPC_COLLECTOR_CONCURRENCY)
+	// no more calls are dispatched once one of them failed
+	assert.Less(calls, 100)
+}
@@ -0,0 +1,171 @@
+package journal_engines
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"sync"
+
+	"code.cloudfoundry.org/filelock"
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+)
+
+// FsJournal appends entries as json lines, every entry is synced to disk before Record returns.
+// The journal is shared by runs of all cycles, so access is serialized across processes with a lock file.
+type FsJournal struct {
+	path string
+	mtx  sync.Mutex
+}
+
+func NewFsJournal(path string) *FsJournal {
+	return &FsJournal{
+		path: path,
+	}
+}
+
+func (journal *FsJournal) lock() (func(), error) {
+	journal.mtx.Lock()
+	if err := os.MkdirAll(path.Dir(journal.path), 0700); err != nil {
+		journal.mtx.Unlock()
+		return nil, err
+	}
+	f, err := filelock.NewLocker(journal.path + ".lock").Open()
+	if err != nil {
+		journal.mtx.Unlock()
+		return nil, err
+	}
+	return func() {
+		f.Close()
+		journal.mtx.Unlock()
+	}, nil
+}
+
+func (journal *FsJournal) Record(entry common.JournalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Join(constants.ErrPayoutJournalWriteFailed, err)
+	}
+	unlock, err := journal.lock()
+	if err != nil {
+		return errors.Join(constants.ErrPayoutJournalWriteFailed, err)
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(journal.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return errors.Join(constants.ErrPayoutJournalWriteFailed, err)
+	}
+	defer file.Close()
+	// entry torn by a crash has to stay on its own line
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return errors.Join(constants.ErrPayoutJournalWriteFailed, err)
+	}
+	if err := file.Sync(); err != nil {
+		return errors.Join(constants.ErrPayoutJournalWriteFailed, err)
+	}
+	return nil
+}
+
+func (journal *FsJournal) load() ([]common.JournalEntry, error) {
+	entries := make([]common.JournalEntry, 0)
+	file, err := os.Open(journal.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry common.JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Warn("skipping incomplete payout journal entry", "path", journal.path, "line", line, "error", err.Error())
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Load reads all entries, entries torn by a crash while writing are skipped
+func (journal *FsJournal) Load() ([]common.JournalEntry, error) {
+	unlock, err := journal.lock()
+	if err != nil {
+		return nil, errors.Join(constants.ErrPayoutJournalLoadFailed, err)
+	}
+	defer unlock()
+
+	entries, err := journal.load()
+	if err != nil {
+		return nil, errors.Join(constants.ErrPayoutJournalLoadFailed, err)
+	}
+	return entries, nil
+}
+
+// Compact drops entries of runs whose results were written to reports. The compacted journal is written
+// to a temporary file and renamed over the journal, so a crash leaves either the old or the new journal in place.
+func (journal *FsJournal) Compact() error {
+	unlock, err := journal.lock()
+	if err != nil {
+		return errors.Join(constants.ErrPayoutJournalCompactionFailed, err)
+	}
+	defer unlock()
+
+	entries, err := journal.load()
+	if err != nil {
+		return errors.Join(constants.ErrPayoutJournalCompactionFailed, err)
+	}
+	unfinished := common.GetUnfinishedJournalEntries(entries)
+	if len(unfinished) == len(entries) {
+		return nil
+	}
+
+	tmpPath := journal.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Join(constants.ErrPayoutJournalCompactionFailed, err)
+	}
+	writer := bufio.NewWriter(file)
+	for _, entry := range unfinished {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			file.Close()
+			return errors.Join(constants.ErrPayoutJournalCompactionFailed, err)
+		}
+		writer.Write(append(data, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return errors.Join(constants.ErrPayoutJournalCompactionFailed, err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return errors.Join(constants.ErrPayoutJournalCompactionFailed, err)
+	}
+	if err := file.Close(); err != nil {
+		return errors.Join(constants.ErrPayoutJournalCompactionFailed, err)
+	}
+	if err := os.Rename(tmpPath, journal.path); err != nil {
+		return errors.Join(constants.ErrPayoutJournalCompactionFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package journal_engines
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsJournalTornEntry(t *testing.T) {
+	assert := assert.New(t)
+
+	journalPath := path.Join(t.TempDir(), "reports", "payouts.journal")
+	journal := NewFsJournal(journalPath)
+
+	entries, err := journal.Load()
+	assert.Nil(err)
+	assert.Empty(entries)
+
+	assert.Nil(journal.Record(common.NewJournalEntry("run", "1/2", enums.JOURNAL_BATCH_BUILT)))
+	// crash while writing the entry
+	file, err := os.OpenFile(journalPath, os.O_APPEND|os.O_WRONLY, 0600)
+	assert.Nil(err)
+	_, err = file.WriteString(`{"run":"run","batch":"1/2","sta`)
+	assert.Nil(err)
+	assert.Nil(file.Close())
+	assert.Nil(journal.Record(common.NewJournalEntry("run", "2/2", enums.JOURNAL_BATCH_BUILT)))
+
+	entries, err = journal.Load()
+	assert.Nil(err)
+	assert.Len(entries, 2)
+	assert.Equal("1/2", entries[0].Batch)
+	assert.Equal("2/2", entries[1].Batch)
+}
+
+func TestFsJournalCompact(t *testing.T) {
+	assert := assert.New(t)
+
+	journalPath := path.Join(t.TempDir(), "reports", "payouts.journal")
+	journal := NewFsJournal(journalPath)
+	// nothing to compact
+	assert.Nil(journal.Compact())
+
+	assert.Nil(journal.Record(common.NewJournalEntry("reported", "1/1", enums.JOURNAL_BATCH_BUILT)))
+	assert.Nil(journal.Record(common.NewJournalEntry("interrupted", "1/1", enums.JOURNAL_BATCH_BUILT)))
+	assert.Nil(journal.Record(common.NewJournalEntry("reported", "1/1", enums.JOURNAL_BATCH_CONFIRMED)))
+	assert.Nil(journal.Record(common.NewJournalEntry("reported", "", enums.JOURNAL_RUN_REPORTED)))
+	assert.Nil(journal.Record(common.NewJournalEntry("interrupted", "1/1", enums.JOURNAL_BATCH_SIGNED)))
+
+	assert.Nil(journal.Compact())
+	entries, err := journal.Load()
+	assert.Nil(err)
+	assert.Len(entries, 2)
+	for _, entry := range entries {
+		assert.Equal("interrupted", entry.Run)
+	}
+	_, err = os.Stat(journalPath + ".tmp")
+	assert.True(os.IsNotExist(err))
+
+	// journal stays appendable after compaction
+	assert.Nil(journal.Record(common.NewJournalEntry("interrupted", "", enums.JOURNAL_RUN_REPORTED)))
+	assert.Nil(journal.Compact())
+	entries, err = journal.Load()
+	assert.Nil(err)
+	assert.Empty(entries)
+}
@@ -11,11 +11,13 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/constants"
 	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mavpay/transport"
 	"github.com/mavryk-network/mavpay/utils"
 	"github.com/mavryk-network/mvgo/mavryk"
 
@@ -24,6 +26,12 @@ import (
 
 const (
 	DELEGATOR_FETCH_LIMIT = 10000
+
+	DEFAULT_DELEGATOR_FETCH_CONCURRENCY = 4
+	DEFAULT_REQUESTS_PER_SECOND         = 10
+	DEFAULT_MAX_RETRIES                 = 5
+	RETRY_BASE_DELAY                    = 500 * time.Millisecond
+	RETRY_MAX_DELAY                     = 30 * time.Second
 )
 
 type splitDelegator struct {
@@ -42,17 +50,19 @@ type mvktBakersCycleData struct {
 	OwnStakedBalance         int64 `json:"ownStakedBalance"`      // OwnDelegatedBalance + ExternalDelegatedBalance
 	ExternalStakedBalance    int64 `json:"externalStakedBalance"` // ExternalDelegatedBalance
 
-	BlockRewardsDelegated  int64 `json:"blockRewardsDelegated"`
-	BlockRewardsLiquid     int64 `json:"blockRewardsLiquid"`
-	BlockRewardsStakedOwn  int64 `json:"blockRewardsStakedOwn"`
-	BlockRewardsStakedEdge int64 `json:"blockRewardsStakedEdge"`
+	BlockRewardsDelegated    int64 `json:"blockRewardsDelegated"`
+	BlockRewardsLiquid       int64 `json:"blockRewardsLiquid"`
+	BlockRewardsStakedOwn    int64 `json:"blockRewardsStakedOwn"`
+	BlockRewardsStakedEdge   int64 `json:"blockRewardsStakedEdge"`
+	BlockRewardsStakedShared int64 `json:"blockRewardsStakedShared"`
 	// BlockRewards             int64            `json:"blockRewards"` // BlockRewardsLiquid + BlockRewardsStakedOwn
 	MissedBlockRewards int64 `json:"missedBlockRewards"`
 
-	EndorsementRewardsDelegated  int64 `json:"endorsementRewardsDelegated"`
-	EndorsementRewardsLiquid     int64 `json:"endorsementRewardsLiquid"`
-	EndorsementRewardsStakedOwn  int64 `json:"endorsementRewardsStakedOwn"`
-	EndorsementRewardsStakedEdge int64 `json:"endorsementRewardsStakedEdge"`
+	EndorsementRewardsDelegated    int64 `json:"endorsementRewardsDelegated"`
+	EndorsementRewardsLiquid       int64 `json:"endorsementRewardsLiquid"`
+	EndorsementRewardsStakedOwn    int64 `json:"endorsementRewardsStakedOwn"`
+	EndorsementRewardsStakedEdge   int64 `json:"endorsementRewardsStakedEdge"`
+	EndorsementRewardsStakedShared int64 `json:"endorsementRewardsStakedShared"`
 	// EndorsementRewards       int64            `json:"endorsementRewards"` // EndorsementRewardsLiquid + EndorsementRewardsStakedOwn
 	MissedEndorsementRewards int64 `json:"missedEndorsementRewards"`
 
@@ -73,11 +83,21 @@ type Client struct {
 	rootUrl            *url.URL
 	protocolRewardsUrl *url.URL
 	balanceCheckMode   enums.EBalanceCheckMode
+
+	limiter                   *transport.TokenBucket
+	maxRetries                int
+	delegatorFetchConcurrency int
 }
 
 type MvktClientOptions struct {
 	BalanceCheckMode enums.EBalanceCheckMode
 	HttpClient       *http.Client
+	// number of delegator pages fetched in parallel
+	DelegatorFetchConcurrency int
+	// requests per second limit shared by mvkt and protocol rewards requests, negative value disables limiting
+	RequestsPerSecond float64
+	// number of retries of failed or throttled requests, negative value disables retries
+	MaxRetries int
 }
 
 func InitClient(rootUrl string, protocolRewardsUrl string, options *MvktClientOptions) (*Client, error) {
@@ -103,40 +123,85 @@ func InitClient(rootUrl string, protocolRewardsUrl string, options *MvktClientOp
 		}
 	}
 
+	concurrency := options.DelegatorFetchConcurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_DELEGATOR_FETCH_CONCURRENCY
+	}
+	requestsPerSecond := options.RequestsPerSecond
+	if requestsPerSecond == 0 {
+		requestsPerSecond = DEFAULT_REQUESTS_PER_SECOND
+	}
+	maxRetries := options.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DEFAULT_MAX_RETRIES
+	}
+
 	return &Client{
-		Client:             options.HttpClient,
-		rootUrl:            root,
-		protocolRewardsUrl: protocolRewards,
-		balanceCheckMode:   options.BalanceCheckMode,
+		Client:                    options.HttpClient,
+		rootUrl:                   root,
+		protocolRewardsUrl:        protocolRewards,
+		balanceCheckMode:          options.BalanceCheckMode,
+		limiter:                   transport.NewTokenBucket(requestsPerSecond, concurrency),
+		maxRetries:                max(maxRetries, 0),
+		delegatorFetchConcurrency: concurrency,
 	}, nil
 }
 
-func (client *Client) Get(ctx context.Context, path string) (*http.Response, error) {
-	rel, err := url.Parse(path)
-	if err != nil {
-		return nil, err
-	}
-	request, _ := http.NewRequestWithContext(ctx, "GET", client.rootUrl.ResolveReference(rel).String(), nil)
+// do sends rate limited GET request, transient failures and throttled requests are retried
+// with exponential backoff honoring Retry-After. The last response is returned if retries are exhausted.
+// Retries are made only here, the failover transport sends each attempt to a single endpoint.
+func (client *Client) do(ctx context.Context, u string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := client.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		request, err := http.NewRequestWithContext(transport.WithSingleAttempt(ctx), "GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(request)
+		if err == nil && !transport.IsRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= client.maxRetries || ctx.Err() != nil {
+			return resp, err
+		}
 
-	resp, err := client.Do(request)
-	if err != nil {
-		return nil, err
+		delay := transport.GetBackoffDelay(attempt, RETRY_BASE_DELAY, RETRY_MAX_DELAY)
+		if err != nil {
+			slog.Debug("mvkt request failed, retrying", "url", u, "attempt", attempt+1, "delay", delay, "error", err.Error())
+		} else {
+			if retryAfter, ok := transport.GetRetryAfter(resp, RETRY_MAX_DELAY); ok {
+				delay = retryAfter
+			}
+			resp.Body.Close()
+			slog.Debug("mvkt request failed, retrying", "url", u, "attempt", attempt+1, "delay", delay, "status", resp.StatusCode)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
 	}
-	return resp, nil
 }
 
-func (client *Client) GetFromProtocolRewards(ctx context.Context, path string) (*http.Response, error) {
+func (client *Client) Get(ctx context.Context, path string) (*http.Response, error) {
 	rel, err := url.Parse(path)
 	if err != nil {
 		return nil, err
 	}
-	request, _ := http.NewRequestWithContext(ctx, "GET", client.protocolRewardsUrl.ResolveReference(rel).String(), nil)
+	return client.do(ctx, client.rootUrl.ResolveReference(rel).String())
+}
 
-	resp, err := client.Do(request)
+func (client *Client) GetFromProtocolRewards(ctx context.Context, path string) (*http.Response, error) {
+	rel, err := url.Parse(path)
 	if err != nil {
 		return nil, err
 	}
-	return resp, nil
+	return client.do(ctx, client.protocolRewardsUrl.ResolveReference(rel).String())
 }
 
 func unmarshallMvktResponse[T any](resp *http.Response, result *T) error {
@@ -164,6 +229,58 @@ func (client *Client) getDelegatorsCycleData(ctx context.Context, baker []byte,
 	return data.Delegators, nil
 }
 
+// getAllDelegatorsCycleData fetches pages of delegators in parallel, expectedCount is used to plan the pages,
+// pages are then fetched sequentially until a page is not full
+func (client *Client) getAllDelegatorsCycleData(ctx context.Context, baker []byte, cycle int64, expectedCount int) ([]splitDelegator, error) {
+	pages := max((expectedCount+DELEGATOR_FETCH_LIMIT-1)/DELEGATOR_FETCH_LIMIT, 1)
+	results := make([][]splitDelegator, pages)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var firstErr error
+	var errMtx sync.Mutex
+	semaphore := make(chan struct{}, client.delegatorFetchConcurrency)
+	for page := 0; page < pages; page++ {
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			delegators, err := client.getDelegatorsCycleData(ctx, baker, cycle, DELEGATOR_FETCH_LIMIT, page*DELEGATOR_FETCH_LIMIT)
+			if err != nil {
+				errMtx.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				errMtx.Unlock()
+				return
+			}
+			results[page] = delegators
+		}(page)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	collectedDelegators := make([]splitDelegator, 0, expectedCount)
+	for _, delegators := range results {
+		collectedDelegators = append(collectedDelegators, delegators...)
+	}
+	fetched := len(results[pages-1])
+	for offset := pages * DELEGATOR_FETCH_LIMIT; fetched == DELEGATOR_FETCH_LIMIT; offset += DELEGATOR_FETCH_LIMIT {
+		newDelegators, err := client.getDelegatorsCycleData(ctx, baker, cycle, DELEGATOR_FETCH_LIMIT, offset)
+		if err != nil {
+			return nil, err
+		}
+		collectedDelegators = append(collectedDelegators, newDelegators...)
+		fetched = len(newDelegators)
+	}
+	return collectedDelegators, nil
+}
+
 func (client *Client) getBakerData(ctx context.Context, baker []byte) (*bakerData, error) {
 	u := fmt.Sprintf("v1/delegates/%s", baker)
 	slog.Debug("getting baker data", "baker", baker, "url", u)
@@ -233,11 +350,15 @@ func (client *Client) getFirstBlockCycleAfterTimestamp(ctx context.Context, time
 		return 0, errors.Join(constants.ErrCycleDataUnmarshalFailed, err)
 	}
 	if len(cycles) == 0 {
-		return 0, errors.Join(constants.ErrCycleDataFetchFailed, fmt.Errorf("no cycles found"))
+		return 0, errors.Join(constants.ErrCycleDataFetchFailed, constants.ErrNoBlockAfterDate, fmt.Errorf("timestamp: %s", timestamp))
 	}
 	return cycles[0], nil
 }
 
+func (client *Client) GetCycleAtDate(ctx context.Context, date time.Time) (int64, error) {
+	return client.getFirstBlockCycleAfterTimestamp(ctx, date)
+}
+
 // https://api.mavryk.network/v1/blocks?select=cycle,level&limit=1&timestamp.lt=2020-02-20T02:40:57Z
 func (client *Client) GetCyclesInDateRange(ctx context.Context, startDate time.Time, endDate time.Time) ([]int64, error) {
 	firstCycle, err := client.getFirstBlockCycleAfterTimestamp(ctx, startDate)
@@ -257,7 +378,50 @@ func (client *Client) GetCyclesInDateRange(ctx context.Context, startDate time.T
 }
 
 // https://api.mavryk.network/v1/rewards/split/${baker}/${cycle}?limit=0
-func (client *Client) GetCycleData(ctx context.Context, baker mavryk.Address, cycle int64) (bakersCycleData *common.BakersCycleData, err error) {
+func (client *Client) GetCycleData(ctx context.Context, baker mavryk.Address, cycle int64) (*common.BakersCycleData, error) {
+	return client.GetCycleDataWithBalanceCheckMode(ctx, baker, cycle, client.balanceCheckMode)
+}
+
+// GetProtocolRewardsCycleData returns balances and delegators as reported by protocol-rewards, rewards are not populated
+func (client *Client) GetProtocolRewardsCycleData(ctx context.Context, baker mavryk.Address, cycle int64) (bakersCycleData *common.BakersCycleData, err error) {
+	bakerAddr, _ := baker.MarshalText()
+	protocolRewardsCycleData, err := client.getProtocolRewardsCycleData(ctx, bakerAddr, cycle)
+	if err != nil {
+		return nil, err
+	}
+
+	// handle delegator parsing errors
+	defer (func() {
+		panicError := recover()
+		if panicError != nil {
+			err = panicError.(error)
+			return
+		}
+	})()
+
+	return &common.BakersCycleData{
+		DelegatorsCount:          protocolRewardsCycleData.DelegatorsCount,
+		OwnDelegatedBalance:      mavryk.NewZ(protocolRewardsCycleData.OwnDelegatedBalance),
+		ExternalDelegatedBalance: mavryk.NewZ(protocolRewardsCycleData.ExternalDelegatedBalance),
+		StakersCount:             protocolRewardsCycleData.StakersCount,
+		OwnStakedBalance:         mavryk.NewZ(protocolRewardsCycleData.OwnStakedBalance),
+		ExternalStakedBalance:    mavryk.NewZ(protocolRewardsCycleData.ExternalStakedBalance),
+		Delegators: lo.Map(protocolRewardsCycleData.Delegators, func(delegator splitDelegator, _ int) common.Delegator {
+			addr, err := mavryk.ParseAddress(delegator.Address)
+			if err != nil {
+				panic(err)
+			}
+			return common.Delegator{
+				Address:          addr,
+				DelegatedBalance: mavryk.NewZ(delegator.DelegatedBalance),
+				StakedBalance:    mavryk.NewZ(delegator.StakedBalance),
+				Emptied:          delegator.Emptied,
+			}
+		}),
+	}, nil
+}
+
+func (client *Client) GetCycleDataWithBalanceCheckMode(ctx context.Context, baker mavryk.Address, cycle int64, balanceCheckMode enums.EBalanceCheckMode) (bakersCycleData *common.BakersCycleData, err error) {
 
 	bakerAddr, _ := baker.MarshalText()
 
@@ -270,15 +434,9 @@ func (client *Client) GetCycleData(ctx context.Context, baker mavryk.Address, cy
 		return nil, err
 	}
 
-	collectedDelegators := make([]splitDelegator, 0)
-	fetched := DELEGATOR_FETCH_LIMIT
-	for fetched == DELEGATOR_FETCH_LIMIT {
-		newDelegators, err := client.getDelegatorsCycleData(ctx, bakerAddr, cycle, DELEGATOR_FETCH_LIMIT, len(collectedDelegators))
-		if err != nil {
-			return nil, err
-		}
-		collectedDelegators = append(collectedDelegators, newDelegators...)
-		fetched = len(newDelegators)
+	collectedDelegators, err := client.getAllDelegatorsCycleData(ctx, bakerAddr, cycle, int(mvktBakerCycleData.DelegatorsCount))
+	if err != nil {
+		return nil, err
 	}
 
 	// handle delegator parsing errors
@@ -309,7 +467,7 @@ func (client *Client) GetCycleData(ctx context.Context, baker mavryk.Address, cy
 	blockDelegatedFees := delegationShare.Mul64(mvktBakerCycleData.BlockFees).Div64(precision)
 	blockStakingFees := mavryk.NewZ(mvktBakerCycleData.BlockFees).Sub(blockDelegatedFees)
 
-	if client.balanceCheckMode == enums.PROTOCOL_BALANCE_CHECK_MODE {
+	if balanceCheckMode == enums.PROTOCOL_BALANCE_CHECK_MODE {
 		protocolRewardsCycleData, err := client.getProtocolRewardsCycleData(ctx, bakerAddr, cycle)
 		if err != nil {
 			return nil, err
@@ -400,12 +558,14 @@ func (client *Client) GetCycleData(ctx context.Context, baker mavryk.Address, cy
 		IdealEndorsementDelegatedRewards: endorsingDelegatedRewards.Add(delegationShare.Mul64(mvktBakerCycleData.MissedEndorsementRewards).Div64(precision)),
 		BlockDelegatedFees:               blockDelegatedFees,
 
-		StakersCount:                  mvktBakerCycleData.StakersCount,
-		OwnStakedBalance:              mavryk.NewZ(mvktBakerCycleData.OwnStakedBalance),
-		ExternalStakedBalance:         mavryk.NewZ(mvktBakerCycleData.ExternalStakedBalance),
-		BlockStakingRewardsEdge:       mavryk.NewZ(mvktBakerCycleData.BlockRewardsStakedEdge),
-		EndorsementStakingRewardsEdge: mavryk.NewZ(mvktBakerCycleData.EndorsementRewardsStakedEdge),
-		BlockStakingFees:              blockStakingFees,
+		StakersCount:                    mvktBakerCycleData.StakersCount,
+		OwnStakedBalance:                mavryk.NewZ(mvktBakerCycleData.OwnStakedBalance),
+		ExternalStakedBalance:           mavryk.NewZ(mvktBakerCycleData.ExternalStakedBalance),
+		BlockStakingRewardsEdge:         mavryk.NewZ(mvktBakerCycleData.BlockRewardsStakedEdge),
+		EndorsementStakingRewardsEdge:   mavryk.NewZ(mvktBakerCycleData.EndorsementRewardsStakedEdge),
+		BlockStakingRewardsShared:       mavryk.NewZ(mvktBakerCycleData.BlockRewardsStakedShared),
+		EndorsementStakingRewardsShared: mavryk.NewZ(mvktBakerCycleData.EndorsementRewardsStakedShared),
+		BlockStakingFees:                blockStakingFees,
 
 		FrozenDepositLimit: mavryk.NewZ(mvktBakerData.FrozenDepositLimit),
 		Delegators: lo.Map(collectedDelegators, func(delegator splitDelegator, _ int) common.Delegator {
@@ -424,6 +584,27 @@ func (client *Client) GetCycleData(ctx context.Context, baker mavryk.Address, cy
 
 }
 
+func (client *Client) GetCycleDelegators(ctx context.Context, baker mavryk.Address, cycle int64) ([]mavryk.Address, error) {
+	bakerAddr, _ := baker.MarshalText()
+	mvktBakerCycleData, err := client.getCycleData(ctx, bakerAddr, cycle)
+	if err != nil {
+		return nil, err
+	}
+	collectedDelegators, err := client.getAllDelegatorsCycleData(ctx, bakerAddr, cycle, int(mvktBakerCycleData.DelegatorsCount))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]mavryk.Address, 0, len(collectedDelegators))
+	for _, delegator := range collectedDelegators {
+		addr, err := mavryk.ParseAddress(delegator.Address)
+		if err != nil {
+			return nil, errors.Join(constants.ErrCycleDataUnmarshalFailed, err)
+		}
+		result = append(result, addr)
+	}
+	return result, nil
+}
+
 // https://api.mavryk.network/v1/operations/transactions/onyUK7ZnQHzeNYbWSLL4zVATBtvLLk5GpPDv3VfoQPLtsBCjPX1/status
 func (client *Client) WasOperationApplied(ctx context.Context, opHash mavryk.OpHash) (common.OperationStatus, error) {
 	op, _ := opHash.MarshalText()
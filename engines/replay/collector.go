@@ -0,0 +1,159 @@
+package replay_engines
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+)
+
+// RecordingColletor forwards all calls to the wrapped collector and records their results
+type RecordingColletor struct {
+	common.CollectorEngine
+	recorder *FixtureRecorder
+}
+
+func NewRecordingColletor(engine common.CollectorEngine, recorder *FixtureRecorder) *RecordingColletor {
+	return &RecordingColletor{
+		CollectorEngine: engine,
+		recorder:        recorder,
+	}
+}
+
+func (engine *RecordingColletor) GetCurrentCycleNumber() (int64, error) {
+	result, err := engine.CollectorEngine.GetCurrentCycleNumber()
+	return record(engine.recorder, COLLECTOR_ENGINE, "GetCurrentCycleNumber", "", result, err)
+}
+
+func (engine *RecordingColletor) GetLastCompletedCycle() (int64, error) {
+	result, err := engine.CollectorEngine.GetLastCompletedCycle()
+	return record(engine.recorder, COLLECTOR_ENGINE, "GetLastCompletedCycle", "", result, err)
+}
+
+func (engine *RecordingColletor) GetCycleStakingData(baker mavryk.Address, cycle int64) (*common.BakersCycleData, error) {
+	result, err := engine.CollectorEngine.GetCycleStakingData(baker, cycle)
+	return record(engine.recorder, COLLECTOR_ENGINE, "GetCycleStakingData", fmt.Sprintf("%s/%d", baker.String(), cycle), result, err)
+}
+
+func (engine *RecordingColletor) GetCycleDelegators(baker mavryk.Address, cycle int64) ([]mavryk.Address, error) {
+	result, err := engine.CollectorEngine.GetCycleDelegators(baker, cycle)
+	return record(engine.recorder, COLLECTOR_ENGINE, "GetCycleDelegators", fmt.Sprintf("%s/%d", baker.String(), cycle), result, err)
+}
+
+func (engine *RecordingColletor) GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error) {
+	result, err := engine.CollectorEngine.GetCyclesInDateRange(startDate, endDate)
+	return record(engine.recorder, COLLECTOR_ENGINE, "GetCyclesInDateRange", fmt.Sprintf("%d/%d", startDate.Unix(), endDate.Unix()), result, err)
+}
+
+func (engine *RecordingColletor) GetCycleAtDate(date time.Time) (int64, error) {
+	result, err := engine.CollectorEngine.GetCycleAtDate(date)
+	return record(engine.recorder, COLLECTOR_ENGINE, "GetCycleAtDate", fmt.Sprintf("%d", date.Unix()), result, err)
+}
+
+func (engine *RecordingColletor) WasOperationApplied(opHash mavryk.OpHash) (common.OperationStatus, error) {
+	result, err := engine.CollectorEngine.WasOperationApplied(opHash)
+	return record(engine.recorder, COLLECTOR_ENGINE, "WasOperationApplied", opHash.String(), result, err)
+}
+
+func (engine *RecordingColletor) GetBranch(offset int64) (mavryk.BlockHash, error) {
+	result, err := engine.CollectorEngine.GetBranch(offset)
+	return record(engine.recorder, COLLECTOR_ENGINE, "GetBranch", fmt.Sprintf("%d", offset), result, err)
+}
+
+func (engine *RecordingColletor) Simulate(o *codec.Op, publicKey mavryk.Key) (*rpc.Receipt, error) {
+	key := getOpKey(o)
+	result, err := engine.CollectorEngine.Simulate(o, publicKey)
+	return record(engine.recorder, COLLECTOR_ENGINE, "Simulate", key, result, err)
+}
+
+func (engine *RecordingColletor) GetBalance(addr mavryk.Address) (mavryk.Z, error) {
+	result, err := engine.CollectorEngine.GetBalance(addr)
+	return record(engine.recorder, COLLECTOR_ENGINE, "GetBalance", addr.String(), result, err)
+}
+
+func (engine *RecordingColletor) GetCurrentProtocol() (mavryk.ProtocolHash, error) {
+	result, err := engine.CollectorEngine.GetCurrentProtocol()
+	return record(engine.recorder, COLLECTOR_ENGINE, "GetCurrentProtocol", "", result, err)
+}
+
+func (engine *RecordingColletor) IsRevealed(addr mavryk.Address) (bool, error) {
+	result, err := engine.CollectorEngine.IsRevealed(addr)
+	return record(engine.recorder, COLLECTOR_ENGINE, "IsRevealed", addr.String(), result, err)
+}
+
+// ReplayColletor serves collector calls from recorded fixtures without any network access
+type ReplayColletor struct {
+	fixtures *Fixtures
+}
+
+func NewReplayColletor(fixtures *Fixtures) *ReplayColletor {
+	return &ReplayColletor{
+		fixtures: fixtures,
+	}
+}
+
+func (engine *ReplayColletor) GetId() string {
+	return "ReplayColletor"
+}
+
+func (engine *ReplayColletor) RefreshParams() error {
+	return nil
+}
+
+func (engine *ReplayColletor) GetCurrentCycleNumber() (int64, error) {
+	return replay[int64](engine.fixtures, COLLECTOR_ENGINE, "GetCurrentCycleNumber", "")
+}
+
+func (engine *ReplayColletor) GetLastCompletedCycle() (int64, error) {
+	return replay[int64](engine.fixtures, COLLECTOR_ENGINE, "GetLastCompletedCycle", "")
+}
+
+func (engine *ReplayColletor) GetCycleStakingData(baker mavryk.Address, cycle int64) (*common.BakersCycleData, error) {
+	return replay[*common.BakersCycleData](engine.fixtures, COLLECTOR_ENGINE, "GetCycleStakingData", fmt.Sprintf("%s/%d", baker.String(), cycle))
+}
+
+func (engine *ReplayColletor) GetCycleDelegators(baker mavryk.Address, cycle int64) ([]mavryk.Address, error) {
+	return replay[[]mavryk.Address](engine.fixtures, COLLECTOR_ENGINE, "GetCycleDelegators", fmt.Sprintf("%s/%d", baker.String(), cycle))
+}
+
+func (engine *ReplayColletor) GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error) {
+	return replay[[]int64](engine.fixtures, COLLECTOR_ENGINE, "GetCyclesInDateRange", fmt.Sprintf("%d/%d", startDate.Unix(), endDate.Unix()))
+}
+
+func (engine *ReplayColletor) GetCycleAtDate(date time.Time) (int64, error) {
+	return replay[int64](engine.fixtures, COLLECTOR_ENGINE, "GetCycleAtDate", fmt.Sprintf("%d", date.Unix()))
+}
+
+func (engine *ReplayColletor) WasOperationApplied(opHash mavryk.OpHash) (common.OperationStatus, error) {
+	return replay[common.OperationStatus](engine.fixtures, COLLECTOR_ENGINE, "WasOperationApplied", opHash.String())
+}
+
+func (engine *ReplayColletor) GetBranch(offset int64) (mavryk.BlockHash, error) {
+	return replay[mavryk.BlockHash](engine.fixtures, COLLECTOR_ENGINE, "GetBranch", fmt.Sprintf("%d", offset))
+}
+
+func (engine *ReplayColletor) Simulate(o *codec.Op, publicKey mavryk.Key) (*rpc.Receipt, error) {
+	return replay[*rpc.Receipt](engine.fixtures, COLLECTOR_ENGINE, "Simulate", getOpKey(o))
+}
+
+func (engine *ReplayColletor) GetBalance(addr mavryk.Address) (mavryk.Z, error) {
+	return replay[mavryk.Z](engine.fixtures, COLLECTOR_ENGINE, "GetBalance", addr.String())
+}
+
+func (engine *ReplayColletor) CreateCycleMonitor(options common.CycleMonitorOptions) (common.CycleMonitor, error) {
+	return nil, constants.ErrNotImplemented
+}
+
+func (engine *ReplayColletor) SendAnalytics(bakerId string, version string) {}
+
+func (engine *ReplayColletor) GetCurrentProtocol() (mavryk.ProtocolHash, error) {
+	return replay[mavryk.ProtocolHash](engine.fixtures, COLLECTOR_ENGINE, "GetCurrentProtocol", "")
+}
+
+func (engine *ReplayColletor) IsRevealed(addr mavryk.Address) (bool, error) {
+	return replay[bool](engine.fixtures, COLLECTOR_ENGINE, "IsRevealed", addr.String())
+}
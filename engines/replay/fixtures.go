@@ -0,0 +1,225 @@
+package replay_engines
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/codec"
+)
+
+const (
+	COLLECTOR_ENGINE  = "collector"
+	TRANSACTOR_ENGINE = "transactor"
+)
+
+var (
+	// sentinel errors restored on replay so errors.Is checks behave as with live engines
+	replayableErrors = []error{
+		constants.ErrNoCycleDataAvailable,
+		constants.ErrCycleDataFetchFailed,
+		constants.ErrNoBlockAfterDate,
+		constants.ErrCycleDataProtocolRewardsFetchFailed,
+		constants.ErrCycleDataProtocolRewardsMismatch,
+		constants.ErrCycleDataUnmarshalFailed,
+		constants.ErrOperationStatusCheckFailed,
+		constants.ErrOperationFailed,
+	}
+)
+
+type FixtureEntry struct {
+	Engine string          `json:"engine"`
+	Method string          `json:"method"`
+	Key    string          `json:"key,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	served bool
+}
+
+// FixtureRecorder appends every recorded engine call as a json line to the fixture file
+type FixtureRecorder struct {
+	file *os.File
+	mtx  sync.Mutex
+}
+
+func NewFixtureRecorder(path string) (*FixtureRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Join(constants.ErrFixtureRecordFailed, err)
+	}
+	slog.Info("recording engine calls", "path", path)
+	return &FixtureRecorder{file: file}, nil
+}
+
+func (recorder *FixtureRecorder) Close() error {
+	recorder.mtx.Lock()
+	defer recorder.mtx.Unlock()
+	return errors.Join(recorder.file.Sync(), recorder.file.Close())
+}
+
+func (recorder *FixtureRecorder) write(entry FixtureEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		slog.Warn("failed to marshal fixture entry", "method", entry.Method, "error", err.Error())
+		return
+	}
+	recorder.mtx.Lock()
+	defer recorder.mtx.Unlock()
+	if _, err := recorder.file.Write(append(data, '\n')); err != nil {
+		slog.Warn("failed to write fixture entry", "method", entry.Method, "error", err.Error())
+	}
+}
+
+func record[T any](recorder *FixtureRecorder, engine string, method string, key string, result T, err error) (T, error) {
+	entry := FixtureEntry{
+		Engine: engine,
+		Method: method,
+		Key:    key,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else if data, marshalErr := json.Marshal(result); marshalErr == nil {
+		entry.Result = data
+	} else {
+		slog.Warn("failed to marshal fixture result", "method", method, "error", marshalErr.Error())
+	}
+	recorder.write(entry)
+	return result, err
+}
+
+type fixtureQueue struct {
+	entries  []*FixtureEntry
+	consumed int
+}
+
+// Fixtures serve recorded engine calls. Calls are matched by engine, method and key,
+// repeated calls with the same key are served in recorded order (the last entry is repeated).
+// If there is no entry with matching key, next entry of the method not served yet is used.
+type Fixtures struct {
+	byKey    map[string]*fixtureQueue
+	byMethod map[string]*fixtureQueue
+	mtx      sync.Mutex
+}
+
+func getFixtureId(parts ...string) string {
+	return strings.Join(parts, "|")
+}
+
+func LoadFixtures(path string) (*Fixtures, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Join(constants.ErrFixtureLoadFailed, err)
+	}
+	defer file.Close()
+
+	fixtures := &Fixtures{
+		byKey:    make(map[string]*fixtureQueue),
+		byMethod: make(map[string]*fixtureQueue),
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry FixtureEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errors.Join(constants.ErrFixtureLoadFailed, fmt.Errorf("line %d", line), err)
+		}
+		fixtures.add(&entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Join(constants.ErrFixtureLoadFailed, err)
+	}
+	slog.Info("replaying engine calls", "path", path)
+	return fixtures, nil
+}
+
+func (fixtures *Fixtures) add(entry *FixtureEntry) {
+	keyId := getFixtureId(entry.Engine, entry.Method, entry.Key)
+	if _, ok := fixtures.byKey[keyId]; !ok {
+		fixtures.byKey[keyId] = &fixtureQueue{}
+	}
+	fixtures.byKey[keyId].entries = append(fixtures.byKey[keyId].entries, entry)
+
+	methodId := getFixtureId(entry.Engine, entry.Method)
+	if _, ok := fixtures.byMethod[methodId]; !ok {
+		fixtures.byMethod[methodId] = &fixtureQueue{}
+	}
+	fixtures.byMethod[methodId].entries = append(fixtures.byMethod[methodId].entries, entry)
+}
+
+func (queue *fixtureQueue) next() *FixtureEntry {
+	entry := queue.entries[min(queue.consumed, len(queue.entries)-1)]
+	entry.served = true
+	queue.consumed++
+	return entry
+}
+
+// nextUnserved returns next entry not served yet by any queue
+func (queue *fixtureQueue) nextUnserved() *FixtureEntry {
+	for ; queue.consumed < len(queue.entries); queue.consumed++ {
+		if entry := queue.entries[queue.consumed]; !entry.served {
+			entry.served = true
+			queue.consumed++
+			return entry
+		}
+	}
+	return nil
+}
+
+func (fixtures *Fixtures) next(engine string, method string, key string) (*FixtureEntry, error) {
+	fixtures.mtx.Lock()
+	defer fixtures.mtx.Unlock()
+	if queue, ok := fixtures.byKey[getFixtureId(engine, method, key)]; ok {
+		return queue.next(), nil
+	}
+	if queue, ok := fixtures.byMethod[getFixtureId(engine, method)]; ok {
+		if entry := queue.nextUnserved(); entry != nil {
+			slog.Debug("no fixture with matching key, using next recorded call", "engine", engine, "method", method, "key", key)
+			return entry, nil
+		}
+	}
+	return nil, errors.Join(constants.ErrFixtureNotFound, fmt.Errorf("engine: %s, method: %s, key: %s", engine, method, key))
+}
+
+func restoreError(msg string) error {
+	result := []error{}
+	for _, line := range strings.Split(msg, "\n") {
+		for _, sentinel := range replayableErrors {
+			if sentinel.Error() == line {
+				result = append(result, sentinel)
+			}
+		}
+	}
+	return errors.Join(append(result, errors.New(msg))...)
+}
+
+func replay[T any](fixtures *Fixtures, engine string, method string, key string) (T, error) {
+	var result T
+	entry, err := fixtures.next(engine, method, key)
+	if err != nil {
+		return result, err
+	}
+	if entry.Error != "" {
+		return result, restoreError(entry.Error)
+	}
+	if len(entry.Result) > 0 {
+		if err := json.Unmarshal(entry.Result, &result); err != nil {
+			return result, errors.Join(constants.ErrFixtureLoadFailed, err)
+		}
+	}
+	return result, nil
+}
+
+func getOpKey(op *codec.Op) string {
+	return hex.EncodeToString(op.Bytes())
+}
@@ -0,0 +1,43 @@
+package replay_engines
+
+import (
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	assert := assert.New(t)
+
+	fixturePath := path.Join(t.TempDir(), "fixtures.jsonl")
+	recorder, err := NewFixtureRecorder(fixturePath)
+	assert.Nil(err)
+	record(recorder, COLLECTOR_ENGINE, "GetLastCompletedCycle", "", int64(10), nil)
+	record(recorder, COLLECTOR_ENGINE, "GetLastCompletedCycle", "", int64(11), nil)
+	record(recorder, COLLECTOR_ENGINE, "GetCyclesInDateRange", "1/2", []int64{1, 2, 3}, nil)
+	record(recorder, COLLECTOR_ENGINE, "GetCycleStakingData", "mv1/1", 0, errors.Join(constants.ErrNoCycleDataAvailable, errors.New("baker: mv1")))
+	assert.Nil(recorder.Close())
+
+	fixtures, err := LoadFixtures(fixturePath)
+	assert.Nil(err)
+
+	// repeated calls are served in recorded order, the last one repeats
+	for _, expected := range []int64{10, 11, 11} {
+		cycle, err := replay[int64](fixtures, COLLECTOR_ENGINE, "GetLastCompletedCycle", "")
+		assert.Nil(err)
+		assert.Equal(expected, cycle)
+	}
+
+	cycles, err := replay[[]int64](fixtures, COLLECTOR_ENGINE, "GetCyclesInDateRange", "1/2")
+	assert.Nil(err)
+	assert.Equal([]int64{1, 2, 3}, cycles)
+
+	_, err = replay[int](fixtures, COLLECTOR_ENGINE, "GetCycleStakingData", "mv1/1")
+	assert.ErrorIs(err, constants.ErrNoCycleDataAvailable)
+
+	_, err = replay[int](fixtures, COLLECTOR_ENGINE, "GetBalance", "mv1")
+	assert.ErrorIs(err, constants.ErrFixtureNotFound)
+}
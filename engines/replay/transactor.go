@@ -0,0 +1,195 @@
+package replay_engines
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+)
+
+// RecordingTransactor forwards all calls to the wrapped transactor and records their results
+type RecordingTransactor struct {
+	common.TransactorEngine
+	recorder *FixtureRecorder
+}
+
+type recordingOpResult struct {
+	common.OpResult
+	recorder *FixtureRecorder
+}
+
+func (result *recordingOpResult) WaitForApply() error {
+	err := result.OpResult.WaitForApply()
+	_, err = record[any](result.recorder, TRANSACTOR_ENGINE, "WaitForApply", result.GetOpHash().String(), nil, err)
+	return err
+}
+
+func NewRecordingTransactor(engine common.TransactorEngine, recorder *FixtureRecorder) *RecordingTransactor {
+	return &RecordingTransactor{
+		TransactorEngine: engine,
+		recorder:         recorder,
+	}
+}
+
+// completedOp holds the operation as completed by the node (branch, counters, limits, reveal)
+type completedOp struct {
+	Bytes  string         `json:"bytes"`
+	Params *mavryk.Params `json:"params,omitempty"`
+}
+
+// applyTo replaces branch and contents of the operation with the completed ones
+func (completed *completedOp) applyTo(op *codec.Op) error {
+	data, err := hex.DecodeString(completed.Bytes)
+	if err != nil {
+		return errors.Join(constants.ErrFixtureLoadFailed, err)
+	}
+	decoded, err := codec.DecodeOp(data)
+	if err != nil {
+		return errors.Join(constants.ErrFixtureLoadFailed, err)
+	}
+	op.Branch = decoded.Branch
+	op.Contents = decoded.Contents
+	if completed.Params != nil {
+		op.WithParams(completed.Params)
+	}
+	return nil
+}
+
+// NOTE: Complete mutates the operation (counters, limits, branch), the completed operation is recorded
+// so replayed operations are identical to the recorded ones
+func (transactor *RecordingTransactor) Complete(op *codec.Op, key mavryk.Key) error {
+	opKey := getOpKey(op)
+	err := transactor.TransactorEngine.Complete(op, key)
+	var completed *completedOp
+	if err == nil {
+		completed = &completedOp{
+			Bytes:  getOpKey(op),
+			Params: op.Params,
+		}
+	}
+	_, err = record(transactor.recorder, TRANSACTOR_ENGINE, "Complete", opKey, completed, err)
+	return err
+}
+
+func (transactor *RecordingTransactor) Dispatch(op *codec.Op, opts *rpc.CallOptions) (common.OpResult, error) {
+	opKey := getOpKey(op)
+	result, err := transactor.TransactorEngine.Dispatch(op, opts)
+	opHash := mavryk.ZeroOpHash
+	if result != nil {
+		opHash = result.GetOpHash()
+	}
+	if _, err := record(transactor.recorder, TRANSACTOR_ENGINE, "Dispatch", opKey, opHash, err); err != nil {
+		return nil, err
+	}
+	return &recordingOpResult{
+		OpResult: result,
+		recorder: transactor.recorder,
+	}, nil
+}
+
+func (transactor *RecordingTransactor) Broadcast(op *codec.Op) (mavryk.OpHash, error) {
+	opKey := getOpKey(op)
+	result, err := transactor.TransactorEngine.Broadcast(op)
+	return record(transactor.recorder, TRANSACTOR_ENGINE, "Broadcast", opKey, result, err)
+}
+
+func (transactor *RecordingTransactor) Send(op *codec.Op, opts *rpc.CallOptions) (*rpc.Receipt, error) {
+	opKey := getOpKey(op)
+	result, err := transactor.TransactorEngine.Send(op, opts)
+	return record(transactor.recorder, TRANSACTOR_ENGINE, "Send", opKey, result, err)
+}
+
+func (transactor *RecordingTransactor) GetLimits() (*common.OperationLimits, error) {
+	result, err := transactor.TransactorEngine.GetLimits()
+	return record(transactor.recorder, TRANSACTOR_ENGINE, "GetLimits", "", result, err)
+}
+
+func (transactor *RecordingTransactor) WaitOpConfirmation(opHash mavryk.OpHash, ttl int64, confirmations int64) (*rpc.Receipt, error) {
+	result, err := transactor.TransactorEngine.WaitOpConfirmation(opHash, ttl, confirmations)
+	return record(transactor.recorder, TRANSACTOR_ENGINE, "WaitOpConfirmation", fmt.Sprintf("%s/%d", opHash.String(), confirmations), result, err)
+}
+
+func (transactor *RecordingTransactor) GetOperationStatus(opHash mavryk.OpHash) (common.OperationStatus, error) {
+	result, err := common.OPERATION_STATUS_UNKNOWN, constants.ErrNotImplemented
+	if statusAware, ok := transactor.TransactorEngine.(common.OperationStatusAwareTransactor); ok {
+		result, err = statusAware.GetOperationStatus(opHash)
+	}
+	return record(transactor.recorder, TRANSACTOR_ENGINE, "GetOperationStatus", opHash.String(), result, err)
+}
+
+// ReplayTransactor serves transactor calls from recorded fixtures, nothing is broadcasted
+type ReplayTransactor struct {
+	fixtures *Fixtures
+}
+
+type replayOpResult struct {
+	opHash   mavryk.OpHash
+	fixtures *Fixtures
+}
+
+func (result *replayOpResult) GetOpHash() mavryk.OpHash {
+	return result.opHash
+}
+
+func (result *replayOpResult) WaitForApply() error {
+	_, err := replay[any](result.fixtures, TRANSACTOR_ENGINE, "WaitForApply", result.opHash.String())
+	return err
+}
+
+func NewReplayTransactor(fixtures *Fixtures) *ReplayTransactor {
+	return &ReplayTransactor{
+		fixtures: fixtures,
+	}
+}
+
+func (transactor *ReplayTransactor) GetId() string {
+	return "ReplayTransactor"
+}
+
+func (transactor *ReplayTransactor) RefreshParams() error {
+	return nil
+}
+
+func (transactor *ReplayTransactor) Complete(op *codec.Op, key mavryk.Key) error {
+	completed, err := replay[*completedOp](transactor.fixtures, TRANSACTOR_ENGINE, "Complete", getOpKey(op))
+	if err != nil || completed == nil {
+		return err
+	}
+	return completed.applyTo(op)
+}
+
+func (transactor *ReplayTransactor) Dispatch(op *codec.Op, opts *rpc.CallOptions) (common.OpResult, error) {
+	opHash, err := replay[mavryk.OpHash](transactor.fixtures, TRANSACTOR_ENGINE, "Dispatch", getOpKey(op))
+	if err != nil {
+		return nil, err
+	}
+	return &replayOpResult{
+		opHash:   opHash,
+		fixtures: transactor.fixtures,
+	}, nil
+}
+
+func (transactor *ReplayTransactor) Broadcast(op *codec.Op) (mavryk.OpHash, error) {
+	return replay[mavryk.OpHash](transactor.fixtures, TRANSACTOR_ENGINE, "Broadcast", getOpKey(op))
+}
+
+func (transactor *ReplayTransactor) Send(op *codec.Op, opts *rpc.CallOptions) (*rpc.Receipt, error) {
+	return replay[*rpc.Receipt](transactor.fixtures, TRANSACTOR_ENGINE, "Send", getOpKey(op))
+}
+
+func (transactor *ReplayTransactor) GetLimits() (*common.OperationLimits, error) {
+	return replay[*common.OperationLimits](transactor.fixtures, TRANSACTOR_ENGINE, "GetLimits", "")
+}
+
+func (transactor *ReplayTransactor) WaitOpConfirmation(opHash mavryk.OpHash, ttl int64, confirmations int64) (*rpc.Receipt, error) {
+	return replay[*rpc.Receipt](transactor.fixtures, TRANSACTOR_ENGINE, "WaitOpConfirmation", fmt.Sprintf("%s/%d", opHash.String(), confirmations))
+}
+
+func (transactor *ReplayTransactor) GetOperationStatus(opHash mavryk.OpHash) (common.OperationStatus, error) {
+	return replay[common.OperationStatus](transactor.fixtures, TRANSACTOR_ENGINE, "GetOperationStatus", opHash.String())
+}
@@ -155,3 +155,77 @@ func (engine *FsReporter) GetExistingCycleSummary(cycle int64) (*common.CyclePay
 	err = json.Unmarshal(data, &summary)
 	return &summary, err
 }
+
+// ReportCycleDataMismatches writes differences found by cycle data verification, empty report is written as well
+func (engine *FsReporter) ReportCycleDataMismatches(cycle int64, mismatches []common.CycleDataMismatch) (string, error) {
+	reportsDirectory, err := engine.getReportsDirectory()
+	if err != nil {
+		return "", err
+	}
+	targetFile := path.Join(reportsDirectory, fmt.Sprintf("%d", cycle), constants.CYCLE_DATA_MISMATCH_REPORT_FILE_NAME)
+	err = os.MkdirAll(path.Dir(targetFile), 0700)
+	if err != nil {
+		return "", err
+	}
+	csv, err := gocsv.MarshalBytes(mismatches)
+	if err != nil {
+		return "", err
+	}
+	return targetFile, os.WriteFile(targetFile, csv, 0644)
+}
+
+// GetCarryOverLedger loads the ledger of carried over payouts, ledger is always loaded from the actual reports so dry runs reflect it
+func (engine *FsReporter) GetCarryOverLedger() (*common.CarryOverLedger, error) {
+	sourceFile := path.Join(state.Global.GetReportsDirectory(), constants.CARRY_OVER_LEDGER_FILE_NAME)
+	data, err := os.ReadFile(sourceFile)
+	if os.IsNotExist(err) {
+		return common.NewCarryOverLedger(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ledger := common.NewCarryOverLedger()
+	err = json.Unmarshal(data, ledger)
+	return ledger, err
+}
+
+func (engine *FsReporter) ReportCarryOverLedger(ledger *common.CarryOverLedger) error {
+	reportsDirectory, err := engine.getReportsDirectory()
+	if err != nil {
+		return err
+	}
+	targetFile := path.Join(reportsDirectory, constants.CARRY_OVER_LEDGER_FILE_NAME)
+	data, err := json.MarshalIndent(ledger, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(targetFile, data, 0644)
+}
+
+// GetSigningPolicyLedger loads amounts signed under the signing policy, ledger is always loaded from the actual reports so dry runs reflect it
+func (engine *FsReporter) GetSigningPolicyLedger() (*common.SigningPolicyLedger, error) {
+	sourceFile := path.Join(state.Global.GetReportsDirectory(), constants.SIGNING_POLICY_LEDGER_FILE_NAME)
+	data, err := os.ReadFile(sourceFile)
+	if os.IsNotExist(err) {
+		return common.NewSigningPolicyLedger(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ledger := common.NewSigningPolicyLedger()
+	err = json.Unmarshal(data, ledger)
+	return ledger, err
+}
+
+func (engine *FsReporter) ReportSigningPolicyLedger(ledger *common.SigningPolicyLedger) error {
+	reportsDirectory, err := engine.getReportsDirectory()
+	if err != nil {
+		return err
+	}
+	targetFile := path.Join(reportsDirectory, constants.SIGNING_POLICY_LEDGER_FILE_NAME)
+	data, err := json.MarshalIndent(ledger, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(targetFile, data, 0644)
+}
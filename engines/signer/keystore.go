@@ -0,0 +1,40 @@
+package signer_engines
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/keystore"
+)
+
+// LoadKeystoreSigner decrypts the keystore and creates InMemorySigner from its key.
+// Passphrase is read from the file descriptor, KEYSTORE_PASSPHRASE environment variable or prompt.
+func LoadKeystoreSigner(keystorePath string, passphraseFd int) (*InMemorySigner, error) {
+	slog.Debug("loading keystore from file", "path", keystorePath)
+	ks, err := keystore.Load(keystorePath)
+	if err != nil {
+		return nil, errors.Join(constants.ErrSignerLoadFailed, err)
+	}
+	passphrase, err := keystore.ReadPassphrase(keystore.PassphraseSource{
+		Fd:     passphraseFd,
+		Env:    keystore.PASSPHRASE_ENV,
+		Prompt: fmt.Sprintf("Enter passphrase of the keystore '%s':", keystorePath),
+	})
+	if err != nil {
+		return nil, errors.Join(constants.ErrSignerLoadFailed, err)
+	}
+	secret, err := ks.Decrypt(passphrase)
+	if err != nil {
+		return nil, errors.Join(constants.ErrSignerLoadFailed, err)
+	}
+	signer, err := InitInMemorySigner(string(secret))
+	if err != nil {
+		return nil, err
+	}
+	if ks.Address != "" && signer.GetPKH().String() != ks.Address {
+		return nil, errors.Join(constants.ErrSignerLoadFailed, fmt.Errorf("keystore address '%s' does not match the key address '%s'", ks.Address, signer.GetPKH().String()))
+	}
+	return signer, nil
+}
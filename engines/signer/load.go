@@ -27,6 +27,20 @@ func Load(kind string) (common.SignerEngine, error) {
 			return nil, errors.Join(constants.ErrSignerLoadFailed, err)
 		}
 		return InitInMemorySigner(strings.TrimSpace(string(keyBytes)))
+	case string(enums.WALLET_MODE_LOCAL_KEYSTORE2):
+		fallthrough
+	case string(enums.WALLET_MODE_LOCAL_KEYSTORE):
+		slog.Debug("creating InMemorySigner from keystore")
+		return LoadKeystoreSigner(state.Global.GetKeystoreFilePath(), state.Global.GetPassphraseFd())
+	case string(enums.WALLET_MODE_OFFLINE):
+		slog.Debug("creating OfflineSigner")
+		publicKeyFile := state.Global.GetPublicKeyFilePath()
+		slog.Debug("loading public key from file", "path", publicKeyFile)
+		keyBytes, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, errors.Join(constants.ErrSignerLoadFailed, err)
+		}
+		return InitOfflineSigner(strings.TrimSpace(string(keyBytes)))
 	case string(enums.WALLET_MODE_REMOTE_SIGNER2):
 		fallthrough
 	case string(enums.WALLET_MODE_REMOTE_SIGNER):
@@ -50,6 +64,11 @@ func Load(kind string) (common.SignerEngine, error) {
 		return InitInMemorySigner(strings.TrimPrefix(kind, "key:"))
 	}
 
+	if strings.HasPrefix(kind, "offline:") {
+		slog.Debug("creating OfflineSigner from parameters")
+		return InitOfflineSigner(strings.TrimPrefix(kind, "offline:"))
+	}
+
 	if strings.HasPrefix(kind, "remote:") {
 		slog.Debug("creating RemoteSigner from parameters")
 		specs := strings.TrimPrefix(kind, "remote:")
@@ -0,0 +1,45 @@
+package signer_engines
+
+import (
+	"errors"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/signer"
+)
+
+// OfflineSigner knows only the public key of the payout wallet, operations are signed on the offline machine
+type OfflineSigner struct {
+	Key mavryk.Key
+}
+
+func InitOfflineSigner(key string) (*OfflineSigner, error) {
+	pk, err := mavryk.ParseKey(key)
+	if err != nil {
+		return nil, errors.Join(constants.ErrSignerLoadFailed, err)
+	}
+	return &OfflineSigner{
+		Key: pk,
+	}, nil
+}
+
+func (offlineSigner *OfflineSigner) GetId() string {
+	return "OfflineSigner"
+}
+
+func (offlineSigner *OfflineSigner) GetPKH() mavryk.Address {
+	return offlineSigner.Key.Address()
+}
+
+func (offlineSigner *OfflineSigner) GetKey() mavryk.Key {
+	return offlineSigner.Key
+}
+
+func (offlineSigner *OfflineSigner) GetSigner() signer.Signer {
+	return nil
+}
+
+func (offlineSigner *OfflineSigner) Sign(op *codec.Op) error {
+	return constants.ErrOfflineSignerCannotSign
+}
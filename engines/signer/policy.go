@@ -0,0 +1,256 @@
+package signer_engines
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+)
+
+type assetLimits struct {
+	operation mavryk.Z
+	cycle     mavryk.Z
+}
+
+// PolicySigner inspects operations before signing and refuses the ones violating the signing policy.
+// Destinations are checked only against the allowed destinations of the policy, blueprints may be altered
+// by extensions or come from an untrusted machine and serve only to account signed amounts to cycles.
+// Amounts count towards the cycle limit once signed, regardless of the operation being applied, and are kept
+// with the reports between runs. Replacements (operations reusing counters of already signed operation) count only once,
+// as only one of them can be applied.
+type PolicySigner struct {
+	common.SignerEngine
+	policy *common.SigningPolicy
+
+	mtx                 sync.Mutex
+	cycles              []int64
+	allowedDestinations map[string]struct{}
+	limits              map[string]assetLimits
+	// amounts of the blueprint payouts by asset and recipient and cycle
+	payoutAmounts map[string]map[int64]mavryk.Z
+	ledger        *common.SigningPolicyLedger
+	ledgerErr     error
+	reporter      common.SigningPolicyLedgerAwareReporter
+}
+
+func NewPolicySigner(signer common.SignerEngine, policy *common.SigningPolicy) *PolicySigner {
+	limits := map[string]assetLimits{
+		common.SIGNING_POLICY_MAV_ASSET: {operation: policy.MaximumOperationAmount, cycle: policy.MaximumCycleAmount},
+	}
+	for _, limit := range policy.TokenLimits {
+		limits[common.GetSigningPolicyTokenAsset(limit.Contract, limit.TokenId)] = assetLimits{operation: limit.MaximumOperationAmount, cycle: limit.MaximumCycleAmount}
+	}
+	return &PolicySigner{
+		SignerEngine: signer,
+		policy:       policy,
+
+		allowedDestinations: lo.SliceToMap(policy.AllowedDestinations, func(address mavryk.Address) (string, struct{}) {
+			return address.String(), struct{}{}
+		}),
+		limits:        limits,
+		payoutAmounts: make(map[string]map[int64]mavryk.Z),
+		ledger:        common.NewSigningPolicyLedger(),
+	}
+}
+
+func (policySigner *PolicySigner) GetId() string {
+	return fmt.Sprintf("PolicySigner(%s)", policySigner.SignerEngine.GetId())
+}
+
+func getPayoutAmountsKey(asset string, recipient mavryk.Address) string {
+	return fmt.Sprintf("%s|%s", asset, recipient)
+}
+
+// SetPayoutBlueprints sets the blueprints signed amounts are accounted to and loads amounts signed in past runs from the reporter
+func (policySigner *PolicySigner) SetPayoutBlueprints(blueprints []*common.CyclePayoutBlueprint, reporter common.ReporterEngine) {
+	policySigner.mtx.Lock()
+	defer policySigner.mtx.Unlock()
+
+	policySigner.cycles = make([]int64, 0, len(blueprints))
+	policySigner.payoutAmounts = make(map[string]map[int64]mavryk.Z)
+	for _, blueprint := range blueprints {
+		if blueprint == nil {
+			continue
+		}
+		policySigner.cycles = append(policySigner.cycles, blueprint.Cycle)
+		for _, payout := range blueprint.Payouts {
+			asset := common.SIGNING_POLICY_MAV_ASSET
+			if lo.Contains(enums.FA_OPERATION_KINDS, payout.TxKind) {
+				asset = common.GetSigningPolicyTokenAsset(payout.FAContract, payout.FATokenId.Int64())
+			}
+			key := getPayoutAmountsKey(asset, payout.Recipient)
+			if _, ok := policySigner.payoutAmounts[key]; !ok {
+				policySigner.payoutAmounts[key] = make(map[int64]mavryk.Z)
+			}
+			policySigner.payoutAmounts[key][blueprint.Cycle] = policySigner.payoutAmounts[key][blueprint.Cycle].Add(payout.Amount)
+		}
+	}
+	slices.Sort(policySigner.cycles)
+	policySigner.cycles = slices.Compact(policySigner.cycles)
+
+	policySigner.reporter, policySigner.ledgerErr = nil, nil
+	if ledgerReporter, ok := reporter.(common.SigningPolicyLedgerAwareReporter); ok {
+		policySigner.reporter = ledgerReporter
+		policySigner.ledger, policySigner.ledgerErr = ledgerReporter.GetSigningPolicyLedger()
+	} else {
+		slog.Warn("reporter can not store signed amounts, signing policy cycle limits apply to this run only")
+		if policySigner.ledger == nil {
+			policySigner.ledger = common.NewSigningPolicyLedger()
+		}
+	}
+}
+
+func (policySigner *PolicySigner) isDestinationAllowed(destination mavryk.Address) bool {
+	if len(policySigner.allowedDestinations) == 0 {
+		return true
+	}
+	_, ok := policySigner.allowedDestinations[destination.String()]
+	return ok
+}
+
+// attribute splits amount sent to the recipient among cycles in proportion to the recipient's payouts in the blueprints,
+// amounts not found in the blueprints are attributed to the latest cycle
+func (policySigner *PolicySigner) attribute(asset string, recipient mavryk.Address, amount mavryk.Z) map[int64]mavryk.Z {
+	if len(policySigner.cycles) == 0 {
+		return nil
+	}
+	payouts := policySigner.payoutAmounts[getPayoutAmountsKey(asset, recipient)]
+	total := lo.Reduce(lo.Values(payouts), func(agg mavryk.Z, amount mavryk.Z, _ int) mavryk.Z { return agg.Add(amount) }, mavryk.Zero)
+	if total.IsZero() {
+		return map[int64]mavryk.Z{policySigner.cycles[len(policySigner.cycles)-1]: amount}
+	}
+	cycles := lo.Keys(payouts)
+	slices.Sort(cycles)
+	result := make(map[int64]mavryk.Z, len(cycles))
+	remaining := amount
+	for i, cycle := range cycles {
+		if i == len(cycles)-1 {
+			result[cycle] = remaining
+			break
+		}
+		part := amount.Mul(payouts[cycle]).Div(total)
+		result[cycle] = part
+		remaining = remaining.Sub(part)
+	}
+	return result
+}
+
+// checkOp returns amounts sent by the operation by cycle and asset or error if the operation violates the policy
+func (policySigner *PolicySigner) checkOp(op *codec.Op) (map[int64]map[string]mavryk.Z, error) {
+	totals := make(map[string]mavryk.Z)
+	amounts := make(map[int64]map[string]mavryk.Z)
+	add := func(asset string, recipient mavryk.Address, amount mavryk.Z) {
+		totals[asset] = totals[asset].Add(amount)
+		for cycle, part := range policySigner.attribute(asset, recipient, amount) {
+			if _, ok := amounts[cycle]; !ok {
+				amounts[cycle] = make(map[string]mavryk.Z)
+			}
+			amounts[cycle][asset] = amounts[cycle][asset].Add(part)
+		}
+	}
+
+	for _, content := range op.Contents {
+		switch content := content.(type) {
+		case *codec.Transaction:
+			if content.Parameters == nil || content.Parameters.Entrypoint == "default" {
+				if !policySigner.isDestinationAllowed(content.Destination) {
+					return nil, errors.Join(constants.ErrSigningPolicyDestinationNotAllowed, fmt.Errorf("destination: %s", content.Destination))
+				}
+				add(common.SIGNING_POLICY_MAV_ASSET, content.Destination, mavryk.NewZ(content.Amount.Int64()))
+				continue
+			}
+			if content.Amount.Int64() != 0 {
+				return nil, errors.Join(constants.ErrSigningPolicyNonTransferContent, fmt.Errorf("mav sent with contract call to %s", content.Destination))
+			}
+			transfers, err := common.DecodeTokenTransfers(content.Parameters)
+			if err != nil {
+				return nil, errors.Join(constants.ErrSigningPolicyNonTransferContent, fmt.Errorf("contract call of %s is not token transfer", content.Destination), err)
+			}
+			for _, transfer := range transfers {
+				asset := common.GetSigningPolicyTokenAsset(content.Destination, transfer.TokenId)
+				if _, ok := policySigner.limits[asset]; !ok {
+					return nil, errors.Join(constants.ErrSigningPolicyTokenNotAllowed, fmt.Errorf("token: %s", asset))
+				}
+				if !transfer.From.Equal(policySigner.GetPKH()) {
+					return nil, errors.Join(constants.ErrSigningPolicyNonTransferContent, fmt.Errorf("transfer of token %s from foreign address %s", asset, transfer.From))
+				}
+				if !policySigner.isDestinationAllowed(transfer.To) {
+					return nil, errors.Join(constants.ErrSigningPolicyDestinationNotAllowed, fmt.Errorf("token: %s, destination: %s", asset, transfer.To))
+				}
+				add(asset, transfer.To, transfer.Amount)
+			}
+		case *codec.Reveal:
+			// reveal of the payout wallet key is added by transactor if needed
+			if !content.PublicKey.Equal(policySigner.GetKey()) {
+				return nil, errors.Join(constants.ErrSigningPolicyNonTransferContent, errors.New("reveal of foreign key"))
+			}
+		default:
+			return nil, errors.Join(constants.ErrSigningPolicyNonTransferContent, fmt.Errorf("kind: %s", content.Kind()))
+		}
+	}
+
+	for asset, total := range totals {
+		limit := policySigner.limits[asset].operation
+		if !limit.IsZero() && limit.IsLess(total) {
+			return nil, errors.Join(constants.ErrSigningPolicyOperationCapExceeded, fmt.Errorf("asset: %s, amount: %s, limit: %s", asset, total, limit))
+		}
+		if !policySigner.limits[asset].cycle.IsZero() && len(policySigner.cycles) == 0 {
+			return nil, errors.Join(constants.ErrSigningPolicyCycleCapExceeded, fmt.Errorf("asset: %s, no cycle to account the amount to", asset))
+		}
+	}
+
+	counter := getOpCounter(op)
+	for cycle, assets := range amounts {
+		for asset, amount := range assets {
+			limit := policySigner.limits[asset].cycle
+			if limit.IsZero() {
+				continue
+			}
+			spent := policySigner.ledger.GetSpent(cycle, asset, counter).Add(amount)
+			if limit.IsLess(spent) {
+				return nil, errors.Join(constants.ErrSigningPolicyCycleCapExceeded, fmt.Errorf("cycle: %d, asset: %s, amount: %s, limit: %s", cycle, asset, spent, limit))
+			}
+		}
+	}
+	return amounts, nil
+}
+
+func getOpCounter(op *codec.Op) int64 {
+	if len(op.Contents) == 0 {
+		return 0
+	}
+	return op.Contents[0].GetCounter()
+}
+
+func (policySigner *PolicySigner) Sign(op *codec.Op) error {
+	policySigner.mtx.Lock()
+	defer policySigner.mtx.Unlock()
+
+	if policySigner.ledgerErr != nil {
+		return errors.Join(constants.ErrSigningPolicyViolation, constants.ErrSigningPolicyLedgerLoadFailed, policySigner.ledgerErr)
+	}
+	amounts, err := policySigner.checkOp(op)
+	if err != nil {
+		slog.Warn("refusing to sign operation", "error", err.Error(), "cycles", policySigner.cycles)
+		return errors.Join(constants.ErrSigningPolicyViolation, err)
+	}
+	if err := policySigner.SignerEngine.Sign(op); err != nil {
+		return err
+	}
+	policySigner.ledger.Record(getOpCounter(op), amounts)
+	if policySigner.reporter != nil {
+		// signature must not be used unless the signed amounts are persisted
+		if err := policySigner.reporter.ReportSigningPolicyLedger(policySigner.ledger); err != nil {
+			return errors.Join(constants.ErrSigningPolicyLedgerWriteFailed, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package signer_engines
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+// ledgerReporter keeps the signing policy ledger serialized like fs reporter does
+type ledgerReporter struct {
+	ledger    []byte
+	failWrite bool
+}
+
+func (engine *ledgerReporter) GetExistingReports(cycle int64) ([]common.PayoutReport, error) {
+	return []common.PayoutReport{}, nil
+}
+
+func (engine *ledgerReporter) ReportPayouts(reports []common.PayoutReport) error {
+	return nil
+}
+
+func (engine *ledgerReporter) ReportInvalidPayouts(reports []common.PayoutRecipe) error {
+	return nil
+}
+
+func (engine *ledgerReporter) ReportCycleSummary(summary common.CyclePayoutSummary) error {
+	return nil
+}
+
+func (engine *ledgerReporter) GetExistingCycleSummary(cycle int64) (*common.CyclePayoutSummary, error) {
+	return nil, nil
+}
+
+func (engine *ledgerReporter) GetSigningPolicyLedger() (*common.SigningPolicyLedger, error) {
+	ledger := common.NewSigningPolicyLedger()
+	if engine.ledger == nil {
+		return ledger, nil
+	}
+	return ledger, json.Unmarshal(engine.ledger, ledger)
+}
+
+func (engine *ledgerReporter) ReportSigningPolicyLedger(ledger *common.SigningPolicyLedger) error {
+	if engine.failWrite {
+		return errors.New("disk full")
+	}
+	data, err := json.Marshal(ledger)
+	engine.ledger = data
+	return err
+}
+
+type testTransfer struct {
+	kind        enums.EPayoutTransactionKind
+	contract    mavryk.Address
+	tokenId     int64
+	destination mavryk.Address
+	amount      int64
+}
+
+func (tx testTransfer) GetTxKind() enums.EPayoutTransactionKind { return tx.kind }
+func (tx testTransfer) GetFAContract() mavryk.Address           { return tx.contract }
+func (tx testTransfer) GetFATokenId() mavryk.Z                  { return mavryk.NewZ(tx.tokenId) }
+func (tx testTransfer) GetDestination() mavryk.Address          { return tx.destination }
+func (tx testTransfer) GetAmount() mavryk.Z                     { return mavryk.NewZ(tx.amount) }
+
+func TestPolicySigner(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+	assert.Nil(err)
+	recipient := mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g")
+	allowed := mavryk.MustParseAddress("mv1DsVn1LCaMTS3DjpA3JRZWGcvAeFRqzaLa")
+	foreign := mavryk.MustParseAddress("mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb")
+	faContract := mavryk.MustParseAddress("KT1UB2Qq7F7kXbDnojXWnGUpsFkuo8hgiXfX")
+
+	signer := NewPolicySigner(&InMemorySigner{Key: key}, &common.SigningPolicy{
+		MaximumOperationAmount: mavryk.NewZ(1000),
+		MaximumCycleAmount:     mavryk.NewZ(1500),
+		AllowedDestinations:    []mavryk.Address{recipient, allowed},
+		TokenLimits: []common.SigningPolicyTokenLimit{
+			{Contract: faContract, TokenId: 1, MaximumOperationAmount: mavryk.NewZ(100), MaximumCycleAmount: mavryk.NewZ(150)},
+		},
+	})
+	signer.SetPayoutBlueprints([]*common.CyclePayoutBlueprint{{
+		Cycle: 10,
+		// blueprints do not extend allowed destinations, they may be altered by extensions
+		Payouts: []common.PayoutRecipe{{Recipient: foreign, TxKind: enums.PAYOUT_TX_KIND_MAV}},
+	}}, nil)
+
+	newOp := func(transfers ...testTransfer) *codec.Op {
+		op := codec.NewOp().WithSource(key.Address()).WithBranch(mavryk.MustParseBlockHash("BM4VEjb3EGdgNgJhwfVUsUqPYvZWJUHdmKKgabuDkwy6SmUKDve"))
+		for _, transfer := range transfers {
+			assert.Nil(common.InjectTransferContents(op, key.Address(), transfer))
+		}
+		return op
+	}
+	mav := func(destination mavryk.Address, amount int64) testTransfer {
+		return testTransfer{kind: enums.PAYOUT_TX_KIND_MAV, destination: destination, amount: amount}
+	}
+	fa2 := func(tokenId int64, destination mavryk.Address, amount int64) testTransfer {
+		return testTransfer{kind: enums.PAYOUT_TX_KIND_FA2, contract: faContract, tokenId: tokenId, destination: destination, amount: amount}
+	}
+
+	assert.Nil(signer.Sign(newOp(mav(recipient, 600), mav(allowed, 100))))
+	// destination outside of allow-list even though it is in the blueprint
+	assert.ErrorIs(signer.Sign(newOp(mav(foreign, 1))), constants.ErrSigningPolicyDestinationNotAllowed)
+	// operation cap
+	assert.ErrorIs(signer.Sign(newOp(mav(recipient, 1001))), constants.ErrSigningPolicyOperationCapExceeded)
+	// cycle cap - 700 already signed
+	assert.ErrorIs(signer.Sign(newOp(mav(recipient, 900))), constants.ErrSigningPolicyCycleCapExceeded)
+	assert.Nil(signer.Sign(newOp(mav(recipient, 800))))
+	// non-transfer contents
+	assert.ErrorIs(signer.Sign(newOp().WithDelegation(recipient)), constants.ErrSigningPolicyNonTransferContent)
+	assert.ErrorIs(signer.Sign(newOp(mav(recipient, 1))), constants.ErrSigningPolicyViolation)
+
+	// token transfers are decoded and checked against token limits and allowed destinations
+	assert.Nil(signer.Sign(newOp(fa2(1, recipient, 100))))
+	assert.ErrorIs(signer.Sign(newOp(fa2(1, foreign, 1))), constants.ErrSigningPolicyDestinationNotAllowed)
+	assert.ErrorIs(signer.Sign(newOp(fa2(2, recipient, 1))), constants.ErrSigningPolicyTokenNotAllowed)
+	assert.ErrorIs(signer.Sign(newOp(fa2(1, recipient, 101))), constants.ErrSigningPolicyOperationCapExceeded)
+	assert.ErrorIs(signer.Sign(newOp(fa2(1, recipient, 60))), constants.ErrSigningPolicyCycleCapExceeded)
+	assert.Nil(signer.Sign(newOp(fa2(1, allowed, 50))))
+	// FA1.2 transfers are token 0 of the contract
+	fa1 := testTransfer{kind: enums.PAYOUT_TX_KIND_FA1_2, contract: faContract, destination: recipient, amount: 1}
+	assert.ErrorIs(signer.Sign(newOp(fa1)), constants.ErrSigningPolicyTokenNotAllowed)
+
+	// other calls of the token contract are refused
+	call := newOp(fa2(1, recipient, 1))
+	call.Contents[0].(*codec.Transaction).Parameters.Entrypoint = "update_operators"
+	assert.ErrorIs(signer.Sign(call), constants.ErrSigningPolicyNonTransferContent)
+
+	// no cycle to account amounts to
+	signer.SetPayoutBlueprints(nil, nil)
+	assert.ErrorIs(signer.Sign(newOp(mav(recipient, 1))), constants.ErrSigningPolicyCycleCapExceeded)
+}
+
+func TestPolicySignerCycleAmounts(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+	assert.Nil(err)
+	recipient := mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g")
+	other := mavryk.MustParseAddress("mv1DsVn1LCaMTS3DjpA3JRZWGcvAeFRqzaLa")
+	policy := &common.SigningPolicy{MaximumCycleAmount: mavryk.NewZ(1000)}
+	blueprints := []*common.CyclePayoutBlueprint{
+		{Cycle: 10, Payouts: []common.PayoutRecipe{{Recipient: recipient, TxKind: enums.PAYOUT_TX_KIND_MAV, Amount: mavryk.NewZ(900)}}},
+		{Cycle: 11, Payouts: []common.PayoutRecipe{{Recipient: recipient, TxKind: enums.PAYOUT_TX_KIND_MAV, Amount: mavryk.NewZ(300)}}},
+	}
+	newOp := func(destination mavryk.Address, amount int64, counter int64) *codec.Op {
+		op := codec.NewOp().WithSource(key.Address()).WithBranch(mavryk.MustParseBlockHash("BM4VEjb3EGdgNgJhwfVUsUqPYvZWJUHdmKKgabuDkwy6SmUKDve")).WithTransfer(destination, amount)
+		op.Contents[0].WithCounter(counter)
+		return op
+	}
+
+	reporter := &ledgerReporter{}
+	signer := NewPolicySigner(&InMemorySigner{Key: key}, policy)
+	signer.SetPayoutBlueprints(blueprints, reporter)
+	// combined payout of two cycles fits, each cycle is checked against its own limit instead of 2x limit
+	assert.Nil(signer.Sign(newOp(recipient, 1200, 1)))
+	// amounts outside of blueprints are accounted to the latest cycle - 300 of 1000 used
+	assert.ErrorIs(signer.Sign(newOp(other, 701, 2)), constants.ErrSigningPolicyCycleCapExceeded)
+	assert.Nil(signer.Sign(newOp(other, 700, 2)))
+	// replacement reusing the counter counts only once
+	assert.Nil(signer.Sign(newOp(other, 700, 2)))
+
+	// amounts are kept across runs
+	signer = NewPolicySigner(&InMemorySigner{Key: key}, policy)
+	signer.SetPayoutBlueprints(blueprints[:1], reporter)
+	assert.ErrorIs(signer.Sign(newOp(recipient, 101, 3)), constants.ErrSigningPolicyCycleCapExceeded)
+	assert.Nil(signer.Sign(newOp(recipient, 100, 3)))
+
+	// signature is not returned unless the amounts are persisted
+	reporter.failWrite = true
+	signer.SetPayoutBlueprints([]*common.CyclePayoutBlueprint{{Cycle: 12}}, reporter)
+	assert.ErrorIs(signer.Sign(newOp(other, 1, 4)), constants.ErrSigningPolicyLedgerWriteFailed)
+
+	// broken ledger refuses everything
+	reporter.ledger = []byte("{")
+	signer.SetPayoutBlueprints(blueprints, reporter)
+	assert.ErrorIs(signer.Sign(newOp(recipient, 1, 5)), constants.ErrSigningPolicyLedgerLoadFailed)
+}
@@ -0,0 +1,81 @@
+package transactor_engines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	mavpay_configuration "github.com/mavryk-network/mavpay/configuration/v"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+)
+
+const (
+	BROADCAST_TIMEOUT = 30 * time.Second
+)
+
+type broadcastNode struct {
+	url string
+	rpc *rpc.Client
+}
+
+type broadcastResult struct {
+	url    string
+	opHash mavryk.OpHash
+	err    error
+}
+
+func initBroadcastNodes(network *mavpay_configuration.MavrykNetworkConfigurationV0) ([]broadcastNode, error) {
+	nodes := make([]broadcastNode, 0, len(network.BroadcastEndpoints))
+	for _, endpoint := range network.BroadcastEndpoints {
+		httpClient, err := network.Http.Merge(endpoint.Http).NewClient(BROADCAST_TIMEOUT)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("invalid broadcast endpoint '%s'", endpoint.Url), err)
+		}
+		client, err := rpc.NewClient(endpoint.Url, httpClient)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("invalid broadcast endpoint '%s'", endpoint.Url), err)
+		}
+		nodes = append(nodes, broadcastNode{url: endpoint.Url, rpc: client})
+	}
+	return nodes, nil
+}
+
+// broadcastToNodes injects the operation to all nodes at once and returns the first accepted operation hash.
+// Results of all nodes are logged, including the ones arriving after the operation was accepted.
+func broadcastToNodes(nodes []broadcastNode, op *codec.Op) (mavryk.OpHash, error) {
+	results := make(chan broadcastResult, len(nodes))
+	for _, node := range nodes {
+		go func(node broadcastNode) {
+			opHash, err := node.rpc.Broadcast(context.Background(), op)
+			results <- broadcastResult{url: node.url, opHash: opHash, err: err}
+		}(node)
+	}
+
+	logResult := func(result broadcastResult) {
+		if result.err != nil {
+			slog.Warn("node rejected operation", "node", result.url, "error", result.err.Error())
+			return
+		}
+		slog.Debug("node accepted operation", "node", result.url, "op_hash", result.opHash)
+	}
+
+	errs := make([]error, 0, len(nodes))
+	for received := 0; received < len(nodes); received++ {
+		result := <-results
+		logResult(result)
+		if result.err == nil {
+			go func(remaining int) {
+				for i := 0; i < remaining; i++ {
+					logResult(<-results)
+				}
+			}(len(nodes) - received - 1)
+			return result.opHash, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", result.url, result.err))
+	}
+	return mavryk.ZeroOpHash, errors.Join(errs...)
+}
@@ -0,0 +1,138 @@
+package transactor_engines
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeInjectionNode answers operation injections with fixed status and body after delay and keeps injected operations
+type fakeInjectionNode struct {
+	mtx      sync.Mutex
+	status   int
+	body     string
+	delay    time.Duration
+	injected []string
+}
+
+func (node *fakeInjectionNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/injection/operation") {
+		http.NotFound(w, r)
+		return
+	}
+	data, _ := io.ReadAll(r.Body)
+	node.mtx.Lock()
+	node.injected = append(node.injected, string(data))
+	node.mtx.Unlock()
+
+	time.Sleep(node.delay)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(node.status)
+	w.Write([]byte(node.body))
+}
+
+func (node *fakeInjectionNode) getInjected() []string {
+	node.mtx.Lock()
+	defer node.mtx.Unlock()
+	return append([]string{}, node.injected...)
+}
+
+func newAcceptingNode(opHash string, delay time.Duration) *fakeInjectionNode {
+	return &fakeInjectionNode{status: http.StatusOK, body: `"` + opHash + `"`, delay: delay}
+}
+
+func newRejectingNode(delay time.Duration) *fakeInjectionNode {
+	return &fakeInjectionNode{status: http.StatusInternalServerError, body: `[{"kind":"temporary","id":"failure","msg":"rejected"}]`, delay: delay}
+}
+
+func startBroadcastNodes(t *testing.T, handlers ...*fakeInjectionNode) []broadcastNode {
+	nodes := make([]broadcastNode, 0, len(handlers))
+	for _, handler := range handlers {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		client, err := rpc.NewClient(server.URL, http.DefaultClient)
+		assert.Nil(t, err)
+		nodes = append(nodes, broadcastNode{url: server.URL, rpc: client})
+	}
+	return nodes
+}
+
+func newSignedTestOp(t *testing.T) *codec.Op {
+	key, err := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+	assert.Nil(t, err)
+	op := codec.NewOp().
+		WithSource(key.Address()).
+		WithBranch(mavryk.MustParseBlockHash("BM4VEjb3EGdgNgJhwfVUsUqPYvZWJUHdmKKgabuDkwy6SmUKDve")).
+		WithTransfer(mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g"), 1000)
+	op.Contents[0].WithCounter(1)
+	assert.Nil(t, op.Sign(key))
+	return op
+}
+
+func TestBroadcastToNodesFansOut(t *testing.T) {
+	assert := assert.New(t)
+
+	handlers := []*fakeInjectionNode{
+		newRejectingNode(0),
+		newAcceptingNode("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ", 0),
+		newAcceptingNode("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ", 100*time.Millisecond),
+	}
+	nodes := startBroadcastNodes(t, handlers...)
+
+	opHash, err := broadcastToNodes(nodes, newSignedTestOp(t))
+	assert.Nil(err)
+	assert.Equal("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ", opHash.String())
+
+	// every node receives the same operation, including the ones answering after the operation was accepted
+	assert.Eventually(func() bool {
+		for _, handler := range handlers {
+			if len(handler.getInjected()) != 1 {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	for _, handler := range handlers[1:] {
+		assert.Equal(handlers[0].getInjected(), handler.getInjected())
+	}
+}
+
+func TestBroadcastToNodesReturnsFirstAccepted(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := startBroadcastNodes(t,
+		newAcceptingNode("ooUkGSF79MoFXdKFjw514YKxP4VHqMhoQUN29s5hkqwLNDGSXDc", 500*time.Millisecond),
+		newRejectingNode(0),
+		newAcceptingNode("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ", 0),
+	)
+
+	start := time.Now()
+	opHash, err := broadcastToNodes(nodes, newSignedTestOp(t))
+	assert.Nil(err)
+	assert.Equal("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ", opHash.String())
+	// slow node is not waited for
+	assert.Less(time.Since(start), 500*time.Millisecond)
+}
+
+func TestBroadcastToNodesAllRejected(t *testing.T) {
+	assert := assert.New(t)
+
+	nodes := startBroadcastNodes(t, newRejectingNode(0), newRejectingNode(50*time.Millisecond))
+
+	opHash, err := broadcastToNodes(nodes, newSignedTestOp(t))
+	assert.NotNil(err)
+	assert.Equal(mavryk.ZeroOpHash, opHash)
+	// errors of all nodes are reported
+	for _, node := range nodes {
+		assert.Contains(err.Error(), node.url)
+	}
+}
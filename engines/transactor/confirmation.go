@@ -0,0 +1,192 @@
+package transactor_engines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+)
+
+const (
+	CONFIRMATION_POLL_INTERVAL = 5 * time.Second
+	OPERATION_LOOKUP_DEPTH     = int64(120)
+)
+
+type rpcBlockHeader struct {
+	Hash  string `json:"hash"`
+	Level int64  `json:"level"`
+}
+
+type rpcOperationResult struct {
+	Status string `json:"status"`
+}
+
+type rpcOperation struct {
+	Hash     string `json:"hash"`
+	Contents []struct {
+		Metadata struct {
+			OperationResult *rpcOperationResult `json:"operation_result,omitempty"`
+		} `json:"metadata"`
+	} `json:"contents"`
+}
+
+func getBlockHeader(ctx context.Context, client *rpc.Client, block string) (*rpcBlockHeader, error) {
+	var header rpcBlockHeader
+	if err := client.Get(ctx, fmt.Sprintf("chains/main/blocks/%s/header", block), &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// findOperationInBlock returns status of the operation if the block includes it, OPERATION_STATUS_NOT_EXISTS otherwise
+func findOperationInBlock(ctx context.Context, client *rpc.Client, blockHash string, opHash mavryk.OpHash) (common.OperationStatus, error) {
+	var operations []rpcOperation
+	// manager operations are in the 4th validation pass
+	if err := client.Get(ctx, fmt.Sprintf("chains/main/blocks/%s/operations/3", blockHash), &operations); err != nil {
+		return common.OPERATION_STATUS_UNKNOWN, err
+	}
+	for _, op := range operations {
+		if op.Hash != opHash.String() {
+			continue
+		}
+		for _, content := range op.Contents {
+			if result := content.Metadata.OperationResult; result != nil && result.Status != "applied" {
+				return common.OPERATION_STATUS_FAILED, nil
+			}
+		}
+		return common.OPERATION_STATUS_APPLIED, nil
+	}
+	return common.OPERATION_STATUS_NOT_EXISTS, nil
+}
+
+// findOperationInRecentBlocks searches last OPERATION_LOOKUP_DEPTH blocks of the main chain for the operation
+func findOperationInRecentBlocks(ctx context.Context, client *rpc.Client, opHash mavryk.OpHash) (common.OperationStatus, error) {
+	for offset := int64(0); offset < OPERATION_LOOKUP_DEPTH; offset++ {
+		header, err := getBlockHeader(ctx, client, fmt.Sprintf("head~%d", offset))
+		if err != nil {
+			return common.OPERATION_STATUS_UNKNOWN, errors.Join(constants.ErrOperationStatusCheckFailed, err)
+		}
+		status, err := findOperationInBlock(ctx, client, header.Hash, opHash)
+		if err != nil {
+			return common.OPERATION_STATUS_UNKNOWN, errors.Join(constants.ErrOperationStatusCheckFailed, err)
+		}
+		if status != common.OPERATION_STATUS_NOT_EXISTS {
+			return status, nil
+		}
+	}
+	return common.OPERATION_STATUS_NOT_EXISTS, nil
+}
+
+// ConfirmationTracker scans blocks of the main chain on node rpc for the operation and waits for the required number of blocks
+// on top of the including block. If the including block is reorganized away, the operation is pending again until it is
+// included in another block.
+type ConfirmationTracker struct {
+	rpc           *rpc.Client
+	opHash        mavryk.OpHash
+	validUntil    int64 // last level the operation can be included at, given by its branch
+	confirmations int64
+	startLevel    int64
+	pollInterval  time.Duration
+}
+
+// NewConfirmationTracker starts tracking at the current head, it has to be created right after the operation is injected.
+// validUntil is the last level the operation can be included at - level of its branch + max operations ttl.
+func NewConfirmationTracker(client *rpc.Client, opHash mavryk.OpHash, validUntil int64, confirmations int64) (*ConfirmationTracker, error) {
+	head, err := getBlockHeader(context.Background(), client, "head")
+	if err != nil {
+		return nil, err
+	}
+	return &ConfirmationTracker{
+		rpc:           client,
+		opHash:        opHash,
+		validUntil:    validUntil,
+		confirmations: confirmations,
+		startLevel:    head.Level,
+		pollInterval:  CONFIRMATION_POLL_INTERVAL,
+	}, nil
+}
+
+// getForkLevel returns the first level below the reorganized one whose block differs from the scanned one,
+// the operation might have been included in any block of the new branch. Falls back to the start level.
+func (tracker *ConfirmationTracker) getForkLevel(ctx context.Context, scanned map[int64]string, reorganizedLevel int64) int64 {
+	for level := reorganizedLevel - 1; level >= tracker.startLevel; level-- {
+		current, err := getBlockHeader(ctx, tracker.rpc, fmt.Sprint(level))
+		if err != nil {
+			return tracker.startLevel
+		}
+		if current.Hash == scanned[level] {
+			return level + 1
+		}
+	}
+	return tracker.startLevel
+}
+
+func (tracker *ConfirmationTracker) Wait(ctx context.Context) error {
+	logger := slog.With("op_hash", tracker.opHash)
+	nextLevel := tracker.startLevel
+	var inclusion *rpcBlockHeader
+	status := common.OPERATION_STATUS_UNKNOWN
+	// hashes of scanned blocks by level, to find where the chain forked on reorganization
+	scanned := make(map[int64]string)
+
+	for ; ctx.Err() == nil; utils.SleepContext(ctx, tracker.pollInterval) {
+		head, err := getBlockHeader(ctx, tracker.rpc, "head")
+		if err != nil {
+			logger.Debug("failed to get head", "error", err.Error())
+			continue
+		}
+
+		if inclusion != nil {
+			current, err := getBlockHeader(ctx, tracker.rpc, fmt.Sprint(inclusion.Level))
+			if err != nil {
+				logger.Debug("failed to get block", "level", inclusion.Level, "error", err.Error())
+				continue
+			}
+			if current.Hash != inclusion.Hash {
+				logger.Warn("block including operation was reorganized away, waiting for the operation to be included again", "level", inclusion.Level, "block", inclusion.Hash)
+				nextLevel = tracker.getForkLevel(ctx, scanned, inclusion.Level)
+				inclusion = nil
+			}
+		}
+
+		for ; inclusion == nil && nextLevel <= head.Level; nextLevel++ {
+			block, err := getBlockHeader(ctx, tracker.rpc, fmt.Sprint(nextLevel))
+			if err != nil {
+				logger.Debug("failed to get block", "level", nextLevel, "error", err.Error())
+				break
+			}
+			status, err = findOperationInBlock(ctx, tracker.rpc, block.Hash, tracker.opHash)
+			if err != nil {
+				logger.Debug("failed to get block operations", "level", nextLevel, "error", err.Error())
+				break
+			}
+			scanned[block.Level] = block.Hash
+			if status != common.OPERATION_STATUS_NOT_EXISTS {
+				logger.Debug("operation included", "level", block.Level, "block", block.Hash, "status", status)
+				inclusion = block
+			}
+		}
+
+		if inclusion == nil {
+			if head.Level > tracker.validUntil {
+				return errors.Join(constants.ErrOperationNotIncluded, fmt.Errorf("not included until branch expiry at level %d", tracker.validUntil))
+			}
+			continue
+		}
+		if head.Level-inclusion.Level < tracker.confirmations {
+			continue
+		}
+		if status == common.OPERATION_STATUS_FAILED {
+			return constants.ErrOperationFailed
+		}
+		return nil
+	}
+	return ctx.Err()
+}
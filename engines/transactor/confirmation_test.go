@@ -0,0 +1,219 @@
+package transactor_engines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeChain serves block headers and manager operations of the main chain, blocks are identified by level and fork
+type fakeChain struct {
+	mtx        sync.Mutex
+	blocks     []string            // hashes of the main chain by level
+	operations map[string][]string // operation hashes by block hash
+	onPoll     func(chain *fakeChain)
+}
+
+func (chain *fakeChain) addBlock(fork string, operations ...string) {
+	hash := fmt.Sprintf("%s-%d", fork, len(chain.blocks))
+	chain.blocks = append(chain.blocks, hash)
+	chain.operations[hash] = operations
+}
+
+func (chain *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	chain.mtx.Lock()
+	defer chain.mtx.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/chains/main/blocks/")
+	parts := strings.Split(path, "/")
+	block := parts[0]
+	hash := block
+	if block == "head" {
+		if chain.onPoll != nil {
+			chain.onPoll(chain)
+		}
+		hash = chain.blocks[len(chain.blocks)-1]
+	} else if level, err := strconv.Atoi(block); err == nil {
+		if level >= len(chain.blocks) {
+			http.NotFound(w, r)
+			return
+		}
+		hash = chain.blocks[level]
+	}
+	if parts[1] == "header" {
+		level := 0
+		for i, h := range chain.blocks {
+			if h == hash {
+				level = i
+			}
+		}
+		json.NewEncoder(w).Encode(rpcBlockHeader{Hash: hash, Level: int64(level)})
+		return
+	}
+	result := make([]map[string]any, 0)
+	for _, opHash := range chain.operations[hash] {
+		result = append(result, map[string]any{
+			"hash":     opHash,
+			"contents": []any{map[string]any{"metadata": map[string]any{"operation_result": map[string]any{"status": "applied"}}}},
+		})
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func TestConfirmationTrackerReorg(t *testing.T) {
+	assert := assert.New(t)
+
+	opHash := mavryk.MustParseOpHash("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ")
+	chain := &fakeChain{operations: make(map[string][]string)}
+	chain.addBlock("a")
+	server := httptest.NewServer(chain)
+	defer server.Close()
+	client, err := rpc.NewClient(server.URL, http.DefaultClient)
+	assert.Nil(err)
+
+	tracker, err := NewConfirmationTracker(client, opHash, 10, 2)
+	assert.Nil(err)
+	tracker.pollInterval = time.Millisecond
+
+	polls := 0
+	reorganized := false
+	chain.onPoll = func(chain *fakeChain) {
+		polls++
+		switch {
+		case polls == 2:
+			chain.addBlock("a", opHash.String())
+		case polls == 3:
+			chain.addBlock("a")
+		case polls == 4 && !reorganized:
+			// including block replaced before reaching required confirmations
+			reorganized = true
+			chain.blocks = chain.blocks[:1]
+			chain.addBlock("b")
+			chain.addBlock("b")
+		case polls == 6:
+			chain.addBlock("b", opHash.String())
+		case polls > 6:
+			chain.addBlock("b")
+		}
+	}
+
+	assert.Nil(tracker.Wait(context.Background()))
+	assert.True(reorganized)
+	chain.mtx.Lock()
+	defer chain.mtx.Unlock()
+	assert.Equal("b-3", chain.blocks[3])
+	assert.GreaterOrEqual(len(chain.blocks), 6)
+}
+
+func TestConfirmationTrackerReorgBelowInclusion(t *testing.T) {
+	assert := assert.New(t)
+
+	opHash := mavryk.MustParseOpHash("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ")
+	chain := &fakeChain{operations: make(map[string][]string)}
+	chain.addBlock("a")
+	server := httptest.NewServer(chain)
+	defer server.Close()
+	client, err := rpc.NewClient(server.URL, http.DefaultClient)
+	assert.Nil(err)
+
+	tracker, err := NewConfirmationTracker(client, opHash, 10, 2)
+	assert.Nil(err)
+	tracker.pollInterval = time.Millisecond
+
+	polls := 0
+	reorganized := false
+	chain.onPoll = func(chain *fakeChain) {
+		polls++
+		switch {
+		case polls == 2:
+			chain.addBlock("a")
+		case polls == 3:
+			chain.addBlock("a", opHash.String())
+		case polls == 4 && !reorganized:
+			// new branch forks below the including block and includes the operation earlier
+			reorganized = true
+			chain.blocks = chain.blocks[:1]
+			chain.addBlock("b", opHash.String())
+			chain.addBlock("b")
+			chain.addBlock("b")
+		case polls > 4:
+			chain.addBlock("b")
+		}
+	}
+
+	assert.Nil(tracker.Wait(context.Background()))
+	assert.True(reorganized)
+}
+
+func TestConfirmationTrackerNotIncluded(t *testing.T) {
+	assert := assert.New(t)
+
+	opHash := mavryk.MustParseOpHash("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ")
+	chain := &fakeChain{operations: make(map[string][]string)}
+	chain.addBlock("a")
+	chain.onPoll = func(chain *fakeChain) { chain.addBlock("a") }
+	server := httptest.NewServer(chain)
+	defer server.Close()
+	client, err := rpc.NewClient(server.URL, http.DefaultClient)
+	assert.Nil(err)
+
+	tracker, err := NewConfirmationTracker(client, opHash, 3, 2)
+	assert.Nil(err)
+	tracker.pollInterval = time.Millisecond
+	assert.ErrorIs(tracker.Wait(context.Background()), constants.ErrOperationNotIncluded)
+}
+
+func TestConfirmationTrackerReorgAfterBranchExpiry(t *testing.T) {
+	assert := assert.New(t)
+
+	opHash := mavryk.MustParseOpHash("op69Poea7LNEZPbcxwrzJb2ub57dNo6L1vsn3fjWoJ3qj6KYyFQ")
+	chain := &fakeChain{operations: make(map[string][]string)}
+	chain.addBlock("a")
+	server := httptest.NewServer(chain)
+	defer server.Close()
+	client, err := rpc.NewClient(server.URL, http.DefaultClient)
+	assert.Nil(err)
+
+	tracker, err := NewConfirmationTracker(client, opHash, 3, 5)
+	assert.Nil(err)
+	tracker.pollInterval = time.Millisecond
+
+	polls := 0
+	reorganized := false
+	chain.onPoll = func(chain *fakeChain) {
+		polls++
+		switch {
+		case polls == 2:
+			chain.addBlock("a", opHash.String())
+		case polls == 3 && !reorganized:
+			// including block replaced after the branch expired, operation can not be included again
+			reorganized = true
+			chain.blocks = chain.blocks[:1]
+			chain.addBlock("b")
+			chain.addBlock("b")
+			chain.addBlock("b")
+			chain.addBlock("b")
+		case polls > 3:
+			chain.addBlock("b")
+		}
+	}
+
+	assert.ErrorIs(tracker.Wait(context.Background()), constants.ErrOperationNotIncluded)
+	assert.True(reorganized)
+	chain.mtx.Lock()
+	defer chain.mtx.Unlock()
+	// waiting does not continue for another ttl after the reorganization
+	assert.Len(chain.blocks, 5)
+}
@@ -2,14 +2,14 @@ package transactor_engines
 
 import (
 	"context"
+	"errors"
 	"log/slog"
-	"net/http"
 	"time"
 
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/configuration"
 	"github.com/mavryk-network/mavpay/constants"
-	"github.com/mavryk-network/mavpay/engines/mvkt"
+	"github.com/mavryk-network/mavpay/transport"
 	"github.com/mavryk-network/mavpay/utils"
 	"github.com/mavryk-network/mvgo/codec"
 	"github.com/mavryk-network/mvgo/mavryk"
@@ -19,14 +19,16 @@ import (
 type DefaultRpcTransactor struct {
 	rpcUrl string
 	rpc    *rpc.Client
-	mvkt   *mvkt.Client
+
+	// number of blocks on top of the including block required by Dispatch results
+	confirmations int64
+	// rpc_url and additional nodes operations are injected to at once, empty if no additional node is configured
+	broadcastNodes []broadcastNode
 }
 
 type DefaultRpcTransactorOpResult struct {
-	opHash mavryk.OpHash
-	result *rpc.Result
-	rpc    *rpc.Client
-	mvkt   *mvkt.Client
+	opHash  mavryk.OpHash
+	tracker *ConfirmationTracker
 }
 
 func (result *DefaultRpcTransactorOpResult) GetOpHash() mavryk.OpHash {
@@ -35,72 +37,42 @@ func (result *DefaultRpcTransactorOpResult) GetOpHash() mavryk.OpHash {
 
 func (result *DefaultRpcTransactorOpResult) WaitForApply() error {
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	utils.CallbackOnInterrupt(ctx, func() {
 		slog.Warn("waiting for confirmation canceled", "op_hash", result.opHash)
 		cancel()
 	})
-	appliedChan := make(chan common.OperationStatus, 1)
-	go func() {
-		utils.SleepContext(ctx, 130*time.Second) //give monitor 4 blocks before fallback kicks in
-		if ctx.Err() != context.Canceled {
-			slog.Debug(`failed to confirm with live monitoring, falling back to polling`)
-		}
-		for ctx.Err() != context.Canceled {
-			applied, _ := result.mvkt.WasOperationApplied(ctx, result.opHash)
-			slog.Debug("operation status checked", "op_hash", result.opHash, "applied", applied)
-			if applied == common.OPERATION_STATUS_APPLIED || applied == common.OPERATION_STATUS_FAILED {
-				cancel()
-				appliedChan <- applied
-				break
-			}
-			time.Sleep(15 * time.Second)
-		}
-		close(appliedChan)
-	}()
-	result.result.WaitContext(ctx)
-	cancel() // cancel fallback
-	switch <-appliedChan {
-	case common.OPERATION_STATUS_FAILED:
-		return constants.ErrOperationFailed
-	case common.OPERATION_STATUS_APPLIED:
-		return nil
-	}
-	if err := result.result.Err(); err != nil {
-		return err
-	}
-	rcpt, err := result.result.GetReceipt(context.Background())
-	result.rpc.Close()
-	if err != nil {
-		return err
-	}
-	if rcpt.IsSuccess() {
-		return nil
-	}
-	return rcpt.Error()
+	return result.tracker.Wait(ctx)
 }
 
 func InitDefaultTransactor(config *configuration.RuntimeConfiguration) (*DefaultRpcTransactor, error) {
-	client := &http.Client{
-		Timeout: 10 * 60 * time.Second,
+	pools, err := config.Network.GetEndpointPools()
+	if err != nil {
+		return nil, err
 	}
+	client := transport.NewHttpClient(10*60*time.Second, pools...)
 
 	rpcClient, err := rpc.NewClient(config.Network.RpcUrl, client)
 	if err != nil {
 		return nil, err
 	}
 
-	mvktClient, err := mvkt.InitClient(config.Network.MvktUrl, config.Network.ProtocolRewardsUrl, &mvkt.MvktClientOptions{
-		HttpClient:       client,
-		BalanceCheckMode: config.PayoutConfiguration.BalanceCheckMode,
-	})
-	if err != nil {
-		return nil, err
+	confirmations := constants.DEFAULT_REQUIRED_CONFIRMATIONS
+	if config.Network.RequiredConfirmations != nil {
+		confirmations = *config.Network.RequiredConfirmations
 	}
 
 	result := &DefaultRpcTransactor{
-		rpcUrl: config.Network.RpcUrl,
-		rpc:    rpcClient,
-		mvkt:   mvktClient,
+		rpcUrl:        config.Network.RpcUrl,
+		rpc:           rpcClient,
+		confirmations: confirmations,
+	}
+	if len(config.Network.BroadcastEndpoints) > 0 {
+		nodes, err := initBroadcastNodes(&config.Network)
+		if err != nil {
+			return nil, err
+		}
+		result.broadcastNodes = append([]broadcastNode{{url: config.Network.RpcUrl, rpc: rpcClient}}, nodes...)
 	}
 	return result, result.RefreshParams()
 }
@@ -132,6 +104,7 @@ func (transactor *DefaultRpcTransactor) GetLimits() (*common.OperationLimits, er
 		HardGasLimitPerOperation:     params.HardGasLimitPerBlock,
 		HardStorageLimitPerOperation: params.HardStorageLimitPerOperation,
 		MaxOperationDataLength:       params.MaxOperationDataLength,
+		MaxOperationsTTL:             params.MaxOperationsTTL,
 	}, nil
 }
 
@@ -141,48 +114,62 @@ func (transactor *DefaultRpcTransactor) Complete(op *codec.Op, key mavryk.Key) e
 	return err
 }
 
-func (transactor *DefaultRpcTransactor) initOpResult(opHash mavryk.OpHash, opts *rpc.CallOptions) (*DefaultRpcTransactorOpResult, error) {
-	if opts == nil {
-		opts = &rpc.DefaultOptions
-	}
-	rpcClient, err := transactor.GetNewRpcClient()
+func (transactor *DefaultRpcTransactor) initOpResult(opHash mavryk.OpHash, validUntil int64) (*DefaultRpcTransactorOpResult, error) {
+	tracker, err := NewConfirmationTracker(transactor.rpc, opHash, validUntil, transactor.confirmations)
 	if err != nil {
 		return nil, err
 	}
-	err = rpcClient.Init(context.Background())
-	if err != nil {
-		return nil, err
-	}
-	rpcClient.Listen()
-	res := rpc.NewResult(opHash).WithTTL(opts.TTL).WithConfirmations(opts.Confirmations)
-	res.Listen(rpcClient.BlockObserver)
 	return &DefaultRpcTransactorOpResult{
-		opHash: opHash,
-		result: res,
-		rpc:    rpcClient,
-		mvkt:   transactor.mvkt,
+		opHash:  opHash,
+		tracker: tracker,
 	}, nil
 }
 
 func (transactor *DefaultRpcTransactor) Broadcast(op *codec.Op) (mavryk.OpHash, error) {
+	if len(transactor.broadcastNodes) > 0 {
+		return broadcastToNodes(transactor.broadcastNodes, op)
+	}
 	return transactor.rpc.Broadcast(context.Background(), op)
 }
 
 func (transactor *DefaultRpcTransactor) Dispatch(op *codec.Op, opts *rpc.CallOptions) (common.OpResult, error) {
-	if opts == nil {
-		opts = &rpc.DefaultOptions
+	// operation can not be included once its branch expires, reorganizations do not extend it
+	validUntil, _, err := transactor.GetBranchValidity(op.Branch)
+	if err != nil {
+		return nil, errors.Join(constants.ErrOperationBranchLookupFailed, err)
 	}
 	opHash, err := transactor.Broadcast(op)
 	if err != nil {
 		return nil, err
 	}
-	result, err := transactor.initOpResult(opHash, opts)
+	result, err := transactor.initOpResult(opHash, validUntil)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
+func (transactor *DefaultRpcTransactor) GetOperationStatus(opHash mavryk.OpHash) (common.OperationStatus, error) {
+	return findOperationInRecentBlocks(context.Background(), transactor.rpc, opHash)
+}
+
+func (transactor *DefaultRpcTransactor) GetBranchValidity(branch mavryk.BlockHash) (int64, int64, error) {
+	ctx := context.Background()
+	header, err := getBlockHeader(ctx, transactor.rpc, branch.String())
+	if err != nil {
+		return 0, 0, err
+	}
+	head, err := getBlockHeader(ctx, transactor.rpc, "head")
+	if err != nil {
+		return 0, 0, err
+	}
+	params, err := transactor.rpc.GetParams(ctx, rpc.NewBlockOffset(rpc.Head, 0))
+	if err != nil {
+		return 0, 0, err
+	}
+	return header.Level + params.MaxOperationsTTL, head.Level, nil
+}
+
 func (transactor *DefaultRpcTransactor) Send(op *codec.Op, opts *rpc.CallOptions) (*rpc.Receipt, error) {
 	return transactor.rpc.Send(context.Background(), op, opts)
 }
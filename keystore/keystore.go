@@ -0,0 +1,193 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	KEYSTORE_VERSION = 1
+
+	KDF_ARGON2ID   = "argon2id"
+	CIPHER_AES_GCM = "aes-256-gcm"
+
+	// https://datatracker.ietf.org/doc/html/rfc9106#section-4 (second recommended option)
+	DEFAULT_ARGON2_TIME    = 3
+	DEFAULT_ARGON2_MEMORY  = 64 * 1024 // KiB
+	DEFAULT_ARGON2_THREADS = 4
+
+	SALT_LENGTH = 16
+	KEY_LENGTH  = 32
+)
+
+type KdfParams struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+	Salt    string `json:"salt"`
+}
+
+// Keystore holds secret encrypted with key derived from passphrase
+type Keystore struct {
+	Version    int       `json:"version"`
+	Kdf        string    `json:"kdf"`
+	KdfParams  KdfParams `json:"kdf_params"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+	// public information to identify the keystore without decrypting it
+	Address string `json:"address,omitempty"`
+}
+
+func deriveKey(passphrase []byte, params *KdfParams) ([]byte, error) {
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, errors.Join(constants.ErrInvalidKeystore, errors.New("invalid salt"))
+	}
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return nil, errors.Join(constants.ErrInvalidKeystore, errors.New("invalid kdf parameters"))
+	}
+	return argon2.IDKey(passphrase, salt, params.Time, params.Memory, params.Threads, KEY_LENGTH), nil
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt creates keystore of the secret protected by the passphrase
+func Encrypt(secret []byte, passphrase []byte, address string) (*Keystore, error) {
+	if len(passphrase) == 0 {
+		return nil, constants.ErrEmptyPassphrase
+	}
+	salt := make([]byte, SALT_LENGTH)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	params := KdfParams{
+		Time:    DEFAULT_ARGON2_TIME,
+		Memory:  DEFAULT_ARGON2_MEMORY,
+		Threads: DEFAULT_ARGON2_THREADS,
+		Salt:    hex.EncodeToString(salt),
+	}
+	key, err := deriveKey(passphrase, &params)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	keystore := &Keystore{
+		Version:   KEYSTORE_VERSION,
+		Kdf:       KDF_ARGON2ID,
+		KdfParams: params,
+		Cipher:    CIPHER_AES_GCM,
+		Nonce:     hex.EncodeToString(nonce),
+		Address:   address,
+	}
+	keystore.Ciphertext = hex.EncodeToString(gcm.Seal(nil, nonce, secret, keystore.getAdditionalData()))
+	return keystore, nil
+}
+
+// header fields are authenticated so they can not be tampered with
+func (keystore *Keystore) getAdditionalData() []byte {
+	return []byte(fmt.Sprintf("%d|%s|%s|%s", keystore.Version, keystore.Kdf, keystore.Cipher, keystore.Address))
+}
+
+// Decrypt returns the secret, ErrInvalidPassphrase is returned if the passphrase does not match
+func (keystore *Keystore) Decrypt(passphrase []byte) ([]byte, error) {
+	if keystore.Version != KEYSTORE_VERSION {
+		return nil, errors.Join(constants.ErrInvalidKeystore, fmt.Errorf("unsupported version %d", keystore.Version))
+	}
+	if keystore.Kdf != KDF_ARGON2ID || keystore.Cipher != CIPHER_AES_GCM {
+		return nil, errors.Join(constants.ErrInvalidKeystore, fmt.Errorf("unsupported kdf '%s' or cipher '%s'", keystore.Kdf, keystore.Cipher))
+	}
+	key, err := deriveKey(passphrase, &keystore.KdfParams)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(keystore.Nonce)
+	if err != nil || len(nonce) != gcm.NonceSize() {
+		return nil, errors.Join(constants.ErrInvalidKeystore, errors.New("invalid nonce"))
+	}
+	ciphertext, err := hex.DecodeString(keystore.Ciphertext)
+	if err != nil {
+		return nil, errors.Join(constants.ErrInvalidKeystore, errors.New("invalid ciphertext"))
+	}
+	secret, err := gcm.Open(nil, nonce, ciphertext, keystore.getAdditionalData())
+	if err != nil {
+		return nil, constants.ErrInvalidPassphrase
+	}
+	return secret, nil
+}
+
+// ChangePassphrase re-encrypts the keystore secret with the new passphrase
+func (keystore *Keystore) ChangePassphrase(passphrase []byte, newPassphrase []byte) (*Keystore, error) {
+	secret, err := keystore.Decrypt(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return Encrypt(secret, newPassphrase, keystore.Address)
+}
+
+func Load(keystorePath string) (*Keystore, error) {
+	data, err := os.ReadFile(keystorePath)
+	if err != nil {
+		return nil, errors.Join(constants.ErrKeystoreLoadFailed, err)
+	}
+	keystore := &Keystore{}
+	if err := json.Unmarshal(data, keystore); err != nil {
+		return nil, errors.Join(constants.ErrKeystoreLoadFailed, constants.ErrInvalidKeystore, err)
+	}
+	return keystore, nil
+}
+
+// Save writes the keystore readable only by the owner, existing keystore is replaced atomically
+func (keystore *Keystore) Save(keystorePath string) error {
+	data, err := json.MarshalIndent(keystore, "", "\t")
+	if err != nil {
+		return errors.Join(constants.ErrKeystoreSaveFailed, err)
+	}
+	tmp, err := os.CreateTemp(path.Dir(keystorePath), ".keystore-*")
+	if err != nil {
+		return errors.Join(constants.ErrKeystoreSaveFailed, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Join(constants.ErrKeystoreSaveFailed, err)
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return errors.Join(constants.ErrKeystoreSaveFailed, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Join(constants.ErrKeystoreSaveFailed, err)
+	}
+	if err := os.Rename(tmp.Name(), keystorePath); err != nil {
+		return errors.Join(constants.ErrKeystoreSaveFailed, err)
+	}
+	return nil
+}
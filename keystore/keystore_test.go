@@ -0,0 +1,75 @@
+package keystore
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeystoreRoundtrip(t *testing.T) {
+	assert := assert.New(t)
+
+	secret := []byte("edsk3nM41ygNfSxVU4w1uAW3G9EnTQEB5rjojeZedLTGmiGRcierVv")
+	keystore, err := Encrypt(secret, []byte("passphrase"), "mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g")
+	assert.Nil(err)
+
+	keystorePath := path.Join(t.TempDir(), "payout_wallet.keystore")
+	assert.Nil(keystore.Save(keystorePath))
+	info, err := os.Stat(keystorePath)
+	assert.Nil(err)
+	assert.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := Load(keystorePath)
+	assert.Nil(err)
+	decrypted, err := loaded.Decrypt([]byte("passphrase"))
+	assert.Nil(err)
+	assert.Equal(secret, decrypted)
+
+	_, err = loaded.Decrypt([]byte("wrong"))
+	assert.ErrorIs(err, constants.ErrInvalidPassphrase)
+
+	// tampering with authenticated header is detected
+	loaded.Address = "mv1DsVn1LCaMTS3DjpA3JRZWGcvAeFRqzaLa"
+	_, err = loaded.Decrypt([]byte("passphrase"))
+	assert.ErrorIs(err, constants.ErrInvalidPassphrase)
+
+	changed, err := keystore.ChangePassphrase([]byte("passphrase"), []byte("new passphrase"))
+	assert.Nil(err)
+	_, err = changed.Decrypt([]byte("passphrase"))
+	assert.ErrorIs(err, constants.ErrInvalidPassphrase)
+	decrypted, err = changed.Decrypt([]byte("new passphrase"))
+	assert.Nil(err)
+	assert.Equal(secret, decrypted)
+
+	_, err = Encrypt(secret, []byte{}, "")
+	assert.ErrorIs(err, constants.ErrEmptyPassphrase)
+}
+
+func TestReadPassphrase(t *testing.T) {
+	assert := assert.New(t)
+
+	reader, writer, err := os.Pipe()
+	assert.Nil(err)
+	writer.WriteString("old passphrase\nnew passphrase\n")
+	writer.Close()
+	defer reader.Close()
+
+	fd := int(reader.Fd())
+	passphrase, err := ReadPassphrase(PassphraseSource{Fd: fd})
+	assert.Nil(err)
+	assert.Equal("old passphrase", string(passphrase))
+	passphrase, err = ReadPassphrase(PassphraseSource{Fd: fd})
+	assert.Nil(err)
+	assert.Equal("new passphrase", string(passphrase))
+
+	t.Setenv("TEST_KEYSTORE_PASSPHRASE", "env passphrase")
+	passphrase, err = ReadPassphrase(PassphraseSource{Fd: -1, Env: "TEST_KEYSTORE_PASSPHRASE"})
+	assert.Nil(err)
+	assert.Equal("env passphrase", string(passphrase))
+
+	_, err = ReadPassphrase(PassphraseSource{Fd: -1, Env: "TEST_KEYSTORE_PASSPHRASE_MISSING"})
+	assert.ErrorIs(err, constants.ErrPassphraseReadFailed)
+}
@@ -0,0 +1,101 @@
+package keystore
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/mavryk-network/mavpay/constants"
+)
+
+const (
+	PASSPHRASE_ENV     = "KEYSTORE_PASSPHRASE"
+	NEW_PASSPHRASE_ENV = "KEYSTORE_NEW_PASSPHRASE"
+)
+
+// PassphraseSource describes where the passphrase is read from.
+// The file descriptor has priority over the environment variable, prompt is used as the last resort.
+type PassphraseSource struct {
+	// file descriptor to read the passphrase from (one passphrase per line), negative value disables it
+	Fd int
+	// environment variable holding the passphrase
+	Env string
+	// prompt message, prompting is disabled if empty or stdin is not a terminal
+	Prompt string
+	// ask for the passphrase twice when prompting
+	Confirm bool
+}
+
+var (
+	// readers of file descriptors are kept so subsequent reads continue with the next line
+	fdReaders    = make(map[int]*bufio.Reader)
+	fdReadersMtx sync.Mutex
+)
+
+func readPassphraseFromFd(fd int) ([]byte, error) {
+	fdReadersMtx.Lock()
+	defer fdReadersMtx.Unlock()
+	reader, ok := fdReaders[fd]
+	if !ok {
+		file := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+		if file == nil {
+			return nil, errors.Join(constants.ErrPassphraseReadFailed, fmt.Errorf("invalid file descriptor %d", fd))
+		}
+		reader = bufio.NewReader(file)
+		fdReaders[fd] = reader
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return nil, errors.Join(constants.ErrPassphraseReadFailed, err)
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+func isStdinTerminal() bool {
+	fileInfo, err := os.Stdin.Stat()
+	return err == nil && (fileInfo.Mode()&os.ModeCharDevice) != 0
+}
+
+func promptPassphrase(message string, confirm bool) ([]byte, error) {
+	var passphrase string
+	if err := survey.AskOne(&survey.Password{Message: message}, &passphrase); err != nil {
+		return nil, errors.Join(constants.ErrPassphraseReadFailed, err)
+	}
+	if confirm {
+		var confirmation string
+		if err := survey.AskOne(&survey.Password{Message: "Confirm passphrase:"}, &confirmation); err != nil {
+			return nil, errors.Join(constants.ErrPassphraseReadFailed, err)
+		}
+		if passphrase != confirmation {
+			return nil, errors.Join(constants.ErrPassphraseReadFailed, errors.New("passphrases do not match"))
+		}
+	}
+	return []byte(passphrase), nil
+}
+
+func ReadPassphrase(source PassphraseSource) ([]byte, error) {
+	var passphrase []byte
+	var err error
+	switch {
+	case source.Fd >= 0:
+		passphrase, err = readPassphraseFromFd(source.Fd)
+	case source.Env != "" && os.Getenv(source.Env) != "":
+		passphrase = []byte(os.Getenv(source.Env))
+	case source.Prompt != "" && isStdinTerminal():
+		passphrase, err = promptPassphrase(source.Prompt, source.Confirm)
+	default:
+		return nil, errors.Join(constants.ErrPassphraseReadFailed, fmt.Errorf("no passphrase source available, use prompt, %s environment variable or passphrase file descriptor", source.Env))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, constants.ErrEmptyPassphrase
+	}
+	return passphrase, nil
+}
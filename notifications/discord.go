@@ -11,14 +11,16 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/transport"
 )
 
 type discordNotificatorConfiguration struct {
-	Type            string `json:"type"`
-	MessageTemplate string `json:"message_template"`
-	WebhookUrl      string `json:"webhook_url"`
-	WebhookId       string `json:"webhook_id"`
-	WebhookToken    string `json:"webhook_token"`
+	Type            string                 `json:"type"`
+	MessageTemplate string                 `json:"message_template"`
+	WebhookUrl      string                 `json:"webhook_url"`
+	WebhookId       string                 `json:"webhook_id"`
+	WebhookToken    string                 `json:"webhook_token"`
+	Http            *transport.HttpOptions `json:"http,omitempty"`
 }
 
 type DiscordNotificator struct {
@@ -65,6 +67,12 @@ func InitDiscordNotificator(configurationBytes []byte) (*DiscordNotificator, err
 	if err != nil {
 		return nil, err
 	}
+	if configuration.Http != nil {
+		session.Client, err = configuration.Http.NewClient(session.Client.Timeout)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	slog.Debug("discord notificator initialized")
 
@@ -109,6 +117,9 @@ func ValidateDiscordConfiguration(configurationBytes []byte) error {
 		}
 		return errors.Join(constants.ErrInvalidNotificatorConfiguration, errors.New("invalid discord webhook token"))
 	}
+	if err := configuration.Http.Validate(); err != nil {
+		return errors.Join(constants.ErrInvalidNotificatorConfiguration, err)
+	}
 	return nil
 }
 
@@ -129,6 +140,7 @@ func (dn *DiscordNotificator) PayoutSummaryNotify(summary *common.CyclePayoutSum
 					{Name: "Distributed", Value: common.MumavToMavS(summary.DistributedRewards.Int64())},
 					{Name: "Delegators", Value: fmt.Sprintf("%d", summary.Delegators)},
 					{Name: "Donated", Value: common.MumavToMavS(summary.DonatedTotal.Int64())},
+					{Name: "Staking Income", Value: common.MumavToMavS(summary.StakingIncome.Int64())},
 				},
 			},
 		},
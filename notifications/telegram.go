@@ -6,17 +6,20 @@ import (
 	"errors"
 	"fmt"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/transport"
 	"github.com/nikoksr/notify/service/telegram"
 	"golang.org/x/exp/slog"
 )
 
 type telegramNotificatorConfiguration struct {
-	Type            string  `json:"type"`
-	Token           string  `json:"api_token"`
-	Receivers       []int64 `json:"receivers"`
-	MessageTemplate string  `json:"message_template"`
+	Type            string                 `json:"type"`
+	Token           string                 `json:"api_token"`
+	Receivers       []int64                `json:"receivers"`
+	MessageTemplate string                 `json:"message_template"`
+	Http            *transport.HttpOptions `json:"http,omitempty"`
 }
 
 type TelegramNotificator struct {
@@ -39,9 +42,23 @@ func InitTelegramNotificator(configurationBytes []byte) (*TelegramNotificator, e
 		msgTemplate = DEFAULT_TELEGRAM_MESSAGE_TEMPLATE
 	}
 
-	session, err := telegram.New(configuration.Token)
-	if err != nil {
-		return nil, err
+	var session *telegram.Telegram
+	if configuration.Http != nil {
+		httpClient, err := configuration.Http.NewClient(0)
+		if err != nil {
+			return nil, err
+		}
+		bot, err := tgbotapi.NewBotAPIWithClient(configuration.Token, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		session = &telegram.Telegram{}
+		session.SetClient(bot)
+	} else {
+		session, err = telegram.New(configuration.Token)
+		if err != nil {
+			return nil, err
+		}
 	}
 	for _, v := range configuration.Receivers {
 		session.AddReceivers(v)
@@ -67,6 +84,9 @@ func ValidateTelegramConfiguration(configurationBytes []byte) error {
 	if len(configuration.Receivers) == 0 {
 		return errors.Join(constants.ErrInvalidNotificatorConfiguration, errors.New("no telegram receivers specified"))
 	}
+	if err := configuration.Http.Validate(); err != nil {
+		return errors.Join(constants.ErrInvalidNotificatorConfiguration, err)
+	}
 	return nil
 }
 
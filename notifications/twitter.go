@@ -12,15 +12,17 @@ import (
 	twitter "github.com/g8rswimmer/go-twitter/v2"
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/transport"
 )
 
 type twitterNotificatorConfiguration struct {
-	Type              string `json:"type"`
-	MessageTemplate   string `json:"message_template"`
-	ConsumerKey       string `json:"consumer_key"`
-	ConsumerSecret    string `json:"consumer_secret"`
-	AccessToken       string `json:"access_token"`
-	AccessTokenSecret string `json:"access_token_secret"`
+	Type              string                 `json:"type"`
+	MessageTemplate   string                 `json:"message_template"`
+	ConsumerKey       string                 `json:"consumer_key"`
+	ConsumerSecret    string                 `json:"consumer_secret"`
+	AccessToken       string                 `json:"access_token"`
+	AccessTokenSecret string                 `json:"access_token_secret"`
+	Http              *transport.HttpOptions `json:"http,omitempty"`
 }
 
 type TwitterNotificator struct {
@@ -46,7 +48,16 @@ func InitTwitterNotificator(configurationBytes []byte) (*TwitterNotificator, err
 	// Pass in your Access Token and your Access Token Secret
 	token := oauth1.NewToken(configuration.AccessToken, configuration.AccessTokenSecret)
 
-	httpClient := config.Client(oauth1.NoContext, token)
+	ctx := oauth1.NoContext
+	if configuration.Http != nil {
+		baseClient, err := configuration.Http.NewClient(0)
+		if err != nil {
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, oauth1.HTTPClient, baseClient)
+	}
+	httpClient := config.Client(ctx, token)
+	httpClient.Timeout = configuration.Http.GetTimeout(0)
 
 	client := &twitter.Client{
 		Authorizer: authorize{},
@@ -84,6 +95,9 @@ func ValidateTwitterConfiguration(configurationBytes []byte) error {
 	if configuration.ConsumerSecret == "" {
 		return errors.Join(constants.ErrInvalidNotificatorConfiguration, errors.New("invalid twitter consumer secret"))
 	}
+	if err := configuration.Http.Validate(); err != nil {
+		return errors.Join(constants.ErrInvalidNotificatorConfiguration, err)
+	}
 
 	return nil
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/mavryk-network/mavpay/common"
 	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/transport"
 )
 
 type webhookAuthorization string
@@ -21,16 +22,18 @@ const (
 )
 
 type webhookNotificatorConfiguration struct {
-	Type  string               `json:"type"`
-	Url   string               `json:"url"`
-	Token string               `json:"token"`
-	Auth  webhookAuthorization `json:"auth"`
+	Type  string                 `json:"type"`
+	Url   string                 `json:"url"`
+	Token string                 `json:"token"`
+	Auth  webhookAuthorization   `json:"auth"`
+	Http  *transport.HttpOptions `json:"http,omitempty"`
 }
 
 type WebhookNotificator struct {
-	url   string
-	token string
-	auth  webhookAuthorization
+	url    string
+	token  string
+	auth   webhookAuthorization
+	client *http.Client
 }
 
 func InitWebhookNotificator(configurationBytes []byte) (*WebhookNotificator, error) {
@@ -40,12 +43,18 @@ func InitWebhookNotificator(configurationBytes []byte) (*WebhookNotificator, err
 		return nil, err
 	}
 
+	client, err := configuration.Http.NewClient(0)
+	if err != nil {
+		return nil, err
+	}
+
 	slog.Debug("webhook notificator initialized")
 
 	return &WebhookNotificator{
-		url:   configuration.Url,
-		token: configuration.Token,
-		auth:  configuration.Auth,
+		url:    configuration.Url,
+		token:  configuration.Token,
+		auth:   configuration.Auth,
+		client: client,
 	}, nil
 }
 
@@ -65,6 +74,9 @@ func ValidateWebhookConfiguration(configurationBytes []byte) error {
 	if configuration.Auth == WebhookAuthBearer && configuration.Token == "" {
 		return errors.Join(constants.ErrInvalidNotificatorConfiguration, errors.New("invalid bearer token"))
 	}
+	if err := configuration.Http.Validate(); err != nil {
+		return errors.Join(constants.ErrInvalidNotificatorConfiguration, err)
+	}
 	return nil
 }
 
@@ -84,7 +96,7 @@ func (wn *WebhookNotificator) post(data any) error {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", wn.token))
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := wn.client.Do(req)
 	if err != nil {
 		return err
 	}
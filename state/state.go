@@ -14,6 +14,8 @@ var (
 	Global                 *State
 	CONFIG_FILE_NAME       = "config.hjson"
 	PRIVATE_KEY_FILE_NAME  = "payout_wallet_private.key"
+	KEYSTORE_FILE_NAME     = "payout_wallet.keystore"
+	PUBLIC_KEY_FILE_NAME   = "payout_wallet_public.key"
 	REMOTE_SPECS_FILE_NAME = "remote_signer.hjson"
 )
 
@@ -23,6 +25,10 @@ type StateInitOptions struct {
 	SignerOverride        common.SignerEngine
 	DisableDonationPrompt bool
 	PayOnlyAddressPrefix  string
+	DisableCache          bool
+	RecordFilePath        string
+	ReplayFilePath        string
+	PassphraseFd          int
 }
 
 type State struct {
@@ -32,6 +38,10 @@ type State struct {
 	hasInjectedConfiguration bool
 	SignerOverride           common.SignerEngine
 	disableDonationPrompt    bool
+	disableCache             bool
+	recordFilePath           string
+	replayFilePath           string
+	passphraseFd             int
 
 	payOnlyAddressPrefix string
 }
@@ -50,6 +60,10 @@ func Init(workingDirectory string, options StateInitOptions) error {
 		SignerOverride:           options.SignerOverride,
 		disableDonationPrompt:    options.DisableDonationPrompt,
 		payOnlyAddressPrefix:     options.PayOnlyAddressPrefix,
+		disableCache:             options.DisableCache,
+		recordFilePath:           options.RecordFilePath,
+		replayFilePath:           options.ReplayFilePath,
+		passphraseFd:             options.PassphraseFd,
 	}
 
 	return errors.Join(Global.validateReportsDirectory())
@@ -92,6 +106,34 @@ func (state *State) GetReportsDirectory() string {
 	return path.Join(state.GetWorkingDirectory(), constants.REPORTS_DIRECTORY)
 }
 
+func (state *State) GetPayoutJournalFilePath() string {
+	journalFilePath := os.Getenv("PAYOUT_JOURNAL_FILE")
+	if journalFilePath != "" {
+		return journalFilePath
+	}
+	return path.Join(state.GetReportsDirectory(), constants.PAYOUT_JOURNAL_FILE_NAME)
+}
+
+func (state *State) GetCacheDirectory() string {
+	cacheDirectoryPath := os.Getenv("CACHE_DIRECTORY")
+	if cacheDirectoryPath != "" {
+		return cacheDirectoryPath
+	}
+	return path.Join(state.GetWorkingDirectory(), constants.CACHE_DIRECTORY)
+}
+
+func (state *State) IsCacheDisabled() bool {
+	return state.disableCache
+}
+
+func (state *State) GetRecordFilePath() string {
+	return state.recordFilePath
+}
+
+func (state *State) GetReplayFilePath() string {
+	return state.replayFilePath
+}
+
 func (state *State) GetConfigurationFilePath() string {
 	configurationFilePath := os.Getenv("CONFIGURATION_FILE")
 	if configurationFilePath != "" {
@@ -108,6 +150,27 @@ func (state *State) GetPrivateKeyFilePath() string {
 	return path.Join(state.GetWorkingDirectory(), PRIVATE_KEY_FILE_NAME)
 }
 
+func (state *State) GetPublicKeyFilePath() string {
+	publicKeyFilePath := os.Getenv("PUBLIC_KEY_FILE")
+	if publicKeyFilePath != "" {
+		return publicKeyFilePath
+	}
+	return path.Join(state.GetWorkingDirectory(), PUBLIC_KEY_FILE_NAME)
+}
+
+func (state *State) GetKeystoreFilePath() string {
+	keystoreFilePath := os.Getenv("KEYSTORE_FILE")
+	if keystoreFilePath != "" {
+		return keystoreFilePath
+	}
+	return path.Join(state.GetWorkingDirectory(), KEYSTORE_FILE_NAME)
+}
+
+// GetPassphraseFd returns file descriptor to read keystore passphrase from, negative if not set
+func (state *State) GetPassphraseFd() int {
+	return state.passphraseFd
+}
+
 func (state *State) GetRemoteSpecsFilePath() string {
 	remoteSpecsConfigurationFile := os.Getenv("REMOTE_SIGNER_CONFIGURATION_FILE")
 	if remoteSpecsConfigurationFile != "" {
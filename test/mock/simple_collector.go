@@ -83,10 +83,24 @@ func (engine *SimpleColletor) GetCycleStakingData(baker mavryk.Address, cycle in
 	}, nil
 }
 
+func (engine *SimpleColletor) GetCycleDelegators(baker mavryk.Address, cycle int64) ([]mavryk.Address, error) {
+	data, err := engine.GetCycleStakingData(baker, cycle)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(data.Delegators, func(delegator common.Delegator, _ int) mavryk.Address {
+		return delegator.Address
+	}), nil
+}
+
 func (engine *SimpleColletor) GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error) {
 	return []int64{500, 501}, nil
 }
 
+func (engine *SimpleColletor) GetCycleAtDate(date time.Time) (int64, error) {
+	return 500, nil
+}
+
 func (engine *SimpleColletor) WasOperationApplied(op mavryk.OpHash) (common.OperationStatus, error) {
 	return common.OPERATION_STATUS_APPLIED, nil
 }
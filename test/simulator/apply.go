@@ -0,0 +1,307 @@
+package simulator
+
+import (
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+	"github.com/samber/lo"
+)
+
+// contentResult is the outcome of a single content of an operation
+type contentResult struct {
+	milliGas       int64
+	storage        int64 // paid storage size diff, bytes
+	allocated      bool
+	allocationBurn int64
+	storageBurn    int64
+	failure        string
+}
+
+// scratch collects changes of an operation, they are committed only if the whole operation is applied
+type scratch struct {
+	ledger    *Ledger
+	balances  map[string]int64
+	allocated map[string]bool
+	tokens    map[string]map[string]int64
+	revealed  bool
+}
+
+func (ledger *Ledger) newScratch() *scratch {
+	return &scratch{
+		ledger:    ledger,
+		balances:  make(map[string]int64),
+		allocated: make(map[string]bool),
+		tokens:    make(map[string]map[string]int64),
+	}
+}
+
+func (s *scratch) getBalance(address mavryk.Address) int64 {
+	if balance, ok := s.balances[address.String()]; ok {
+		return balance
+	}
+	if acc, ok := s.ledger.accounts[address.String()]; ok {
+		return acc.balance
+	}
+	return 0
+}
+
+func (s *scratch) isAllocated(address mavryk.Address) bool {
+	_, ok := s.ledger.accounts[address.String()]
+	return ok || s.allocated[address.String()]
+}
+
+func (s *scratch) getTokens(contract *Contract, key string) (int64, bool) {
+	if tokens, ok := s.tokens[contract.Address.String()]; ok {
+		if amount, ok := tokens[key]; ok {
+			return amount, true
+		}
+	}
+	amount, ok := contract.tokens[key]
+	return amount, ok
+}
+
+func (s *scratch) setTokens(contract *Contract, key string, amount int64) {
+	if _, ok := s.tokens[contract.Address.String()]; !ok {
+		s.tokens[contract.Address.String()] = make(map[string]int64)
+	}
+	s.tokens[contract.Address.String()][key] = amount
+}
+
+// burn charges storage from the source, it fails the content if the balance is not sufficient
+func (s *scratch) burn(source mavryk.Address, bytes int64) (int64, bool) {
+	burn := bytes * s.ledger.options.Costs.StorageCostPerByte
+	if s.getBalance(source) < burn {
+		return 0, false
+	}
+	s.balances[source.String()] = s.getBalance(source) - burn
+	return burn, true
+}
+
+func (s *scratch) commit() {
+	for address, balance := range s.balances {
+		acc, ok := s.ledger.accounts[address]
+		if !ok {
+			acc = &account{}
+			s.ledger.accounts[address] = acc
+		}
+		acc.balance = balance
+	}
+	for address := range s.allocated {
+		if _, ok := s.ledger.accounts[address]; !ok {
+			s.ledger.accounts[address] = &account{}
+		}
+	}
+	for address, tokens := range s.tokens {
+		contract := s.ledger.contracts[address]
+		for key, amount := range tokens {
+			contract.tokens[key] = amount
+		}
+	}
+}
+
+func (s *scratch) applyTokenTransfers(source mavryk.Address, contract *Contract, tx *codec.Transaction, result *contentResult) {
+	transfers, err := contract.decodeTransfers(tx.Parameters)
+	if err != nil {
+		result.failure = FAILURE_INVALID_PARAMETERS
+		return
+	}
+	costs := s.ledger.options.Costs
+	for _, transfer := range transfers {
+		result.milliGas += costs.TokenTransferMilliGas
+		// operators are not simulated, only holders can transfer their tokens
+		if !transfer.from.Equal(source) {
+			result.failure = FAILURE_INVALID_PARAMETERS
+			return
+		}
+		fromKey, toKey := tokenKey(transfer.from, transfer.tokenId), tokenKey(transfer.to, transfer.tokenId)
+		fromBalance, _ := s.getTokens(contract, fromKey)
+		if fromBalance < transfer.amount {
+			result.failure = FAILURE_TOKEN_BALANCE_TOO_LOW
+			return
+		}
+		s.setTokens(contract, fromKey, fromBalance-transfer.amount)
+		toBalance, exists := s.getTokens(contract, toKey)
+		if !exists {
+			result.storage += costs.TokenHolderStorage
+		}
+		s.setTokens(contract, toKey, toBalance+transfer.amount)
+	}
+}
+
+func (s *scratch) applyTransaction(source mavryk.Address, tx *codec.Transaction) contentResult {
+	costs := s.ledger.options.Costs
+	result := contentResult{milliGas: costs.TransactionMilliGas}
+	amount := tx.Amount.Int64()
+	if s.getBalance(source) < amount {
+		result.failure = FAILURE_BALANCE_TOO_LOW
+		return result
+	}
+
+	destination := tx.Destination
+	if destination.IsContract() {
+		contract, ok := s.ledger.contracts[destination.String()]
+		if !ok {
+			result.failure = FAILURE_NON_EXISTING_CONTRACT
+			return result
+		}
+		result.milliGas = costs.ContractCallMilliGas
+		switch contract.Kind {
+		case CONTRACT_REJECTING:
+			result.failure = FAILURE_SCRIPT_REJECTED
+			return result
+		case CONTRACT_FA1_2, CONTRACT_FA2:
+			s.applyTokenTransfers(source, contract, tx, &result)
+			if result.failure != "" {
+				return result
+			}
+		default:
+			if tx.Parameters != nil && tx.Parameters.Entrypoint != "" && tx.Parameters.Entrypoint != "default" {
+				result.failure = FAILURE_INVALID_PARAMETERS
+				return result
+			}
+		}
+		result.storage += contract.StorageDiff
+	} else if !s.isAllocated(destination) && amount > 0 {
+		allocationBurn, ok := s.burn(source, costs.AllocationStorage)
+		if !ok {
+			result.failure = FAILURE_BALANCE_TOO_LOW
+			return result
+		}
+		result.allocated = true
+		result.allocationBurn = allocationBurn
+		s.allocated[destination.String()] = true
+	}
+
+	if result.storage > 0 {
+		storageBurn, ok := s.burn(source, result.storage)
+		if !ok {
+			result.failure = FAILURE_BALANCE_TOO_LOW
+			return result
+		}
+		result.storageBurn = storageBurn
+	}
+
+	s.balances[source.String()] = s.getBalance(source) - amount
+	s.balances[destination.String()] = s.getBalance(destination) + amount
+	return result
+}
+
+// checkLimits fails the content if it consumed more than its limits allow
+func (ledger *Ledger) checkLimits(content codec.Operation, result *contentResult) {
+	limits := content.Limits()
+	storage := result.storage
+	if result.allocated {
+		storage += ledger.options.Costs.AllocationStorage
+	}
+	switch {
+	case result.milliGas > limits.GasLimit*1000:
+		result.failure = FAILURE_GAS_EXHAUSTED
+	case storage > limits.StorageLimit:
+		result.failure = FAILURE_STORAGE_EXHAUSTED
+	}
+}
+
+// apply executes the operation against the ledger and builds its receipt. Operation fails as a whole, if any of its
+// contents fails, none of the changes are kept. Only fees are charged and the counter is moved.
+// If commit is false, the operation is only simulated, limits are not checked and the ledger is not changed.
+func (ledger *Ledger) apply(op *codec.Op, commit bool, failure string) *rpc.Receipt {
+	source := getOpSource(op)
+	costs := ledger.options.Costs
+	s := ledger.newScratch()
+
+	fees := lo.SumBy(op.Contents, func(content codec.Operation) int64 { return content.Limits().Fee })
+	s.balances[source.String()] = s.getBalance(source) - fees
+	feeBalances := lo.Assign(s.balances)
+
+	results := make([]contentResult, len(op.Contents))
+	failedAt := -1
+	for i, content := range op.Contents {
+		var result contentResult
+		switch content := content.(type) {
+		case *codec.Reveal:
+			result = contentResult{milliGas: costs.RevealMilliGas}
+			s.revealed = true
+		case *codec.Transaction:
+			result = s.applyTransaction(source, content)
+		default:
+			result = contentResult{failure: FAILURE_INVALID_PARAMETERS}
+		}
+		if i == 0 {
+			result.milliGas += int64(len(op.Bytes())) * costs.SerializationMilliGas
+		}
+		if commit && result.failure == "" {
+			ledger.checkLimits(content, &result)
+		}
+		results[i] = result
+		if result.failure != "" {
+			failedAt = i
+			break
+		}
+	}
+	if failedAt < 0 && commit && lo.SumBy(results, func(result contentResult) int64 { return result.milliGas }) > costs.HardGasLimitPerOperation*1000 {
+		failedAt = len(results) - 1
+		results[failedAt].failure = FAILURE_GAS_EXHAUSTED
+	}
+	if failedAt < 0 && failure != "" {
+		failedAt = len(results) - 1
+		results[failedAt].failure = failure
+	}
+
+	if commit {
+		acc := ledger.getAccount(source)
+		if len(op.Contents) > 0 {
+			acc.counter = op.Contents[len(op.Contents)-1].GetCounter()
+		}
+		if failedAt < 0 {
+			s.commit()
+			if s.revealed {
+				acc.revealed = true
+			}
+		} else {
+			acc.balance = feeBalances[source.String()]
+		}
+	}
+
+	contents := make([]rpc.TypedOperation, len(op.Contents))
+	for i, content := range op.Contents {
+		result := rpc.OperationResult{Status: mavryk.OpStatusApplied}
+		switch {
+		case failedAt >= 0 && i < failedAt:
+			result.Status = mavryk.OpStatusBacktracked
+		case failedAt >= 0 && i > failedAt:
+			result.Status = mavryk.OpStatusSkipped
+		case i == failedAt:
+			result.Status = mavryk.OpStatusFailed
+			result.Errors = []rpc.OperationError{{GenericError: rpc.GenericError{Kind: results[i].failure}}}
+		}
+		if failedAt < 0 || i <= failedAt {
+			result.ConsumedMilliGas = results[i].milliGas
+		}
+		if failedAt < 0 {
+			result.Allocated = results[i].allocated
+			result.PaidStorageSizeDiff = results[i].storage
+			// allocation burn goes first, the same way as in receipts of the node
+			if results[i].allocated {
+				result.BalanceUpdates = append(result.BalanceUpdates, rpc.BalanceUpdate{Kind: "contract", Change: -results[i].allocationBurn})
+			}
+			if results[i].storageBurn > 0 {
+				result.BalanceUpdates = append(result.BalanceUpdates, rpc.BalanceUpdate{Kind: "contract", Change: -results[i].storageBurn})
+			}
+		}
+		contents[i] = rpc.Transaction{
+			Manager: rpc.Manager{
+				Fee: content.Limits().Fee,
+				Generic: rpc.Generic{
+					Metadata: rpc.OperationMetadata{Result: result},
+				},
+			},
+		}
+	}
+
+	return &rpc.Receipt{
+		Block: mavryk.ZeroBlockHash,
+		Op: &rpc.Operation{
+			Contents: contents,
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package simulator
+
+import (
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/configuration"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/state"
+	"github.com/mavryk-network/mvgo/mavryk"
+)
+
+// Chain bundles the simulated ledger with engines operating on it and the baker paying out from a funded payout wallet
+type Chain struct {
+	Ledger     *Ledger
+	Collector  *Collector
+	Transactor *Transactor
+	Signer     *Signer
+	Reporter   *Reporter
+	Baker      mavryk.Address
+}
+
+func NewChain(options LedgerOptions, payoutWalletBalance int64) *Chain {
+	ledger := NewLedger(options)
+	signer := NewRandomSigner()
+	ledger.AddImplicitAccount(signer.GetKey(), payoutWalletBalance, true)
+	baker, _ := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+	ledger.AddImplicitAccount(baker.Public(), 0, true)
+
+	return &Chain{
+		Ledger:     ledger,
+		Collector:  NewCollector(ledger),
+		Transactor: NewTransactor(ledger).WithConfirmations(0),
+		Signer:     signer,
+		Reporter:   NewReporter(),
+		Baker:      baker.Address(),
+	}
+}
+
+// SetCycleRewards sets delegated rewards of the baker and its delegators for the cycle
+func (chain *Chain) SetCycleRewards(cycle int64, rewards int64, delegators ...common.Delegator) {
+	external := mavryk.Zero
+	for _, delegator := range delegators {
+		external = external.Add(delegator.DelegatedBalance)
+	}
+	chain.Ledger.SetCycleData(cycle, &common.BakersCycleData{
+		OwnDelegatedBalance:         mavryk.NewZ(10_000).Mul64(constants.MUMAV_FACTOR),
+		ExternalDelegatedBalance:    external,
+		BlockDelegatedRewards:       mavryk.NewZ(rewards),
+		EndorsementDelegatedRewards: mavryk.Zero,
+		BlockDelegatedFees:          mavryk.Zero,
+		FrozenDepositLimit:          mavryk.Zero,
+		DelegatorsCount:             int32(len(delegators)),
+		Delegators:                  delegators,
+	})
+}
+
+// GetConfiguration returns default runtime configuration of the chain baker
+func (chain *Chain) GetConfiguration() *configuration.RuntimeConfiguration {
+	config := configuration.GetDefaultRuntimeConfiguration()
+	config.BakerPKH = chain.Baker
+	return &config
+}
+
+// InitState initializes global state read by the core (output format, reports directory...),
+// engines of the chain are passed to the core directly
+func (chain *Chain) InitState(workingDirectory string) error {
+	return state.Init(workingDirectory, state.StateInitOptions{
+		DisableDonationPrompt: true,
+		DisableCache:          true,
+	})
+}
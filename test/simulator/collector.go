@@ -0,0 +1,206 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+	"github.com/samber/lo"
+)
+
+// Collector implements common.CollectorEngine on top of the simulated ledger
+type Collector struct {
+	ledger *Ledger
+	// cycle monitors stop after the cycle is reported as completed, -1 means never
+	stopMonitorsAfterCycle int64
+}
+
+func NewCollector(ledger *Ledger) *Collector {
+	return &Collector{
+		ledger:                 ledger,
+		stopMonitorsAfterCycle: -1,
+	}
+}
+
+// StopMonitorsAfterCycle cancels cycle monitors once the cycle was reported as completed, so continual runs end
+func (engine *Collector) StopMonitorsAfterCycle(cycle int64) *Collector {
+	engine.stopMonitorsAfterCycle = cycle
+	return engine
+}
+
+func (engine *Collector) GetId() string {
+	return "SimulatorCollector"
+}
+
+func (engine *Collector) RefreshParams() error {
+	return nil
+}
+
+func (engine *Collector) GetCurrentCycleNumber() (int64, error) {
+	return engine.ledger.GetCurrentCycle(), nil
+}
+
+func (engine *Collector) GetLastCompletedCycle() (int64, error) {
+	cycle, err := engine.GetCurrentCycleNumber()
+	return cycle - 1, err
+}
+
+func (engine *Collector) GetCycleStakingData(baker mavryk.Address, cycle int64) (*common.BakersCycleData, error) {
+	engine.ledger.mtx.Lock()
+	defer engine.ledger.mtx.Unlock()
+	data, ok := engine.ledger.cycles[cycle]
+	if !ok {
+		return nil, errors.Join(ErrCycleDataNotAvailable, fmt.Errorf("cycle: %d", cycle))
+	}
+	return data, nil
+}
+
+func (engine *Collector) GetCycleDelegators(baker mavryk.Address, cycle int64) ([]mavryk.Address, error) {
+	data, err := engine.GetCycleStakingData(baker, cycle)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(data.Delegators, func(delegator common.Delegator, _ int) mavryk.Address {
+		return delegator.Address
+	}), nil
+}
+
+// GetCyclesInDateRange returns cycles ending within the range, the current cycle is never included
+func (engine *Collector) GetCyclesInDateRange(startDate time.Time, endDate time.Time) ([]int64, error) {
+	engine.ledger.mtx.Lock()
+	defer engine.ledger.mtx.Unlock()
+	ledger := engine.ledger
+	cycles := make([]int64, 0)
+	for cycle := int64(0); cycle < ledger.getCycle(ledger.level); cycle++ {
+		end := ledger.getLevelTime((cycle + 1) * ledger.options.BlocksPerCycle)
+		if !end.Before(startDate) && !end.After(endDate) {
+			cycles = append(cycles, cycle)
+		}
+	}
+	return cycles, nil
+}
+
+// GetCycleAtDate returns the cycle of the first block after the date, blocks are baked in regular intervals
+func (engine *Collector) GetCycleAtDate(date time.Time) (int64, error) {
+	engine.ledger.mtx.Lock()
+	defer engine.ledger.mtx.Unlock()
+	ledger := engine.ledger
+	for level := int64(1); level <= ledger.level; level++ {
+		if ledger.getLevelTime(level).After(date) {
+			return ledger.getCycle(level), nil
+		}
+	}
+	return 0, errors.Join(constants.ErrNoBlockAfterDate, fmt.Errorf("date: %s", date))
+}
+
+func (engine *Collector) WasOperationApplied(opHash mavryk.OpHash) (common.OperationStatus, error) {
+	engine.ledger.mtx.Lock()
+	defer engine.ledger.mtx.Unlock()
+	return engine.ledger.getOperationStatus(opHash), nil
+}
+
+func (engine *Collector) GetBranch(offset int64) (mavryk.BlockHash, error) {
+	return mavryk.MustParseBlockHash("BM4VEjb3EGdgNgJhwfVUsUqPYvZWJUHdmKKgabuDkwy6SmUKDve"), nil
+}
+
+// Simulate completes the operation and applies it without changing the ledger
+func (engine *Collector) Simulate(o *codec.Op, publicKey mavryk.Key) (*rpc.Receipt, error) {
+	engine.ledger.mtx.Lock()
+	defer engine.ledger.mtx.Unlock()
+	ledger := engine.ledger
+	if len(ledger.failures.simulation) > 0 {
+		err := ledger.failures.simulation[0]
+		ledger.failures.simulation = ledger.failures.simulation[1:]
+		return nil, err
+	}
+
+	if err := ledger.complete(o, publicKey); err != nil {
+		return nil, err
+	}
+	receipt := ledger.apply(o, false, "")
+	if !receipt.IsSuccess() {
+		return receipt, receipt.Error()
+	}
+	return receipt, nil
+}
+
+func (engine *Collector) GetBalance(pkh mavryk.Address) (mavryk.Z, error) {
+	return mavryk.NewZ(engine.ledger.GetBalance(pkh)), nil
+}
+
+func (engine *Collector) CreateCycleMonitor(options common.CycleMonitorOptions) (common.CycleMonitor, error) {
+	return newCycleMonitor(engine.ledger, engine.stopMonitorsAfterCycle), nil
+}
+
+func (engine *Collector) SendAnalytics(bakerId string, version string) {}
+
+func (engine *Collector) GetCurrentProtocol() (mavryk.ProtocolHash, error) {
+	return mavryk.ZeroProtocolHash, nil
+}
+
+func (engine *Collector) IsRevealed(addr mavryk.Address) (bool, error) {
+	engine.ledger.mtx.Lock()
+	defer engine.ledger.mtx.Unlock()
+	if acc, ok := engine.ledger.accounts[addr.String()]; ok {
+		return acc.revealed, nil
+	}
+	return false, nil
+}
+
+// cycleMonitor bakes blocks instead of waiting for them
+type cycleMonitor struct {
+	ledger    *Ledger
+	cycle     chan int64
+	stopAfter int64
+	canceled  bool
+	mtx       sync.Mutex
+}
+
+func newCycleMonitor(ledger *Ledger, stopAfter int64) *cycleMonitor {
+	return &cycleMonitor{
+		ledger:    ledger,
+		cycle:     make(chan int64),
+		stopAfter: stopAfter,
+	}
+}
+
+func (monitor *cycleMonitor) GetCycleChannel() chan int64 {
+	return monitor.cycle
+}
+
+func (monitor *cycleMonitor) Cancel() {
+	monitor.Terminate()
+}
+
+func (monitor *cycleMonitor) Terminate() {
+	monitor.mtx.Lock()
+	defer monitor.mtx.Unlock()
+	if !monitor.canceled {
+		monitor.canceled = true
+		close(monitor.cycle)
+	}
+}
+
+func (monitor *cycleMonitor) CreateBlockHeaderMonitor() error {
+	return nil
+}
+
+func (monitor *cycleMonitor) isCanceled() bool {
+	monitor.mtx.Lock()
+	defer monitor.mtx.Unlock()
+	return monitor.canceled
+}
+
+func (monitor *cycleMonitor) WaitForNextCompletedCycle(lastProcessedCycle int64) (int64, error) {
+	if monitor.isCanceled() || (monitor.stopAfter >= 0 && lastProcessedCycle >= monitor.stopAfter) {
+		return -1, constants.ErrMonitoringCanceled
+	}
+	monitor.ledger.BakeUntilCycle(lastProcessedCycle + 2)
+	return monitor.ledger.GetCurrentCycle() - 1, nil
+}
@@ -0,0 +1,90 @@
+package simulator
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/micheline"
+)
+
+type EContractKind string
+
+const (
+	CONTRACT_ACCEPTING EContractKind = "accepting"
+	CONTRACT_REJECTING EContractKind = "rejecting"
+	CONTRACT_FA1_2     EContractKind = "fa1.2"
+	CONTRACT_FA2       EContractKind = "fa2"
+)
+
+// Contract is a KT1 account with simulated behavior, token contracts keep ledger of token balances
+type Contract struct {
+	Address mavryk.Address
+	Kind    EContractKind
+	// storage paid by every call of the contract, bytes
+	StorageDiff int64
+	tokens      map[string]int64
+}
+
+func NewContract(address mavryk.Address, kind EContractKind) *Contract {
+	return &Contract{
+		Address: address,
+		Kind:    kind,
+		tokens:  make(map[string]int64),
+	}
+}
+
+// NewContractAddress returns a random KT1 address
+func NewContractAddress() mavryk.Address {
+	hash := make([]byte, 20)
+	_, _ = rand.Read(hash)
+	return mavryk.NewAddress(mavryk.AddressTypeContract, hash)
+}
+
+// WithTokens mints tokens of the contract to the holder
+func (contract *Contract) WithTokens(holder mavryk.Address, tokenId int64, amount int64) *Contract {
+	contract.tokens[tokenKey(holder, tokenId)] += amount
+	return contract
+}
+
+func (contract *Contract) IsTokenContract() bool {
+	return contract.Kind == CONTRACT_FA1_2 || contract.Kind == CONTRACT_FA2
+}
+
+func tokenKey(holder mavryk.Address, tokenId int64) string {
+	return fmt.Sprintf("%s/%d", holder.String(), tokenId)
+}
+
+type tokenTransfer struct {
+	from    mavryk.Address
+	to      mavryk.Address
+	tokenId int64
+	amount  int64
+}
+
+func (contract *Contract) decodeTransfers(params *micheline.Parameters) ([]tokenTransfer, error) {
+	if params == nil || params.Entrypoint != "transfer" {
+		return nil, constants.ErrInvalidTransferParameters
+	}
+	var transfers []common.TokenTransfer
+	var err error
+	if contract.Kind == CONTRACT_FA1_2 {
+		transfers, err = common.DecodeFA1TransferParameters(params.Value)
+	} else {
+		transfers, err = common.DecodeFA2TransferParameters(params.Value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := make([]tokenTransfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		if !transfer.Amount.Big().IsInt64() {
+			return nil, errors.Join(constants.ErrInvalidTransferParameters, errors.New("amount out of range"))
+		}
+		result = append(result, tokenTransfer{from: transfer.From, to: transfer.To, tokenId: transfer.TokenId, amount: transfer.Amount.Int64()})
+	}
+	return result, nil
+}
@@ -0,0 +1,467 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/utils"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+	"github.com/samber/lo"
+)
+
+var (
+	ErrUnknownAccount        = errors.New("simulator: unknown account")
+	ErrUnrevealedKey         = errors.New("simulator: unrevealed key")
+	ErrCounterInThePast      = errors.New("simulator: counter in the past")
+	ErrOperationConflict     = errors.New("simulator: operation conflicts with operation in mempool")
+	ErrInvalidSignature      = errors.New("simulator: invalid signature")
+	ErrBalanceTooLowForFees  = errors.New("simulator: balance too low to pay fees")
+	ErrOperationNotFound     = errors.New("simulator: operation not found")
+	ErrOperationExpired      = errors.New("simulator: operation expired")
+	ErrCycleDataNotAvailable = errors.New("simulator: cycle data not available")
+)
+
+// failure reasons of applied operations, reported as kinds of operation errors in receipts
+const (
+	FAILURE_BALANCE_TOO_LOW       = "proto.balance_too_low"
+	FAILURE_SCRIPT_REJECTED       = "proto.script_rejected"
+	FAILURE_GAS_EXHAUSTED         = "proto.gas_exhausted.operation"
+	FAILURE_STORAGE_EXHAUSTED     = "proto.storage_exhausted.operation"
+	FAILURE_TOKEN_BALANCE_TOO_LOW = "FA.NotEnoughBalance"
+	FAILURE_INVALID_PARAMETERS    = "proto.michelson_v1.bad_contract_parameter"
+	FAILURE_NON_EXISTING_CONTRACT = "proto.contract.non_existing_contract"
+)
+
+// Costs of operations in the simulated ledger
+type Costs struct {
+	TransactionMilliGas      int64
+	ContractCallMilliGas     int64
+	TokenTransferMilliGas    int64
+	RevealMilliGas           int64
+	SerializationMilliGas    int64 // per byte of the operation, accounted to its first content
+	StorageCostPerByte       int64
+	AllocationStorage        int64 // bytes paid when a new implicit account is allocated
+	TokenHolderStorage       int64 // bytes paid when a new holder is added to the token ledger
+	HardGasLimitPerOperation int64
+	HardStorageLimitPerOp    int64
+	MaxOperationDataLength   int
+}
+
+func DefaultCosts() Costs {
+	return Costs{
+		TransactionMilliGas:      1_000_000,
+		ContractCallMilliGas:     2_500_000,
+		TokenTransferMilliGas:    4_000_000,
+		RevealMilliGas:           1_000_000,
+		SerializationMilliGas:    10,
+		StorageCostPerByte:       250,
+		AllocationStorage:        constants.ALLOCATION_STORAGE,
+		TokenHolderStorage:       67,
+		HardGasLimitPerOperation: 1_040_000,
+		HardStorageLimitPerOp:    60_000,
+		MaxOperationDataLength:   32 * 1024,
+	}
+}
+
+type LedgerOptions struct {
+	Costs          Costs
+	BlocksPerCycle int64
+	BlockTime      time.Duration
+	Genesis        time.Time
+	// only one manager operation of a source can be included in a block
+	OneOperationPerSourcePerBlock bool
+}
+
+func DefaultLedgerOptions() LedgerOptions {
+	return LedgerOptions{
+		Costs:                         DefaultCosts(),
+		BlocksPerCycle:                128,
+		BlockTime:                     15 * time.Second,
+		Genesis:                       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		OneOperationPerSourcePerBlock: true,
+	}
+}
+
+type account struct {
+	balance  int64
+	counter  int64
+	key      mavryk.Key
+	revealed bool
+}
+
+type pendingOperation struct {
+	op            *codec.Op
+	hash          mavryk.OpHash
+	source        mavryk.Address
+	injectedLevel int64
+	// dropped operations stay in mempool until they expire, but are never included
+	dropped bool
+}
+
+type includedOperation struct {
+	level   int64
+	receipt *rpc.Receipt
+	status  common.OperationStatus
+}
+
+// Ledger is an in-memory simulation of a small Mavryk chain. Operations are injected to mempool and included when blocks are baked.
+// Failures can be injected to broadcasting, inclusion and application of operations.
+type Ledger struct {
+	mtx sync.Mutex
+
+	options   LedgerOptions
+	level     int64
+	accounts  map[string]*account
+	contracts map[string]*Contract
+	mempool   []*pendingOperation
+	included  map[string]*includedOperation
+	expired   map[string]bool
+	cycles    map[int64]*common.BakersCycleData
+
+	failures failures
+}
+
+type failures struct {
+	broadcast   []error
+	drop        int
+	application []string
+	simulation  []error
+}
+
+func NewLedger(options LedgerOptions) *Ledger {
+	return &Ledger{
+		options:   options,
+		level:     1,
+		accounts:  make(map[string]*account),
+		contracts: make(map[string]*Contract),
+		mempool:   make([]*pendingOperation, 0),
+		included:  make(map[string]*includedOperation),
+		expired:   make(map[string]bool),
+		cycles:    make(map[int64]*common.BakersCycleData),
+	}
+}
+
+func (ledger *Ledger) getAccount(address mavryk.Address) *account {
+	acc, ok := ledger.accounts[address.String()]
+	if !ok {
+		acc = &account{}
+		ledger.accounts[address.String()] = acc
+	}
+	return acc
+}
+
+// AddImplicitAccount funds the account of the key, revealed accounts can send operations right away
+func (ledger *Ledger) AddImplicitAccount(key mavryk.Key, balance int64, revealed bool) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	acc := ledger.getAccount(key.Address())
+	acc.key = key
+	acc.balance = balance
+	acc.revealed = revealed
+}
+
+// SetBalance sets balance of any account, implicit accounts are allocated if they did not exist
+func (ledger *Ledger) SetBalance(address mavryk.Address, balance int64) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	ledger.getAccount(address).balance = balance
+}
+
+func (ledger *Ledger) GetBalance(address mavryk.Address) int64 {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	if acc, ok := ledger.accounts[address.String()]; ok {
+		return acc.balance
+	}
+	return 0
+}
+
+func (ledger *Ledger) IsAllocated(address mavryk.Address) bool {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	_, ok := ledger.accounts[address.String()]
+	return ok
+}
+
+func (ledger *Ledger) GetCounter(address mavryk.Address) int64 {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	if acc, ok := ledger.accounts[address.String()]; ok {
+		return acc.counter
+	}
+	return 0
+}
+
+// AddContract originates the contract, its balance is kept in the ledger accounts
+func (ledger *Ledger) AddContract(contract *Contract) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	ledger.contracts[contract.Address.String()] = contract
+	ledger.getAccount(contract.Address)
+}
+
+func (ledger *Ledger) GetTokenBalance(contract mavryk.Address, holder mavryk.Address, tokenId int64) int64 {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	if c, ok := ledger.contracts[contract.String()]; ok {
+		return c.tokens[tokenKey(holder, tokenId)]
+	}
+	return 0
+}
+
+// SetCycleData sets staking data of the baker for the cycle
+func (ledger *Ledger) SetCycleData(cycle int64, data *common.BakersCycleData) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	ledger.cycles[cycle] = data
+}
+
+func (ledger *Ledger) GetLevel() int64 {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	return ledger.level
+}
+
+func (ledger *Ledger) getCycle(level int64) int64 {
+	return (level - 1) / ledger.options.BlocksPerCycle
+}
+
+func (ledger *Ledger) getLevelTime(level int64) time.Time {
+	return ledger.options.Genesis.Add(time.Duration(level) * ledger.options.BlockTime)
+}
+
+func (ledger *Ledger) GetCurrentCycle() int64 {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	return ledger.getCycle(ledger.level)
+}
+
+// FailNextBroadcasts makes next broadcasts fail with the errors, one error per broadcast
+func (ledger *Ledger) FailNextBroadcasts(errs ...error) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	ledger.failures.broadcast = append(ledger.failures.broadcast, errs...)
+}
+
+// DropNextOperations accepts next n operations to mempool, but they are never included and expire
+func (ledger *Ledger) DropNextOperations(n int) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	ledger.failures.drop += n
+}
+
+// FailNextApplications includes next operations as failed with the reasons, one reason per operation
+func (ledger *Ledger) FailNextApplications(reasons ...string) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	ledger.failures.application = append(ledger.failures.application, reasons...)
+}
+
+// FailNextSimulations makes next simulations fail with the errors, one error per simulation
+func (ledger *Ledger) FailNextSimulations(errs ...error) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	ledger.failures.simulation = append(ledger.failures.simulation, errs...)
+}
+
+// complete sets counters of the operation and reveals the key if needed, the same way node rpc does
+func (ledger *Ledger) complete(op *codec.Op, key mavryk.Key) error {
+	acc, ok := ledger.accounts[key.Address().String()]
+	if !ok {
+		return errors.Join(ErrUnknownAccount, fmt.Errorf("address: %s", key.Address()))
+	}
+	if !acc.revealed && !lo.SomeBy(op.Contents, func(content codec.Operation) bool {
+		_, isReveal := content.(*codec.Reveal)
+		return isReveal
+	}) {
+		op.WithContentsFront(&codec.Reveal{
+			Manager:   codec.Manager{Source: key.Address()},
+			PublicKey: key,
+		})
+		op.Contents[0].WithLimits(mavryk.Limits{GasLimit: ledger.options.Costs.RevealMilliGas / 1000})
+		utils.EstimateContentFee(op.Contents[0], ledger.options.Costs.RevealMilliGas/1000, nil)
+	}
+	op.WithBranch(mavryk.MustParseBlockHash("BM4VEjb3EGdgNgJhwfVUsUqPYvZWJUHdmKKgabuDkwy6SmUKDve"))
+	// node completes operations with the counter of the chain, operations in mempool are not considered
+	counter := acc.counter + 1
+	for i := range op.Contents {
+		op.Contents[i].WithCounter(counter + int64(i))
+	}
+	return nil
+}
+
+func getOpSource(op *codec.Op) mavryk.Address {
+	if len(op.Contents) == 0 {
+		return mavryk.ZeroAddress
+	}
+	switch content := op.Contents[0].(type) {
+	case *codec.Reveal:
+		return content.Source
+	case *codec.Transaction:
+		return content.Source
+	}
+	return op.Source
+}
+
+// broadcast validates the operation and adds it to mempool
+func (ledger *Ledger) broadcast(op *codec.Op) (mavryk.OpHash, error) {
+	if len(ledger.failures.broadcast) > 0 {
+		err := ledger.failures.broadcast[0]
+		ledger.failures.broadcast = ledger.failures.broadcast[1:]
+		return mavryk.ZeroOpHash, err
+	}
+
+	source := getOpSource(op)
+	acc, ok := ledger.accounts[source.String()]
+	if !ok {
+		return mavryk.ZeroOpHash, errors.Join(ErrUnknownAccount, fmt.Errorf("address: %s", source))
+	}
+	key := acc.key
+	if len(op.Contents) > 0 {
+		if reveal, ok := op.Contents[0].(*codec.Reveal); ok {
+			key = reveal.PublicKey
+		}
+	}
+	if !key.IsValid() || !op.Signature.IsValid() || key.Verify(op.Digest(), op.Signature) != nil {
+		return mavryk.ZeroOpHash, ErrInvalidSignature
+	}
+	if !acc.revealed && key.Equal(acc.key) {
+		if _, ok := op.Contents[0].(*codec.Reveal); !ok {
+			return mavryk.ZeroOpHash, ErrUnrevealedKey
+		}
+	}
+	counter := op.Contents[0].GetCounter()
+	if counter <= acc.counter {
+		return mavryk.ZeroOpHash, errors.Join(ErrCounterInThePast, fmt.Errorf("expected %d, got %d", acc.counter+1, counter))
+	}
+	fees := lo.SumBy(op.Contents, func(content codec.Operation) int64 { return content.Limits().Fee })
+	if fees > acc.balance {
+		return mavryk.ZeroOpHash, ErrBalanceTooLowForFees
+	}
+
+	// operation with the same counter is replaced only by operation paying higher fee
+	for i, pending := range ledger.mempool {
+		if !pending.source.Equal(source) || pending.op.Contents[0].GetCounter() != counter {
+			continue
+		}
+		pendingFees := lo.SumBy(pending.op.Contents, func(content codec.Operation) int64 { return content.Limits().Fee })
+		if fees <= pendingFees {
+			return mavryk.ZeroOpHash, ErrOperationConflict
+		}
+		ledger.mempool = append(ledger.mempool[:i], ledger.mempool[i+1:]...)
+		break
+	}
+
+	pending := &pendingOperation{
+		op:            op,
+		hash:          op.Hash(),
+		source:        source,
+		injectedLevel: ledger.level,
+	}
+	if ledger.failures.drop > 0 {
+		ledger.failures.drop--
+		pending.dropped = true
+	}
+	ledger.mempool = append(ledger.mempool, pending)
+	return pending.hash, nil
+}
+
+// bake produces a new block including applicable operations from mempool
+func (ledger *Ledger) bake() {
+	ledger.level++
+	sort.SliceStable(ledger.mempool, func(i, j int) bool {
+		return ledger.mempool[i].op.Contents[0].GetCounter() < ledger.mempool[j].op.Contents[0].GetCounter()
+	})
+
+	includedSources := make(map[string]bool)
+	remaining := make([]*pendingOperation, 0, len(ledger.mempool))
+	for _, pending := range ledger.mempool {
+		acc := ledger.getAccount(pending.source)
+		counter := pending.op.Contents[0].GetCounter()
+		switch {
+		case ledger.level > pending.injectedLevel+constants.MAX_OPERATION_TTL:
+			ledger.expired[pending.hash.String()] = true
+			continue
+		case counter <= acc.counter:
+			// conflicting operation was included
+			continue
+		case pending.dropped || counter != acc.counter+1:
+			remaining = append(remaining, pending)
+			continue
+		case ledger.options.OneOperationPerSourcePerBlock && includedSources[pending.source.String()]:
+			remaining = append(remaining, pending)
+			continue
+		}
+
+		reason := ""
+		if len(ledger.failures.application) > 0 {
+			reason = ledger.failures.application[0]
+			ledger.failures.application = ledger.failures.application[1:]
+		}
+		receipt := ledger.apply(pending.op, true, reason)
+		receipt.Block = mavryk.MustParseBlockHash("BM4VEjb3EGdgNgJhwfVUsUqPYvZWJUHdmKKgabuDkwy6SmUKDve")
+		status := common.OPERATION_STATUS_APPLIED
+		if !receipt.IsSuccess() {
+			status = common.OPERATION_STATUS_FAILED
+		}
+		ledger.included[pending.hash.String()] = &includedOperation{
+			level:   ledger.level,
+			receipt: receipt,
+			status:  status,
+		}
+		includedSources[pending.source.String()] = true
+	}
+	ledger.mempool = remaining
+}
+
+// Bake produces n blocks
+func (ledger *Ledger) Bake(n int) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	for i := 0; i < n; i++ {
+		ledger.bake()
+	}
+}
+
+// BakeUntilCycle produces blocks until the first block of the cycle
+func (ledger *Ledger) BakeUntilCycle(cycle int64) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+	for ledger.getCycle(ledger.level) < cycle {
+		ledger.bake()
+	}
+}
+
+func (ledger *Ledger) getOperationStatus(opHash mavryk.OpHash) common.OperationStatus {
+	if included, ok := ledger.included[opHash.String()]; ok {
+		return included.status
+	}
+	return common.OPERATION_STATUS_NOT_EXISTS
+}
+
+// waitForOperation bakes blocks until the operation is included and has the required confirmations, or it expires
+func (ledger *Ledger) waitForOperation(opHash mavryk.OpHash, ttl int64, confirmations int64) (*rpc.Receipt, error) {
+	ledger.mtx.Lock()
+	defer ledger.mtx.Unlock()
+
+	deadline := ledger.level + ttl
+	for {
+		if included, ok := ledger.included[opHash.String()]; ok {
+			if ledger.level-included.level >= confirmations {
+				return included.receipt, nil
+			}
+		} else if ledger.expired[opHash.String()] || ledger.level >= deadline {
+			return nil, errors.Join(ErrOperationExpired, fmt.Errorf("op_hash: %s", opHash))
+		} else if !lo.ContainsBy(ledger.mempool, func(pending *pendingOperation) bool { return pending.hash.Equal(opHash) }) {
+			return nil, errors.Join(ErrOperationNotFound, fmt.Errorf("op_hash: %s", opHash))
+		}
+		ledger.bake()
+	}
+}
@@ -0,0 +1,117 @@
+package simulator
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/utils"
+	"github.com/samber/lo"
+)
+
+// Reporter implements common.ReporterEngine in memory, reports of a cycle are replaced on every write like fs reporter does
+type Reporter struct {
+	mtx       sync.Mutex
+	reports   map[int64][]common.PayoutReport
+	invalid   map[int64][]common.PayoutReport
+	summaries map[int64]common.CyclePayoutSummary
+	carryOver *common.CarryOverLedger
+	// signing policy ledger is kept serialized so callers never share it
+	signingPolicyLedger []byte
+}
+
+func NewReporter() *Reporter {
+	return &Reporter{
+		reports:   make(map[int64][]common.PayoutReport),
+		invalid:   make(map[int64][]common.PayoutReport),
+		summaries: make(map[int64]common.CyclePayoutSummary),
+		carryOver: common.NewCarryOverLedger(),
+	}
+}
+
+func (engine *Reporter) GetExistingReports(cycle int64) ([]common.PayoutReport, error) {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	reports, ok := engine.reports[cycle]
+	if !ok {
+		return []common.PayoutReport{}, os.ErrNotExist
+	}
+	return append([]common.PayoutReport{}, reports...), nil
+}
+
+func (engine *Reporter) ReportPayouts(reports []common.PayoutReport) error {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	for cycle, cycleReports := range lo.GroupBy(reports, func(report common.PayoutReport) int64 { return report.Cycle }) {
+		engine.reports[cycle] = cycleReports
+	}
+	return nil
+}
+
+func (engine *Reporter) ReportInvalidPayouts(payouts []common.PayoutRecipe) error {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	for cycle, cyclePayouts := range lo.GroupBy(utils.OnlyInvalidPayouts(payouts), func(payout common.PayoutRecipe) int64 { return payout.Cycle }) {
+		engine.invalid[cycle] = lo.Map(cyclePayouts, func(payout common.PayoutRecipe, _ int) common.PayoutReport { return payout.ToPayoutReport() })
+	}
+	return nil
+}
+
+func (engine *Reporter) ReportCycleSummary(summary common.CyclePayoutSummary) error {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	engine.summaries[summary.Cycle] = summary
+	return nil
+}
+
+func (engine *Reporter) GetExistingCycleSummary(cycle int64) (*common.CyclePayoutSummary, error) {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	summary, ok := engine.summaries[cycle]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return &summary, nil
+}
+
+// GetInvalidReports returns reports of invalid payouts of the cycle
+func (engine *Reporter) GetInvalidReports(cycle int64) []common.PayoutReport {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	return append([]common.PayoutReport{}, engine.invalid[cycle]...)
+}
+
+func (engine *Reporter) GetCarryOverLedger() (*common.CarryOverLedger, error) {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	return &common.CarryOverLedger{Entries: append([]common.CarryOver{}, engine.carryOver.Entries...)}, nil
+}
+
+func (engine *Reporter) ReportCarryOverLedger(ledger *common.CarryOverLedger) error {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	engine.carryOver = &common.CarryOverLedger{Entries: append([]common.CarryOver{}, ledger.Entries...)}
+	return nil
+}
+
+func (engine *Reporter) GetSigningPolicyLedger() (*common.SigningPolicyLedger, error) {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	ledger := common.NewSigningPolicyLedger()
+	if engine.signingPolicyLedger == nil {
+		return ledger, nil
+	}
+	return ledger, json.Unmarshal(engine.signingPolicyLedger, ledger)
+}
+
+func (engine *Reporter) ReportSigningPolicyLedger(ledger *common.SigningPolicyLedger) error {
+	engine.mtx.Lock()
+	defer engine.mtx.Unlock()
+	data, err := json.Marshal(ledger)
+	if err != nil {
+		return err
+	}
+	engine.signingPolicyLedger = data
+	return nil
+}
@@ -0,0 +1,64 @@
+package simulator
+
+import (
+	"sync"
+
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/signer"
+)
+
+// Signer implements common.SignerEngine with the private key held in memory, signing can be made to fail
+type Signer struct {
+	key mavryk.PrivateKey
+
+	mtx      sync.Mutex
+	failures []error
+}
+
+func NewSigner(key mavryk.PrivateKey) *Signer {
+	return &Signer{
+		key: key,
+	}
+}
+
+// NewRandomSigner generates a new ed25519 key
+func NewRandomSigner() *Signer {
+	key, _ := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+	return NewSigner(key)
+}
+
+// FailNextSignings makes next signings fail with the errors, one error per signing
+func (s *Signer) FailNextSignings(errs ...error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.failures = append(s.failures, errs...)
+}
+
+func (s *Signer) GetId() string {
+	return "SimulatorSigner"
+}
+
+func (s *Signer) Sign(op *codec.Op) error {
+	s.mtx.Lock()
+	if len(s.failures) > 0 {
+		err := s.failures[0]
+		s.failures = s.failures[1:]
+		s.mtx.Unlock()
+		return err
+	}
+	s.mtx.Unlock()
+	return op.Sign(s.key)
+}
+
+func (s *Signer) GetPKH() mavryk.Address {
+	return s.key.Address()
+}
+
+func (s *Signer) GetKey() mavryk.Key {
+	return s.key.Public()
+}
+
+func (s *Signer) GetSigner() signer.Signer {
+	return signer.NewFromKey(s.key)
+}
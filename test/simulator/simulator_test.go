@@ -0,0 +1,541 @@
+package simulator
+
+import (
+	"errors"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/configuration"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mavpay/core"
+	journal_engines "github.com/mavryk-network/mavpay/engines/journal"
+	replay_engines "github.com/mavryk-network/mavpay/engines/replay"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLedgerOptions() LedgerOptions {
+	options := DefaultLedgerOptions()
+	options.BlocksPerCycle = 16
+	return options
+}
+
+func newTestChain(t *testing.T) *Chain {
+	chain := NewChain(testLedgerOptions(), mavryk.NewZ(10_000).Mul64(constants.MUMAV_FACTOR).Int64())
+	assert.Nil(t, chain.InitState(t.TempDir()))
+	return chain
+}
+
+func newTestDelegators(count int) []common.Delegator {
+	return lo.Times(count, func(_ int) common.Delegator {
+		key, _ := mavryk.GenerateKey(mavryk.KeyTypeEd25519)
+		return common.Delegator{
+			Address:          key.Address(),
+			DelegatedBalance: mavryk.NewZ(1_000).Mul64(constants.MUMAV_FACTOR),
+		}
+	})
+}
+
+func generatePayouts(chain *Chain, config *configuration.RuntimeConfiguration, cycle int64) (*common.CyclePayoutBlueprint, error) {
+	return core.GeneratePayouts(config, common.NewGeneratePayoutsEngines(chain.Collector, chain.Signer, func(string) {}), &common.GeneratePayoutsOptions{
+		Cycle: cycle,
+	})
+}
+
+// payBlueprints prepares and executes payouts of the blueprints the same way pay and pay-date-range commands do
+func payBlueprints(chain *Chain, config *configuration.RuntimeConfiguration, blueprints []*common.CyclePayoutBlueprint, accumulate bool) (*common.PreparePayoutsResult, *common.ExecutePayoutsResult, error) {
+	preparationResult, err := core.PreparePayouts(blueprints, config, common.NewPreparePayoutsEngineContext(chain.Collector, chain.Signer, chain.Reporter, func(string) {}).WithTransactor(chain.Transactor), &common.PreparePayoutsOptions{
+		Accumulate: accumulate,
+	})
+	if err != nil || len(preparationResult.ValidPayouts) == 0 {
+		return preparationResult, nil, err
+	}
+	executionResult, err := core.ExecutePayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(chain.Signer, chain.Transactor, chain.Reporter, func(string) {}), &common.ExecutePayoutsOptions{})
+	return preparationResult, executionResult, err
+}
+
+func payCycle(t *testing.T, chain *Chain, config *configuration.RuntimeConfiguration, cycle int64) (*common.PreparePayoutsResult, *common.ExecutePayoutsResult) {
+	blueprint, err := generatePayouts(chain, config, cycle)
+	assert.Nil(t, err)
+	preparationResult, executionResult, _ := payBlueprints(chain, config, []*common.CyclePayoutBlueprint{blueprint}, false)
+	return preparationResult, executionResult
+}
+
+func getPaidAmounts(results common.BatchResults) map[string]int64 {
+	paid := make(map[string]int64)
+	for _, result := range results {
+		if !result.IsSuccess {
+			continue
+		}
+		for _, payout := range result.Payouts {
+			paid[payout.Recipient.String()] += payout.Amount.Int64()
+		}
+	}
+	return paid
+}
+
+func TestPayCycle(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	delegators := newTestDelegators(5)
+	chain.SetCycleRewards(1, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), delegators...)
+	chain.Ledger.BakeUntilCycle(2)
+	config := chain.GetConfiguration()
+
+	preparationResult, executionResult := payCycle(t, chain, config, 1)
+	assert.Len(preparationResult.ValidPayouts, len(delegators))
+	assert.True(lo.EveryBy(executionResult.BatchResults, func(result common.BatchResult) bool { return result.IsSuccess }))
+	for _, payout := range preparationResult.ValidPayouts {
+		assert.True(chain.Ledger.IsAllocated(payout.Recipient))
+		assert.Equal(payout.Amount.Int64(), chain.Ledger.GetBalance(payout.Recipient))
+	}
+	assert.Equal(int64(len(delegators)), chain.Ledger.GetCounter(chain.Signer.GetPKH()))
+
+	reports, err := chain.Reporter.GetExistingReports(1)
+	assert.Nil(err)
+	assert.Len(lo.Filter(reports, func(report common.PayoutReport, _ int) bool { return report.IsSuccess }), len(delegators))
+
+	// paid payouts are not paid again
+	preparationResult, executionResult = payCycle(t, chain, config, 1)
+	assert.Empty(preparationResult.ValidPayouts)
+	assert.Nil(executionResult)
+}
+
+func TestPayRejectingContract(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	rejecting := NewContract(NewContractAddress(), CONTRACT_REJECTING)
+	accepting := NewContract(NewContractAddress(), CONTRACT_ACCEPTING)
+	chain.Ledger.AddContract(rejecting)
+	chain.Ledger.AddContract(accepting)
+	delegators := append(newTestDelegators(2),
+		common.Delegator{Address: rejecting.Address, DelegatedBalance: mavryk.NewZ(1_000).Mul64(constants.MUMAV_FACTOR)},
+		common.Delegator{Address: accepting.Address, DelegatedBalance: mavryk.NewZ(1_000).Mul64(constants.MUMAV_FACTOR)},
+	)
+	chain.SetCycleRewards(1, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), delegators...)
+	chain.Ledger.BakeUntilCycle(2)
+	config := chain.GetConfiguration()
+
+	preparationResult, executionResult := payCycle(t, chain, config, 1)
+	invalid, ok := lo.Find(preparationResult.InvalidPayouts, func(payout common.PayoutRecipe) bool { return payout.Recipient.Equal(rejecting.Address) })
+	assert.True(ok)
+	assert.Equal(string(enums.INVALID_FAILED_TO_ESTIMATE_TX_COSTS), invalid.Note)
+	assert.Equal(int64(0), chain.Ledger.GetBalance(rejecting.Address))
+
+	paid := getPaidAmounts(executionResult.BatchResults)
+	assert.Len(paid, 3)
+	assert.Greater(chain.Ledger.GetBalance(accepting.Address), int64(0))
+	assert.Equal(paid[accepting.Address.String()], chain.Ledger.GetBalance(accepting.Address))
+}
+
+func TestPayReplacesDroppedOperation(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	delegators := newTestDelegators(3)
+	chain.SetCycleRewards(1, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), delegators...)
+	chain.Ledger.BakeUntilCycle(2)
+	config := chain.GetConfiguration()
+	config.PayoutConfiguration.FeeBumping.MaximumReplacements = 2
+
+	chain.Ledger.DropNextOperations(1)
+	preparationResult, executionResult := payCycle(t, chain, config, 1)
+	assert.Len(executionResult.BatchResults, 1)
+	result := executionResult.BatchResults[0]
+	assert.True(result.IsSuccess)
+	assert.Len(result.OpHashChain, 2)
+	assert.Equal(result.OpHashChain[1], result.OpHash)
+	for _, payout := range preparationResult.ValidPayouts {
+		assert.Equal(payout.Amount.Int64(), chain.Ledger.GetBalance(payout.Recipient))
+	}
+	// replacement keeps counters of the dropped operation
+	assert.Equal(int64(len(delegators)), chain.Ledger.GetCounter(chain.Signer.GetPKH()))
+}
+
+func TestPayRetriesFailedBroadcast(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	delegators := newTestDelegators(3)
+	chain.SetCycleRewards(1, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), delegators...)
+	chain.Ledger.BakeUntilCycle(2)
+	config := chain.GetConfiguration()
+
+	chain.Ledger.FailNextBroadcasts(errors.New("node unavailable"))
+	preparationResult, executionResult := payCycle(t, chain, config, 1)
+	assert.Len(executionResult.BatchResults, 1)
+	assert.False(executionResult.BatchResults[0].IsSuccess)
+	assert.ErrorIs(executionResult.BatchResults[0].Err, constants.ErrOperationBroadcastFailed)
+	for _, payout := range preparationResult.ValidPayouts {
+		assert.Equal(int64(0), chain.Ledger.GetBalance(payout.Recipient))
+	}
+
+	preparationResult, executionResult = payCycle(t, chain, config, 1)
+	assert.Len(preparationResult.ValidPayouts, len(delegators))
+	assert.True(executionResult.BatchResults[0].IsSuccess)
+	for _, payout := range preparationResult.ValidPayouts {
+		assert.Equal(payout.Amount.Int64(), chain.Ledger.GetBalance(payout.Recipient))
+	}
+}
+
+func TestPayFailedOperationChargesFees(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	delegators := newTestDelegators(3)
+	chain.SetCycleRewards(1, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), delegators...)
+	chain.Ledger.BakeUntilCycle(2)
+	config := chain.GetConfiguration()
+
+	balance := chain.Ledger.GetBalance(chain.Signer.GetPKH())
+	chain.Ledger.FailNextApplications(FAILURE_GAS_EXHAUSTED)
+	preparationResult, executionResult := payCycle(t, chain, config, 1)
+	assert.Len(executionResult.BatchResults, 1)
+	assert.False(executionResult.BatchResults[0].IsSuccess)
+	assert.ErrorIs(executionResult.BatchResults[0].Err, constants.ErrOperationFailed)
+
+	fees := lo.SumBy(preparationResult.ValidPayouts, func(payout common.PayoutRecipe) int64 { return payout.OpLimits.TransactionFee })
+	assert.Equal(balance-fees, chain.Ledger.GetBalance(chain.Signer.GetPKH()))
+	for _, payout := range preparationResult.ValidPayouts {
+		assert.False(chain.Ledger.IsAllocated(payout.Recipient))
+	}
+	status, err := chain.Collector.WasOperationApplied(executionResult.BatchResults[0].OpHash)
+	assert.Nil(err)
+	assert.Equal(common.OPERATION_STATUS_FAILED, status)
+}
+
+func TestContinualPayouts(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	delegators := newTestDelegators(3)
+	for cycle := int64(1); cycle <= 3; cycle++ {
+		chain.SetCycleRewards(cycle, mavryk.NewZ(100*cycle).Mul64(constants.MUMAV_FACTOR).Int64(), delegators...)
+	}
+	chain.Collector.StopMonitorsAfterCycle(3)
+	chain.Ledger.BakeUntilCycle(1)
+	config := chain.GetConfiguration()
+
+	monitor, err := chain.Collector.CreateCycleMonitor(common.CycleMonitorOptions{})
+	assert.Nil(err)
+	lastProcessedCycle, err := chain.Collector.GetLastCompletedCycle()
+	assert.Nil(err)
+
+	paidCycles := make([]int64, 0)
+	paid := make(map[string]int64)
+	for {
+		cycle, err := monitor.WaitForNextCompletedCycle(lastProcessedCycle)
+		if errors.Is(err, constants.ErrMonitoringCanceled) {
+			break
+		}
+		assert.Nil(err)
+		_, executionResult := payCycle(t, chain, config, cycle)
+		for recipient, amount := range getPaidAmounts(executionResult.BatchResults) {
+			paid[recipient] += amount
+		}
+		paidCycles = append(paidCycles, cycle)
+		lastProcessedCycle = cycle
+	}
+
+	assert.Equal([]int64{1, 2, 3}, paidCycles)
+	for _, delegator := range delegators {
+		assert.Greater(paid[delegator.Address.String()], int64(0))
+		assert.Equal(paid[delegator.Address.String()], chain.Ledger.GetBalance(delegator.Address))
+	}
+}
+
+func TestPayDateRange(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	delegators := newTestDelegators(3)
+	for cycle := int64(0); cycle <= 3; cycle++ {
+		chain.SetCycleRewards(cycle, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), delegators...)
+	}
+	chain.Ledger.BakeUntilCycle(4)
+	config := chain.GetConfiguration()
+
+	options := testLedgerOptions()
+	cycleDuration := time.Duration(options.BlocksPerCycle) * options.BlockTime
+	cycles, err := chain.Collector.GetCyclesInDateRange(options.Genesis.Add(cycleDuration+time.Second), options.Genesis.Add(3*cycleDuration))
+	assert.Nil(err)
+	assert.Equal([]int64{1, 2}, cycles)
+
+	blueprints := lo.Map(cycles, func(cycle int64, _ int) *common.CyclePayoutBlueprint {
+		blueprint, err := generatePayouts(chain, config, cycle)
+		assert.Nil(err)
+		return blueprint
+	})
+	preparationResult, executionResult, err := payBlueprints(chain, config, blueprints, true)
+	assert.Nil(err)
+	// payouts of both cycles are accumulated into single transaction per delegator
+	assert.Len(preparationResult.ValidPayouts, len(delegators))
+	assert.Len(preparationResult.AccumulatedPayouts, len(delegators))
+	paid := getPaidAmounts(executionResult.BatchResults)
+	for _, delegator := range delegators {
+		assert.Equal(paid[delegator.Address.String()], chain.Ledger.GetBalance(delegator.Address))
+	}
+	for _, cycle := range cycles {
+		reports, err := chain.Reporter.GetExistingReports(cycle)
+		assert.Nil(err)
+		assert.Len(reports, len(delegators))
+	}
+}
+
+func sendTransfer(chain *Chain, recipe *common.PayoutRecipe) (bool, error) {
+	op := codec.NewOp().WithSource(chain.Signer.GetPKH())
+	op.WithTTL(constants.MAX_OPERATION_TTL)
+	if err := common.InjectTransferContentsWithLimits(op, chain.Signer.GetPKH(), recipe, mavryk.Limits{Fee: 10_000, GasLimit: 10_000, StorageLimit: 1_000}); err != nil {
+		return false, err
+	}
+	if err := chain.Transactor.Complete(op, chain.Signer.GetKey()); err != nil {
+		return false, err
+	}
+	if err := chain.Signer.Sign(op); err != nil {
+		return false, err
+	}
+	receipt, err := chain.Transactor.Send(op, nil)
+	if err != nil {
+		return false, err
+	}
+	return receipt.IsSuccess(), nil
+}
+
+func TestTokenTransfers(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	payoutAddress := chain.Signer.GetPKH()
+	fa12 := NewContract(NewContractAddress(), CONTRACT_FA1_2).WithTokens(payoutAddress, 0, 1_000)
+	fa2 := NewContract(NewContractAddress(), CONTRACT_FA2).WithTokens(payoutAddress, 7, 1_000)
+	chain.Ledger.AddContract(fa12)
+	chain.Ledger.AddContract(fa2)
+	recipient := newTestDelegators(1)[0].Address
+
+	balance := chain.Ledger.GetBalance(payoutAddress)
+	ok, err := sendTransfer(chain, &common.PayoutRecipe{Recipient: recipient, TxKind: enums.PAYOUT_TX_KIND_FA1_2, FAContract: fa12.Address, Amount: mavryk.NewZ(300)})
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal(int64(700), chain.Ledger.GetTokenBalance(fa12.Address, payoutAddress, 0))
+	assert.Equal(int64(300), chain.Ledger.GetTokenBalance(fa12.Address, recipient, 0))
+	// new holder of the token pays storage
+	costs := DefaultCosts()
+	assert.Equal(balance-10_000-costs.TokenHolderStorage*costs.StorageCostPerByte, chain.Ledger.GetBalance(payoutAddress))
+
+	ok, err = sendTransfer(chain, &common.PayoutRecipe{Recipient: recipient, TxKind: enums.PAYOUT_TX_KIND_FA2, FAContract: fa2.Address, FATokenId: mavryk.NewZ(7), Amount: mavryk.NewZ(1_000)})
+	assert.Nil(err)
+	assert.True(ok)
+	assert.Equal(int64(0), chain.Ledger.GetTokenBalance(fa2.Address, payoutAddress, 7))
+	assert.Equal(int64(1_000), chain.Ledger.GetTokenBalance(fa2.Address, recipient, 7))
+
+	// insufficient token balance fails the whole operation
+	ok, err = sendTransfer(chain, &common.PayoutRecipe{Recipient: recipient, TxKind: enums.PAYOUT_TX_KIND_FA1_2, FAContract: fa12.Address, Amount: mavryk.NewZ(701)})
+	assert.Nil(err)
+	assert.False(ok)
+	assert.Equal(int64(700), chain.Ledger.GetTokenBalance(fa12.Address, payoutAddress, 0))
+}
+
+func TestPayCarryOver(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	big := newTestDelegators(1)[0]
+	small := newTestDelegators(1)[0]
+	small.DelegatedBalance = mavryk.NewZ(10).Mul64(constants.MUMAV_FACTOR)
+	for cycle := int64(1); cycle <= 3; cycle++ {
+		chain.SetCycleRewards(cycle, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), big, small)
+	}
+	chain.Ledger.BakeUntilCycle(4)
+	config := chain.GetConfiguration()
+	config.PayoutConfiguration.MinimumAmount = mavryk.NewZ(200_000)
+	config.PayoutConfiguration.IsPayingAllocationTxFee = true
+	config.PayoutConfiguration.CarryOver = &configuration.RuntimeCarryOverConfiguration{}
+
+	isSmall := func(payout common.PayoutRecipe) bool { return payout.Delegator.Equal(small.Address) }
+	carried := mavryk.Zero
+	for cycle := int64(1); cycle <= 2; cycle++ {
+		preparationResult, _ := payCycle(t, chain, config, cycle)
+		invalid, ok := lo.Find(preparationResult.InvalidPayouts, isSmall)
+		assert.True(ok)
+		assert.Equal(string(enums.INVALID_PAYOUT_BELLOW_MINIMUM), invalid.Note)
+		carried = carried.Add(invalid.Amount)
+
+		ledger, err := chain.Reporter.GetCarryOverLedger()
+		assert.Nil(err)
+		assert.Len(ledger.GetEntries(small.Address.String()), int(cycle))
+		assert.Equal(int64(0), chain.Ledger.GetBalance(small.Address))
+	}
+
+	preparationResult, executionResult := payCycle(t, chain, config, 3)
+	payout, ok := lo.Find(preparationResult.ValidPayouts, isSmall)
+	assert.True(ok)
+	assert.Equal([]int64{1, 2, 3}, payout.CarriedOverCycles)
+	assert.Equal("carried over from #1-2", payout.Note)
+	assert.True(payout.Amount.IsGreater(carried))
+	assert.Equal(getPaidAmounts(executionResult.BatchResults)[small.Address.String()], chain.Ledger.GetBalance(small.Address))
+	ledger, err := chain.Reporter.GetCarryOverLedger()
+	assert.Nil(err)
+	assert.Empty(ledger.Entries)
+
+	reports, err := chain.Reporter.GetExistingReports(3)
+	assert.Nil(err)
+	report, ok := lo.Find(reports, func(report common.PayoutReport) bool { return report.Delegator.Equal(small.Address) })
+	assert.True(ok)
+	assert.Equal("carried over from #1-2", report.Note)
+
+	// paid out payout bellow minimum is not carried over again
+	preparationResult, _ = payCycle(t, chain, config, 3)
+	assert.Empty(preparationResult.ValidPayouts)
+	assert.Empty(preparationResult.CarryOverLedger.Entries)
+}
+
+func TestPayCarryOverAfterMaximumCycles(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	big := newTestDelegators(1)[0]
+	small := newTestDelegators(1)[0]
+	small.DelegatedBalance = mavryk.NewZ(10).Mul64(constants.MUMAV_FACTOR)
+	for cycle := int64(1); cycle <= 2; cycle++ {
+		chain.SetCycleRewards(cycle, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), big, small)
+	}
+	chain.Ledger.BakeUntilCycle(3)
+	config := chain.GetConfiguration()
+	config.PayoutConfiguration.MinimumAmount = mavryk.NewZ(1_000_000)
+	config.PayoutConfiguration.IsPayingAllocationTxFee = true
+	config.PayoutConfiguration.CarryOver = &configuration.RuntimeCarryOverConfiguration{MaximumCycles: 2}
+
+	payCycle(t, chain, config, 1)
+	assert.Equal(int64(0), chain.Ledger.GetBalance(small.Address))
+
+	preparationResult, _ := payCycle(t, chain, config, 2)
+	payout, ok := lo.Find(preparationResult.ValidPayouts, func(payout common.PayoutRecipe) bool { return payout.Delegator.Equal(small.Address) })
+	assert.True(ok)
+	assert.True(payout.Amount.IsLess(config.PayoutConfiguration.MinimumAmount))
+	assert.Equal(payout.Amount.Int64(), chain.Ledger.GetBalance(small.Address))
+}
+
+// crashedReporter loses all writes, as if the process crashed right after the batches were confirmed
+type crashedReporter struct {
+	*Reporter
+}
+
+func (engine *crashedReporter) ReportPayouts(reports []common.PayoutReport) error {
+	return errors.New("crashed")
+}
+
+func (engine *crashedReporter) ReportCarryOverLedger(ledger *common.CarryOverLedger) error {
+	return errors.New("crashed")
+}
+
+func TestReconcileCarryOverAfterCrash(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	big := newTestDelegators(1)[0]
+	small := newTestDelegators(1)[0]
+	small.DelegatedBalance = mavryk.NewZ(10).Mul64(constants.MUMAV_FACTOR)
+	for cycle := int64(1); cycle <= 3; cycle++ {
+		chain.SetCycleRewards(cycle, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), big, small)
+	}
+	chain.Ledger.BakeUntilCycle(4)
+	config := chain.GetConfiguration()
+	config.PayoutConfiguration.MinimumAmount = mavryk.NewZ(200_000)
+	config.PayoutConfiguration.IsPayingAllocationTxFee = true
+	config.PayoutConfiguration.CarryOver = &configuration.RuntimeCarryOverConfiguration{}
+
+	payCycle(t, chain, config, 1)
+	payCycle(t, chain, config, 2)
+	ledger, err := chain.Reporter.GetCarryOverLedger()
+	assert.Nil(err)
+	assert.Len(ledger.GetEntries(small.Address.String()), 2)
+
+	journal := journal_engines.NewFsJournal(path.Join(t.TempDir(), "payouts.journal"))
+	reporter := &crashedReporter{Reporter: chain.Reporter}
+	blueprint, err := generatePayouts(chain, config, 3)
+	assert.Nil(err)
+	preparationResult, err := core.PreparePayouts([]*common.CyclePayoutBlueprint{blueprint}, config, common.NewPreparePayoutsEngineContext(chain.Collector, chain.Signer, reporter, func(string) {}).WithTransactor(chain.Transactor), &common.PreparePayoutsOptions{})
+	assert.Nil(err)
+	payout, ok := lo.Find(preparationResult.ValidPayouts, func(payout common.PayoutRecipe) bool { return payout.Delegator.Equal(small.Address) })
+	assert.True(ok)
+	assert.Equal([]int64{1, 2, 3}, payout.CarriedOverCycles)
+	executionResult, err := core.ExecutePayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(chain.Signer, chain.Transactor, reporter, func(string) {}).WithJournal(journal), &common.ExecutePayoutsOptions{})
+	assert.Nil(err)
+	paid := chain.Ledger.GetBalance(small.Address)
+	assert.Equal(getPaidAmounts(executionResult.BatchResults)[small.Address.String()], paid)
+
+	// paid out amount is still in the ledger stored before the crash
+	ledger, err = chain.Reporter.GetCarryOverLedger()
+	assert.Nil(err)
+	assert.Len(ledger.GetEntries(small.Address.String()), 2)
+
+	assert.Nil(core.ReconcilePayoutJournal(common.NewReconcilePayoutsEngineContext(journal, chain.Collector, chain.Transactor, chain.Reporter), []int64{3}))
+	ledger, err = chain.Reporter.GetCarryOverLedger()
+	assert.Nil(err)
+	assert.Empty(ledger.Entries)
+	reports, err := chain.Reporter.GetExistingReports(3)
+	assert.Nil(err)
+	assert.True(lo.ContainsBy(reports, func(report common.PayoutReport) bool {
+		return report.Delegator.Equal(small.Address) && report.IsSuccess
+	}))
+
+	// carried over amount is not paid again
+	preparationResult, _ = payCycle(t, chain, config, 3)
+	assert.Empty(preparationResult.ValidPayouts)
+	assert.Equal(paid, chain.Ledger.GetBalance(small.Address))
+}
+
+// payCycleWith generates, prepares and executes payouts of the cycle with the given engines
+func payCycleWith(t *testing.T, config *configuration.RuntimeConfiguration, collector common.CollectorEngine, signer common.SignerEngine, transactor common.TransactorEngine, reporter common.ReporterEngine, cycle int64) (*common.CyclePayoutBlueprint, *common.ExecutePayoutsResult) {
+	blueprint, err := core.GeneratePayouts(config, common.NewGeneratePayoutsEngines(collector, signer, func(string) {}), &common.GeneratePayoutsOptions{
+		Cycle: cycle,
+	})
+	assert.Nil(t, err)
+	preparationResult, err := core.PreparePayouts([]*common.CyclePayoutBlueprint{blueprint}, config, common.NewPreparePayoutsEngineContext(collector, signer, reporter, func(string) {}).WithTransactor(transactor), &common.PreparePayoutsOptions{})
+	assert.Nil(t, err)
+	executionResult, err := core.ExecutePayouts(preparationResult, config, common.NewExecutePayoutsEngineContext(signer, transactor, reporter, func(string) {}), &common.ExecutePayoutsOptions{})
+	assert.Nil(t, err)
+	return blueprint, executionResult
+}
+
+func TestReplayRecordedPayouts(t *testing.T) {
+	assert := assert.New(t)
+
+	chain := newTestChain(t)
+	delegators := newTestDelegators(3)
+	chain.SetCycleRewards(1, mavryk.NewZ(100).Mul64(constants.MUMAV_FACTOR).Int64(), delegators...)
+	chain.Ledger.BakeUntilCycle(2)
+	config := chain.GetConfiguration()
+
+	fixturePath := path.Join(t.TempDir(), "fixtures.jsonl")
+	recorder, err := replay_engines.NewFixtureRecorder(fixturePath)
+	assert.Nil(err)
+	recordedBlueprint, recordedResult := payCycleWith(t, config,
+		replay_engines.NewRecordingColletor(chain.Collector, recorder), chain.Signer,
+		replay_engines.NewRecordingTransactor(chain.Transactor, recorder), NewReporter(), 1)
+	assert.Nil(recorder.Close())
+
+	fixtures, err := replay_engines.LoadFixtures(fixturePath)
+	assert.Nil(err)
+	replayedBlueprint, replayedResult := payCycleWith(t, config,
+		replay_engines.NewReplayColletor(fixtures), chain.Signer,
+		replay_engines.NewReplayTransactor(fixtures), NewReporter(), 1)
+
+	assert.Equal(recordedBlueprint.Payouts, replayedBlueprint.Payouts)
+	replayedBlueprint.Summary.Timestamp = recordedBlueprint.Summary.Timestamp
+	assert.Equal(recordedBlueprint.Summary, replayedBlueprint.Summary)
+	// replayed operations are completed as recorded so they match recorded dispatches
+	assert.Len(replayedResult.BatchResults, len(recordedResult.BatchResults))
+	for i, result := range replayedResult.BatchResults {
+		assert.True(result.IsSuccess)
+		assert.Equal(recordedResult.BatchResults[i].OpHash, result.OpHash)
+	}
+	assert.Equal(getPaidAmounts(recordedResult.BatchResults), getPaidAmounts(replayedResult.BatchResults))
+}
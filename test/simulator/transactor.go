@@ -0,0 +1,122 @@
+package simulator
+
+import (
+	"errors"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants"
+	"github.com/mavryk-network/mvgo/codec"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/mavryk-network/mvgo/rpc"
+)
+
+// Transactor implements common.TransactorEngine on top of the simulated ledger. Waiting for operations bakes blocks.
+type Transactor struct {
+	ledger        *Ledger
+	confirmations int64
+}
+
+type TransactorOpResult struct {
+	opHash        mavryk.OpHash
+	ledger        *Ledger
+	ttl           int64
+	confirmations int64
+}
+
+func (result *TransactorOpResult) GetOpHash() mavryk.OpHash {
+	return result.opHash
+}
+
+func (result *TransactorOpResult) WaitForApply() error {
+	receipt, err := result.ledger.waitForOperation(result.opHash, result.ttl, result.confirmations)
+	if err != nil {
+		return errors.Join(constants.ErrOperationNotIncluded, err)
+	}
+	if !receipt.IsSuccess() {
+		return errors.Join(constants.ErrOperationFailed, receipt.Error())
+	}
+	return nil
+}
+
+func NewTransactor(ledger *Ledger) *Transactor {
+	return &Transactor{
+		ledger:        ledger,
+		confirmations: constants.DEFAULT_REQUIRED_CONFIRMATIONS,
+	}
+}
+
+// WithConfirmations sets number of blocks on top of the including block required by Dispatch results
+func (transactor *Transactor) WithConfirmations(confirmations int64) *Transactor {
+	transactor.confirmations = confirmations
+	return transactor
+}
+
+func (transactor *Transactor) GetId() string {
+	return "SimulatorTransactor"
+}
+
+func (transactor *Transactor) RefreshParams() error {
+	return nil
+}
+
+func (transactor *Transactor) GetLimits() (*common.OperationLimits, error) {
+	costs := transactor.ledger.options.Costs
+	return &common.OperationLimits{
+		HardGasLimitPerOperation:     costs.HardGasLimitPerOperation,
+		HardStorageLimitPerOperation: costs.HardStorageLimitPerOp,
+		MaxOperationDataLength:       costs.MaxOperationDataLength,
+	}, nil
+}
+
+func (transactor *Transactor) Complete(op *codec.Op, key mavryk.Key) error {
+	transactor.ledger.mtx.Lock()
+	defer transactor.ledger.mtx.Unlock()
+	return transactor.ledger.complete(op, key)
+}
+
+func (transactor *Transactor) Broadcast(op *codec.Op) (mavryk.OpHash, error) {
+	transactor.ledger.mtx.Lock()
+	defer transactor.ledger.mtx.Unlock()
+	return transactor.ledger.broadcast(op)
+}
+
+func (transactor *Transactor) Dispatch(op *codec.Op, opts *rpc.CallOptions) (common.OpResult, error) {
+	if opts == nil {
+		opts = &rpc.DefaultOptions
+	}
+	opHash, err := transactor.Broadcast(op)
+	if err != nil {
+		return nil, err
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = constants.MAX_OPERATION_TTL
+	}
+	return &TransactorOpResult{
+		opHash:        opHash,
+		ledger:        transactor.ledger,
+		ttl:           ttl,
+		confirmations: transactor.confirmations,
+	}, nil
+}
+
+func (transactor *Transactor) Send(op *codec.Op, opts *rpc.CallOptions) (*rpc.Receipt, error) {
+	if opts == nil {
+		opts = &rpc.DefaultOptions
+	}
+	opHash, err := transactor.Broadcast(op)
+	if err != nil {
+		return nil, err
+	}
+	return transactor.WaitOpConfirmation(opHash, max(opts.TTL, constants.MAX_OPERATION_TTL), opts.Confirmations)
+}
+
+func (transactor *Transactor) WaitOpConfirmation(opHash mavryk.OpHash, ttl int64, confirmations int64) (*rpc.Receipt, error) {
+	return transactor.ledger.waitForOperation(opHash, ttl, confirmations)
+}
+
+func (transactor *Transactor) GetOperationStatus(opHash mavryk.OpHash) (common.OperationStatus, error) {
+	transactor.ledger.mtx.Lock()
+	defer transactor.ledger.mtx.Unlock()
+	return transactor.ledger.getOperationStatus(opHash), nil
+}
@@ -0,0 +1,392 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	QUARANTINE_AFTER_FAILURES  = 3
+	MIN_QUARANTINE_DURATION    = 30 * time.Second
+	MAX_QUARANTINE_DURATION    = 10 * time.Minute
+	LATENCY_SMOOTHING_FACTOR   = 0.3
+	LATENCY_PRIORITY_TOLERANCE = 2 * time.Second
+)
+
+var (
+	ErrNoEndpointAvailable = errors.New("no endpoint available")
+)
+
+type EndpointDefinition struct {
+	Url      string       `json:"url" comment:"url of the endpoint"`
+	Priority int          `json:"priority,omitempty" comment:"lower number means higher priority, endpoints with the same priority are ordered by latency"`
+	Http     *HttpOptions `json:"http,omitempty" comment:"http options of the endpoint (timeout, proxy, headers, tls), merged with http options of the network"`
+}
+
+type endpoint struct {
+	url       *url.URL
+	priority  int
+	transport http.RoundTripper
+	timeout   time.Duration
+
+	latency            time.Duration
+	failures           int
+	quarantinedUntil   time.Time
+	quarantineDuration time.Duration
+}
+
+func (e *endpoint) isQuarantined(now time.Time) bool {
+	return now.Before(e.quarantinedUntil)
+}
+
+// EndpointPool tracks health of a group of interchangeable endpoints.
+// The first (primary) endpoint url is the one requests are addressed to,
+// the pool decides which endpoint actually serves them.
+type EndpointPool struct {
+	id        string
+	primary   *url.URL
+	endpoints []*endpoint
+	mtx       sync.Mutex
+	now       func() time.Time
+}
+
+func newEndpoint(u *url.URL, priority int, options *HttpOptions) (*endpoint, error) {
+	result := &endpoint{
+		url:      u,
+		priority: priority,
+		timeout:  options.GetTimeout(0),
+	}
+	if options != nil {
+		transport, err := options.NewTransport()
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("invalid http options of endpoint '%s'", u.Host), err)
+		}
+		result.transport = transport
+	}
+	return result, nil
+}
+
+// NewEndpointPool creates pool of the primary and additional endpoints. Options apply to all endpoints
+// and are merged with options of the endpoint definitions. To configure the primary endpoint
+// list it among additional endpoints.
+func NewEndpointPool(id string, primary string, options *HttpOptions, additional []EndpointDefinition) (*EndpointPool, error) {
+	primaryUrl, err := url.Parse(primary)
+	if err != nil {
+		return nil, err
+	}
+	primaryEndpoint, err := newEndpoint(primaryUrl, 0, options)
+	if err != nil {
+		return nil, err
+	}
+	pool := &EndpointPool{
+		id:        id,
+		primary:   primaryUrl,
+		endpoints: []*endpoint{primaryEndpoint},
+		now:       time.Now,
+	}
+	for _, definition := range additional {
+		u, err := url.Parse(definition.Url)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("invalid %s endpoint '%s'", id, definition.Url), err)
+		}
+		e, err := newEndpoint(u, definition.Priority, options.Merge(definition.Http))
+		if err != nil {
+			return nil, err
+		}
+		if u.String() == primaryUrl.String() {
+			e.url = primaryUrl
+			pool.endpoints[0] = e
+			continue
+		}
+		pool.endpoints = append(pool.endpoints, e)
+	}
+	return pool, nil
+}
+
+var (
+	pools    = make(map[string]*EndpointPool)
+	poolsMtx sync.Mutex
+)
+
+// GetEndpointPool returns shared pool for the given endpoints so health
+// of the endpoints is tracked across all engines using them.
+func GetEndpointPool(id string, primary string, options *HttpOptions, additional []EndpointDefinition) (*EndpointPool, error) {
+	definition, _ := json.Marshal(struct {
+		Options    *HttpOptions
+		Additional []EndpointDefinition
+	}{options, additional})
+	key := fmt.Sprintf("%s|%s|%s", id, primary, definition)
+	poolsMtx.Lock()
+	defer poolsMtx.Unlock()
+	if pool, ok := pools[key]; ok {
+		return pool, nil
+	}
+	pool, err := NewEndpointPool(id, primary, options, additional)
+	if err != nil {
+		return nil, err
+	}
+	pools[key] = pool
+	return pool, nil
+}
+
+func (pool *EndpointPool) GetId() string {
+	return pool.id
+}
+
+func (pool *EndpointPool) matches(u *url.URL) bool {
+	return u.Scheme == pool.primary.Scheme && u.Host == pool.primary.Host &&
+		strings.HasPrefix(u.Path, pool.primary.Path)
+}
+
+// returns endpoints in order they should be tried, quarantined endpoints are used only as the last resort
+func (pool *EndpointPool) candidates() []*endpoint {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	now := pool.now()
+	result := slices.Clone(pool.endpoints)
+	slices.SortStableFunc(result, func(a, b *endpoint) int {
+		aQuarantined, bQuarantined := a.isQuarantined(now), b.isQuarantined(now)
+		switch {
+		case aQuarantined && !bQuarantined:
+			return 1
+		case !aQuarantined && bQuarantined:
+			return -1
+		case aQuarantined && bQuarantined:
+			return a.quarantinedUntil.Compare(b.quarantinedUntil)
+		}
+		if a.priority != b.priority {
+			return a.priority - b.priority
+		}
+		// prefer noticeably faster endpoints within the same priority
+		if a.latency-b.latency > LATENCY_PRIORITY_TOLERANCE {
+			return 1
+		}
+		if b.latency-a.latency > LATENCY_PRIORITY_TOLERANCE {
+			return -1
+		}
+		return 0
+	})
+	return result
+}
+
+func (pool *EndpointPool) reportSuccess(e *endpoint, latency time.Duration) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = time.Duration(LATENCY_SMOOTHING_FACTOR*float64(latency) + (1-LATENCY_SMOOTHING_FACTOR)*float64(e.latency))
+	}
+	e.failures = 0
+	e.quarantineDuration = 0
+	e.quarantinedUntil = time.Time{}
+}
+
+func (pool *EndpointPool) reportFailure(e *endpoint, reason string) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	e.failures++
+	slog.Debug("endpoint request failed", "pool", pool.id, "endpoint", e.url.Host, "failures", e.failures, "reason", reason)
+	if e.failures < QUARANTINE_AFTER_FAILURES {
+		return
+	}
+	if e.quarantineDuration == 0 {
+		e.quarantineDuration = MIN_QUARANTINE_DURATION
+	} else {
+		e.quarantineDuration = min(e.quarantineDuration*2, MAX_QUARANTINE_DURATION)
+	}
+	e.quarantinedUntil = pool.now().Add(e.quarantineDuration)
+	e.failures = 0
+	slog.Warn("endpoint quarantined", "pool", pool.id, "endpoint", e.url.Host, "until", e.quarantinedUntil.Format(time.RFC3339))
+}
+
+func (pool *EndpointPool) rewrite(req *http.Request, e *endpoint, body []byte) *http.Request {
+	result := req.Clone(req.Context())
+	u := *req.URL
+	u.Scheme = e.url.Scheme
+	u.Host = e.url.Host
+	u.Path = e.url.Path + strings.TrimPrefix(req.URL.Path, pool.primary.Path)
+	u.RawPath = ""
+	result.URL = &u
+	result.Host = e.url.Host
+	if body != nil {
+		result.Body = io.NopCloser(bytes.NewReader(body))
+		result.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return result
+}
+
+// cancelOnCloseBody releases request context once the response body is consumed
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelOnCloseBody) Close() error {
+	defer body.cancel()
+	return body.ReadCloser.Close()
+}
+
+// roundTripWithTimeout limits duration of a single attempt including reading of the response body
+func roundTripWithTimeout(base http.RoundTripper, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 {
+		return base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil || resp == nil {
+		cancel()
+		return resp, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// IsRetryableStatus reports whether the request may succeed if repeated later (server errors, throttling)
+func IsRetryableStatus(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
+
+type singleAttemptKey struct{}
+
+// WithSingleAttempt marks requests of callers retrying on their own. Such requests are sent only
+// to the healthiest endpoint of the pool and failures are returned to the caller, health of the endpoint
+// is still tracked so repeated requests fail over once the endpoint is quarantined.
+func WithSingleAttempt(ctx context.Context) context.Context {
+	return context.WithValue(ctx, singleAttemptKey{}, true)
+}
+
+func isSingleAttempt(req *http.Request) bool {
+	singleAttempt, _ := req.Context().Value(singleAttemptKey{}).(bool)
+	return singleAttempt
+}
+
+// isIdempotent reports whether the request may be sent to another endpoint after it possibly reached the previous one,
+// requests changing state (e.g. operation injections) are idempotent only if they carry an idempotency key
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// isNotDelivered reports whether the request failed before it could reach the endpoint
+func isNotDelivered(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// FailoverTransport routes requests addressed to a pool primary endpoint
+// to the healthiest endpoint of the pool and fails over to the others on errors.
+// Non-idempotent requests fail over only if they could not be delivered to the endpoint.
+// Each attempt is limited by the endpoint timeout or the default timeout of the transport.
+type FailoverTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+	pools   []*EndpointPool
+}
+
+func NewFailoverTransport(base http.RoundTripper, timeout time.Duration, pools ...*EndpointPool) *FailoverTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &FailoverTransport{
+		base:    base,
+		timeout: timeout,
+		pools:   pools,
+	}
+}
+
+func (t *FailoverTransport) getPool(u *url.URL) *EndpointPool {
+	for _, pool := range t.pools {
+		if pool.matches(u) {
+			return pool
+		}
+	}
+	return nil
+}
+
+func (t *FailoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	pool := t.getPool(req.URL)
+	if pool == nil {
+		return roundTripWithTimeout(t.base, req, t.timeout)
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	idempotent := isIdempotent(req)
+	candidates := pool.candidates()
+	if isSingleAttempt(req) {
+		candidates = candidates[:1]
+	}
+	var lastErr error
+	for i, e := range candidates {
+		start := time.Now()
+		base, timeout := t.base, t.timeout
+		if e.transport != nil {
+			base = e.transport
+		}
+		if e.timeout > 0 {
+			timeout = e.timeout
+		}
+		resp, err := roundTripWithTimeout(base, pool.rewrite(req, e, body), timeout)
+		if req.Context().Err() != nil {
+			// canceled by caller, not a fault of the endpoint
+			return resp, err
+		}
+		isLast := i == len(candidates)-1
+		switch {
+		case err != nil:
+			// also reached on timeout of the attempt, hanging endpoints are quarantined as any other failing endpoint
+			pool.reportFailure(e, err.Error())
+			lastErr = err
+			if !idempotent && !isNotDelivered(err) {
+				return nil, err
+			}
+		case IsRetryableStatus(resp.StatusCode):
+			pool.reportFailure(e, resp.Status)
+			if isLast || !idempotent {
+				return resp, nil
+			}
+			resp.Body.Close()
+		default:
+			pool.reportSuccess(e, time.Since(start))
+			return resp, nil
+		}
+		if !isLast {
+			slog.Debug("failing over to next endpoint", "pool", pool.id, "from", e.url.Host, "to", candidates[i+1].url.Host)
+		}
+	}
+	return nil, errors.Join(ErrNoEndpointAvailable, fmt.Errorf("pool: %s", pool.id), lastErr)
+}
+
+// NewHttpClient creates http client failing over between endpoints of the pools.
+// The timeout applies to each attempt unless the endpoint has its own.
+func NewHttpClient(timeout time.Duration, pools ...*EndpointPool) *http.Client {
+	return &http.Client{
+		Transport: NewFailoverTransport(http.DefaultTransport, timeout, pools...),
+	}
+}
@@ -0,0 +1,203 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(status int, body string, hits *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(status)
+		w.Write([]byte(body + r.URL.Path))
+	}))
+}
+
+func TestFailoverToHealthyEndpoint(t *testing.T) {
+	assert := assert.New(t)
+
+	var brokenHits, healthyHits atomic.Int32
+	broken := newTestServer(http.StatusBadGateway, "broken", &brokenHits)
+	defer broken.Close()
+	healthy := newTestServer(http.StatusOK, "healthy", &healthyHits)
+	defer healthy.Close()
+
+	pool, err := NewEndpointPool("rpc", broken.URL+"/", nil, []EndpointDefinition{{Url: healthy.URL + "/", Priority: 1}})
+	assert.Nil(err)
+	client := NewHttpClient(5*time.Second, pool)
+
+	for i := 0; i < QUARANTINE_AFTER_FAILURES; i++ {
+		resp, err := client.Get(broken.URL + "/chains/main/blocks/head")
+		assert.Nil(err)
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal("healthy/chains/main/blocks/head", string(data))
+	}
+	assert.Equal(int32(QUARANTINE_AFTER_FAILURES), brokenHits.Load())
+
+	// broken endpoint is quarantined now and should not be hit anymore
+	resp, err := client.Get(broken.URL + "/chains/main/blocks/head")
+	assert.Nil(err)
+	resp.Body.Close()
+	assert.Equal(int32(QUARANTINE_AFTER_FAILURES), brokenHits.Load())
+	assert.Equal(int32(QUARANTINE_AFTER_FAILURES+1), healthyHits.Load())
+}
+
+func TestQuarantineExpires(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	pool, err := NewEndpointPool("mvkt", "https://a.example/", nil, []EndpointDefinition{{Url: "https://b.example/", Priority: 1}})
+	assert.Nil(err)
+	pool.now = func() time.Time { return now }
+
+	primary := pool.endpoints[0]
+	for i := 0; i < QUARANTINE_AFTER_FAILURES; i++ {
+		pool.reportFailure(primary, "test")
+	}
+	assert.Equal("b.example", pool.candidates()[0].url.Host)
+
+	now = now.Add(MIN_QUARANTINE_DURATION + time.Second)
+	assert.Equal("a.example", pool.candidates()[0].url.Host)
+
+	// repeated quarantine doubles its duration
+	for i := 0; i < QUARANTINE_AFTER_FAILURES; i++ {
+		pool.reportFailure(primary, "test")
+	}
+	assert.Equal(2*MIN_QUARANTINE_DURATION, primary.quarantineDuration)
+}
+
+func TestUnmatchedRequestsPassThrough(t *testing.T) {
+	assert := assert.New(t)
+
+	var hits atomic.Int32
+	server := newTestServer(http.StatusOK, "other", &hits)
+	defer server.Close()
+
+	pool, err := NewEndpointPool("rpc", "https://rpc.example/", nil, nil)
+	assert.Nil(err)
+	client := NewHttpClient(5*time.Second, pool)
+
+	resp, err := client.Get(server.URL + "/test")
+	assert.Nil(err)
+	resp.Body.Close()
+	assert.Equal(int32(1), hits.Load())
+}
+
+func TestFailoverFromHangingEndpoint(t *testing.T) {
+	assert := assert.New(t)
+
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer hanging.Close()
+	defer close(release)
+	var healthyHits atomic.Int32
+	healthy := newTestServer(http.StatusOK, "healthy", &healthyHits)
+	defer healthy.Close()
+
+	pool, err := NewEndpointPool("rpc", hanging.URL+"/", nil, []EndpointDefinition{{Url: healthy.URL + "/", Priority: 1}})
+	assert.Nil(err)
+	client := NewHttpClient(50*time.Millisecond, pool)
+
+	for i := 0; i < QUARANTINE_AFTER_FAILURES; i++ {
+		resp, err := client.Get(hanging.URL + "/chains/main/blocks/head")
+		assert.Nil(err)
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal("healthy/chains/main/blocks/head", string(data))
+	}
+	assert.True(pool.endpoints[0].isQuarantined(time.Now()))
+	assert.Equal(healthy.URL, pool.candidates()[0].url.Scheme+"://"+pool.candidates()[0].url.Host)
+}
+
+func TestNonIdempotentRequestsAreNotRepeated(t *testing.T) {
+	assert := assert.New(t)
+
+	var brokenHits, healthyHits atomic.Int32
+	broken := newTestServer(http.StatusInternalServerError, "broken", &brokenHits)
+	defer broken.Close()
+	healthy := newTestServer(http.StatusOK, "healthy", &healthyHits)
+	defer healthy.Close()
+
+	pool, err := NewEndpointPool("rpc", broken.URL+"/", nil, []EndpointDefinition{{Url: healthy.URL + "/", Priority: 1}})
+	assert.Nil(err)
+	client := NewHttpClient(5*time.Second, pool)
+
+	// injection may have been processed by the failing node, it must not be injected again elsewhere
+	resp, err := client.Post(broken.URL+"/injection/operation", "application/json", strings.NewReader(`"00"`))
+	assert.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(int32(1), brokenHits.Load())
+	assert.Equal(int32(0), healthyHits.Load())
+
+	// unless the request carries idempotency key
+	req, err := http.NewRequest(http.MethodPost, broken.URL+"/injection/operation", strings.NewReader(`"00"`))
+	assert.Nil(err)
+	req.Header.Set("Idempotency-Key", "test")
+	resp, err = client.Do(req)
+	assert.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(int32(1), healthyHits.Load())
+
+	// requests which never reached the node are safe to send elsewhere
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	pool, err = NewEndpointPool("rpc", unreachable.URL+"/", nil, []EndpointDefinition{{Url: healthy.URL + "/", Priority: 1}})
+	assert.Nil(err)
+	client = NewHttpClient(5*time.Second, pool)
+	resp, err = client.Post(unreachable.URL+"/injection/operation", "application/json", strings.NewReader(`"00"`))
+	assert.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(int32(2), healthyHits.Load())
+}
+
+func TestSingleAttemptRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	var brokenHits, healthyHits atomic.Int32
+	broken := newTestServer(http.StatusTooManyRequests, "broken", &brokenHits)
+	defer broken.Close()
+	healthy := newTestServer(http.StatusOK, "healthy", &healthyHits)
+	defer healthy.Close()
+
+	pool, err := NewEndpointPool("mvkt", broken.URL+"/", nil, []EndpointDefinition{{Url: healthy.URL + "/", Priority: 1}})
+	assert.Nil(err)
+	client := NewHttpClient(5*time.Second, pool)
+
+	// caller retries on its own, throttled response is returned instead of failing over
+	for i := 0; i < QUARANTINE_AFTER_FAILURES; i++ {
+		req, err := http.NewRequestWithContext(WithSingleAttempt(context.Background()), http.MethodGet, broken.URL+"/v1/head", nil)
+		assert.Nil(err)
+		resp, err := client.Do(req)
+		assert.Nil(err)
+		resp.Body.Close()
+		assert.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	}
+	assert.Equal(int32(QUARANTINE_AFTER_FAILURES), brokenHits.Load())
+	assert.Equal(int32(0), healthyHits.Load())
+
+	// retries reach the healthy endpoint once the throttling one is quarantined
+	req, err := http.NewRequestWithContext(WithSingleAttempt(context.Background()), http.MethodGet, broken.URL+"/v1/head", nil)
+	assert.Nil(err)
+	resp, err := client.Do(req)
+	assert.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal(int32(1), healthyHits.Load())
+}
@@ -0,0 +1,60 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeHttpOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	network := &HttpOptions{Timeout: 10, Proxy: "http://proxy:3128", Headers: map[string]string{"X-Api-Key": "network", "X-Client": "mavpay"}}
+	endpoint := &HttpOptions{Timeout: 30, Headers: map[string]string{"X-Api-Key": "endpoint"}}
+
+	merged := network.Merge(endpoint)
+	assert.Equal(int64(30), merged.Timeout)
+	assert.Equal("http://proxy:3128", merged.Proxy)
+	assert.Equal(map[string]string{"X-Api-Key": "endpoint", "X-Client": "mavpay"}, merged.Headers)
+	// original options are not modified
+	assert.Equal("network", network.Headers["X-Api-Key"])
+
+	var empty *HttpOptions
+	assert.Equal(endpoint, empty.Merge(endpoint))
+	assert.Equal(5*time.Second, empty.GetTimeout(5*time.Second))
+
+	assert.NotNil((&HttpOptions{ClientCert: "cert.pem"}).Validate())
+	assert.NotNil((&HttpOptions{Proxy: "not a url"}).Validate())
+	assert.NotNil((&HttpOptions{CaBundle: "/nonexistent/ca.pem"}).Validate())
+}
+
+func TestEndpointHttpOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Second)
+		w.Write([]byte("slow"))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Api-Key")))
+	}))
+	defer fast.Close()
+
+	pool, err := NewEndpointPool("rpc", slow.URL+"/", &HttpOptions{Timeout: 1}, []EndpointDefinition{
+		{Url: fast.URL + "/", Priority: 1, Http: &HttpOptions{Headers: map[string]string{"X-Api-Key": "secret"}}},
+	})
+	assert.Nil(err)
+	client := NewHttpClient(time.Minute, pool)
+
+	// slow primary times out on its own timeout and the request is served by the next endpoint with its headers
+	resp, err := client.Get(slow.URL + "/chains/main/blocks/head")
+	assert.Nil(err)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal("secret", string(data))
+}
@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits rate of requests. Tokens are refilled continuously at the given rate
+// up to the burst size, every request consumes one token.
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mtx    sync.Mutex
+	now    func() time.Time
+}
+
+// NewTokenBucket creates bucket refilled with rate tokens per second, non-positive rate disables limiting
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token and returns how long the caller has to wait before using it
+func (bucket *TokenBucket) reserve() time.Duration {
+	bucket.mtx.Lock()
+	defer bucket.mtx.Unlock()
+	now := bucket.now()
+	bucket.tokens = min(bucket.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*bucket.rate)
+	bucket.last = now
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / bucket.rate * float64(time.Second))
+}
+
+// Wait blocks until a token is available or the context is done
+func (bucket *TokenBucket) Wait(ctx context.Context) error {
+	if bucket == nil || bucket.rate <= 0 {
+		return ctx.Err()
+	}
+	delay := bucket.reserve()
+	if delay == 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// GetRetryAfter parses Retry-After header of the response, both delay seconds and http date are supported.
+// The delay is capped by maximum so a misbehaving server can not stall the caller indefinitely.
+func GetRetryAfter(resp *http.Response, maximum time.Duration) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, maximum), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return min(max(time.Until(date), 0), maximum), true
+	}
+	return 0, false
+}
+
+// GetBackoffDelay returns exponential backoff delay of the attempt (starting with 0) capped by maximum
+func GetBackoffDelay(attempt int, base time.Duration, maximum time.Duration) time.Duration {
+	delay := base
+	for i := 0; i < attempt && delay < maximum; i++ {
+		delay *= 2
+	}
+	return min(delay, maximum)
+}
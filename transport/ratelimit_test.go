@@ -0,0 +1,60 @@
+package transport
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	bucket := NewTokenBucket(2, 2)
+	bucket.now = func() time.Time { return now }
+	bucket.last = now
+
+	// burst is served immediately
+	assert.Equal(time.Duration(0), bucket.reserve())
+	assert.Equal(time.Duration(0), bucket.reserve())
+	// next token is available after 1/rate
+	assert.Equal(500*time.Millisecond, bucket.reserve())
+	assert.Equal(time.Second, bucket.reserve())
+
+	now = now.Add(10 * time.Second)
+	assert.Equal(time.Duration(0), bucket.reserve())
+}
+
+func TestRetryAfterAndBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	resp := &http.Response{Header: http.Header{}}
+	_, ok := GetRetryAfter(resp, time.Minute)
+	assert.False(ok)
+
+	resp.Header.Set("Retry-After", "7")
+	delay, ok := GetRetryAfter(resp, time.Minute)
+	assert.True(ok)
+	assert.Equal(7*time.Second, delay)
+
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	delay, ok = GetRetryAfter(resp, time.Minute)
+	assert.True(ok)
+	assert.Equal(time.Duration(0), delay)
+
+	// excessive delays are capped
+	resp.Header.Set("Retry-After", "86400")
+	delay, ok = GetRetryAfter(resp, time.Minute)
+	assert.True(ok)
+	assert.Equal(time.Minute, delay)
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	delay, ok = GetRetryAfter(resp, time.Minute)
+	assert.True(ok)
+	assert.Equal(time.Minute, delay)
+
+	assert.Equal(time.Second, GetBackoffDelay(0, time.Second, 10*time.Second))
+	assert.Equal(4*time.Second, GetBackoffDelay(2, time.Second, 10*time.Second))
+	assert.Equal(10*time.Second, GetBackoffDelay(10, time.Second, 10*time.Second))
+}
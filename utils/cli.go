@@ -188,6 +188,25 @@ func PrintReports(payouts []common.PayoutReport, header string, printTotals bool
 	payoutTable.Render()
 }
 
+func PrintCycleDataMismatches(mismatches []common.CycleDataMismatch, header string) {
+	if len(mismatches) == 0 {
+		return
+	}
+	mismatchTable := table.NewWriter()
+	mismatchTable.SetStyle(table.StyleLight)
+	mismatchTable.SetColumnConfigs([]table.ColumnConfig{{Number: 1, Align: text.AlignLeft}, {Number: 2, Align: text.AlignLeft}, {Number: 5, Align: text.AlignRight}})
+	mismatchTable.SetOutputMirror(os.Stdout)
+	mismatchTable.SetTitle(header)
+	mismatchTable.Style().Title.Align = text.AlignCenter
+
+	mismatchTable.AppendHeader(columnsAsInterfaces(mismatches[0].GetTableHeaders()), table.RowConfig{AutoMerge: true})
+	for _, mismatch := range mismatches {
+		row := replaceZeroFields(mismatch.ToTableRowData(), "-", false)
+		mismatchTable.AppendRow(columnsAsInterfaces(row), table.RowConfig{AutoMerge: false})
+	}
+	mismatchTable.Render()
+}
+
 func PrintCycleSummary(summary common.CyclePayoutSummary, header string) {
 	summaryTable := table.NewWriter()
 	summaryTable.SetStyle(table.StyleLight)
@@ -206,6 +225,13 @@ func PrintCycleSummary(summary common.CyclePayoutSummary, header string) {
 	summaryTable.AppendRow(table.Row{"Bond Income", common.MumavToMavS(summary.BondIncome.Int64())}, table.RowConfig{AutoMerge: false})
 	summaryTable.AppendRow(table.Row{"Fee Income", common.MumavToMavS(summary.FeeIncome.Int64())}, table.RowConfig{AutoMerge: false})
 	summaryTable.AppendRow(table.Row{"Income Total", common.MumavToMavS(summary.IncomeTotal.Int64())}, table.RowConfig{AutoMerge: false})
+	summaryTable.AppendSeparator()
+	summaryTable.AppendRow(table.Row{"Stakers", summary.Stakers}, table.RowConfig{AutoMerge: false})
+	summaryTable.AppendRow(table.Row{"Staking Rewards Edge", common.MumavToMavS(summary.StakingRewardsEdge.Int64())}, table.RowConfig{AutoMerge: false})
+	summaryTable.AppendRow(table.Row{"Staking Fees", common.MumavToMavS(summary.StakingFees.Int64())}, table.RowConfig{AutoMerge: false})
+	summaryTable.AppendRow(table.Row{"Staking Income", common.MumavToMavS(summary.StakingIncome.Int64())}, table.RowConfig{AutoMerge: false})
+	summaryTable.AppendSeparator()
+	summaryTable.AppendRow(table.Row{"Baker Income Total", common.MumavToMavS(summary.GetBakerIncomeTotal().Int64())}, table.RowConfig{AutoMerge: false})
 	summaryTable.Render()
 }
 
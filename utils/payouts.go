@@ -108,43 +108,71 @@ func FilterReportsByCycle(payouts []common.PayoutReport, cycle int64) []common.P
 	})
 }
 
+// payoutId identifies what was paid to the delegator, recipients of split payouts are not part of it,
+// so a delegator is not paid again if its split changes between runs
 type payoutId struct {
-	kind     enums.EPayoutKind
-	txKind   enums.EPayoutTransactionKind
-	contract string
-	token    string
-	address  string
+	kind      enums.EPayoutKind
+	txKind    enums.EPayoutTransactionKind
+	contract  string
+	token     string
+	delegator string
 }
 
-func FilterRecipesByReports(payouts []common.PayoutRecipe, reports []common.PayoutReport, collector common.CollectorEngine) ([]common.PayoutRecipe, []common.PayoutReport) {
-	paidOut := make(map[payoutId]common.PayoutReport)
+// paidPayoutId identifies a single paid report, recipient is set only for payouts split among several recipients
+type paidPayoutId struct {
+	payoutId
+	recipient string
+}
+
+func getPayoutId(kind enums.EPayoutKind, txKind enums.EPayoutTransactionKind, contract mavryk.Address, tokenId mavryk.Z, delegator mavryk.Address, recipient mavryk.Address, recipientShare float64) paidPayoutId {
+	addr := delegator.String()
+	if delegator.Equal(mavryk.ZeroAddress) {
+		addr = recipient.String()
+	}
+	id := paidPayoutId{payoutId: payoutId{kind, txKind, contract.String(), tokenId.String(), addr}}
+	if recipientShare > 0 {
+		id.recipient = recipient.String()
+	}
+	return id
+}
+
+func getReportPayoutId(report *common.PayoutReport) paidPayoutId {
+	return getPayoutId(report.Kind, report.TxKind, report.FAContract, report.FATokenId, report.Delegator, report.Recipient, report.RecipientShare)
+}
+
+func getRecipePayoutId(payout *common.PayoutRecipe) paidPayoutId {
+	return getPayoutId(payout.Kind, payout.TxKind, payout.FAContract, payout.FATokenId, payout.Delegator, payout.Recipient, payout.RecipientShare)
+}
+
+// FilterRecipesByReports removes payouts already paid according to the reports, operations of the reports are checked on chain
+// through statusChecker if available, so payouts of applied operations are recognized even if the report was not updated.
+// A paid payout covers the whole delegator. Remaining recipients of a split payout are paid only if the split
+// did not change since the paid part was reported, otherwise the delegator is considered paid.
+func FilterRecipesByReports(payouts []common.PayoutRecipe, reports []common.PayoutReport, statusChecker common.OperationStatusAwareTransactor) ([]common.PayoutRecipe, []common.PayoutReport) {
+	paidOut := make(map[paidPayoutId]common.PayoutReport)
 	validOpHashes := make(map[string]bool)
-	if collector == nil {
-		slog.Debug("collector undefined filtering payout recipes only by succcess status from reports")
+	if statusChecker == nil {
+		slog.Debug("operation status checker undefined filtering payout recipes only by succcess status from reports")
 	}
 
 	for _, report := range reports {
-		addr := report.Delegator.String()
-		if report.Delegator.Equal(mavryk.ZeroAddress) {
-			addr = report.Recipient.String()
-		}
-		payoutId := payoutId{report.Kind, report.TxKind, report.FAContract.String(), report.FATokenId.String(), addr}
-		if collector != nil && !report.OpHash.Equal(mavryk.ZeroOpHash) {
+		payoutId := getReportPayoutId(&report)
+		if statusChecker != nil && !report.OpHash.Equal(mavryk.ZeroOpHash) {
 			if _, ok := validOpHashes[report.OpHash.String()]; ok {
 				paidOut[payoutId] = report
 				continue
 			}
 
-			slog.Debug("checking with collector whether operation applied", "collector", collector.GetId(), "op_hash", report.OpHash.String())
-			paid, err := collector.WasOperationApplied(report.OpHash)
+			slog.Debug("checking whether operation applied", "op_hash", report.OpHash.String())
+			paid, err := statusChecker.GetOperationStatus(report.OpHash)
 			if err != nil {
-				slog.Warn("collector check failed", "op_hash", report.OpHash.String(), "error", err.Error())
+				slog.Warn("operation status check failed", "op_hash", report.OpHash.String(), "error", err.Error())
 			}
 			if paid == common.OPERATION_STATUS_APPLIED {
 				paidOut[payoutId] = report
 				validOpHashes[report.OpHash.String()] = true
 			}
-			// NOTE: in case we would like to rely only on collector status we could continue here
+			// NOTE: in case we would like to rely only on chain status we could continue here
 			// but reports are fairly reliable so we will continue to check them rn
 			// continue
 		}
@@ -155,13 +183,27 @@ func FilterRecipesByReports(payouts []common.PayoutRecipe, reports []common.Payo
 		}
 	}
 
+	recipeShares := make(map[paidPayoutId]float64, len(payouts))
+	for _, payout := range payouts {
+		recipeShares[getRecipePayoutId(&payout)] = payout.RecipientShare
+	}
+	// delegators paid in full or with a split which differs from the current one
+	paidDelegators := make(map[payoutId]bool)
+	for id, report := range paidOut {
+		if share, ok := recipeShares[id]; id.recipient == "" || !ok || share != report.RecipientShare {
+			paidDelegators[id.payoutId] = true
+		}
+	}
+
 	return lo.Filter(payouts, func(payout common.PayoutRecipe, _ int) bool {
-		addr := payout.Delegator.String()
-		if payout.Delegator.Equal(mavryk.ZeroAddress) {
-			addr = payout.Recipient.String()
+		id := getRecipePayoutId(&payout)
+		if _, ok := paidOut[id]; ok {
+			return false
+		}
+		if paidDelegators[id.payoutId] {
+			slog.Warn("delegator already paid, skipping payout", "delegator", payout.Delegator.String(), "recipient", payout.Recipient.String(), "kind", payout.Kind)
+			return false
 		}
-		payoutId := payoutId{payout.Kind, payout.TxKind, payout.FAContract.String(), payout.FATokenId.String(), addr}
-		_, ok := paidOut[payoutId]
-		return !ok
+		return true
 	}), lo.Values(paidOut)
 }
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/mavryk-network/mavpay/common"
+	"github.com/mavryk-network/mavpay/constants/enums"
+	"github.com/mavryk-network/mvgo/mavryk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterRecipesByReportsSplitChanged(t *testing.T) {
+	assert := assert.New(t)
+
+	delegator := mavryk.MustParseAddress("mv1DsVn1LCaMTS3DjpA3JRZWGcvAeFRqzaLa")
+	first := mavryk.MustParseAddress("mv1HCXRedE7zVSwmSqxDe3XZcMPLeF7xYqP3")
+	second := mavryk.MustParseAddress("mv1Qe2hoRHRHYxYCHzD8vUX2We8uEJrEdWAb")
+	third := mavryk.MustParseAddress("mv1V4h45W3p4e1sjSBvRkK2uYbvkTnSuHg8g")
+
+	recipe := func(recipient mavryk.Address, share float64) common.PayoutRecipe {
+		return common.PayoutRecipe{Delegator: delegator, Recipient: recipient, RecipientShare: share, Kind: enums.PAYOUT_KIND_DELEGATOR_REWARD, TxKind: enums.PAYOUT_TX_KIND_MAV}
+	}
+	report := func(recipient mavryk.Address, share float64) common.PayoutReport {
+		return common.PayoutReport{Delegator: delegator, Recipient: recipient, RecipientShare: share, Kind: enums.PAYOUT_KIND_DELEGATOR_REWARD, TxKind: enums.PAYOUT_TX_KIND_MAV, IsSuccess: true}
+	}
+
+	// split unchanged, only the unpaid recipient is paid
+	remaining, paid := FilterRecipesByReports([]common.PayoutRecipe{recipe(first, 0.5), recipe(second, 0.5)}, []common.PayoutReport{report(first, 0.5)}, nil)
+	assert.Len(paid, 1)
+	assert.Len(remaining, 1)
+	assert.Equal(second, remaining[0].Recipient)
+
+	// split changed since the delegator was paid
+	remaining, _ = FilterRecipesByReports([]common.PayoutRecipe{recipe(first, 0.3), recipe(third, 0.7)}, []common.PayoutReport{report(first, 0.5), report(second, 0.5)}, nil)
+	assert.Empty(remaining)
+
+	// delegator was paid in full before the split was configured
+	remaining, _ = FilterRecipesByReports([]common.PayoutRecipe{recipe(first, 0.5), recipe(second, 0.5)}, []common.PayoutReport{report(delegator, 0)}, nil)
+	assert.Empty(remaining)
+
+	// split removed after the delegator was paid
+	remaining, _ = FilterRecipesByReports([]common.PayoutRecipe{recipe(delegator, 0)}, []common.PayoutReport{report(first, 0.5)}, nil)
+	assert.Empty(remaining)
+}